@@ -0,0 +1,158 @@
+package alerts
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/indicators"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/ecetinerdem/forseer/valuation"
+)
+
+// SeriesData resolves symbol metrics from a price history ordered by date ascending
+type SeriesData struct {
+	bars   []types.PriceBar
+	closes []float64
+}
+
+func NewSeriesData(bars []types.PriceBar) *SeriesData {
+	closes := make([]float64, len(bars))
+	for i, bar := range bars {
+		closes[i] = bar.Close
+	}
+	return &SeriesData{bars: bars, closes: closes}
+}
+
+func (d *SeriesData) Metric(name string, args []float64) (float64, error) {
+	if len(d.bars) == 0 {
+		return 0, fmt.Errorf("no price history")
+	}
+	last := d.bars[len(d.bars)-1]
+
+	switch name {
+	case "close", "price":
+		return last.Close, nil
+	case "open":
+		return last.Open, nil
+	case "high":
+		return last.High, nil
+	case "low":
+		return last.Low, nil
+	case "volume":
+		return float64(last.Volume), nil
+	case "change":
+		return percentChange(d.closes)
+	case "move":
+		change, err := percentChange(d.closes)
+		return math.Abs(change), err
+	case "sma":
+		return latest(indicators.SMA(d.closes, int(args[0])), int(args[0]))
+	case "ema":
+		return latest(indicators.EMA(d.closes, int(args[0])), int(args[0]))
+	case "rsi":
+		period := 14
+		if len(args) > 0 {
+			period = int(args[0])
+		}
+		return latest(indicators.RSI(d.closes, period), period+1)
+	}
+
+	return 0, fmt.Errorf("unknown metric")
+}
+
+// PortfolioData resolves portfolio metrics from the daily value of a portfolio
+type PortfolioData struct {
+	values []float64
+	cost   float64
+}
+
+// NewPortfolioData values the holdings on every date any of them has a bar,
+// carrying each holding's last close forward over the dates it has none.
+// The history must be in the base currency the holdings' cost rates convert to, see fx.Converter.
+func NewPortfolioData(stocks []types.Stock, history map[string][]types.PriceBar) *PortfolioData {
+	dates := make(map[time.Time]bool)
+	var cost float64
+	for _, stock := range stocks {
+		valuation.ValueHolding(&stock)
+		cost += stock.CostBasis
+		for _, bar := range history[stock.Symbol] {
+			dates[bar.Date] = true
+		}
+	}
+
+	ordered := make([]time.Time, 0, len(dates))
+	for date := range dates {
+		ordered = append(ordered, date)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Before(ordered[j]) })
+
+	values := make([]float64, len(ordered))
+	for _, stock := range stocks {
+		bars := history[stock.Symbol]
+		next, price := 0, 0.0
+		for i, date := range ordered {
+			for next < len(bars) && !bars[next].Date.After(date) {
+				price = bars[next].Close
+				next++
+			}
+			values[i] += stock.Quantity * price
+		}
+	}
+
+	return &PortfolioData{values: values, cost: cost}
+}
+
+func (d *PortfolioData) Metric(name string, args []float64) (float64, error) {
+	if len(d.values) == 0 {
+		return 0, fmt.Errorf("no price history")
+	}
+	last := d.values[len(d.values)-1]
+
+	switch name {
+	case "value":
+		return last, nil
+	case "change":
+		return percentChange(d.values)
+	case "move":
+		change, err := percentChange(d.values)
+		return math.Abs(change), err
+	case "drawdown":
+		peak := 0.0
+		for _, value := range d.values {
+			peak = math.Max(peak, value)
+		}
+		if peak == 0 {
+			return 0, nil
+		}
+		return (peak - last) / peak * 100, nil
+	case "gain":
+		if d.cost == 0 {
+			return 0, fmt.Errorf("portfolio has no cost basis")
+		}
+		return (last - d.cost) / d.cost * 100, nil
+	}
+
+	return 0, fmt.Errorf("unknown metric")
+}
+
+// latest returns the last value of an indicator series, an error while it is still warming up
+func latest(series []float64, required int) (float64, error) {
+	if len(series) == 0 || math.IsNaN(series[len(series)-1]) {
+		return 0, fmt.Errorf("needs %d bars, have %d", required, len(series))
+	}
+	return series[len(series)-1], nil
+}
+
+// percentChange is the change of the last value against the previous one, in percent
+func percentChange(values []float64) (float64, error) {
+	if len(values) < 2 {
+		return 0, fmt.Errorf("needs 2 bars, have %d", len(values))
+	}
+	previous := values[len(values)-2]
+	if previous == 0 {
+		return 0, fmt.Errorf("previous value is zero")
+	}
+	return (values[len(values)-1] - previous) / previous * 100, nil
+}
@@ -0,0 +1,252 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/corporate"
+	"github.com/ecetinerdem/forseer/fx"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Store is the persistence the evaluator needs
+type Store interface {
+	GetEnabledAlerts(ctx context.Context) ([]types.Alert, error)
+	GetPriceHistory(ctx context.Context, symbol string, interval types.Interval, from, to time.Time) ([]types.PriceBar, error)
+	GetCorporateActions(ctx context.Context, symbol string) ([]types.CorporateAction, error)
+	GetPortfolioStocks(ctx context.Context, portfolioID string) ([]types.Stock, error)
+	GetPortfolioLedger(ctx context.Context, portfolioID string) ([]types.Transaction, *types.Portfolio, error)
+	SaveAlertState(ctx context.Context, alertID string, conditionMet bool, evaluatedAt time.Time) error
+	RecordAlertTrigger(ctx context.Context, trigger *types.AlertTrigger) (*types.AlertTrigger, error)
+}
+
+// Rates loads the exchange rates converting currencies to a base currency
+type Rates interface {
+	Converter(ctx context.Context, base string, currencies ...string) (*fx.Converter, error)
+}
+
+// Evaluator checks alerts against fresh prices. An alert fires when its condition turns true and
+// its cooldown has elapsed; a condition that stays true does not fire again until it has been false.
+type Evaluator struct {
+	store Store
+	rates Rates
+	now   func() time.Time
+	hooks []TriggerHook
+}
+
+// TriggerHook is called after an alert fires with the recorded trigger
+type TriggerHook func(ctx context.Context, trigger *types.AlertTrigger)
+
+func NewEvaluator(store Store, rates Rates) *Evaluator {
+	return &Evaluator{store: store, rates: rates, now: time.Now}
+}
+
+// OnTriggered registers a hook called whenever an alert fires
+func (e *Evaluator) OnTriggered(hook TriggerHook) {
+	e.hooks = append(e.hooks, hook)
+}
+
+// Compile parses an expression and checks it against the subject of an alert.
+// A subject named in the expression must match the symbol or portfolio given, when either is set;
+// the returned rule carries the resolved subject.
+func Compile(expression, symbol, portfolioID string) (*Rule, error) {
+	rule, err := Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	symbol = strings.ToUpper(symbol)
+	switch {
+	case rule.Subject == PortfolioSubject:
+		if symbol != "" {
+			return nil, fmt.Errorf("expression is about the portfolio but symbol %s was given", symbol)
+		}
+	case rule.Subject != "":
+		if portfolioID != "" {
+			return nil, fmt.Errorf("expression is about %s but a portfolio was given", rule.Subject)
+		}
+		if symbol != "" && symbol != rule.Subject {
+			return nil, fmt.Errorf("expression is about %s but symbol %s was given", rule.Subject, symbol)
+		}
+	case symbol != "" && portfolioID != "":
+		return nil, fmt.Errorf("an alert watches either a symbol or a portfolio, not both")
+	case symbol != "":
+		rule.Subject = symbol
+	case portfolioID != "":
+		rule.Subject = PortfolioSubject
+	default:
+		return nil, fmt.Errorf("expression must name a symbol or the portfolio, e.g. AAPL close < 150")
+	}
+
+	scope := ScopeSymbol
+	if rule.Subject == PortfolioSubject {
+		scope = ScopePortfolio
+	}
+	if err := rule.Validate(scope); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// Evaluate checks the alerts affected by a refresh. Symbol alerts are checked when their series was
+// refreshed, portfolio alerts are checked on every refresh.
+func (e *Evaluator) Evaluate(ctx context.Context, refreshed []types.TrackedSymbol) {
+	alertList, err := e.store.GetEnabledAlerts(ctx)
+	if err != nil {
+		log.Printf("Alert evaluation could not list alerts: %v", err)
+		return
+	}
+
+	fresh := make(map[types.TrackedSymbol]bool, len(refreshed))
+	for _, symbol := range refreshed {
+		fresh[symbol] = true
+	}
+
+	for _, alert := range alertList {
+		if alert.Symbol != "" && !fresh[types.TrackedSymbol{Symbol: alert.Symbol, Interval: alert.Interval}] {
+			continue
+		}
+
+		if err := e.evaluate(ctx, alert); err != nil {
+			log.Printf("Alert %s (%s) was not evaluated: %v", alert.ID, alert.Expression, err)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (e *Evaluator) evaluate(ctx context.Context, alert types.Alert) error {
+	rule, err := Compile(alert.Expression, alert.Symbol, alert.PortfolioID)
+	if err != nil {
+		return err
+	}
+
+	var data Data
+	if alert.PortfolioID != "" {
+		data, err = e.portfolioData(ctx, alert.PortfolioID)
+	} else {
+		data, err = e.seriesData(ctx, alert.Symbol, alert.Interval)
+	}
+	if err != nil {
+		return err
+	}
+
+	met, observed, err := rule.Eval(data)
+	if err != nil {
+		return err
+	}
+
+	now := e.now()
+	if err := e.store.SaveAlertState(ctx, alert.ID, met, now); err != nil {
+		return err
+	}
+
+	if !met || alert.ConditionMet {
+		return nil
+	}
+
+	cooldown := time.Duration(alert.CooldownMinutes) * time.Minute
+	if alert.LastTriggeredAt != nil && now.Sub(*alert.LastTriggeredAt) < cooldown {
+		return nil
+	}
+
+	values := make([]string, len(observed))
+	for i, observation := range observed {
+		values[i] = observation.String()
+	}
+
+	trigger, err := e.store.RecordAlertTrigger(ctx, &types.AlertTrigger{
+		AlertID:     alert.ID,
+		UserID:      alert.UserID,
+		Name:        alert.Name,
+		Symbol:      alert.Symbol,
+		PortfolioID: alert.PortfolioID,
+		Expression:  alert.Expression,
+		Observed:    strings.Join(values, ", "),
+		TriggeredAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range e.hooks {
+		hook(ctx, trigger)
+	}
+	return nil
+}
+
+func (e *Evaluator) seriesData(ctx context.Context, symbol string, interval types.Interval) (*SeriesData, error) {
+	bars, err := e.history(ctx, symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	return NewSeriesData(bars), nil
+}
+
+// history returns the price history of a symbol adjusted for its splits, so a split does not read as a crash
+func (e *Evaluator) history(ctx context.Context, symbol string, interval types.Interval) ([]types.PriceBar, error) {
+	bars, err := e.store.GetPriceHistory(ctx, symbol, interval, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := e.store.GetCorporateActions(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return corporate.Adjust(bars, actions), nil
+}
+
+// portfolioData values a portfolio in its base currency the way the API does: the daily closes at the rate
+// of their date and the cost lot by lot at the rates of the trade dates
+func (e *Evaluator) portfolioData(ctx context.Context, portfolioID string) (*PortfolioData, error) {
+	stocks, err := e.store.GetPortfolioStocks(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, portfolio, err := e.store.GetPortfolioLedger(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := fx.TransactionCurrencies(transactions)
+	for _, stock := range stocks {
+		currencies = append(currencies, stock.Currency)
+	}
+
+	converter, err := e.rates.Converter(ctx, portfolio.BaseCurrency, currencies...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+
+	costBases, err := converter.CostBases(transactions, portfolio.CostBasisMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[string][]types.PriceBar, len(stocks))
+	for i := range stocks {
+		if err := converter.Holding(&stocks[i], costBases); err != nil {
+			return nil, err
+		}
+
+		bars, err := e.history(ctx, stocks[i].Symbol, stocks[i].Interval)
+		if err != nil {
+			return nil, err
+		}
+
+		if history[stocks[i].Symbol], err = converter.Bars(bars, stocks[i].Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewPortfolioData(stocks, history), nil
+}
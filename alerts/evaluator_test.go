@@ -0,0 +1,140 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// fakeStore serves one price history and records what the evaluator saves
+type fakeStore struct {
+	alerts   []types.Alert
+	bars     []types.PriceBar
+	states   map[string]bool
+	triggers []types.AlertTrigger
+}
+
+func (s *fakeStore) GetEnabledAlerts(ctx context.Context) ([]types.Alert, error) {
+	return s.alerts, nil
+}
+
+func (s *fakeStore) GetPriceHistory(ctx context.Context, symbol string, interval types.Interval, from, to time.Time) ([]types.PriceBar, error) {
+	return s.bars, nil
+}
+
+func (s *fakeStore) GetCorporateActions(ctx context.Context, symbol string) ([]types.CorporateAction, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) GetPortfolioStocks(ctx context.Context, portfolioID string) ([]types.Stock, error) {
+	return nil, fmt.Errorf("no portfolios")
+}
+
+func (s *fakeStore) GetPortfolioLedger(ctx context.Context, portfolioID string) ([]types.Transaction, *types.Portfolio, error) {
+	return nil, nil, fmt.Errorf("no portfolios")
+}
+
+func (s *fakeStore) SaveAlertState(ctx context.Context, alertID string, conditionMet bool, evaluatedAt time.Time) error {
+	s.states[alertID] = conditionMet
+	return nil
+}
+
+func (s *fakeStore) RecordAlertTrigger(ctx context.Context, trigger *types.AlertTrigger) (*types.AlertTrigger, error) {
+	s.triggers = append(s.triggers, *trigger)
+	return trigger, nil
+}
+
+func TestEvaluateCooldown(t *testing.T) {
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	bars := []types.PriceBar{
+		{Date: now.AddDate(0, 0, -1), Close: 150},
+		{Date: now, Close: 140},
+	}
+
+	tests := []struct {
+		name          string
+		expression    string
+		conditionMet  bool // Result of the previous evaluation
+		lastTriggered *time.Time
+		cooldown      int
+		wantMet       bool
+		wantTrigger   bool
+	}{
+		{"turns true", "close < 145", false, nil, 60, true, true},
+		{"stays false", "close > 145", false, nil, 60, false, false},
+		{"stays true", "close < 145", true, ago(24 * time.Hour), 60, true, false},
+		{"turns false", "close > 145", true, ago(time.Minute), 60, false, false},
+		{"turns true within the cooldown", "close < 145", false, ago(30 * time.Minute), 60, true, false},
+		{"turns true after the cooldown", "close < 145", false, ago(61 * time.Minute), 60, true, true},
+		{"turns true at the end of the cooldown", "close < 145", false, ago(time.Hour), 60, true, true},
+		{"turns true without a cooldown", "close < 145", false, ago(time.Second), 0, true, true},
+		{"change turns true", "change < -5%", false, nil, 60, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := types.Alert{
+				ID:              "a1",
+				UserID:          "u1",
+				Symbol:          "AAPL",
+				Interval:        types.IntervalDaily,
+				Expression:      tt.expression,
+				CooldownMinutes: tt.cooldown,
+				Enabled:         true,
+				ConditionMet:    tt.conditionMet,
+				LastTriggeredAt: tt.lastTriggered,
+			}
+			store := &fakeStore{alerts: []types.Alert{alert}, bars: bars, states: make(map[string]bool)}
+
+			evaluator := NewEvaluator(store, nil)
+			evaluator.now = func() time.Time { return now }
+			var hooked int
+			evaluator.OnTriggered(func(ctx context.Context, trigger *types.AlertTrigger) { hooked++ })
+
+			evaluator.Evaluate(context.Background(), []types.TrackedSymbol{{Symbol: "AAPL", Interval: types.IntervalDaily}})
+
+			met, saved := store.states["a1"]
+			if !saved {
+				t.Fatal("alert state was not saved")
+			}
+			if met != tt.wantMet {
+				t.Errorf("condition met %v, want %v", met, tt.wantMet)
+			}
+
+			wantTriggers := 0
+			if tt.wantTrigger {
+				wantTriggers = 1
+			}
+			if len(store.triggers) != wantTriggers {
+				t.Fatalf("recorded %d triggers, want %d", len(store.triggers), wantTriggers)
+			}
+			if hooked != len(store.triggers) {
+				t.Errorf("hooks called %d times for %d triggers", hooked, len(store.triggers))
+			}
+			if tt.wantTrigger && !store.triggers[0].TriggeredAt.Equal(now) {
+				t.Errorf("triggered at %v, want %v", store.triggers[0].TriggeredAt, now)
+			}
+		})
+	}
+}
+
+func TestEvaluateSkipsSeriesNotRefreshed(t *testing.T) {
+	store := &fakeStore{
+		alerts: []types.Alert{{ID: "a1", Symbol: "AAPL", Interval: types.IntervalDaily, Expression: "close > 0", Enabled: true}},
+		bars:   []types.PriceBar{{Date: time.Now(), Close: 1}},
+		states: make(map[string]bool),
+	}
+
+	NewEvaluator(store, nil).Evaluate(context.Background(), []types.TrackedSymbol{{Symbol: "AAPL", Interval: types.IntervalWeekly}})
+
+	if len(store.states) != 0 || len(store.triggers) != 0 {
+		t.Errorf("evaluated an alert whose series was not refreshed")
+	}
+}
@@ -0,0 +1,479 @@
+package alerts
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rule is a parsed alert expression. The grammar is
+//
+//	rule       = [subject] or
+//	or         = and { "or" and }
+//	and        = comparison { "and" comparison }
+//	comparison = operand ( "<" | "<=" | ">" | ">=" | "==" | "!=" ) operand | "(" or ")"
+//	operand    = number [ "%" ] | metric [ "(" number { "," number } ")" ]
+//
+// where subject is a ticker or "portfolio", e.g. "AAPL close < 150" or "rsi(14) > 70 and move > 5%".
+// Percentages are plain numbers, change, move and drawdown are expressed in percent.
+type Rule struct {
+	Subject string // Upper-cased ticker or "PORTFOLIO", empty when the expression names none
+	root    node
+}
+
+// PortfolioSubject is the subject of rules over a portfolio's value
+const PortfolioSubject = "PORTFOLIO"
+
+// Scope selects which metrics a rule may use
+type Scope int
+
+const (
+	ScopeSymbol Scope = iota
+	ScopePortfolio
+)
+
+// metricArity lists the metrics of each scope with the number of arguments they take.
+// A negative arity means the single argument is optional.
+var metricArity = map[Scope]map[string]int{
+	ScopeSymbol: {
+		"close": 0, "price": 0, "open": 0, "high": 0, "low": 0, "volume": 0,
+		"change": 0, "move": 0, "sma": 1, "ema": 1, "rsi": -1,
+	},
+	ScopePortfolio: {
+		"value": 0, "change": 0, "move": 0, "drawdown": 0, "gain": 0,
+	},
+}
+
+// Data resolves the metrics of a rule
+type Data interface {
+	Metric(name string, args []float64) (float64, error)
+}
+
+// Observation is the value a metric had during an evaluation
+type Observation struct {
+	Metric string
+	Value  float64
+}
+
+func (o Observation) String() string {
+	return fmt.Sprintf("%s = %s", o.Metric, strconv.FormatFloat(o.Value, 'f', -1, 64))
+}
+
+// Parse parses an alert expression
+func Parse(expression string) (*Rule, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	p := &parser{tokens: tokens}
+	rule := &Rule{}
+
+	// A leading identifier followed by another identifier is the subject
+	if len(tokens) > 1 && tokens[0].kind == tokenIdent && tokens[1].kind == tokenIdent && !isKeyword(tokens[1].text) {
+		rule.Subject = strings.ToUpper(tokens[0].text)
+		p.pos = 1
+	}
+
+	rule.root, err = p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+
+	if _, ok := rule.root.(*logicalNode); !ok {
+		if _, ok := rule.root.(*comparisonNode); !ok {
+			return nil, fmt.Errorf("expression must compare values, e.g. close < 150")
+		}
+	}
+
+	return rule, nil
+}
+
+// Validate checks that every metric exists in the scope and has the right arguments
+func (r *Rule) Validate(scope Scope) error {
+	return r.root.validate(metricArity[scope])
+}
+
+// Eval evaluates the rule and returns the metric values it read
+func (r *Rule) Eval(data Data) (bool, []Observation, error) {
+	var observed []Observation
+	value, err := r.root.eval(data, &observed)
+	if err != nil {
+		return false, observed, err
+	}
+	return value != 0, observed, nil
+}
+
+type node interface {
+	eval(data Data, observed *[]Observation) (float64, error)
+	validate(metrics map[string]int) error
+}
+
+type numberNode struct {
+	value float64
+}
+
+func (n *numberNode) eval(Data, *[]Observation) (float64, error) { return n.value, nil }
+func (n *numberNode) validate(map[string]int) error              { return nil }
+
+type metricNode struct {
+	name string
+	args []float64
+}
+
+func (n *metricNode) label() string {
+	if len(n.args) == 0 {
+		return n.name
+	}
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		args[i] = strconv.FormatFloat(arg, 'f', -1, 64)
+	}
+	return n.name + "(" + strings.Join(args, ",") + ")"
+}
+
+func (n *metricNode) eval(data Data, observed *[]Observation) (float64, error) {
+	value, err := data.Metric(n.name, n.args)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", n.label(), err)
+	}
+	*observed = append(*observed, Observation{Metric: n.label(), Value: value})
+	return value, nil
+}
+
+func (n *metricNode) validate(metrics map[string]int) error {
+	arity, ok := metrics[n.name]
+	if !ok {
+		return fmt.Errorf("unknown metric %q", n.name)
+	}
+
+	switch {
+	case arity < 0 && len(n.args) > -arity:
+		return fmt.Errorf("%s takes at most %d argument", n.name, -arity)
+	case arity >= 0 && len(n.args) != arity:
+		return fmt.Errorf("%s takes %d argument(s)", n.name, arity)
+	}
+
+	for _, arg := range n.args {
+		if arg < 1 || arg != math.Trunc(arg) {
+			return fmt.Errorf("%s period must be a positive whole number", n.name)
+		}
+	}
+
+	return nil
+}
+
+type comparisonNode struct {
+	op          string
+	left, right node
+}
+
+func (n *comparisonNode) eval(data Data, observed *[]Observation) (float64, error) {
+	left, err := n.left.eval(data, observed)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(data, observed)
+	if err != nil {
+		return 0, err
+	}
+
+	var result bool
+	switch n.op {
+	case "<":
+		result = left < right
+	case "<=":
+		result = left <= right
+	case ">":
+		result = left > right
+	case ">=":
+		result = left >= right
+	case "==":
+		result = left == right
+	case "!=":
+		result = left != right
+	}
+
+	if result {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (n *comparisonNode) validate(metrics map[string]int) error {
+	if err := n.left.validate(metrics); err != nil {
+		return err
+	}
+	return n.right.validate(metrics)
+}
+
+type logicalNode struct {
+	op          string // "and" or "or"
+	left, right node
+}
+
+func (n *logicalNode) eval(data Data, observed *[]Observation) (float64, error) {
+	left, err := n.left.eval(data, observed)
+	if err != nil {
+		return 0, err
+	}
+
+	// Short circuit
+	if n.op == "and" && left == 0 {
+		return 0, nil
+	}
+	if n.op == "or" && left != 0 {
+		return 1, nil
+	}
+
+	return n.right.eval(data, observed)
+}
+
+func (n *logicalNode) validate(metrics map[string]int) error {
+	if err := n.left.validate(metrics); err != nil {
+		return err
+	}
+	return n.right.validate(metrics)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() *token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for t := p.peek(); t != nil && t.kind == tokenIdent && strings.EqualFold(t.text, "or"); t = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "or", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for t := p.peek(); t != nil && t.kind == tokenIdent && strings.EqualFold(t.text, "and"); t = p.peek() {
+		p.pos++
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "and", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	if t := p.peek(); t != nil && t.kind == tokenLParen {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t == nil || t.kind != tokenRParen {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t == nil || t.kind != tokenOp {
+		return nil, fmt.Errorf("expected a comparison operator after %s", describe(left))
+	}
+	p.pos++
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return &comparisonNode{op: t.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	switch t.kind {
+	case tokenNumber:
+		p.pos++
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		if next := p.peek(); next != nil && next.kind == tokenPercent {
+			p.pos++
+		}
+		return &numberNode{value: value}, nil
+
+	case tokenIdent:
+		if isKeyword(t.text) {
+			return nil, fmt.Errorf("unexpected %q", t.text)
+		}
+		p.pos++
+		metric := &metricNode{name: strings.ToLower(t.text)}
+
+		if next := p.peek(); next != nil && next.kind == tokenLParen {
+			p.pos++
+			for {
+				arg := p.peek()
+				if arg == nil || arg.kind != tokenNumber {
+					return nil, fmt.Errorf("%s arguments must be numbers", metric.name)
+				}
+				value, err := strconv.ParseFloat(arg.text, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid number %q", arg.text)
+				}
+				metric.args = append(metric.args, value)
+				p.pos++
+
+				sep := p.peek()
+				if sep == nil {
+					return nil, fmt.Errorf("missing closing parenthesis")
+				}
+				p.pos++
+				if sep.kind == tokenRParen {
+					break
+				}
+				if sep.kind != tokenComma {
+					return nil, fmt.Errorf("unexpected %q in %s arguments", sep.text, metric.name)
+				}
+			}
+		}
+
+		return metric, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func describe(n node) string {
+	switch n := n.(type) {
+	case *metricNode:
+		return n.label()
+	case *numberNode:
+		return strconv.FormatFloat(n.value, 'f', -1, 64)
+	}
+	return "operand"
+}
+
+func isKeyword(text string) bool {
+	return strings.EqualFold(text, "and") || strings.EqualFold(text, "or")
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenNumber
+	tokenOp
+	tokenPercent
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i])})
+
+		case unicode.IsDigit(r) || r == '.' || (r == '-' && i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.')):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i])})
+
+		case r == '<' || r == '>' || r == '=' || r == '!':
+			start := i
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			op := string(runes[start:i])
+			if op == "=" {
+				op = "=="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected \"!\", did you mean \"!=\"")
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op})
+
+		case r == '%':
+			tokens = append(tokens, token{kind: tokenPercent, text: "%"})
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ","})
+			i++
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+
+	return tokens, nil
+}
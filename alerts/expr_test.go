@@ -0,0 +1,154 @@
+package alerts
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// metrics resolves metrics by their label, e.g. "close" or "sma(50)"
+type metrics map[string]float64
+
+func (m metrics) Metric(name string, args []float64) (float64, error) {
+	label := (&metricNode{name: name, args: args}).label()
+	value, ok := m[label]
+	if !ok {
+		return 0, fmt.Errorf("no value")
+	}
+	return value, nil
+}
+
+func TestParseAndEval(t *testing.T) {
+	data := metrics{"close": 148.2, "sma(50)": 150, "sma(200)": 140, "rsi": 72, "rsi(7)": 25, "move": 6, "volume": 1e6}
+
+	tests := []struct {
+		expression  string
+		wantSubject string
+		wantMet     bool
+		wantSeen    []string // Metrics read, in order, short circuits skip the rest
+	}{
+		{"close < 150", "", true, []string{"close"}},
+		{"AAPL close >= 150", "AAPL", false, []string{"close"}},
+		{"brk.b close <= 148.2", "BRK.B", true, []string{"close"}},
+		{"close = 148.2", "", true, []string{"close"}},
+		{"close != 148.2", "", false, []string{"close"}},
+		{"rsi > 70 and move > 5%", "", true, []string{"rsi", "move"}},
+		{"rsi(7) > 70 and move > 5%", "", false, []string{"rsi(7)"}},
+		{"rsi(7) < 30 or close > 1000", "", true, []string{"rsi(7)"}},
+		{"close > 1000 or sma(50) > sma(200)", "", true, []string{"close", "sma(50)", "sma(200)"}},
+		{"(close > 1000 or rsi > 70) and volume >= 1000000", "", true, []string{"close", "rsi", "volume"}},
+		{"close > 1000 OR close < -1.5 or close < .5", "", false, []string{"close", "close", "close"}},
+		{"portfolio value > 100", "PORTFOLIO", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			rule, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if rule.Subject != tt.wantSubject {
+				t.Errorf("subject %q, want %q", rule.Subject, tt.wantSubject)
+			}
+			if tt.wantSubject == PortfolioSubject {
+				return
+			}
+
+			met, observed, err := rule.Eval(data)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if met != tt.wantMet {
+				t.Errorf("met %v, want %v", met, tt.wantMet)
+			}
+
+			var seen []string
+			for _, observation := range observed {
+				seen = append(seen, observation.Metric)
+			}
+			if !reflect.DeepEqual(seen, tt.wantSeen) {
+				t.Errorf("read %v, want %v", seen, tt.wantSeen)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    string
+	}{
+		{"", "empty"},
+		{"   ", "empty"},
+		{"close", "expected a comparison operator"},
+		{"close 150", "expected a comparison operator"},
+		{"150", "expected a comparison operator"},
+		{"close <", "unexpected end"},
+		{"close < 150 and", "unexpected end"},
+		{"close ! 150", "did you mean"},
+		{"close < 150 $", "unexpected character"},
+		{"(close < 150", "missing closing parenthesis"},
+		{"close < 150)", "unexpected \")\""},
+		{"sma(close) > 1", "arguments must be numbers"},
+		{"sma(50 > 1", "unexpected \">\""},
+		{"and < 1", "unexpected \"and\""},
+		{"close < 1..2", "invalid number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := Parse(tt.expression)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name        string
+		expression  string
+		symbol      string
+		portfolioID string
+		wantSubject string
+		wantErr     string
+	}{
+		{"subject in the expression", "aapl close < 150", "", "", "AAPL", ""},
+		{"subject from the symbol", "close < 150", "msft", "", "MSFT", ""},
+		{"matching symbol", "AAPL close < 150", "aapl", "", "AAPL", ""},
+		{"portfolio from the id", "drawdown > 10%", "", "p1", PortfolioSubject, ""},
+		{"portfolio in the expression", "portfolio gain < 0", "", "p1", PortfolioSubject, ""},
+		{"rsi with its default period", "close > 1 and rsi < 30", "AAPL", "", "AAPL", ""},
+		{"no subject", "close < 150", "", "", "", "must name a symbol or the portfolio"},
+		{"other symbol", "AAPL close < 150", "MSFT", "", "", "expression is about AAPL"},
+		{"symbol and portfolio", "close < 150", "AAPL", "p1", "", "either a symbol or a portfolio"},
+		{"symbol with a portfolio id", "AAPL close < 150", "", "p1", "", "a portfolio was given"},
+		{"portfolio with a symbol", "portfolio value > 1", "AAPL", "", "", "about the portfolio"},
+		{"portfolio metric on a symbol", "AAPL drawdown > 10", "", "", "", "unknown metric \"drawdown\""},
+		{"symbol metric on a portfolio", "portfolio rsi > 70", "", "", "", "unknown metric \"rsi\""},
+		{"missing period", "AAPL sma > 1", "", "", "", "sma takes 1 argument"},
+		{"too many periods", "AAPL rsi(14,2) > 1", "", "", "", "rsi takes at most 1 argument"},
+		{"fractional period", "AAPL ema(2.5) > 1", "", "", "", "positive whole number"},
+		{"zero period", "AAPL sma(0) > 1", "", "", "", "positive whole number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Compile(tt.expression, tt.symbol, tt.portfolioID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			if rule.Subject != tt.wantSubject {
+				t.Errorf("subject %q, want %q", rule.Subject, tt.wantSubject)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ecetinerdem/forseer/alerts"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultAlertCooldownMinutes = 60
+	defaultAlertTriggerLimit    = 50
+	maxAlertTriggerLimit        = 500
+)
+
+// HandleGetAlerts returns the authenticated user's alerts
+func (s *Server) HandleGetAlerts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	alertList, err := s.db.GetUserAlerts(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve alerts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(alertList); err != nil {
+		http.Error(w, "Could not encode alerts", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCreateAlert creates an alert, e.g. {"expression": "AAPL close < 150"} or
+// {"expression": "portfolio drawdown > 10%"} for the default portfolio
+func (s *Server) HandleCreateAlert(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	var req types.CreateAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Expression = strings.TrimSpace(req.Expression)
+	rule, err := alerts.Compile(req.Expression, strings.TrimSpace(req.Symbol), req.PortfolioID)
+	if err != nil {
+		http.Error(w, "Invalid alert expression: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	alert := &types.Alert{
+		Name:            strings.TrimSpace(req.Name),
+		Expression:      req.Expression,
+		Interval:        req.Interval,
+		CooldownMinutes: defaultAlertCooldownMinutes,
+		Enabled:         true,
+	}
+	if alert.Name == "" {
+		alert.Name = req.Expression
+	}
+	if req.CooldownMinutes != nil {
+		if *req.CooldownMinutes < 0 {
+			http.Error(w, "Cooldown cannot be negative", http.StatusBadRequest)
+			return
+		}
+		alert.CooldownMinutes = *req.CooldownMinutes
+	}
+	if alert.Interval == "" {
+		alert.Interval = types.IntervalDaily
+	}
+	if !alert.Interval.IsValid() {
+		http.Error(w, "Invalid interval, expected one of 1min, 5min, 15min, 60min, daily, daily_adjusted, weekly, monthly", http.StatusBadRequest)
+		return
+	}
+
+	if rule.Subject == alerts.PortfolioSubject {
+		// Resolve the requested portfolio, or the user's default portfolio
+		if req.PortfolioID != "" {
+			owns, err := s.db.UserOwnsPortfolio(ctx, user.ID, req.PortfolioID)
+			if err != nil {
+				http.Error(w, "Could not verify portfolio ownership", http.StatusInternalServerError)
+				return
+			}
+			if !owns {
+				http.Error(w, "Portfolio not found or you don't have access to it", http.StatusNotFound)
+				return
+			}
+			alert.PortfolioID = req.PortfolioID
+		} else {
+			portfolio, err := s.db.GetUserDefaultPortfolio(ctx, user.ID)
+			if err != nil {
+				http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+				return
+			}
+			alert.PortfolioID = portfolio.ID
+		}
+	} else {
+		// Fetch the full price series so the symbol is known and has history to evaluate
+		series, err := s.fetchPriceSeries(ctx, rule.Subject, alert.Interval)
+		if err != nil {
+			writeMarketDataError(w, err, "Error while fetching stock data")
+			return
+		}
+		alert.Symbol = series.Symbol
+	}
+
+	created, err := s.db.CreateUserAlert(ctx, user.ID, alert)
+	if err != nil {
+		http.Error(w, "Could not create alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		http.Error(w, "Could not encode alert", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetAlert returns an alert of the authenticated user
+func (s *Server) HandleGetAlert(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	alertID := chi.URLParam(r, "alertID")
+	if alertID == "" {
+		http.Error(w, "Alert ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	alert, err := s.db.GetUserAlert(ctx, user.ID, alertID)
+	if err != nil {
+		writeAlertError(w, err, "Could not retrieve alert")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(alert); err != nil {
+		http.Error(w, "Could not encode alert", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleUpdateAlert changes the name, expression, cooldown or enabled flag of an alert.
+// The expression must keep the subject of the alert.
+func (s *Server) HandleUpdateAlert(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	alertID := chi.URLParam(r, "alertID")
+	if alertID == "" {
+		http.Error(w, "Alert ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UpdateAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	alert, err := s.db.GetUserAlert(ctx, user.ID, alertID)
+	if err != nil {
+		writeAlertError(w, err, "Could not retrieve alert")
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			http.Error(w, "Alert name cannot be empty", http.StatusBadRequest)
+			return
+		}
+		alert.Name = name
+	}
+	if req.Expression != nil {
+		expression := strings.TrimSpace(*req.Expression)
+		if _, err := alerts.Compile(expression, alert.Symbol, alert.PortfolioID); err != nil {
+			http.Error(w, "Invalid alert expression: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		alert.Expression = expression
+	}
+	if req.CooldownMinutes != nil {
+		if *req.CooldownMinutes < 0 {
+			http.Error(w, "Cooldown cannot be negative", http.StatusBadRequest)
+			return
+		}
+		alert.CooldownMinutes = *req.CooldownMinutes
+	}
+	if req.Enabled != nil {
+		alert.Enabled = *req.Enabled
+	}
+
+	updated, err := s.db.UpdateUserAlert(ctx, user.ID, alert)
+	if err != nil {
+		writeAlertError(w, err, "Could not update alert")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		http.Error(w, "Could not encode alert", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeleteAlert deletes an alert and its trigger history
+func (s *Server) HandleDeleteAlert(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	alertID := chi.URLParam(r, "alertID")
+	if alertID == "" {
+		http.Error(w, "Alert ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteUserAlert(ctx, user.ID, alertID); err != nil {
+		writeAlertError(w, err, "Could not delete alert")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]string{
+		"message":  "Alert deleted successfully",
+		"alert_id": alertID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Could not encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetAlertTriggers returns the trigger history of the user's alerts, newest first.
+// Scoped to one alert under /alerts/{alertID}/triggers, limit query param defaults to 50.
+func (s *Server) HandleGetAlertTriggers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	limit := defaultAlertTriggerLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit, expected a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxAlertTriggerLimit)
+	}
+
+	alertID := chi.URLParam(r, "alertID")
+	if alertID != "" {
+		if _, err := s.db.GetUserAlert(ctx, user.ID, alertID); err != nil {
+			writeAlertError(w, err, "Could not retrieve alert")
+			return
+		}
+	}
+
+	triggers, err := s.db.GetUserAlertTriggers(ctx, user.ID, alertID, limit)
+	if err != nil {
+		http.Error(w, "Could not retrieve alert triggers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(triggers); err != nil {
+		http.Error(w, "Could not encode alert triggers", http.StatusInternalServerError)
+		return
+	}
+}
+
+// writeAlertError maps a missing alert to 404 and anything else to 500 with message
+func writeAlertError(w http.ResponseWriter, err error, message string) {
+	var notFoundErr *types.AlertNotFoundError
+	if errors.As(err, &notFoundErr) {
+		http.Error(w, "Alert not found or you don't have access to it", http.StatusNotFound)
+		return
+	}
+	http.Error(w, message, http.StatusInternalServerError)
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ecetinerdem/forseer/ledger"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/rebalance"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetAllocationTargets lists the allocation targets of the scoped portfolio
+func (s *Server) HandleGetAllocationTargets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	targets, err := s.db.GetAllocationTargets(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not retrieve allocation targets", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(targets); err != nil {
+		http.Error(w, "Could not encode allocation targets", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleSetAllocationTargets replaces the allocation targets of the scoped portfolio, e.g.
+// {"targets": [{"kind": "asset_class", "key": "equity", "weight": 0.6, "tolerance": 0.05}, ...]}
+func (s *Server) HandleSetAllocationTargets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	var req types.SetAllocationTargetsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	targets, err := rebalance.Targets(req.Targets)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := s.db.SetAllocationTargets(ctx, portfolioID, targets)
+	if err != nil {
+		http.Error(w, "Could not save allocation targets", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		http.Error(w, "Could not encode allocation targets", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetClassifications lists the sector and asset class of the classified symbols of the scoped portfolio
+func (s *Server) HandleGetClassifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	classifications, err := s.db.GetSymbolClassifications(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not retrieve classifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(classifications); err != nil {
+		http.Error(w, "Could not encode classifications", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleSetClassification sets the sector and asset class of a symbol in the scoped portfolio,
+// the symbol does not have to be held
+func (s *Server) HandleSetClassification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "symbol")))
+	if symbol == "" || len(symbol) > 10 {
+		http.Error(w, "Invalid symbol", http.StatusBadRequest)
+		return
+	}
+
+	var req types.SetClassificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	classification := &types.SymbolClassification{
+		PortfolioID: portfolioID,
+		Symbol:      symbol,
+		Sector:      strings.TrimSpace(req.Sector),
+		AssetClass:  strings.TrimSpace(req.AssetClass),
+	}
+	if len(classification.Sector) > 100 || len(classification.AssetClass) > 100 {
+		http.Error(w, "Sector and asset class cannot be longer than 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := s.db.SetSymbolClassification(ctx, classification)
+	if err != nil {
+		http.Error(w, "Could not save classification", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		http.Error(w, "Could not encode classification", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseRebalanceOptions reads the cash (money to add), min_trade (value), no_sells and whole_shares query params
+func parseRebalanceOptions(r *http.Request) (rebalance.Options, error) {
+	var opts rebalance.Options
+	query := r.URL.Query()
+
+	if value := query.Get("cash"); value != "" {
+		cash, err := strconv.ParseFloat(value, 64)
+		if err != nil || cash < 0 {
+			return opts, fmt.Errorf("invalid cash, expected an amount of zero or more")
+		}
+		opts.Cash = cash
+	}
+
+	if value := query.Get("min_trade"); value != "" {
+		minTrade, err := strconv.ParseFloat(value, 64)
+		if err != nil || minTrade < 0 {
+			return opts, fmt.Errorf("invalid min_trade, expected an amount of zero or more")
+		}
+		opts.MinTradeValue = minTrade
+	}
+
+	if value := query.Get("no_sells"); value != "" {
+		noSells, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid no_sells, expected true or false")
+		}
+		opts.NoSells = noSells
+	}
+
+	if value := query.Get("whole_shares"); value != "" {
+		wholeShares, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid whole_shares, expected true or false")
+		}
+		opts.WholeShares = wholeShares
+	}
+
+	return opts, nil
+}
+
+// HandleGetRebalancePlan proposes the trades that bring the scoped portfolio back within its allocation bands,
+// valued at the latest closes. The ledger's cash balance plus the cash query param funds the buys.
+// Query params: cash, min_trade, no_sells and whole_shares. Nothing is traded.
+func (s *Server) HandleGetRebalancePlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	opts, err := parseRebalanceOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	targets, err := s.db.GetAllocationTargets(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not retrieve allocation targets", http.StatusInternalServerError)
+		return
+	}
+
+	if len(targets) == 0 {
+		http.Error(w, "Portfolio has no allocation targets, set them with PUT targets first", http.StatusUnprocessableEntity)
+		return
+	}
+
+	portfolio, err := s.db.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	classifications, err := s.db.GetSymbolClassifications(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not retrieve classifications", http.StatusInternalServerError)
+		return
+	}
+
+	transactions, err := s.db.GetPortfolioTransactions(ctx, portfolioID, types.TransactionFilter{})
+	if err != nil {
+		http.Error(w, "Could not get transactions", http.StatusInternalServerError)
+		return
+	}
+
+	transactions, _, err = s.baseLedger(ctx, portfolio.BaseCurrency, transactions)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
+	book, err := ledger.Replay(transactions, portfolio.CostBasisMethod)
+	if err != nil {
+		http.Error(w, "Could not replay the ledger", http.StatusInternalServerError)
+		return
+	}
+	// Ledgers that only record trades run a negative balance, there is no cash to spend then
+	opts.Cash += max(book.Cash, 0)
+
+	if err := s.valuePortfolio(ctx, portfolio); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
+	bySymbol := make(map[string]types.SymbolClassification, len(classifications))
+	for _, c := range classifications {
+		bySymbol[c.Symbol] = c
+	}
+
+	held := make(map[string]bool, len(portfolio.Stocks))
+	holdings := make([]rebalance.Holding, 0, len(portfolio.Stocks))
+	for _, stock := range portfolio.Stocks {
+		held[stock.Symbol] = true
+		holdings = append(holdings, rebalance.Holding{
+			Symbol:     stock.Symbol,
+			Quantity:   stock.Quantity,
+			Price:      stock.Close * stock.FXRate,
+			Sector:     bySymbol[stock.Symbol].Sector,
+			AssetClass: bySymbol[stock.Symbol].AssetClass,
+		})
+	}
+
+	// Symbol targets not held yet are bought at their latest daily close, converted from the listing currency
+	prices := make(map[string]float64)
+	for _, target := range targets {
+		if target.Kind != types.AllocationSymbol || held[target.Key] || target.Weight == 0 {
+			continue
+		}
+		series, err := s.fetchPriceSeries(ctx, target.Key, types.IntervalDaily)
+		if err != nil {
+			writeMarketDataError(w, err, "Error while fetching stock data")
+			return
+		}
+		latest := series.Latest()
+		if latest == nil {
+			continue
+		}
+		currency := types.ListingCurrency(target.Key)
+		converter, err := s.fx.Converter(ctx, portfolio.BaseCurrency, currency)
+		if err != nil {
+			writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+			return
+		}
+		if prices[target.Key], err = converter.Convert(latest.Close, currency, latest.Date); err != nil {
+			writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+			return
+		}
+	}
+
+	plan := rebalance.Plan(holdings, targets, prices, opts)
+	plan.PortfolioID = portfolioID
+	plan.Currency = portfolio.BaseCurrency
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		http.Error(w, "Could not encode rebalance plan", http.StatusInternalServerError)
+		return
+	}
+}
@@ -4,9 +4,13 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/ecetinerdem/forseer/indicators"
 	"github.com/ecetinerdem/forseer/middleware"
+	services "github.com/ecetinerdem/forseer/service"
 	"github.com/ecetinerdem/forseer/types"
 	"github.com/go-chi/chi/v5"
 )
@@ -27,6 +31,12 @@ func (s *Server) HandleAnalyzeStock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Get the stock and verify ownership
 	stock, err := s.db.GetUserStockByID(ctx, user.ID, stockID)
 	if err != nil {
@@ -39,8 +49,33 @@ func (s *Server) HandleAnalyzeStock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.applyInterval(ctx, stock, interval); err != nil {
+		writeMarketDataError(w, err, "Error while fetching stock data")
+		return
+	}
+
+	if err := s.valueHolding(ctx, stock); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
+	history, err := s.priceHistory(ctx, stock.Symbol, stock.Interval, time.Time{}, time.Time{})
+	if err != nil {
+		writeMarketDataError(w, err, "Could not retrieve price history")
+		return
+	}
+
+	// Risk over the trailing year, the analysis goes ahead without it if it cannot be measured
+	risk, err := s.stockRisk(ctx, stock, defaultRiskParams())
+	if err != nil {
+		log.Printf("Stock analysis without risk of %s: %v", stock.Symbol, err)
+	}
+
+	// Computed indicators so the model does not have to estimate them
+	indicatorReport := indicators.Compute(history, time.Time{}, indicators.DefaultOptions())
+
 	// Generate analysis using OpenAI
-	analysis, err := s.openAIService.AnalyzeStock(ctx, stock)
+	analysis, err := s.openAIService.AnalyzeStock(ctx, stock, history, risk, indicatorReport)
 	if err != nil {
 		http.Error(w, "Failed to generate stock analysis", http.StatusInternalServerError)
 		return
@@ -53,6 +88,8 @@ func (s *Server) HandleAnalyzeStock(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.webhooks.Publish(ctx, user.ID, types.WebhookEventAnalysisCompleted, savedAnalysis)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -62,7 +99,7 @@ func (s *Server) HandleAnalyzeStock(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleAnalyzePortfolio generates AI analysis for the user's entire portfolio
+// HandleAnalyzePortfolio generates AI analysis for an entire portfolio (portfolio_id query param, default portfolio otherwise)
 func (s *Server) HandleAnalyzePortfolio(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -72,8 +109,29 @@ func (s *Server) HandleAnalyzePortfolio(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get the user's portfolio
-	portfolio, err := s.db.GetUserPortfolio(ctx, user.ID)
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get the requested portfolio, or the user's default portfolio
+	var portfolio *types.Portfolio
+	if portfolioID := r.URL.Query().Get("portfolio_id"); portfolioID != "" {
+		var owns bool
+		owns, err = s.db.UserOwnsPortfolio(ctx, user.ID, portfolioID)
+		if err != nil {
+			http.Error(w, "Could not verify portfolio ownership", http.StatusInternalServerError)
+			return
+		}
+		if !owns {
+			http.Error(w, "Portfolio not found or you don't have access to it", http.StatusNotFound)
+			return
+		}
+		portfolio, err = s.db.GetPortfolio(ctx, portfolioID)
+	} else {
+		portfolio, err = s.db.GetUserPortfolio(ctx, user.ID)
+	}
 	if err != nil {
 		http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
 		return
@@ -84,8 +142,48 @@ func (s *Server) HandleAnalyzePortfolio(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Load the price history of every holding at the requested interval and compute its indicators
+	history := make(map[string][]types.PriceBar, len(portfolio.Stocks))
+	var metrics services.PortfolioMetrics
+	metrics.Indicators = make(map[string]*types.IndicatorReport, len(portfolio.Stocks))
+	for i := range portfolio.Stocks {
+		stock := &portfolio.Stocks[i]
+		if err := s.applyInterval(ctx, stock, interval); err != nil {
+			writeMarketDataError(w, err, "Error while fetching stock data")
+			return
+		}
+
+		bars, err := s.priceHistory(ctx, stock.Symbol, stock.Interval, time.Time{}, time.Time{})
+		if err != nil {
+			writeMarketDataError(w, err, "Could not retrieve price history")
+			return
+		}
+		history[stock.Symbol] = bars
+		metrics.Indicators[stock.Symbol] = indicators.Compute(bars, time.Time{}, indicators.DefaultOptions())
+	}
+
+	if err := s.valuePortfolio(ctx, portfolio); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
+	// The analysis goes ahead without the figures that cannot be computed
+	if metrics.Performance, err = s.portfolioPerformance(ctx, portfolio, time.Time{}, time.Time{}); err != nil {
+		log.Printf("Portfolio analysis without performance of %s: %v", portfolio.ID, err)
+	}
+
+	riskParams := defaultRiskParams()
+	if metrics.Risk, err = s.portfolioRisk(ctx, portfolio, riskParams); err != nil {
+		log.Printf("Portfolio analysis without risk of %s: %v", portfolio.ID, err)
+	}
+
+	correlationsFrom := riskParams.to.AddDate(0, 0, -defaultCorrelationLookback)
+	if metrics.Correlations, err = s.portfolioCorrelations(ctx, portfolio, correlationsFrom, riskParams.to, defaultCorrelationThreshold); err != nil {
+		log.Printf("Portfolio analysis without correlations of %s: %v", portfolio.ID, err)
+	}
+
 	// Generate analysis using OpenAI
-	analysis, err := s.openAIService.AnalyzePortfolio(ctx, portfolio)
+	analysis, err := s.openAIService.AnalyzePortfolio(ctx, portfolio, history, metrics)
 	if err != nil {
 		http.Error(w, "Failed to generate portfolio analysis", http.StatusInternalServerError)
 		return
@@ -98,6 +196,8 @@ func (s *Server) HandleAnalyzePortfolio(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	s.webhooks.Publish(ctx, user.ID, types.WebhookEventAnalysisCompleted, savedAnalysis)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
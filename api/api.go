@@ -1,23 +1,43 @@
 package api
 
 import (
+	"github.com/ecetinerdem/forseer/backtest"
+	"github.com/ecetinerdem/forseer/corporate"
 	"github.com/ecetinerdem/forseer/database"
+	"github.com/ecetinerdem/forseer/fx"
+	"github.com/ecetinerdem/forseer/marketdata"
 	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/scheduler"
 	services "github.com/ecetinerdem/forseer/service"
+	"github.com/ecetinerdem/forseer/webhooks"
 	"github.com/go-chi/chi/v5"
 )
 
 type Server struct {
-	db            *database.DB
-	Router        *chi.Mux
-	openAIService *services.OpenAIService
+	db               *database.DB
+	Router           *chi.Mux
+	openAIService    *services.OpenAIService
+	marketData       marketdata.MarketDataProvider
+	fx               *fx.Service
+	corporateActions *corporate.Applier
+	backtests        *backtest.Runner
+	refreshScheduler *scheduler.Scheduler
+	webhooks         *webhooks.Dispatcher
+	projections      chan struct{} // Slots of the projections running
 }
 
-func NewServer(database *database.DB, openAIAPIKey string) *Server {
+func NewServer(database *database.DB, openAIAPIKey string, marketData marketdata.MarketDataProvider, fxService *fx.Service, corporateActions *corporate.Applier, backtests *backtest.Runner, refreshScheduler *scheduler.Scheduler, webhookDispatcher *webhooks.Dispatcher) *Server {
 	s := &Server{
-		db:            database,
-		Router:        chi.NewRouter(),
-		openAIService: services.NewOpenAIService(openAIAPIKey),
+		db:               database,
+		Router:           chi.NewRouter(),
+		openAIService:    services.NewOpenAIService(openAIAPIKey),
+		marketData:       marketData,
+		fx:               fxService,
+		corporateActions: corporateActions,
+		backtests:        backtests,
+		refreshScheduler: refreshScheduler,
+		webhooks:         webhookDispatcher,
+		projections:      make(chan struct{}, maxConcurrentProjections),
 	}
 	s.setUpRoutes()
 	return s
@@ -41,24 +61,108 @@ func (s *Server) setUpRoutes() *chi.Mux {
 			userRouter.Delete("/{id}", s.HandleDeleteUserById)
 		})
 
-		// Portfolio routes
+		// Portfolio routes acting on the user's default portfolio
 		r.Route("/portfolio", func(portfolioRouter chi.Router) {
 			portfolioRouter.Use(middleware.UserAuthentication)
 
-			// Portfolio operations
-			portfolioRouter.Get("/", s.HandleGetPortfolio)
 			portfolioRouter.Post("/", s.HandleCreatePortfolio) // For creating new portfolios
 
-			// Stock operations
-			portfolioRouter.Route("/stocks", func(stockRouter chi.Router) {
-				stockRouter.Get("/", s.HandleGetUserStocks)                // Get all stocks
-				stockRouter.Post("/{symbol}", s.HandleAddStockToPortfolio) // Add stock by symbol
-				stockRouter.Get("/search", s.HandleGetStockBySymbol)       // Search stocks by symbol (query param)
-				stockRouter.Get("/{id}", s.HandleGetStockByID)             // Get specific stock
-				stockRouter.Delete("/{id}", s.HandleDeleteStockByID)       // Delete stock
+			portfolioRouter.Group(func(defaultRouter chi.Router) {
+				defaultRouter.Use(s.PortfolioScope)
+				s.setUpPortfolioScopedRoutes(defaultRouter)
 			})
 		})
 
+		// Portfolio routes scoped by portfolio ID
+		r.Route("/portfolios", func(portfoliosRouter chi.Router) {
+			portfoliosRouter.Use(middleware.UserAuthentication)
+
+			portfoliosRouter.Get("/", s.HandleGetPortfolios)    // List portfolios, default first
+			portfoliosRouter.Post("/", s.HandleCreatePortfolio) // Create portfolio
+
+			portfoliosRouter.Route("/{portfolioID}", func(portfolioRouter chi.Router) {
+				portfolioRouter.Use(s.PortfolioScope) // Ownership check through UserOwnsPortfolio
+
+				portfolioRouter.Put("/", s.HandleRenamePortfolio)            // Rename portfolio
+				portfolioRouter.Delete("/", s.HandleDeletePortfolio)         // Delete portfolio with its stocks and transactions
+				portfolioRouter.Put("/default", s.HandleSetDefaultPortfolio) // Make default portfolio
+				s.setUpPortfolioScopedRoutes(portfolioRouter)
+			})
+		})
+
+		// Watchlist routes, symbols tracked without being held
+		r.Route("/watchlists", func(watchlistRouter chi.Router) {
+			watchlistRouter.Use(middleware.UserAuthentication)
+			watchlistRouter.Get("/", s.HandleGetWatchlists)                                                 // List watchlists
+			watchlistRouter.Post("/", s.HandleCreateWatchlist)                                              // Create watchlist
+			watchlistRouter.Get("/{watchlistID}", s.HandleGetWatchlist)                                     // Get watchlist with entries
+			watchlistRouter.Put("/{watchlistID}", s.HandleRenameWatchlist)                                  // Rename watchlist
+			watchlistRouter.Delete("/{watchlistID}", s.HandleDeleteWatchlist)                               // Delete watchlist
+			watchlistRouter.Post("/{watchlistID}/entries", s.HandleAddWatchlistEntry)                       // Add symbol
+			watchlistRouter.Delete("/{watchlistID}/entries/{entryID}", s.HandleDeleteWatchlistEntry)        // Remove symbol
+			watchlistRouter.Post("/{watchlistID}/entries/{entryID}/promote", s.HandlePromoteWatchlistEntry) // Turn entry into a holding
+		})
+
+		// Alert routes, rules evaluated whenever prices refresh
+		r.Route("/alerts", func(alertRouter chi.Router) {
+			alertRouter.Use(middleware.UserAuthentication)
+			alertRouter.Get("/", s.HandleGetAlerts)                          // List alerts
+			alertRouter.Post("/", s.HandleCreateAlert)                       // Create alert from an expression
+			alertRouter.Get("/triggers", s.HandleGetAlertTriggers)           // Recent triggers of every alert
+			alertRouter.Get("/{alertID}", s.HandleGetAlert)                  // Get alert
+			alertRouter.Put("/{alertID}", s.HandleUpdateAlert)               // Update name, expression, cooldown or enabled
+			alertRouter.Delete("/{alertID}", s.HandleDeleteAlert)            // Delete alert with its history
+			alertRouter.Get("/{alertID}/triggers", s.HandleGetAlertTriggers) // Trigger history of one alert
+		})
+
+		// Webhook routes, signed pushes of portfolio, alert and analysis events
+		r.Route("/webhooks", func(webhookRouter chi.Router) {
+			webhookRouter.Use(middleware.UserAuthentication)
+			webhookRouter.Get("/", s.HandleGetWebhooks)                                                      // List webhooks
+			webhookRouter.Post("/", s.HandleCreateWebhook)                                                   // Register webhook, returns its secret once
+			webhookRouter.Get("/{webhookID}", s.HandleGetWebhook)                                            // Get webhook
+			webhookRouter.Put("/{webhookID}", s.HandleUpdateWebhook)                                         // Update URL, events or enabled
+			webhookRouter.Delete("/{webhookID}", s.HandleDeleteWebhook)                                      // Delete webhook with its delivery log
+			webhookRouter.Post("/{webhookID}/ping", s.HandlePingWebhook)                                     // Send a test event
+			webhookRouter.Get("/{webhookID}/deliveries", s.HandleGetWebhookDeliveries)                       // Delivery log, newest first
+			webhookRouter.Post("/{webhookID}/deliveries/{deliveryID}/replay", s.HandleReplayWebhookDelivery) // Send a delivery's payload again
+		})
+
+		// Export routes, streamed as CSV, JSON lines or XLSX
+		r.Route("/exports", func(exportRouter chi.Router) {
+			exportRouter.Use(middleware.UserAuthentication)
+			exportRouter.Get("/{dataset}", s.HandleExport) // holdings, transactions, prices, stock-analyses or portfolio-analyses
+		})
+
+		// Benchmark routes, single symbols or weighted composites portfolios are compared against
+		r.Route("/benchmarks", func(benchmarkRouter chi.Router) {
+			benchmarkRouter.Use(middleware.UserAuthentication)
+			benchmarkRouter.Get("/", s.HandleGetBenchmarks)                   // List benchmarks
+			benchmarkRouter.Post("/", s.HandleCreateBenchmark)                // Define benchmark, weights are scaled to sum to 1
+			benchmarkRouter.Get("/{benchmarkID}", s.HandleGetBenchmark)       // Get benchmark
+			benchmarkRouter.Put("/{benchmarkID}", s.HandleUpdateBenchmark)    // Update name, rebalance or components
+			benchmarkRouter.Delete("/{benchmarkID}", s.HandleDeleteBenchmark) // Delete benchmark
+		})
+
+		// Backtest routes, strategy simulations run as background jobs
+		r.Route("/backtests", func(backtestRouter chi.Router) {
+			backtestRouter.Use(middleware.UserAuthentication)
+			backtestRouter.Get("/", s.HandleGetBacktests)                       // List backtests without results
+			backtestRouter.Post("/", s.HandleCreateBacktest)                    // Queue a backtest of sma_crossover, rebalance or dca
+			backtestRouter.Get("/{backtestID}", s.HandleGetBacktest)            // Status, with the equity curve, trades and summary once completed
+			backtestRouter.Post("/{backtestID}/cancel", s.HandleCancelBacktest) // Stop a queued or running backtest
+			backtestRouter.Delete("/{backtestID}", s.HandleDeleteBacktest)      // Delete backtest, stopping it first
+		})
+
+		// Market data routes
+		r.Route("/market-data", func(marketDataRouter chi.Router) {
+			marketDataRouter.Use(middleware.UserAuthentication)
+			marketDataRouter.Get("/refresh-status", s.HandleGetRefreshStatus)                       // Background price refresh status per symbol
+			marketDataRouter.Get("/corporate-actions/{symbol}", s.HandleGetCorporateActions)        // Known splits and dividends of a symbol
+			marketDataRouter.Post("/corporate-actions", s.HandleCreateCorporateAction)              // Enter a split or dividend (admin)
+			marketDataRouter.Post("/corporate-actions/{symbol}/sync", s.HandleSyncCorporateActions) // Read actions from the adjusted daily series (admin)
+		})
+
 		// AI Analysis routes
 		r.Route("/analysis", func(analysisRouter chi.Router) {
 			analysisRouter.Use(middleware.UserAuthentication)
@@ -83,3 +187,50 @@ func (s *Server) setUpRoutes() *chi.Mux {
 
 	return s.Router
 }
+
+// setUpPortfolioScopedRoutes registers the routes acting on the portfolio resolved by PortfolioScope
+func (s *Server) setUpPortfolioScopedRoutes(portfolioRouter chi.Router) {
+	// Portfolio operations
+	portfolioRouter.Get("/", s.HandleGetPortfolio)
+
+	// Stock operations
+	portfolioRouter.Route("/stocks", func(stockRouter chi.Router) {
+		stockRouter.Get("/", s.HandleGetUserStocks)                     // Get all stocks
+		stockRouter.Post("/{symbol}", s.HandleAddStockToPortfolio)      // Add stock by symbol
+		stockRouter.Get("/search", s.HandleGetStockBySymbol)            // Search stocks by symbol (query param)
+		stockRouter.Get("/{id}", s.HandleGetStockByID)                  // Get specific stock
+		stockRouter.Get("/{id}/history", s.HandleGetStockHistory)       // Get stored price history (from/to query params)
+		stockRouter.Get("/{id}/indicators", s.HandleGetStockIndicators) // SMA, EMA, RSI, MACD, Bollinger Bands, ATR, OBV and VWAP over the stored history (from/to, interval, sma, ema, rsi, macd, bollinger, atr)
+		stockRouter.Delete("/{id}", s.HandleDeleteStockByID)            // Delete stock and its buys (purge=true erases its whole ledger)
+	})
+
+	// Transaction ledger, holdings are derived from it
+	portfolioRouter.Route("/transactions", func(transactionRouter chi.Router) {
+		transactionRouter.Get("/", s.HandleGetTransactions)              // List transactions (symbol and type query params)
+		transactionRouter.Post("/", s.HandleCreateTransaction)           // Record buy, sell, dividend, split, fee, deposit or withdrawal
+		transactionRouter.Post("/import", s.HandleImportTransactions)    // Import a CSV file (multipart, dry_run supported)
+		transactionRouter.Get("/{id}", s.HandleGetTransactionByID)       // Get specific transaction
+		transactionRouter.Delete("/{id}", s.HandleDeleteTransactionByID) // Delete transaction (interval query param for a reopened holding)
+	})
+
+	// Gains and lot matching
+	portfolioRouter.Get("/pnl", s.HandleGetPnL)                           // Realized and unrealized gains (from/to query params)
+	portfolioRouter.Get("/lots", s.HandleGetOpenLots)                     // Open lots (symbol query param)
+	portfolioRouter.Get("/lots/realized", s.HandleGetRealizedLots)        // Closed lots (symbol, from/to query params)
+	portfolioRouter.Put("/cost-basis-method", s.HandleSetCostBasisMethod) // fifo, lifo, specific or average
+	portfolioRouter.Put("/base-currency", s.HandleSetBaseCurrency)        // Currency values and reports are in
+
+	// Target allocations and rebalancing
+	portfolioRouter.Get("/targets", s.HandleGetAllocationTargets)               // Allocation targets per symbol, sector or asset class
+	portfolioRouter.Put("/targets", s.HandleSetAllocationTargets)               // Replace allocation targets, weights sum to 1
+	portfolioRouter.Get("/classifications", s.HandleGetClassifications)         // Sector and asset class of classified symbols
+	portfolioRouter.Put("/classifications/{symbol}", s.HandleSetClassification) // Set sector and asset class of a symbol
+	portfolioRouter.Get("/rebalance", s.HandleGetRebalancePlan)                 // Proposed trades back within the bands (cash, min_trade, no_sells, whole_shares)
+
+	// Analytics computed from the ledger and the daily price history
+	portfolioRouter.Get("/performance", s.HandleGetPerformance)   // Time and money-weighted returns (from/to query params)
+	portfolioRouter.Get("/risk", s.HandleGetRisk)                 // Volatility, ratios, drawdown, beta and VaR (from/to, benchmark, risk_free_rate, confidence)
+	portfolioRouter.Get("/benchmark", s.HandleCompareBenchmark)   // Returns against a benchmark with alpha, tracking error and information ratio (benchmark_id or benchmark, from/to, risk_free_rate)
+	portfolioRouter.Get("/correlations", s.HandleGetCorrelations) // Correlation and covariance matrices with clusters (lookback or from/to, threshold)
+	portfolioRouter.Get("/projection", s.HandleGetProjection)     // Monte Carlo percentile bands of the value (years, paths, method, contribution, frequency, seed, target, lookback or from/to)
+}
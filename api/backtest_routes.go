@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/backtest"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetBacktests lists the backtests of the user without their results, newest first
+func (s *Server) HandleGetBacktests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	backtests, err := s.db.GetUserBacktests(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve backtests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(backtests); err != nil {
+		http.Error(w, "Could not encode backtests", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCreateBacktest queues a backtest of a strategy over the stored daily history of its universe.
+// It responds with the queued backtest, its result is read from HandleGetBacktest once it completes.
+func (s *Server) HandleCreateBacktest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	var req types.CreateBacktestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	definition := types.BacktestDefinition{
+		Strategy:     req.Strategy,
+		Universe:     req.Universe,
+		StartingCash: req.StartingCash,
+		Commission:   req.Commission,
+		WholeShares:  req.WholeShares,
+	}
+
+	var err error
+	if definition.From, err = time.Parse(dateLayout, req.From); err != nil {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	definition.To = time.Now().UTC().Truncate(24 * time.Hour)
+	if req.To != "" {
+		if definition.To, err = time.Parse(dateLayout, req.To); err != nil {
+			http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := backtest.Normalize(&definition); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if len(name) > 255 {
+		http.Error(w, "Backtest name cannot be longer than 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	created, err := s.backtests.Submit(ctx, &types.Backtest{
+		UserID:     user.ID,
+		Name:       name,
+		Definition: definition,
+	}, s.priceHistory)
+	if err != nil {
+		writeBacktestError(w, err, "Could not start backtest")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		http.Error(w, "Could not encode backtest", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetBacktest returns a backtest of the user with its result once completed
+func (s *Server) HandleGetBacktest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := s.db.GetUserBacktest(ctx, user.ID, chi.URLParam(r, "backtestID"))
+	if err != nil {
+		writeBacktestError(w, err, "Could not retrieve backtest")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Could not encode backtest", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCancelBacktest stops a queued or running backtest of the user
+func (s *Server) HandleCancelBacktest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	cancelled, err := s.backtests.Cancel(ctx, user.ID, chi.URLParam(r, "backtestID"))
+	if err != nil {
+		writeBacktestError(w, err, "Could not cancel backtest")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(cancelled); err != nil {
+		http.Error(w, "Could not encode backtest", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeleteBacktest deletes a backtest of the user, stopping it first when it has not finished
+func (s *Server) HandleDeleteBacktest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	backtestID := chi.URLParam(r, "backtestID")
+
+	var finishedErr *types.BacktestFinishedError
+	if _, err := s.backtests.Cancel(ctx, user.ID, backtestID); err != nil && !errors.As(err, &finishedErr) {
+		writeBacktestError(w, err, "Could not delete backtest")
+		return
+	}
+
+	if err := s.db.DeleteUserBacktest(ctx, user.ID, backtestID); err != nil {
+		writeBacktestError(w, err, "Could not delete backtest")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]string{
+		"message":     "Backtest deleted successfully",
+		"backtest_id": backtestID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Could not encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// writeBacktestError maps backtest repository errors to HTTP responses
+func writeBacktestError(w http.ResponseWriter, err error, message string) {
+	var notFoundErr *types.BacktestNotFoundError
+	var finishedErr *types.BacktestFinishedError
+	var limitErr *types.BacktestLimitError
+
+	switch {
+	case errors.As(err, &notFoundErr):
+		http.Error(w, "Backtest not found or you don't have access to it", http.StatusNotFound)
+	case errors.As(err, &finishedErr):
+		http.Error(w, finishedErr.Error(), http.StatusConflict)
+	case errors.As(err, &limitErr):
+		http.Error(w, limitErr.Error(), http.StatusTooManyRequests)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
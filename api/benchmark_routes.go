@@ -0,0 +1,351 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/benchmark"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/performance"
+	"github.com/ecetinerdem/forseer/risk"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetBenchmarks lists the benchmarks of the user
+func (s *Server) HandleGetBenchmarks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	benchmarks, err := s.db.GetUserBenchmarks(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve benchmarks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(benchmarks); err != nil {
+		http.Error(w, "Could not encode benchmarks", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCreateBenchmark defines a benchmark, e.g. {"name": "60/40", "rebalance": "quarterly",
+// "components": [{"symbol": "SPY", "weight": 60}, {"symbol": "AGG", "weight": 40}]}
+func (s *Server) HandleCreateBenchmark(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	var req types.CreateBenchmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	definition := &types.Benchmark{
+		Name:      strings.TrimSpace(req.Name),
+		Rebalance: req.Rebalance,
+	}
+	if definition.Name == "" {
+		http.Error(w, "Benchmark name cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if definition.Rebalance == "" {
+		definition.Rebalance = types.RebalanceNone
+	}
+	if !definition.Rebalance.IsValid() {
+		http.Error(w, "Invalid rebalance, expected none, daily, monthly, quarterly or annually", http.StatusBadRequest)
+		return
+	}
+
+	components, err := benchmark.Normalize(req.Components)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.checkBenchmarkSymbols(ctx, w, components) {
+		return
+	}
+	definition.Components = components
+
+	created, err := s.db.CreateUserBenchmark(ctx, user.ID, definition)
+	if err != nil {
+		writeBenchmarkError(w, err, "Could not create benchmark")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		http.Error(w, "Could not encode benchmark", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetBenchmark returns a benchmark of the user
+func (s *Server) HandleGetBenchmark(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	definition, err := s.db.GetUserBenchmark(ctx, user.ID, chi.URLParam(r, "benchmarkID"))
+	if err != nil {
+		writeBenchmarkError(w, err, "Could not retrieve benchmark")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(definition); err != nil {
+		http.Error(w, "Could not encode benchmark", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleUpdateBenchmark changes the name, rebalance schedule or components of a benchmark
+func (s *Server) HandleUpdateBenchmark(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	var req types.UpdateBenchmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			http.Error(w, "Benchmark name cannot be empty", http.StatusBadRequest)
+			return
+		}
+		req.Name = &name
+	}
+
+	if req.Rebalance != nil && !req.Rebalance.IsValid() {
+		http.Error(w, "Invalid rebalance, expected none, daily, monthly, quarterly or annually", http.StatusBadRequest)
+		return
+	}
+
+	if req.Components != nil {
+		components, err := benchmark.Normalize(req.Components)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !s.checkBenchmarkSymbols(ctx, w, components) {
+			return
+		}
+		req.Components = components
+	}
+
+	updated, err := s.db.UpdateUserBenchmark(ctx, user.ID, chi.URLParam(r, "benchmarkID"), &req)
+	if err != nil {
+		writeBenchmarkError(w, err, "Could not update benchmark")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		http.Error(w, "Could not encode benchmark", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeleteBenchmark deletes a benchmark of the user
+func (s *Server) HandleDeleteBenchmark(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.db.DeleteUserBenchmark(ctx, user.ID, chi.URLParam(r, "benchmarkID")); err != nil {
+		writeBenchmarkError(w, err, "Could not delete benchmark")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleCompareBenchmark returns the performance of the scoped portfolio alongside a benchmark's, with alpha,
+// tracking error and information ratio. Query params: benchmark_id of a saved benchmark or benchmark symbol
+// (SPY by default), from and to (the whole ledger by default) and risk_free_rate as an annual percentage.
+func (s *Server) HandleCompareBenchmark(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	riskFreeRate, err := parseRiskFreeRate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// A saved benchmark, or a single symbol held without rebalancing
+	var definition *types.Benchmark
+	if benchmarkID := r.URL.Query().Get("benchmark_id"); benchmarkID != "" {
+		if definition, err = s.db.GetUserBenchmark(ctx, user.ID, benchmarkID); err != nil {
+			writeBenchmarkError(w, err, "Could not retrieve benchmark")
+			return
+		}
+	} else {
+		symbol := r.URL.Query().Get("benchmark")
+		if symbol == "" {
+			symbol = defaultBenchmark
+		}
+		components, err := benchmark.Normalize([]types.BenchmarkComponent{{Symbol: symbol, Weight: 1}})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		definition = &types.Benchmark{Name: components[0].Symbol, Rebalance: types.RebalanceNone, Components: components}
+	}
+
+	portfolio, err := s.db.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	transactions, err := s.db.GetPortfolioTransactions(ctx, portfolioID, types.TransactionFilter{To: to})
+	if err != nil {
+		http.Error(w, "Could not get transactions", http.StatusInternalServerError)
+		return
+	}
+
+	if len(transactions) == 0 {
+		http.Error(w, "Portfolio has no transactions to compare", http.StatusUnprocessableEntity)
+		return
+	}
+
+	transactions, history, err := s.ledgerHistory(ctx, portfolio.BaseCurrency, transactions, to)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not retrieve price history")
+		return
+	}
+
+	benchmarkHistory, err := s.benchmarkHistory(ctx, portfolio.BaseCurrency, definition.Components, to)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not retrieve benchmark price history")
+		return
+	}
+
+	portfolioReturns := risk.Between(risk.SeriesReturns(performance.Series(transactions, history, to)), from, to)
+	benchmarkReturns := risk.Between(benchmark.Returns(definition.Components, definition.Rebalance, benchmarkHistory), from, to)
+
+	comparison := risk.Compare(portfolioReturns, benchmarkReturns, from, risk.Options{RiskFreeRate: riskFreeRate})
+	comparison.PortfolioID = portfolioID
+	comparison.Benchmark = definition.Name
+	comparison.Rebalance = definition.Rebalance
+	comparison.Components = definition.Components
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(comparison); err != nil {
+		http.Error(w, "Could not encode benchmark comparison", http.StatusInternalServerError)
+		return
+	}
+}
+
+// benchmarkHistory loads the daily price history of every component of a benchmark up to the to date,
+// converted to the base currency so its returns compare with the portfolio's
+func (s *Server) benchmarkHistory(ctx context.Context, baseCurrency string, components []types.BenchmarkComponent, to time.Time) (map[string][]types.PriceBar, error) {
+	currencies := make([]string, len(components))
+	for i, component := range components {
+		currencies[i] = types.ListingCurrency(component.Symbol)
+	}
+
+	converter, err := s.fx.Converter(ctx, baseCurrency, currencies...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+
+	history := make(map[string][]types.PriceBar, len(components))
+	for i, component := range components {
+		bars, err := s.priceHistory(ctx, component.Symbol, types.IntervalDaily, time.Time{}, to)
+		if err != nil {
+			return nil, err
+		}
+		if history[component.Symbol], err = converter.Bars(bars, currencies[i]); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+// checkBenchmarkSymbols fetches the daily series of every component so unknown symbols are rejected.
+// On failure the error reply is written and false returned.
+func (s *Server) checkBenchmarkSymbols(ctx context.Context, w http.ResponseWriter, components []types.BenchmarkComponent) bool {
+	for _, component := range components {
+		if _, err := s.fetchPriceSeries(ctx, component.Symbol, types.IntervalDaily); err != nil {
+			writeMarketDataError(w, err, "Error while fetching benchmark data")
+			return false
+		}
+	}
+	return true
+}
+
+// writeBenchmarkError maps benchmark repository errors to HTTP responses
+func writeBenchmarkError(w http.ResponseWriter, err error, message string) {
+	var notFoundErr *types.BenchmarkNotFoundError
+	var nameTakenErr *types.BenchmarkNameTakenError
+
+	switch {
+	case errors.As(err, &notFoundErr):
+		http.Error(w, "Benchmark not found or you don't have access to it", http.StatusNotFound)
+	case errors.As(err, &nameTakenErr):
+		http.Error(w, nameTakenErr.Error(), http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
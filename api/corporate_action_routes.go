@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetCorporateActions returns the known splits and dividends of a symbol
+func (s *Server) HandleGetCorporateActions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "symbol")))
+	if symbol == "" || len(symbol) > 10 {
+		http.Error(w, "Invalid symbol", http.StatusBadRequest)
+		return
+	}
+
+	actions, err := s.db.GetCorporateActions(ctx, symbol)
+	if err != nil {
+		http.Error(w, "Could not retrieve corporate actions", http.StatusInternalServerError)
+		return
+	}
+
+	if actions == nil {
+		actions = []types.CorporateAction{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(actions); err != nil {
+		http.Error(w, "Could not encode corporate actions", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCreateCorporateAction enters a split or dividend by hand and records it in the ledgers holding the symbol,
+// e.g. {"symbol": "AAPL", "type": "split", "ex_date": "2020-08-31", "ratio": 4}
+func (s *Server) HandleCreateCorporateAction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	if !s.isAdmin(ctx, user.ID) {
+		http.Error(w, "Only admins can enter corporate actions", http.StatusForbidden)
+		return
+	}
+
+	var req types.CreateCorporateActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" || len(symbol) > 10 {
+		http.Error(w, "Invalid symbol", http.StatusBadRequest)
+		return
+	}
+
+	if !req.Type.IsValid() {
+		http.Error(w, "Type must be split or dividend", http.StatusBadRequest)
+		return
+	}
+
+	exDate, err := time.Parse(dateLayout, req.ExDate)
+	if err != nil {
+		http.Error(w, "Invalid ex_date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	action := &types.CorporateAction{
+		Symbol: symbol,
+		Type:   req.Type,
+		ExDate: exDate,
+		Source: types.CorporateActionManual,
+	}
+
+	switch req.Type {
+	case types.CorporateActionSplit:
+		if req.Ratio <= 0 || req.Ratio == 1 {
+			http.Error(w, "Split ratio must be positive and not 1", http.StatusBadRequest)
+			return
+		}
+		action.Ratio = req.Ratio
+	case types.CorporateActionDividend:
+		if req.Amount <= 0 {
+			http.Error(w, "Dividend amount must be positive", http.StatusBadRequest)
+			return
+		}
+		action.Amount = req.Amount
+	}
+
+	created, err := s.db.CreateCorporateAction(ctx, action)
+	if err != nil {
+		var existsErr *types.CorporateActionExistsError
+		if errors.As(err, &existsErr) {
+			http.Error(w, existsErr.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not create corporate action", http.StatusInternalServerError)
+		return
+	}
+
+	// The action is stored either way, ledgers that failed are retried on the next refresh of the symbol
+	if _, err := s.corporateActions.Apply(ctx, symbol); err != nil {
+		log.Printf("corporate actions: %s: %v", symbol, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		http.Error(w, "Could not encode corporate action", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleSyncCorporateActions reads the splits and dividends of a symbol from its adjusted daily series
+// and records the known actions in the ledgers holding it
+func (s *Server) HandleSyncCorporateActions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	if !s.isAdmin(ctx, user.ID) {
+		http.Error(w, "Only admins can sync corporate actions", http.StatusForbidden)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "symbol")))
+	if symbol == "" || len(symbol) > 10 {
+		http.Error(w, "Invalid symbol", http.StatusBadRequest)
+		return
+	}
+
+	series, err := s.fetchPriceSeries(ctx, symbol, types.IntervalDailyAdjusted)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not fetch adjusted price series")
+		return
+	}
+
+	// Series served from the cache carry no actions, those were stored when it was fetched
+	if err := s.db.SaveCorporateActions(ctx, symbol, series.Actions); err != nil {
+		http.Error(w, "Could not save corporate actions", http.StatusInternalServerError)
+		return
+	}
+
+	actions, err := s.db.GetCorporateActions(ctx, symbol)
+	if err != nil {
+		http.Error(w, "Could not retrieve corporate actions", http.StatusInternalServerError)
+		return
+	}
+
+	if actions == nil {
+		actions = []types.CorporateAction{}
+	}
+
+	recorded, err := s.corporateActions.Apply(ctx, symbol)
+	if err != nil {
+		log.Printf("corporate actions: %s: %v", symbol, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(types.CorporateActionSync{
+		Symbol:   symbol,
+		Actions:  actions,
+		Recorded: recorded,
+	}); err != nil {
+		http.Error(w, "Could not encode corporate action sync", http.StatusInternalServerError)
+		return
+	}
+}
+
+// isAdmin reports whether the user may manage shared market data
+func (s *Server) isAdmin(ctx context.Context, userID string) bool {
+	user, err := s.db.GetUserById(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return user.IsAdmin
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/risk"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+const (
+	defaultCorrelationLookback  = 90 // Days
+	maxCorrelationLookback      = 3650
+	defaultCorrelationThreshold = 0.7
+)
+
+// parseCorrelationParams reads the from and to or lookback (days before to, 90 by default) query params
+// and the threshold correlation of clusters
+func parseCorrelationParams(r *http.Request) (time.Time, time.Time, float64, error) {
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		return from, to, 0, err
+	}
+
+	end := to
+	if end.IsZero() {
+		end = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+
+	if value := r.URL.Query().Get("lookback"); value != "" {
+		if !from.IsZero() {
+			return from, to, 0, fmt.Errorf("use either from or lookback, not both")
+		}
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 2 || days > maxCorrelationLookback {
+			return from, to, 0, fmt.Errorf("invalid lookback, expected a number of days between 2 and %d", maxCorrelationLookback)
+		}
+		from = end.AddDate(0, 0, -days)
+	} else if from.IsZero() {
+		from = end.AddDate(0, 0, -defaultCorrelationLookback)
+	}
+
+	threshold := defaultCorrelationThreshold
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return from, to, 0, fmt.Errorf("invalid threshold, expected a correlation between 0 and 1")
+		}
+	}
+
+	return from, end, threshold, nil
+}
+
+// portfolioCorrelations correlates the daily returns of a valued portfolio's holdings between from and to
+func (s *Server) portfolioCorrelations(ctx context.Context, portfolio *types.Portfolio, from, to time.Time, threshold float64) (*types.CorrelationReport, error) {
+	returns := make([][]risk.Return, len(portfolio.Stocks))
+	for i, stock := range portfolio.Stocks {
+		bars, err := s.priceHistory(ctx, stock.Symbol, types.IntervalDaily, time.Time{}, to)
+		if err != nil {
+			return nil, err
+		}
+		returns[i] = risk.Between(risk.BarReturns(bars), from, to)
+	}
+
+	report := risk.CorrelationReport(portfolio.Stocks, returns, threshold)
+	report.PortfolioID = portfolio.ID
+	report.From = from
+	report.To = to
+
+	return report, nil
+}
+
+// HandleGetCorrelations returns the correlation and covariance matrices of the daily returns of the scoped
+// portfolio's holdings and the clusters of holdings correlated at or above the threshold.
+// Query params: lookback in days (90 by default) or from and to, and threshold (0.7 by default).
+func (s *Server) HandleGetCorrelations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	from, to, threshold, err := parseCorrelationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := s.db.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.valuePortfolio(ctx, portfolio); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
+	report, err := s.portfolioCorrelations(ctx, portfolio, from, to, threshold)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not compute correlations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Could not encode correlations", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ecetinerdem/forseer/fx"
+	"github.com/ecetinerdem/forseer/marketdata"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/ecetinerdem/forseer/valuation"
+)
+
+// convertHoldings sets the rates converting each holding to its portfolio's base currency.
+// Prices convert at the rate of the latest bar's date, the cost lot by lot at the rates of the trade dates.
+func (s *Server) convertHoldings(ctx context.Context, stocks []types.Stock) error {
+	byPortfolio := make(map[string][]int)
+	var portfolioIDs []string
+	for i, stock := range stocks {
+		if _, ok := byPortfolio[stock.PortfolioID]; !ok {
+			portfolioIDs = append(portfolioIDs, stock.PortfolioID)
+		}
+		byPortfolio[stock.PortfolioID] = append(byPortfolio[stock.PortfolioID], i)
+	}
+
+	for _, portfolioID := range portfolioIDs {
+		transactions, portfolio, err := s.db.GetPortfolioLedger(ctx, portfolioID)
+		if err != nil {
+			return err
+		}
+
+		currencies := fx.TransactionCurrencies(transactions)
+		for _, i := range byPortfolio[portfolioID] {
+			stocks[i].BaseCurrency = portfolio.BaseCurrency
+			currencies = append(currencies, stocks[i].Currency)
+		}
+
+		converter, err := s.fx.Converter(ctx, portfolio.BaseCurrency, currencies...)
+		if err != nil {
+			return fmt.Errorf("failed to load exchange rates: %w", err)
+		}
+
+		costBases, err := converter.CostBases(transactions, portfolio.CostBasisMethod)
+		if err != nil {
+			return fmt.Errorf("failed to match lots: %w", err)
+		}
+
+		for _, i := range byPortfolio[portfolioID] {
+			if err := converter.Holding(&stocks[i], costBases); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// valueHolding values a holding in its portfolio's base currency
+func (s *Server) valueHolding(ctx context.Context, stock *types.Stock) error {
+	stocks := []types.Stock{*stock}
+	if err := s.convertHoldings(ctx, stocks); err != nil {
+		return err
+	}
+
+	*stock = stocks[0]
+	valuation.ValueHolding(stock)
+	return nil
+}
+
+// valueHoldings values the holdings of a portfolio and their weights in its base currency
+func (s *Server) valueHoldings(ctx context.Context, stocks []types.Stock) error {
+	if err := s.convertHoldings(ctx, stocks); err != nil {
+		return err
+	}
+
+	valuation.ValueHoldings(stocks)
+	return nil
+}
+
+// valuePortfolio values a portfolio and its holdings in its base currency
+func (s *Server) valuePortfolio(ctx context.Context, portfolio *types.Portfolio) error {
+	if err := s.convertHoldings(ctx, portfolio.Stocks); err != nil {
+		return err
+	}
+
+	valuation.ValuePortfolio(portfolio)
+	return nil
+}
+
+// baseLedger converts a ledger to the base currency, every entry at the rate of its trade date
+func (s *Server) baseLedger(ctx context.Context, baseCurrency string, transactions []types.Transaction) ([]types.Transaction, *fx.Converter, error) {
+	converter, err := s.fx.Converter(ctx, baseCurrency, fx.TransactionCurrencies(transactions)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+
+	converted, err := converter.Transactions(transactions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return converted, converter, nil
+}
+
+// HandleSetBaseCurrency changes the currency the scoped portfolio is valued and reported in.
+// Holdings and transactions keep their own currency, they are converted at historical rates when read.
+func (s *Server) HandleSetBaseCurrency(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	var req types.SetBaseCurrencyRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	currency, err := parseCurrency(req.BaseCurrency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if currency == "" || currency == types.PenceCurrency {
+		http.Error(w, "Invalid base currency, expected a three letter code such as USD, EUR or GBP", http.StatusBadRequest)
+		return
+	}
+
+	// Reject currencies the provider has no rates for before any report depends on them
+	if _, err := s.fx.Converter(ctx, currency, types.DefaultCurrency); err != nil {
+		var symbolErr *marketdata.InvalidSymbolError
+		if errors.As(err, &symbolErr) {
+			http.Error(w, fmt.Sprintf("No exchange rates available for %s", currency), http.StatusBadRequest)
+			return
+		}
+		writeMarketDataError(w, err, "Could not retrieve exchange rates")
+		return
+	}
+
+	portfolio, err := s.db.SetPortfolioBaseCurrency(ctx, portfolioID, currency)
+	if err != nil {
+		http.Error(w, "Could not update base currency", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(portfolio); err != nil {
+		http.Error(w, "Could not encode portfolio", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/corporate"
+	"github.com/ecetinerdem/forseer/export"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// exportDataset writes the rows of a dataset for the selected portfolios
+type exportDataset struct {
+	columns []string
+	write   func(ctx context.Context, s *Server, w export.Writer, portfolios []*types.Portfolio, filter exportFilter) error
+}
+
+// exportFilter holds the query params narrowing an export
+type exportFilter struct {
+	from, to time.Time
+	interval types.Interval // Only used by the prices dataset
+}
+
+var exportDatasets = map[string]exportDataset{
+	"holdings":           {columns: export.HoldingColumns, write: exportHoldings},
+	"transactions":       {columns: export.TransactionColumns, write: exportTransactions},
+	"prices":             {columns: export.PriceColumns, write: exportPrices},
+	"stock-analyses":     {columns: export.StockAnalysisColumns, write: exportStockAnalyses},
+	"portfolio-analyses": {columns: export.PortfolioAnalysisColumns, write: exportPortfolioAnalyses},
+}
+
+// HandleExport streams a dataset as CSV, JSON lines or XLSX.
+// Query params: format (csv by default), portfolio_id (repeatable or comma separated, every portfolio of the
+// user by default), from and to (YYYY-MM-DD) and interval for prices.
+func (s *Server) HandleExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	name := chi.URLParam(r, "dataset")
+	dataset, ok := exportDatasets[name]
+	if !ok {
+		http.Error(w, "Unknown export, expected one of holdings, transactions, prices, stock-analyses, portfolio-analyses", http.StatusNotFound)
+		return
+	}
+
+	format := export.FormatCSV
+	if value := r.URL.Query().Get("format"); value != "" {
+		format = export.Format(strings.ToLower(value))
+	}
+	if !format.IsValid() {
+		http.Error(w, "Invalid format, expected csv, jsonl or xlsx", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	portfolios, ok := s.exportPortfolios(ctx, w, user.ID, exportPortfolioIDs(r))
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("forseer-%s-%s.%s", name, time.Now().UTC().Format(dateLayout), format)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, errors from here on can only cut the file short
+	writer, err := export.NewWriter(format, w, name, dataset.columns)
+	if err != nil {
+		log.Printf("Export %s could not start: %v", name, err)
+		return
+	}
+
+	filter := exportFilter{from: from, to: to, interval: interval}
+	if err := dataset.write(ctx, s, writer, portfolios, filter); err != nil {
+		log.Printf("Export %s stopped: %v", name, err)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("Export %s could not finish: %v", name, err)
+	}
+}
+
+// exportPortfolioIDs reads the portfolio_id query params, both repeated and comma separated
+func exportPortfolioIDs(r *http.Request) []string {
+	var ids []string
+	for _, value := range r.URL.Query()["portfolio_id"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// exportPortfolios loads the requested portfolios with their valued holdings, checking the user owns each.
+// Without IDs every portfolio of the user is loaded. On failure the error reply is written and false returned.
+func (s *Server) exportPortfolios(ctx context.Context, w http.ResponseWriter, userID string, ids []string) ([]*types.Portfolio, bool) {
+	if len(ids) == 0 {
+		owned, err := s.db.GetUserPortfolios(ctx, userID)
+		if err != nil {
+			http.Error(w, "Could not get portfolios", http.StatusInternalServerError)
+			return nil, false
+		}
+		for _, portfolio := range owned {
+			ids = append(ids, portfolio.ID)
+		}
+	}
+
+	seen := make(map[string]bool, len(ids))
+	portfolios := make([]*types.Portfolio, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		owns, err := s.db.UserOwnsPortfolio(ctx, userID, id)
+		if err != nil {
+			http.Error(w, "Could not verify portfolio ownership", http.StatusInternalServerError)
+			return nil, false
+		}
+		if !owns {
+			http.Error(w, fmt.Sprintf("Portfolio %s not found or you don't have access to it", id), http.StatusNotFound)
+			return nil, false
+		}
+
+		portfolio, err := s.db.GetPortfolio(ctx, id)
+		if err != nil {
+			http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+			return nil, false
+		}
+		if err := s.valuePortfolio(ctx, portfolio); err != nil {
+			writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+			return nil, false
+		}
+		portfolios = append(portfolios, portfolio)
+	}
+
+	return portfolios, true
+}
+
+// exportHoldings writes the current holdings, the date range does not apply to them
+func exportHoldings(ctx context.Context, s *Server, w export.Writer, portfolios []*types.Portfolio, filter exportFilter) error {
+	for _, portfolio := range portfolios {
+		for i := range portfolio.Stocks {
+			if err := w.WriteRow(export.HoldingRow(portfolio, &portfolio.Stocks[i])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func exportTransactions(ctx context.Context, s *Server, w export.Writer, portfolios []*types.Portfolio, filter exportFilter) error {
+	for _, portfolio := range portfolios {
+		transactions, err := s.db.GetPortfolioTransactions(ctx, portfolio.ID, types.TransactionFilter{From: filter.from, To: filter.to})
+		if err != nil {
+			return err
+		}
+		for i := range transactions {
+			if err := w.WriteRow(export.TransactionRow(portfolio, &transactions[i])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportPrices writes the stored price history of every series held in the portfolios, each series once
+func exportPrices(ctx context.Context, s *Server, w export.Writer, portfolios []*types.Portfolio, filter exportFilter) error {
+	type series struct {
+		symbol   string
+		interval types.Interval
+	}
+
+	seen := make(map[series]bool)
+	for _, portfolio := range portfolios {
+		for _, stock := range portfolio.Stocks {
+			key := series{symbol: stock.Symbol, interval: stock.Interval}
+			if filter.interval != "" {
+				key.interval = filter.interval
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			bars, err := s.db.GetPriceHistory(ctx, key.symbol, key.interval, filter.from, filter.to)
+			if err != nil {
+				return err
+			}
+			actions, err := s.db.GetCorporateActions(ctx, key.symbol)
+			if err != nil {
+				return err
+			}
+			bars = corporate.Adjust(bars, actions)
+			for i := range bars {
+				if err := w.WriteRow(export.PriceRow(&bars[i])); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func exportStockAnalyses(ctx context.Context, s *Server, w export.Writer, portfolios []*types.Portfolio, filter exportFilter) error {
+	for _, portfolio := range portfolios {
+		analyses, err := s.db.GetPortfolioStockAnalyses(ctx, portfolio.ID, filter.from, filter.to)
+		if err != nil {
+			return err
+		}
+		for _, analysis := range analyses {
+			if err := w.WriteRow(export.StockAnalysisRow(portfolio, analysis)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func exportPortfolioAnalyses(ctx context.Context, s *Server, w export.Writer, portfolios []*types.Portfolio, filter exportFilter) error {
+	for _, portfolio := range portfolios {
+		analyses, err := s.db.GetPortfolioAnalyses(ctx, portfolio.ID, filter.from, filter.to)
+		if err != nil {
+			return err
+		}
+		for _, analysis := range analyses {
+			if err := w.WriteRow(export.PortfolioAnalysisRow(portfolio, analysis)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ecetinerdem/forseer/importer"
+	"github.com/ecetinerdem/forseer/marketdata"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// maxImportSize bounds the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+// importDateFormats maps the date formats accepted by imports to Go layouts
+var importDateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD/MM/YYYY": "02/01/2006",
+	"DD.MM.YYYY": "02.01.2006",
+}
+
+// HandleImportTransactions imports a CSV file into the scoped portfolio's ledger.
+// The multipart form carries the file in "file" and optionally:
+//   - kind: transactions (default, one ledger entry per row) or holdings (one position per row)
+//   - mapping: JSON object from field name to column header, e.g. {"symbol": "Ticker", "trade_date": "Date"}
+//   - date_format: YYYY-MM-DD (default), MM/DD/YYYY, DD/MM/YYYY or DD.MM.YYYY
+//   - dry_run: true to validate without writing, also accepted as a query param
+//
+// Rows already imported are skipped, so the same file can be uploaded again. Everything is written in one
+// database transaction and nothing is written when any row is invalid. A dry run does not check symbols
+// against the market data provider.
+func (s *Server) HandleImportTransactions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if interval == "" {
+		interval = types.IntervalMonthly
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "Invalid multipart form, expected a CSV file of at most 10MB in the file field", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing CSV file in the file field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts, dryRun, err := parseImportOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	parsed := importer.Parse(file, opts)
+
+	report := &types.ImportReport{
+		Kind:         opts.Kind,
+		DryRun:       dryRun,
+		Rows:         parsed.Total,
+		Errors:       parsed.Errors,
+		Transactions: []types.Transaction{},
+	}
+	if report.Errors == nil {
+		report.Errors = []types.ImportRowError{}
+	}
+
+	if len(report.Errors) > 0 {
+		writeImportReport(w, report)
+		return
+	}
+
+	if !dryRun {
+		// Buys may open holdings, store the price series of their symbols first
+		var unknown map[string]bool
+		report.PendingPrices, unknown = s.fetchImportPrices(ctx, parsed.Rows, interval)
+		for _, row := range parsed.Rows {
+			if unknown[row.Transaction.Symbol] {
+				report.Errors = append(report.Errors, types.ImportRowError{Line: row.Line, Field: "symbol", Message: "stock symbol not found"})
+			}
+		}
+		if len(report.Errors) > 0 {
+			writeImportReport(w, report)
+			return
+		}
+	}
+
+	transactions := make([]types.Transaction, len(parsed.Rows))
+	for i, row := range parsed.Rows {
+		transactions[i] = row.Transaction
+	}
+
+	imported, duplicates, err := s.db.ImportPortfolioTransactions(ctx, portfolioID, transactions, interval, dryRun)
+	if err != nil {
+		var holdingsErr *types.InsufficientHoldingsError
+		var lotErr *types.InvalidLotError
+		var currencyErr *types.CurrencyMismatchError
+		switch {
+		case errors.As(err, &holdingsErr):
+			report.Errors = append(report.Errors, types.ImportRowError{Line: sellLine(parsed.Rows, holdingsErr), Message: holdingsErr.Error()})
+		case errors.As(err, &lotErr):
+			report.Errors = append(report.Errors, types.ImportRowError{Message: lotErr.Error()})
+		case errors.As(err, &currencyErr):
+			report.Errors = append(report.Errors, types.ImportRowError{Field: "currency", Message: currencyErr.Error()})
+		default:
+			http.Error(w, "Could not import transactions", http.StatusInternalServerError)
+			return
+		}
+		writeImportReport(w, report)
+		return
+	}
+
+	report.Imported = len(imported)
+	report.Duplicates = duplicates
+	if imported != nil {
+		report.Transactions = imported
+	}
+
+	writeImportReport(w, report)
+}
+
+// parseImportOptions reads the import settings from the multipart form
+func parseImportOptions(r *http.Request) (importer.Options, bool, error) {
+	opts := importer.Options{Kind: types.ImportKind(r.FormValue("kind"))}
+	if opts.Kind == "" {
+		opts.Kind = types.ImportTransactions
+	}
+	if !opts.Kind.IsValid() {
+		return opts, false, fmt.Errorf("invalid kind, expected transactions or holdings")
+	}
+
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			return opts, false, fmt.Errorf("invalid mapping, expected a JSON object from field name to column header")
+		}
+	}
+
+	if format := r.FormValue("date_format"); format != "" {
+		layout, ok := importDateFormats[format]
+		if !ok {
+			return opts, false, fmt.Errorf("invalid date_format, expected one of YYYY-MM-DD, MM/DD/YYYY, DD/MM/YYYY, DD.MM.YYYY")
+		}
+		opts.DateLayout = layout
+	}
+
+	dryRun := false
+	if value := r.FormValue("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, false, fmt.Errorf("invalid dry_run, expected true or false")
+		}
+		dryRun = parsed
+	}
+
+	return opts, dryRun, nil
+}
+
+// fetchImportPrices stores the price series of every symbol bought by the import. It returns the symbols
+// the provider could not serve right now, which the background refresh will pick up once they are held,
+// and the symbols the provider does not know.
+func (s *Server) fetchImportPrices(ctx context.Context, rows []importer.Row, interval types.Interval) ([]string, map[string]bool) {
+	var pending []string
+	unknown := make(map[string]bool)
+	fetched := make(map[string]bool)
+
+	for _, row := range rows {
+		symbol := row.Transaction.Symbol
+		if row.Transaction.Type != types.TransactionBuy || fetched[symbol] {
+			continue
+		}
+		fetched[symbol] = true
+
+		if _, err := s.fetchPriceSeries(ctx, symbol, interval); err != nil {
+			var symbolErr *marketdata.InvalidSymbolError
+			if errors.As(err, &symbolErr) {
+				unknown[symbol] = true
+				continue
+			}
+			log.Printf("Import could not fetch prices of %s: %v", symbol, err)
+			pending = append(pending, symbol)
+		}
+	}
+
+	return pending, unknown
+}
+
+// sellLine finds the line of the imported sell rejected by the ledger, 0 when it was already recorded
+func sellLine(rows []importer.Row, err *types.InsufficientHoldingsError) int {
+	for _, row := range rows {
+		t := row.Transaction
+		if t.Type == types.TransactionSell && t.Symbol == err.Symbol && t.TradeDate.Equal(err.TradeDate) {
+			return row.Line
+		}
+	}
+	return 0
+}
+
+// writeImportReport responds 422 when the import has errors, 201 when rows were written and 200 otherwise
+func writeImportReport(w http.ResponseWriter, report *types.ImportReport) {
+	status := http.StatusOK
+	switch {
+	case len(report.Errors) > 0 && !report.DryRun:
+		status = http.StatusUnprocessableEntity
+	case !report.DryRun && report.Imported > 0:
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Could not encode import report", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/indicators"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxIndicatorPeriod   = 500
+	maxIndicatorAverages = 5
+)
+
+// HandleGetStockIndicators computes the technical indicators of a stock over its stored price history
+func (s *Server) HandleGetStockIndicators(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	stockID := chi.URLParam(r, "id")
+	if stockID == "" {
+		http.Error(w, "Stock ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseIndicatorOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stock, err := s.db.GetPortfolioStockByID(ctx, portfolioID, stockID)
+	if err != nil {
+		var notFoundErr *types.StockNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Stock not found in this portfolio", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not retrieve stock", http.StatusInternalServerError)
+		return
+	}
+
+	if interval == "" {
+		interval = stock.Interval
+	}
+
+	// Bars before the from date warm the indicators up
+	bars, err := s.priceHistory(ctx, stock.Symbol, interval, time.Time{}, to)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not retrieve price history")
+		return
+	}
+
+	report := indicators.Compute(bars, from, opts)
+	report.Symbol = stock.Symbol
+	report.Interval = interval
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Could not encode indicators", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseIndicatorOptions reads the optional sma and ema (comma separated periods), rsi, atr,
+// macd (fast,slow,signal) and bollinger (period,std_dev) query params
+func parseIndicatorOptions(r *http.Request) (indicators.Options, error) {
+	opts := indicators.DefaultOptions()
+	query := r.URL.Query()
+
+	if value := query.Get("sma"); value != "" {
+		periods, err := parsePeriods("sma", value, 1, maxIndicatorAverages)
+		if err != nil {
+			return opts, err
+		}
+		opts.SMAPeriods = periods
+	}
+
+	if value := query.Get("ema"); value != "" {
+		periods, err := parsePeriods("ema", value, 1, maxIndicatorAverages)
+		if err != nil {
+			return opts, err
+		}
+		opts.EMAPeriods = periods
+	}
+
+	if value := query.Get("rsi"); value != "" {
+		periods, err := parsePeriods("rsi", value, 1, 1)
+		if err != nil {
+			return opts, err
+		}
+		opts.RSIPeriod = periods[0]
+	}
+
+	if value := query.Get("atr"); value != "" {
+		periods, err := parsePeriods("atr", value, 1, 1)
+		if err != nil {
+			return opts, err
+		}
+		opts.ATRPeriod = periods[0]
+	}
+
+	if value := query.Get("macd"); value != "" {
+		periods, err := parsePeriods("macd", value, 3, 3)
+		if err != nil {
+			return opts, err
+		}
+		if periods[0] >= periods[1] {
+			return opts, fmt.Errorf("invalid macd, the fast period must be shorter than the slow one, e.g. 12,26,9")
+		}
+		opts.MACDFast, opts.MACDSlow, opts.MACDSignal = periods[0], periods[1], periods[2]
+	}
+
+	if value := query.Get("bollinger"); value != "" {
+		parts := strings.Split(value, ",")
+		periods, err := parsePeriods("bollinger", parts[0], 1, 1)
+		if err != nil || len(parts) > 2 {
+			return opts, fmt.Errorf("invalid bollinger, expected a period and an optional number of standard deviations, e.g. 20,2")
+		}
+		opts.BollingerPeriod = periods[0]
+
+		if len(parts) == 2 {
+			stdDev, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err != nil || stdDev <= 0 || stdDev > 5 {
+				return opts, fmt.Errorf("invalid bollinger standard deviations, expected a number between 0 and 5")
+			}
+			opts.BollingerStdDev = stdDev
+		}
+	}
+
+	return opts, nil
+}
+
+// parsePeriods reads a comma separated list of between min and max periods of 2 to maxIndicatorPeriod bars
+func parsePeriods(name, value string, min, max int) ([]int, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) < min || len(parts) > max {
+		if min == max {
+			return nil, fmt.Errorf("invalid %s, expected %d period(s)", name, min)
+		}
+		return nil, fmt.Errorf("invalid %s, expected up to %d periods", name, max)
+	}
+
+	periods := make([]int, 0, len(parts))
+	for _, part := range parts {
+		period, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || period < 2 || period > maxIndicatorPeriod {
+			return nil, fmt.Errorf("invalid %s period %q, expected a whole number of bars between 2 and %d", name, part, maxIndicatorPeriod)
+		}
+		periods = append(periods, period)
+	}
+
+	return periods, nil
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ecetinerdem/forseer/corporate"
+	"github.com/ecetinerdem/forseer/marketdata"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// fetchPriceSeries returns a fresh series through the market data cache.
+// The cache stores every series it fetches in the price history.
+func (s *Server) fetchPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error) {
+	series, err := s.marketData.GetPriceSeries(ctx, symbol, interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s series for %s: %w", interval, symbol, err)
+	}
+
+	return series, nil
+}
+
+// priceHistory returns the stored price history of a symbol adjusted for its splits, fetching the series
+// first when it is missing or expired. Stored bars are still read when an expired series cannot be refreshed.
+func (s *Server) priceHistory(ctx context.Context, symbol string, interval types.Interval, from, to time.Time) ([]types.PriceBar, error) {
+	bars, err := s.unadjustedHistory(ctx, symbol, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := s.db.GetCorporateActions(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return corporate.Adjust(bars, actions), nil
+}
+
+// unadjustedHistory returns the stored price history of a symbol as traded. Replays of a ledger read it,
+// the ledger's split entries rescale its quantities at the ex-date instead.
+func (s *Server) unadjustedHistory(ctx context.Context, symbol string, interval types.Interval, from, to time.Time) ([]types.PriceBar, error) {
+	if _, err := s.fetchPriceSeries(ctx, symbol, interval); err != nil {
+		return nil, err
+	}
+
+	return s.db.GetPriceHistory(ctx, symbol, interval, from, to)
+}
+
+// applyInterval replaces the stock's latest bar with the latest bar of the requested interval.
+// An empty interval keeps the holding's own interval.
+func (s *Server) applyInterval(ctx context.Context, stock *types.Stock, interval types.Interval) error {
+	if interval == "" || interval == stock.Interval {
+		return nil
+	}
+
+	bars, err := s.priceHistory(ctx, stock.Symbol, interval, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	stock.Interval = interval
+	if len(bars) == 0 {
+		return nil
+	}
+
+	latest := bars[len(bars)-1]
+	stock.Date = latest.Date
+	stock.Open = latest.Open
+	stock.High = latest.High
+	stock.Low = latest.Low
+	stock.Close = latest.Close
+	stock.Volume = latest.Volume
+
+	return nil
+}
+
+// writeMarketDataError maps market data provider errors to HTTP responses.
+// Rate limits become 429, exhausted quota or a full queue 503 and refused requests 422, so bogus data is never returned.
+func writeMarketDataError(w http.ResponseWriter, err error, message string) {
+	var rateLimitErr *marketdata.RateLimitError
+	var quotaErr *marketdata.QuotaExceededError
+	var queueErr *marketdata.QueueFullError
+	var symbolErr *marketdata.InvalidSymbolError
+	var unsupportedErr *marketdata.UnsupportedRequestError
+	var fxErr *types.MissingFXRateError
+
+	switch {
+	case errors.As(err, &rateLimitErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+		http.Error(w, "Market data rate limit reached, please retry later", http.StatusTooManyRequests)
+	case errors.As(err, &quotaErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quotaErr.ResetAt).Seconds()))))
+		http.Error(w, "Market data daily quota exceeded", http.StatusServiceUnavailable)
+	case errors.As(err, &queueErr):
+		http.Error(w, "Market data service is busy, please retry later", http.StatusServiceUnavailable)
+	case errors.As(err, &symbolErr):
+		http.Error(w, "Stock symbol not found", http.StatusNotFound)
+	case errors.As(err, &unsupportedErr):
+		http.Error(w, "Market data request is not available with the configured API key", http.StatusUnprocessableEntity)
+	case errors.As(err, &fxErr):
+		http.Error(w, fxErr.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// HandleGetRefreshStatus returns the background price refresh schedules and the last refresh of the symbols
+// the user holds, watches or has alerts on
+func (s *Server) HandleGetRefreshStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	tracked, err := s.db.GetUserTrackedSymbols(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve tracked symbols", http.StatusInternalServerError)
+		return
+	}
+
+	own := make(map[types.TrackedSymbol]bool, len(tracked))
+	for _, symbol := range tracked {
+		own[symbol] = true
+	}
+
+	// Other users' symbols are not shown
+	status := s.refreshScheduler.Status()
+	symbols := status.Symbols[:0]
+	for _, symbolStatus := range status.Symbols {
+		if own[types.TrackedSymbol{Symbol: symbolStatus.Symbol, Interval: symbolStatus.Interval}] {
+			symbols = append(symbols, symbolStatus)
+		}
+	}
+	status.Symbols = symbols
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		http.Error(w, "Could not encode refresh status", http.StatusInternalServerError)
+		return
+	}
+}
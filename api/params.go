@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseDateRange reads the optional from and to query params (YYYY-MM-DD).
+// A missing bound is returned as the zero time.
+func parseDateRange(r *http.Request) (time.Time, time.Time, error) {
+	var from, to time.Time
+
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+		from = parsed
+	}
+
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		// Include the whole end day
+		to = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return from, to, fmt.Errorf("from date must be before to date")
+	}
+
+	return from, to, nil
+}
+
+// parseInterval reads the optional interval query param.
+// An empty interval is returned when the param is missing.
+func parseInterval(r *http.Request) (types.Interval, error) {
+	interval := types.Interval(r.URL.Query().Get("interval"))
+	if interval == "" {
+		return "", nil
+	}
+
+	if !interval.IsValid() {
+		return "", fmt.Errorf("invalid interval, expected one of 1min, 5min, 15min, 60min, daily, daily_adjusted, weekly, monthly")
+	}
+
+	return interval, nil
+}
+
+// parseAddStockRequest validates the body of a request opening a holding and returns its acquisition date,
+// today when not given
+func parseAddStockRequest(req types.AddStockRequest) (time.Time, error) {
+	if req.Quantity <= 0 {
+		return time.Time{}, fmt.Errorf("quantity must be greater than zero")
+	}
+
+	if req.AverageCost < 0 {
+		return time.Time{}, fmt.Errorf("average cost cannot be negative")
+	}
+
+	if req.AcquiredAt == "" {
+		return time.Now().UTC().Truncate(24 * time.Hour), nil
+	}
+
+	acquiredAt, err := time.Parse(dateLayout, req.AcquiredAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid acquired_at date, expected YYYY-MM-DD")
+	}
+
+	return acquiredAt, nil
+}
+
+// parseCurrency normalizes a currency code, an empty code is returned as is for the caller's default
+func parseCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code != "" && !types.ValidCurrency(code) {
+		return "", fmt.Errorf("invalid currency %q, expected a three letter code such as USD", code)
+	}
+	return code, nil
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/performance"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// ledgerHistory loads the daily price history as traded of every symbol the transactions traded, held or not,
+// up to the to date (open when zero). The transactions and prices are returned converted to the base
+// currency, each at the rate of its date, so values include currency moves.
+func (s *Server) ledgerHistory(ctx context.Context, baseCurrency string, transactions []types.Transaction, to time.Time) ([]types.Transaction, map[string][]types.PriceBar, error) {
+	converted, converter, err := s.baseLedger(ctx, baseCurrency, transactions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	history := make(map[string][]types.PriceBar)
+	for _, tx := range transactions {
+		if tx.Symbol == "" {
+			continue
+		}
+		if _, ok := history[tx.Symbol]; ok {
+			continue
+		}
+
+		bars, err := s.unadjustedHistory(ctx, tx.Symbol, types.IntervalDaily, time.Time{}, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		if history[tx.Symbol], err = converter.Bars(bars, tx.Currency); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return converted, history, nil
+}
+
+// portfolioPerformance measures the returns of a portfolio's ledger between from and to, both may be zero
+func (s *Server) portfolioPerformance(ctx context.Context, portfolio *types.Portfolio, from, to time.Time) (*types.PerformanceReport, error) {
+	transactions, err := s.db.GetPortfolioTransactions(ctx, portfolio.ID, types.TransactionFilter{To: to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	transactions, history, err := s.ledgerHistory(ctx, portfolio.BaseCurrency, transactions, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := performance.Compute(transactions, history, from, to)
+	if err != nil {
+		return nil, err
+	}
+	report.PortfolioID = portfolio.ID
+	report.Currency = portfolio.BaseCurrency
+
+	return report, nil
+}
+
+// HandleGetPerformance returns the time-weighted and money-weighted returns of the scoped portfolio
+// with its daily values. The from and to query params narrow the period, by default it spans the whole ledger.
+func (s *Server) HandleGetPerformance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := s.db.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	report, err := s.portfolioPerformance(ctx, portfolio, from, to)
+	if err != nil {
+		var noPerformanceErr *types.NoPerformanceError
+		if errors.As(err, &noPerformanceErr) {
+			http.Error(w, noPerformanceErr.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		writeMarketDataError(w, err, "Could not compute performance")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Could not encode performance", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/lots"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// matchPortfolioLots matches the ledger of a portfolio with its cost basis method, in the portfolio's
+// base currency. Costs convert at the rate of the buy's trade date and proceeds at the sell's, so gains
+// include currency moves. The latest close of every holding in the base currency is returned keyed by symbol.
+func (s *Server) matchPortfolioLots(ctx context.Context, portfolioID string) (*types.Portfolio, *lots.Result, map[string]float64, error) {
+	portfolio, err := s.db.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+
+	transactions, err := s.db.GetPortfolioTransactions(ctx, portfolioID, types.TransactionFilter{})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	transactions, _, err = s.baseLedger(ctx, portfolio.BaseCurrency, transactions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	result, err := lots.Match(transactions, portfolio.CostBasisMethod)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to match lots: %w", err)
+	}
+
+	if err := s.valuePortfolio(ctx, portfolio); err != nil {
+		return nil, nil, nil, err
+	}
+
+	prices := make(map[string]float64, len(portfolio.Stocks))
+	for _, stock := range portfolio.Stocks {
+		prices[stock.Symbol] = stock.Close * stock.FXRate
+	}
+
+	return portfolio, result, prices, nil
+}
+
+// HandleGetPnL returns the realized and unrealized gains of the scoped portfolio.
+// The from and to query params limit the realized gains to sells in that range.
+func (s *Server) HandleGetPnL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	portfolio, result, prices, err := s.matchPortfolioLots(ctx, portfolioID)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not compute gains")
+		return
+	}
+
+	report := lots.Report(result.Open, result.RealizedBetween(from, to), prices, time.Now().UTC())
+	report.PortfolioID = portfolio.ID
+	report.Method = portfolio.CostBasisMethod
+	report.Currency = portfolio.BaseCurrency
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Could not encode gains", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetOpenLots returns the open lots of the scoped portfolio (symbol query param)
+func (s *Server) HandleGetOpenLots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
+
+	_, result, prices, err := s.matchPortfolioLots(ctx, portfolioID)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not compute lots")
+		return
+	}
+
+	report := lots.Report(result.Open, nil, prices, time.Now().UTC())
+
+	openLots := []types.Lot{}
+	for _, holding := range report.Holdings {
+		if symbol == "" || holding.Symbol == symbol {
+			openLots = append(openLots, holding.Lots...)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(openLots); err != nil {
+		http.Error(w, "Could not encode lots", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetRealizedLots returns the closed lots of the scoped portfolio
+// (symbol, from and to query params)
+func (s *Server) HandleGetRealizedLots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
+
+	_, result, _, err := s.matchPortfolioLots(ctx, portfolioID)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not compute lots")
+		return
+	}
+
+	realized := []types.RealizedLot{}
+	for _, lot := range result.RealizedBetween(from, to) {
+		if symbol == "" || lot.Symbol == symbol {
+			realized = append(realized, lot)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(realized); err != nil {
+		http.Error(w, "Could not encode lots", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleSetCostBasisMethod changes the lot matching method of the scoped portfolio
+func (s *Server) HandleSetCostBasisMethod(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		Method types.CostBasisMethod `json:"method"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !req.Method.IsValid() {
+		http.Error(w, "Invalid method, expected one of fifo, lifo, specific, average", http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := s.db.SetPortfolioCostBasisMethod(ctx, portfolioID, req.Method)
+	if err != nil {
+		var lotErr *types.InvalidLotError
+		if errors.As(err, &lotErr) {
+			http.Error(w, lotErr.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Could not update cost basis method", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(portfolio); err != nil {
+		http.Error(w, "Could not encode portfolio", http.StatusInternalServerError)
+		return
+	}
+}
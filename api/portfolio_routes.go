@@ -1,17 +1,20 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/ecetinerdem/forseer/api/utils"
 	"github.com/ecetinerdem/forseer/middleware"
 	"github.com/ecetinerdem/forseer/types"
 	"github.com/go-chi/chi/v5"
 )
 
-// HandleGetPortfolio returns the authenticated user's portfolio with all stocks
+// HandleGetPortfolio returns the scoped portfolio with all stocks
 func (s *Server) HandleGetPortfolio(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -21,17 +24,28 @@ func (s *Server) HandleGetPortfolio(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
 	if s.db == nil {
 		http.Error(w, "Database connection not available", http.StatusInternalServerError)
 		return
 	}
 
-	portfolio, err := s.db.GetUserPortfolio(ctx, user.ID)
+	portfolio, err := s.db.GetPortfolio(ctx, portfolioID)
 	if err != nil {
 		http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
 		return
 	}
 
+	if err := s.valuePortfolio(ctx, portfolio); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -41,7 +55,7 @@ func (s *Server) HandleGetPortfolio(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleGetStockByID returns a specific stock if it belongs to the authenticated user
+// HandleGetStockByID returns a specific stock of the scoped portfolio
 func (s *Server) HandleGetStockByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -51,23 +65,45 @@ func (s *Server) HandleGetStockByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
 	stockID := chi.URLParam(r, "id")
 	if stockID == "" {
 		http.Error(w, "Stock ID cannot be empty", http.StatusBadRequest)
 		return
 	}
 
-	stock, err := s.db.GetUserStockByID(ctx, user.ID, stockID)
+	interval, err := parseInterval(r)
 	if err != nil {
-		var ownershipErr *types.StockOwnershipError
-		if errors.As(err, &ownershipErr) {
-			http.Error(w, "Stock not found or you don't have access to it", http.StatusNotFound)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stock, err := s.db.GetPortfolioStockByID(ctx, portfolioID, stockID)
+	if err != nil {
+		var notFoundErr *types.StockNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Stock not found in this portfolio", http.StatusNotFound)
 			return
 		}
 		http.Error(w, "Could not retrieve stock", http.StatusInternalServerError)
 		return
 	}
 
+	if err := s.applyInterval(ctx, stock, interval); err != nil {
+		writeMarketDataError(w, err, "Error while fetching stock data")
+		return
+	}
+
+	if err := s.valueHolding(ctx, stock); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -77,7 +113,7 @@ func (s *Server) HandleGetStockByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleAddStockToPortfolio adds a stock to the authenticated user's portfolio
+// HandleAddStockToPortfolio adds a stock to the scoped portfolio
 func (s *Server) HandleAddStockToPortfolio(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -87,33 +123,64 @@ func (s *Server) HandleAddStockToPortfolio(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	stockSymbol := chi.URLParam(r, "symbol")
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	stockSymbol := strings.ToUpper(chi.URLParam(r, "symbol"))
 	if stockSymbol == "" {
 		http.Error(w, "Stock symbol cannot be empty", http.StatusBadRequest)
 		return
 	}
 
-	// Check if user already has this stock
-	existingStock, err := s.db.GetUserStockBySymbol(ctx, user.ID, stockSymbol)
-	if err == nil && existingStock != nil {
-		http.Error(w, "Stock already exists in your portfolio", http.StatusConflict)
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if interval == "" {
+		interval = types.IntervalMonthly
+	}
+
+	var req types.AddStockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Fetch stock data from external API
-	stock, err := utils.GetAlphaVentageStock(user, stockSymbol)
+	acquiredAt, err := parseAddStockRequest(req)
 	if err != nil {
-		http.Error(w, "Error while fetching stock data", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Add stock to user's portfolio
-	addedStock, err := s.db.AddStockToUserPortfolio(ctx, user.ID, stock)
+	currency, err := parseCurrency(req.Currency)
 	if err != nil {
-		http.Error(w, "Could not add stock to portfolio", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// Check if the portfolio already holds this stock
+	existingStock, err := s.db.GetPortfolioStockBySymbol(ctx, portfolioID, stockSymbol)
+	if err == nil && existingStock != nil {
+		http.Error(w, "Stock already exists in this portfolio", http.StatusConflict)
+		return
+	}
+
+	addedStock, err := s.openHolding(ctx, portfolioID, stockSymbol, interval, req.Quantity, req.AverageCost, currency, acquiredAt)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not add stock to portfolio")
+		return
+	}
+
+	if err := s.valueHolding(ctx, addedStock); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+	s.webhooks.Publish(ctx, user.ID, types.WebhookEventStockAdded, addedStock)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 
@@ -123,7 +190,72 @@ func (s *Server) HandleAddStockToPortfolio(w http.ResponseWriter, r *http.Reques
 	}
 }
 
-// HandleDeleteStockByID deletes a stock from the authenticated user's portfolio
+// HandleGetStockHistory returns the stored price history of a stock of the scoped portfolio
+func (s *Server) HandleGetStockHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	stockID := chi.URLParam(r, "id")
+	if stockID == "" {
+		http.Error(w, "Stock ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stock, err := s.db.GetPortfolioStockByID(ctx, portfolioID, stockID)
+	if err != nil {
+		var notFoundErr *types.StockNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Stock not found in this portfolio", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not retrieve stock", http.StatusInternalServerError)
+		return
+	}
+
+	if interval == "" {
+		interval = stock.Interval
+	}
+
+	bars, err := s.priceHistory(ctx, stock.Symbol, interval, from, to)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not retrieve price history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(bars); err != nil {
+		http.Error(w, "Could not encode price history", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeleteStockByID deletes a stock and its buys from the scoped portfolio. A stock with sells, dividends
+// or splits in the ledger is refused with 409, record a sell to close it or pass purge=true to erase its ledger.
 func (s *Server) HandleDeleteStockByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -133,23 +265,50 @@ func (s *Server) HandleDeleteStockByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
 	stockID := chi.URLParam(r, "id")
 	if stockID == "" {
 		http.Error(w, "Stock ID cannot be empty", http.StatusBadRequest)
 		return
 	}
 
-	err := s.db.DeleteUserStockByID(ctx, user.ID, stockID)
+	purge := false
+	if value := r.URL.Query().Get("purge"); value != "" {
+		var err error
+		purge, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid purge, expected true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Read the holding first so the stock.removed event can describe it
+	stock, err := s.db.GetPortfolioStockByID(ctx, portfolioID, stockID)
+	if err == nil {
+		err = s.db.DeletePortfolioStockByID(ctx, portfolioID, stockID, purge)
+	}
 	if err != nil {
-		var ownershipErr *types.StockOwnershipError
-		if errors.As(err, &ownershipErr) {
-			http.Error(w, "Stock not found or you don't have access to it", http.StatusNotFound)
+		var notFoundErr *types.StockNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Stock not found in this portfolio", http.StatusNotFound)
+			return
+		}
+		var historyErr *types.StockHistoryError
+		if errors.As(err, &historyErr) {
+			http.Error(w, historyErr.Error()+", record a sell to close it or delete it with purge=true to erase its ledger", http.StatusConflict)
 			return
 		}
 		http.Error(w, "Could not delete stock", http.StatusInternalServerError)
 		return
 	}
 
+	s.webhooks.Publish(ctx, user.ID, types.WebhookEventStockRemoved, stock)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -164,7 +323,7 @@ func (s *Server) HandleDeleteStockByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleGetStockBySymbol returns a stock by symbol if it belongs to the authenticated user
+// HandleGetStockBySymbol returns a stock of the scoped portfolio by symbol
 func (s *Server) HandleGetStockBySymbol(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -174,15 +333,37 @@ func (s *Server) HandleGetStockBySymbol(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	symbol := r.URL.Query().Get("symbol")
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
 	if symbol == "" {
 		http.Error(w, "Stock symbol cannot be empty", http.StatusBadRequest)
 		return
 	}
 
-	stock, err := s.db.GetUserStockBySymbol(ctx, user.ID, symbol)
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stock, err := s.db.GetPortfolioStockBySymbol(ctx, portfolioID, symbol)
 	if err != nil {
-		http.Error(w, "Stock with given symbol not found in your portfolio", http.StatusNotFound)
+		http.Error(w, "Stock with given symbol not found in this portfolio", http.StatusNotFound)
+		return
+	}
+
+	if err := s.applyInterval(ctx, stock, interval); err != nil {
+		writeMarketDataError(w, err, "Error while fetching stock data")
+		return
+	}
+
+	if err := s.valueHolding(ctx, stock); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
 		return
 	}
 
@@ -195,7 +376,7 @@ func (s *Server) HandleGetStockBySymbol(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// HandleGetUserStocks returns all stocks in the authenticated user's portfolio
+// HandleGetUserStocks returns all stocks in the scoped portfolio
 func (s *Server) HandleGetUserStocks(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -205,12 +386,36 @@ func (s *Server) HandleGetUserStocks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stocks, err := s.db.GetUserStocks(ctx, user.ID)
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stocks, err := s.db.GetPortfolioStocks(ctx, portfolioID)
 	if err != nil {
 		http.Error(w, "Could not retrieve stocks", http.StatusInternalServerError)
 		return
 	}
 
+	for i := range stocks {
+		if err := s.applyInterval(ctx, &stocks[i], interval); err != nil {
+			writeMarketDataError(w, err, "Error while fetching stock data")
+			return
+		}
+	}
+
+	if err := s.valueHoldings(ctx, stocks); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -245,6 +450,11 @@ func (s *Server) HandleCreatePortfolio(w http.ResponseWriter, r *http.Request) {
 
 	portfolio, err := s.db.CreateUserPortfolio(ctx, user.ID, req.Name)
 	if err != nil {
+		var nameErr *types.PortfolioNameTakenError
+		if errors.As(err, &nameErr) {
+			http.Error(w, nameErr.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "Could not create portfolio", http.StatusInternalServerError)
 		return
 	}
@@ -257,3 +467,192 @@ func (s *Server) HandleCreatePortfolio(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// HandleGetPortfolios returns every portfolio of the authenticated user, the default one first
+func (s *Server) HandleGetPortfolios(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolios, err := s.db.GetUserPortfolios(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve portfolios", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(portfolios); err != nil {
+		http.Error(w, "Could not encode portfolios", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleRenamePortfolio renames the scoped portfolio
+func (s *Server) HandleRenamePortfolio(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Portfolio name cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := s.db.RenamePortfolio(ctx, portfolioID, req.Name)
+	if err != nil {
+		var nameErr *types.PortfolioNameTakenError
+		if errors.As(err, &nameErr) {
+			http.Error(w, nameErr.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not rename portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(portfolio); err != nil {
+		http.Error(w, "Could not encode portfolio", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleSetDefaultPortfolio makes the scoped portfolio the authenticated user's default one
+func (s *Server) HandleSetDefaultPortfolio(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	portfolio, err := s.db.SetUserDefaultPortfolio(ctx, user.ID, portfolioID)
+	if err != nil {
+		var ownershipErr *types.PortfolioOwnershipError
+		if errors.As(err, &ownershipErr) {
+			http.Error(w, "Portfolio not found or you don't have access to it", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not set default portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(portfolio); err != nil {
+		http.Error(w, "Could not encode portfolio", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeletePortfolio deletes the scoped portfolio with its stocks and transactions
+func (s *Server) HandleDeletePortfolio(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	err := s.db.DeleteUserPortfolio(ctx, user.ID, portfolioID)
+	if err != nil {
+		var ownershipErr *types.PortfolioOwnershipError
+		if errors.As(err, &ownershipErr) {
+			http.Error(w, "Portfolio not found or you don't have access to it", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not delete portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]string{
+		"message":     "Portfolio deleted successfully",
+		"portfolioId": portfolioID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Could not encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// openHolding fetches and stores the symbol's price series, then records the opening buy of a holding.
+func (s *Server) openHolding(ctx context.Context, portfolioID, symbol string, interval types.Interval, quantity, averageCost float64, currency string, acquiredAt time.Time) (*types.Stock, error) {
+	stock, err := s.newHolding(ctx, symbol, interval, quantity, averageCost, currency, acquiredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.db.AddStockToPortfolio(ctx, portfolioID, stock)
+}
+
+// newHolding fetches and stores the symbol's price series and returns the holding to open on it.
+// Without an explicit cost the holding is assumed to be bought at the latest close. An empty currency
+// defaults to the currency of the symbol's listing.
+func (s *Server) newHolding(ctx context.Context, symbol string, interval types.Interval, quantity, averageCost float64, currency string, acquiredAt time.Time) (*types.Stock, error) {
+	series, err := s.fetchPriceSeries(ctx, symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	if averageCost == 0 && series.Latest() != nil {
+		averageCost = series.Latest().Close
+	}
+
+	// The holding is derived from the ledger and references the stored series
+	stock := &types.Stock{
+		Symbol:      series.Symbol,
+		Interval:    series.Interval,
+		Quantity:    quantity,
+		AverageCost: averageCost,
+		Currency:    currency,
+		AcquiredAt:  acquiredAt,
+	}
+
+	return stock, nil
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// PortfolioScope resolves the portfolio a request acts on and stores its ID in the context.
+// Routes with a {portfolioID} param must name a portfolio the user owns,
+// other routes use the user's default portfolio.
+func (s *Server) PortfolioScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		user := middleware.User(ctx)
+		if user == nil {
+			http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+			return
+		}
+
+		portfolioID := chi.URLParam(r, "portfolioID")
+		if portfolioID == "" {
+			portfolio, err := s.db.GetUserDefaultPortfolio(ctx, user.ID)
+			if err != nil {
+				http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+				return
+			}
+			portfolioID = portfolio.ID
+		} else {
+			owns, err := s.db.UserOwnsPortfolio(ctx, user.ID, portfolioID)
+			if err != nil {
+				http.Error(w, "Could not verify portfolio ownership", http.StatusInternalServerError)
+				return
+			}
+			if !owns {
+				http.Error(w, "Portfolio not found or you don't have access to it", http.StatusNotFound)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(middleware.WithPortfolioID(ctx, portfolioID)))
+	})
+}
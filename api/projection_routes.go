@@ -0,0 +1,235 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/ecetinerdem/forseer/benchmark"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/montecarlo"
+	"github.com/ecetinerdem/forseer/risk"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+const (
+	defaultProjectionYears    = 10
+	maxProjectionYears        = 50
+	defaultProjectionPaths    = 1000
+	minProjectionPaths        = 100
+	maxProjectionPaths        = 10000
+	defaultProjectionLookback = 5 * 365
+	maxProjectionLookback     = 30 * 365
+
+	// maxProjectionPathYears bounds the work and memory of a projection, 10000 paths over 20 years
+	maxProjectionPathYears = 200000
+
+	// maxProjectionSeed keeps the seed exact as a JSON number so a response reproduces its projection
+	maxProjectionSeed = 1 << 53
+
+	// maxConcurrentProjections is how many projections run at once, each on its share of the CPUs
+	maxConcurrentProjections = 2
+)
+
+// parseProjectionParams reads the years, paths, method, contribution, frequency, seed and target query params
+// and the from and to or lookback (days before to, five years by default) of the history drawn from
+func parseProjectionParams(r *http.Request) (montecarlo.Options, time.Time, time.Time, error) {
+	opts := montecarlo.Options{
+		Years:     defaultProjectionYears,
+		Paths:     defaultProjectionPaths,
+		Method:    types.ProjectionBootstrap,
+		Frequency: types.RebalanceMonthly,
+		Seed:      rand.Int64N(maxProjectionSeed),
+		Workers:   max(runtime.GOMAXPROCS(0)/maxConcurrentProjections, 1),
+	}
+	query := r.URL.Query()
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		return opts, from, to, err
+	}
+	if to.IsZero() {
+		to = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+
+	if value := query.Get("lookback"); value != "" {
+		if !from.IsZero() {
+			return opts, from, to, fmt.Errorf("use either from or lookback, not both")
+		}
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 2 || days > maxProjectionLookback {
+			return opts, from, to, fmt.Errorf("invalid lookback, expected a number of days between 2 and %d", maxProjectionLookback)
+		}
+		from = to.AddDate(0, 0, -days)
+	} else if from.IsZero() {
+		from = to.AddDate(0, 0, -defaultProjectionLookback)
+	}
+
+	if value := query.Get("years"); value != "" {
+		opts.Years, err = strconv.Atoi(value)
+		if err != nil || opts.Years < 1 || opts.Years > maxProjectionYears {
+			return opts, from, to, fmt.Errorf("invalid years, expected a whole number between 1 and %d", maxProjectionYears)
+		}
+	}
+
+	if value := query.Get("paths"); value != "" {
+		opts.Paths, err = strconv.Atoi(value)
+		if err != nil || opts.Paths < minProjectionPaths || opts.Paths > maxProjectionPaths {
+			return opts, from, to, fmt.Errorf("invalid paths, expected a whole number between %d and %d", minProjectionPaths, maxProjectionPaths)
+		}
+	}
+
+	if value := query.Get("method"); value != "" {
+		opts.Method = types.ProjectionMethod(value)
+		if !opts.Method.IsValid() {
+			return opts, from, to, fmt.Errorf("invalid method, expected bootstrap or normal")
+		}
+	}
+
+	if value := query.Get("contribution"); value != "" {
+		opts.Contribution, err = strconv.ParseFloat(value, 64)
+		if err != nil || opts.Contribution < 0 {
+			return opts, from, to, fmt.Errorf("invalid contribution, expected an amount of at least 0")
+		}
+	}
+
+	if value := query.Get("frequency"); value != "" {
+		opts.Frequency = types.RebalanceFrequency(value)
+		if opts.Frequency != types.RebalanceMonthly && opts.Frequency != types.RebalanceQuarterly && opts.Frequency != types.RebalanceAnnually {
+			return opts, from, to, fmt.Errorf("invalid frequency, expected monthly, quarterly or annually")
+		}
+	}
+
+	if value := query.Get("seed"); value != "" {
+		opts.Seed, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || opts.Seed < -maxProjectionSeed || opts.Seed > maxProjectionSeed {
+			return opts, from, to, fmt.Errorf("invalid seed, expected a whole number between -2^53 and 2^53")
+		}
+	}
+
+	if value := query.Get("target"); value != "" {
+		target, err := strconv.ParseFloat(value, 64)
+		if err != nil || target <= 0 {
+			return opts, from, to, fmt.Errorf("invalid target, expected an amount greater than 0")
+		}
+		opts.Target = &target
+	}
+
+	if opts.Paths*opts.Years > maxProjectionPathYears {
+		return opts, from, to, fmt.Errorf("too many paths for the years, paths times years can be at most %d", maxProjectionPathYears)
+	}
+
+	return opts, from, to, nil
+}
+
+// HandleGetProjection runs a Monte Carlo projection of the scoped portfolio's value. Query params: years (10 by
+// default), paths (1000 by default), method (bootstrap or normal), contribution in the base currency with its
+// frequency (monthly by default), seed to reproduce a projection, target value, and lookback in days (five
+// years by default) or from and to for the history returns are drawn from. At most maxConcurrentProjections
+// run at once, requests over it are turned away with 429.
+func (s *Server) HandleGetProjection(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	opts, from, to, err := parseProjectionParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := s.db.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.valuePortfolio(ctx, portfolio); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
+	// Holdings at their current weights, a symbol held twice counts once
+	weights := make(map[string]float64)
+	var components []types.BenchmarkComponent
+	for _, stock := range portfolio.Stocks {
+		if stock.MarketValue <= 0 {
+			continue
+		}
+		if _, ok := weights[stock.Symbol]; !ok {
+			components = append(components, types.BenchmarkComponent{Symbol: stock.Symbol})
+		}
+		weights[stock.Symbol] += stock.MarketValue
+	}
+	if len(components) == 0 {
+		http.Error(w, "Portfolio has no valued holdings to project", http.StatusBadRequest)
+		return
+	}
+
+	history := make(map[string][]types.PriceBar, len(components))
+	for i := range components {
+		components[i].Weight = weights[components[i].Symbol] / portfolio.TotalValue
+
+		bars, err := s.priceHistory(ctx, components[i].Symbol, types.IntervalDaily, time.Time{}, to)
+		if err != nil {
+			writeMarketDataError(w, err, "Could not retrieve price history")
+			return
+		}
+		history[components[i].Symbol] = bars
+	}
+
+	// Kept at their weights every day, like a composite benchmark
+	daily := risk.Between(benchmark.Returns(components, types.RebalanceDaily, history), from, to)
+	returns := make([]float64, len(daily))
+	for i, r := range daily {
+		returns[i] = r.Value
+	}
+
+	select {
+	case s.projections <- struct{}{}:
+		defer func() { <-s.projections }()
+	default:
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Too many projections are running, please retry later", http.StatusTooManyRequests)
+		return
+	}
+
+	report, err := montecarlo.Project(ctx, portfolio.TotalValue, returns, time.Now().UTC().Truncate(24*time.Hour), opts)
+	if err != nil {
+		var historyErr *types.InsufficientHistoryError
+		if errors.As(err, &historyErr) {
+			http.Error(w, historyErr.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Could not run projection", http.StatusInternalServerError)
+		return
+	}
+
+	report.PortfolioID = portfolio.ID
+	report.Currency = portfolio.BaseCurrency
+	report.HistoryFrom = from
+	report.HistoryTo = to
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Could not encode projection", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/performance"
+	"github.com/ecetinerdem/forseer/risk"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+const defaultBenchmark = "SPY"
+
+var benchmarkPattern = regexp.MustCompile(`^[A-Z0-9.\-]{1,10}$`)
+
+// riskParams select the period, benchmark and assumptions of a risk measurement
+type riskParams struct {
+	from, to  time.Time
+	benchmark string
+	options   risk.Options
+}
+
+// defaultRiskParams measure the trailing year against the default benchmark at 95% confidence
+func defaultRiskParams() riskParams {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	return riskParams{
+		from:      to.AddDate(-1, 0, 0),
+		to:        to,
+		benchmark: defaultBenchmark,
+		options:   risk.Options{Confidence: 0.95},
+	}
+}
+
+// parseRiskParams reads the from, to, benchmark, risk_free_rate (annual percent) and confidence query params.
+// Without a from date the year before the to date, or today, is measured.
+func parseRiskParams(r *http.Request) (riskParams, error) {
+	params := defaultRiskParams()
+
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		return params, err
+	}
+	if !to.IsZero() {
+		params.to = to
+		params.from = to.AddDate(-1, 0, 0)
+	}
+	if !from.IsZero() {
+		params.from = from
+	}
+
+	if value := r.URL.Query().Get("benchmark"); value != "" {
+		params.benchmark = strings.ToUpper(strings.TrimSpace(value))
+		if !benchmarkPattern.MatchString(params.benchmark) {
+			return params, fmt.Errorf("invalid benchmark symbol")
+		}
+	}
+
+	if params.options.RiskFreeRate, err = parseRiskFreeRate(r); err != nil {
+		return params, err
+	}
+
+	if value := r.URL.Query().Get("confidence"); value != "" {
+		confidence, err := strconv.ParseFloat(value, 64)
+		if err != nil || confidence < 0.5 || confidence >= 1 {
+			return params, fmt.Errorf("invalid confidence, expected a fraction between 0.5 and 1, e.g. 0.95")
+		}
+		params.options.Confidence = confidence
+	}
+
+	return params, nil
+}
+
+// parseRiskFreeRate reads the optional risk_free_rate query param, an annual percentage returned as a fraction
+func parseRiskFreeRate(r *http.Request) (float64, error) {
+	value := r.URL.Query().Get("risk_free_rate")
+	if value == "" {
+		return 0, nil
+	}
+
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 100 {
+		return 0, fmt.Errorf("invalid risk_free_rate, expected an annual percentage between 0 and 100")
+	}
+
+	return rate / 100, nil
+}
+
+// benchmarkReturns loads the daily returns of the benchmark symbol
+func (s *Server) benchmarkReturns(ctx context.Context, symbol string) ([]risk.Return, error) {
+	bars, err := s.priceHistory(ctx, symbol, types.IntervalDaily, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return risk.BarReturns(bars), nil
+}
+
+// holdingRisk measures the risk of a valued holding from the daily bars of its symbol
+func holdingRisk(stock *types.Stock, bars []types.PriceBar, benchmark []risk.Return, params riskParams) types.RiskMetrics {
+	returns := risk.Between(risk.BarReturns(bars), params.from, params.to)
+	return risk.Measure(returns, benchmark, stock.MarketValue, params.options)
+}
+
+// stockRisk measures the risk of a single valued holding
+func (s *Server) stockRisk(ctx context.Context, stock *types.Stock, params riskParams) (*types.RiskMetrics, error) {
+	benchmark, err := s.benchmarkReturns(ctx, params.benchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	bars, err := s.priceHistory(ctx, stock.Symbol, types.IntervalDaily, time.Time{}, params.to)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := holdingRisk(stock, bars, benchmark, params)
+	return &metrics, nil
+}
+
+// portfolioRisk measures the risk of a valued portfolio from its daily time-weighted returns
+// and the risk of each of its holdings
+func (s *Server) portfolioRisk(ctx context.Context, portfolio *types.Portfolio, params riskParams) (*types.RiskReport, error) {
+	benchmark, err := s.benchmarkReturns(ctx, params.benchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.db.GetPortfolioTransactions(ctx, portfolio.ID, types.TransactionFilter{To: params.to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	transactions, history, err := s.ledgerHistory(ctx, portfolio.BaseCurrency, transactions, params.to)
+	if err != nil {
+		return nil, err
+	}
+
+	series := performance.Series(transactions, history, params.to)
+	value := 0.0
+	if len(series) > 0 {
+		value = series[len(series)-1].Value
+	}
+
+	report := &types.RiskReport{
+		PortfolioID:     portfolio.ID,
+		From:            params.from,
+		To:              params.to,
+		Benchmark:       params.benchmark,
+		RiskFreeRatePct: params.options.RiskFreeRate * 100,
+		Portfolio:       risk.Measure(risk.Between(risk.SeriesReturns(series), params.from, params.to), benchmark, value, params.options),
+		Holdings:        make([]types.HoldingRisk, 0, len(portfolio.Stocks)),
+	}
+
+	for i := range portfolio.Stocks {
+		stock := &portfolio.Stocks[i]
+
+		// The ledger history is as traded, holding returns need it adjusted for splits
+		bars, err := s.priceHistory(ctx, stock.Symbol, types.IntervalDaily, time.Time{}, params.to)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Holdings = append(report.Holdings, types.HoldingRisk{
+			Symbol:      stock.Symbol,
+			MarketValue: stock.MarketValue,
+			Weight:      stock.Weight,
+			Risk:        holdingRisk(stock, bars, benchmark, params),
+		})
+	}
+
+	return report, nil
+}
+
+// HandleGetRisk returns the volatility, Sharpe and Sortino ratios, maximum drawdown, beta and value at risk of
+// the scoped portfolio and each of its holdings. Query params: from and to (the trailing year by default),
+// benchmark (SPY by default), risk_free_rate as an annual percentage and confidence (0.95 by default).
+func (s *Server) HandleGetRisk(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	params, err := parseRiskParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := s.db.GetPortfolio(ctx, portfolioID)
+	if err != nil {
+		http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.valuePortfolio(ctx, portfolio); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+
+	report, err := s.portfolioRisk(ctx, portfolio, params)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not measure portfolio risk")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Could not encode risk", http.StatusInternalServerError)
+		return
+	}
+}
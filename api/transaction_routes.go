@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/ledger"
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetTransactions returns the ledger of the scoped portfolio (symbol and type query params)
+func (s *Server) HandleGetTransactions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	filter := types.TransactionFilter{
+		Symbol: strings.ToUpper(r.URL.Query().Get("symbol")),
+		Type:   types.TransactionType(r.URL.Query().Get("type")),
+	}
+	if filter.Type != "" && !filter.Type.IsValid() {
+		http.Error(w, "Invalid transaction type, expected one of buy, sell, dividend, split, fee, deposit, withdrawal", http.StatusBadRequest)
+		return
+	}
+
+	transactions, err := s.db.GetPortfolioTransactions(ctx, portfolioID, filter)
+	if err != nil {
+		http.Error(w, "Could not retrieve transactions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(transactions); err != nil {
+		http.Error(w, "Could not encode transactions", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetTransactionByID returns a specific transaction of the scoped portfolio
+func (s *Server) HandleGetTransactionByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	transactionID := chi.URLParam(r, "id")
+	if transactionID == "" {
+		http.Error(w, "Transaction ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := s.db.GetPortfolioTransactionByID(ctx, portfolioID, transactionID)
+	if err != nil {
+		var notFoundErr *types.TransactionNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Transaction not found in this portfolio", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not retrieve transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(transaction); err != nil {
+		http.Error(w, "Could not encode transaction", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCreateTransaction records a transaction in the scoped portfolio's ledger and updates the holding.
+// Buys of a new symbol open a holding using the interval query param (default monthly).
+func (s *Server) HandleCreateTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if interval == "" {
+		interval = types.IntervalMonthly
+	}
+
+	var req types.CreateTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tradeDate := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.TradeDate != "" {
+		tradeDate, err = time.Parse(dateLayout, req.TradeDate)
+		if err != nil {
+			http.Error(w, "Invalid trade_date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transaction := &types.Transaction{
+		Type:       req.Type,
+		Symbol:     strings.ToUpper(req.Symbol),
+		Quantity:   req.Quantity,
+		Price:      req.Price,
+		Amount:     req.Amount,
+		Fees:       req.Fees,
+		Currency:   currency,
+		SplitRatio: req.SplitRatio,
+		LotID:      req.LotID,
+		TradeDate:  tradeDate,
+		Notes:      req.Notes,
+	}
+
+	if err := ledger.Validate(transaction); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Buys may open a holding, make sure the symbol exists and its price series is stored
+	if transaction.Type == types.TransactionBuy {
+		if _, err := s.fetchPriceSeries(ctx, transaction.Symbol, interval); err != nil {
+			writeMarketDataError(w, err, "Error while fetching stock data")
+			return
+		}
+	}
+
+	recorded, err := s.db.RecordPortfolioTransaction(ctx, portfolioID, transaction, interval)
+	if err != nil {
+		var holdingsErr *types.InsufficientHoldingsError
+		if errors.As(err, &holdingsErr) {
+			http.Error(w, holdingsErr.Error(), http.StatusBadRequest)
+			return
+		}
+		var lotErr *types.InvalidLotError
+		if errors.As(err, &lotErr) {
+			http.Error(w, lotErr.Error(), http.StatusBadRequest)
+			return
+		}
+		var currencyErr *types.CurrencyMismatchError
+		if errors.As(err, &currencyErr) {
+			http.Error(w, currencyErr.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Could not record transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(recorded); err != nil {
+		http.Error(w, "Could not encode transaction", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeleteTransactionByID removes a transaction from the scoped portfolio's ledger.
+// A holding reopened by deleting the sell that closed it uses the interval query param (default monthly).
+func (s *Server) HandleDeleteTransactionByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolioID := middleware.PortfolioID(ctx)
+	if portfolioID == "" {
+		http.Error(w, "Could not get portfolio from context", http.StatusInternalServerError)
+		return
+	}
+
+	transactionID := chi.URLParam(r, "id")
+	if transactionID == "" {
+		http.Error(w, "Transaction ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	interval, err := parseInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.db.DeletePortfolioTransactionByID(ctx, portfolioID, transactionID, interval)
+	if err != nil {
+		var notFoundErr *types.TransactionNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Transaction not found in this portfolio", http.StatusNotFound)
+			return
+		}
+		var holdingsErr *types.InsufficientHoldingsError
+		if errors.As(err, &holdingsErr) {
+			http.Error(w, "Deleting this transaction would leave a later sell uncovered: "+holdingsErr.Error(), http.StatusBadRequest)
+			return
+		}
+		var lotErr *types.InvalidLotError
+		if errors.As(err, &lotErr) {
+			http.Error(w, "Deleting this transaction would leave a later sell without its lot: "+lotErr.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Could not delete transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]string{
+		"message":       "Transaction deleted successfully",
+		"transactionId": transactionID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Could not encode response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,463 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// HandleGetWatchlists returns the authenticated user's watchlists without entries
+func (s *Server) HandleGetWatchlists(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlists, err := s.db.GetUserWatchlists(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve watchlists", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(watchlists); err != nil {
+		http.Error(w, "Could not encode watchlists", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCreateWatchlist creates a new watchlist for the authenticated user
+func (s *Server) HandleCreateWatchlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Watchlist name cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	watchlist, err := s.db.CreateUserWatchlist(ctx, user.ID, req.Name)
+	if err != nil {
+		var nameErr *types.WatchlistNameTakenError
+		if errors.As(err, &nameErr) {
+			http.Error(w, nameErr.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not create watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(watchlist); err != nil {
+		http.Error(w, "Could not encode watchlist", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetWatchlist returns a watchlist with the latest bar of every entry
+func (s *Server) HandleGetWatchlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlistID := chi.URLParam(r, "watchlistID")
+	if watchlistID == "" {
+		http.Error(w, "Watchlist ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	watchlist, err := s.db.GetUserWatchlist(ctx, user.ID, watchlistID)
+	if err != nil {
+		var notFoundErr *types.WatchlistNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Watchlist not found or you don't have access to it", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not retrieve watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(watchlist); err != nil {
+		http.Error(w, "Could not encode watchlist", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleRenameWatchlist renames a watchlist of the authenticated user
+func (s *Server) HandleRenameWatchlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlistID := chi.URLParam(r, "watchlistID")
+	if watchlistID == "" {
+		http.Error(w, "Watchlist ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Watchlist name cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	watchlist, err := s.db.RenameUserWatchlist(ctx, user.ID, watchlistID, req.Name)
+	if err != nil {
+		var notFoundErr *types.WatchlistNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Watchlist not found or you don't have access to it", http.StatusNotFound)
+			return
+		}
+		var nameErr *types.WatchlistNameTakenError
+		if errors.As(err, &nameErr) {
+			http.Error(w, nameErr.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not rename watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(watchlist); err != nil {
+		http.Error(w, "Could not encode watchlist", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeleteWatchlist deletes a watchlist of the authenticated user with its entries
+func (s *Server) HandleDeleteWatchlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlistID := chi.URLParam(r, "watchlistID")
+	if watchlistID == "" {
+		http.Error(w, "Watchlist ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	err := s.db.DeleteUserWatchlist(ctx, user.ID, watchlistID)
+	if err != nil {
+		var notFoundErr *types.WatchlistNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Watchlist not found or you don't have access to it", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not delete watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]string{
+		"message":     "Watchlist deleted successfully",
+		"watchlistId": watchlistID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Could not encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleAddWatchlistEntry adds a symbol to a watchlist and stores its price series.
+// The series is kept fresh by the background price refresh from then on.
+func (s *Server) HandleAddWatchlistEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlistID := chi.URLParam(r, "watchlistID")
+	if watchlistID == "" {
+		http.Error(w, "Watchlist ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	owns, err := s.db.UserOwnsWatchlist(ctx, user.ID, watchlistID)
+	if err != nil {
+		http.Error(w, "Could not verify watchlist ownership", http.StatusInternalServerError)
+		return
+	}
+	if !owns {
+		http.Error(w, "Watchlist not found or you don't have access to it", http.StatusNotFound)
+		return
+	}
+
+	var req types.AddWatchlistEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" {
+		http.Error(w, "Stock symbol cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	if req.Interval == "" {
+		req.Interval = types.IntervalMonthly
+	}
+	if !req.Interval.IsValid() {
+		http.Error(w, "Invalid interval, expected one of 1min, 5min, 15min, 60min, daily, daily_adjusted, weekly, monthly", http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the full price series from the market data provider and store it
+	series, err := s.fetchPriceSeries(ctx, symbol, req.Interval)
+	if err != nil {
+		writeMarketDataError(w, err, "Error while fetching stock data")
+		return
+	}
+
+	entry := &types.WatchlistEntry{
+		Symbol:   series.Symbol,
+		Interval: series.Interval,
+		Notes:    req.Notes,
+	}
+
+	addedEntry, err := s.db.AddWatchlistEntry(ctx, watchlistID, entry)
+	if err != nil {
+		var existsErr *types.WatchlistEntryExistsError
+		if errors.As(err, &existsErr) {
+			http.Error(w, existsErr.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not add symbol to watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(addedEntry); err != nil {
+		http.Error(w, "Could not encode watchlist entry", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeleteWatchlistEntry removes a symbol from a watchlist
+func (s *Server) HandleDeleteWatchlistEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlistID := chi.URLParam(r, "watchlistID")
+	entryID := chi.URLParam(r, "entryID")
+	if watchlistID == "" || entryID == "" {
+		http.Error(w, "Watchlist ID and entry ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	owns, err := s.db.UserOwnsWatchlist(ctx, user.ID, watchlistID)
+	if err != nil {
+		http.Error(w, "Could not verify watchlist ownership", http.StatusInternalServerError)
+		return
+	}
+	if !owns {
+		http.Error(w, "Watchlist not found or you don't have access to it", http.StatusNotFound)
+		return
+	}
+
+	err = s.db.DeleteWatchlistEntry(ctx, watchlistID, entryID)
+	if err != nil {
+		var notFoundErr *types.WatchlistEntryNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Entry not found in this watchlist", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not delete watchlist entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]string{
+		"message": "Watchlist entry deleted successfully",
+		"entryId": entryID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Could not encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandlePromoteWatchlistEntry turns a watchlist entry into a holding and removes it from the watchlist.
+// The holding is opened in the portfolio named in the body, or the user's default portfolio.
+func (s *Server) HandlePromoteWatchlistEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlistID := chi.URLParam(r, "watchlistID")
+	entryID := chi.URLParam(r, "entryID")
+	if watchlistID == "" || entryID == "" {
+		http.Error(w, "Watchlist ID and entry ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	owns, err := s.db.UserOwnsWatchlist(ctx, user.ID, watchlistID)
+	if err != nil {
+		http.Error(w, "Could not verify watchlist ownership", http.StatusInternalServerError)
+		return
+	}
+	if !owns {
+		http.Error(w, "Watchlist not found or you don't have access to it", http.StatusNotFound)
+		return
+	}
+
+	entry, err := s.db.GetWatchlistEntry(ctx, watchlistID, entryID)
+	if err != nil {
+		var notFoundErr *types.WatchlistEntryNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Entry not found in this watchlist", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not retrieve watchlist entry", http.StatusInternalServerError)
+		return
+	}
+
+	var req types.PromoteWatchlistEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	acquiredAt, err := parseAddStockRequest(req.AddStockRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	portfolioID := req.PortfolioID
+	if portfolioID == "" {
+		portfolio, err := s.db.GetUserDefaultPortfolio(ctx, user.ID)
+		if err != nil {
+			http.Error(w, "Could not get portfolio", http.StatusInternalServerError)
+			return
+		}
+		portfolioID = portfolio.ID
+	} else {
+		owns, err := s.db.UserOwnsPortfolio(ctx, user.ID, portfolioID)
+		if err != nil {
+			http.Error(w, "Could not verify portfolio ownership", http.StatusInternalServerError)
+			return
+		}
+		if !owns {
+			http.Error(w, "Portfolio not found or you don't have access to it", http.StatusNotFound)
+			return
+		}
+	}
+
+	// Check if the portfolio already holds this stock
+	existingStock, err := s.db.GetPortfolioStockBySymbol(ctx, portfolioID, entry.Symbol)
+	if err == nil && existingStock != nil {
+		http.Error(w, "Stock already exists in this portfolio", http.StatusConflict)
+		return
+	}
+
+	stock, err := s.newHolding(ctx, entry.Symbol, entry.Interval, req.Quantity, req.AverageCost, currency, acquiredAt)
+	if err != nil {
+		writeMarketDataError(w, err, "Could not add stock to portfolio")
+		return
+	}
+
+	stock, err = s.db.PromoteWatchlistEntry(ctx, watchlistID, entryID, portfolioID, stock)
+	if err != nil {
+		var notFoundErr *types.WatchlistEntryNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Entry not found in this watchlist", http.StatusNotFound)
+			return
+		}
+		writeMarketDataError(w, err, "Could not add stock to portfolio")
+		return
+	}
+
+	if err := s.valueHolding(ctx, stock); err != nil {
+		writeMarketDataError(w, err, "Could not convert to the portfolio's base currency")
+		return
+	}
+	s.webhooks.Publish(ctx, user.ID, types.WebhookEventStockAdded, stock)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(stock); err != nil {
+		http.Error(w, "Could not encode stock", http.StatusInternalServerError)
+		return
+	}
+}
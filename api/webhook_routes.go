@@ -0,0 +1,414 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/middleware"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/ecetinerdem/forseer/webhooks"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 500
+)
+
+// HandleGetWebhooks returns the authenticated user's webhooks without their secrets
+func (s *Server) HandleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	webhookList, err := s.db.GetUserWebhooks(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Could not retrieve webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(webhookList); err != nil {
+		http.Error(w, "Could not encode webhooks", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleCreateWebhook registers a webhook. The response is the only time its signing secret is returned.
+func (s *Server) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	var req types.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	webhookURL, err := parseWebhookURL(req.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateWebhookEvents(req.Events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		http.Error(w, "Could not generate webhook secret", http.StatusInternalServerError)
+		return
+	}
+
+	webhook, err := s.db.CreateUserWebhook(ctx, user.ID, &types.Webhook{
+		URL:     webhookURL,
+		Events:  req.Events,
+		Secret:  secret,
+		Enabled: true,
+	})
+	if err != nil {
+		http.Error(w, "Could not create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(webhook); err != nil {
+		http.Error(w, "Could not encode webhook", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetWebhook returns a webhook of the authenticated user
+func (s *Server) HandleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID := chi.URLParam(r, "webhookID")
+	if webhookID == "" {
+		http.Error(w, "Webhook ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := s.db.GetUserWebhook(ctx, user.ID, webhookID)
+	if err != nil {
+		writeWebhookError(w, err, "Could not retrieve webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(webhook); err != nil {
+		http.Error(w, "Could not encode webhook", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleUpdateWebhook changes the URL, events or enabled flag of a webhook
+func (s *Server) HandleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID := chi.URLParam(r, "webhookID")
+	if webhookID == "" {
+		http.Error(w, "Webhook ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := s.db.GetUserWebhook(ctx, user.ID, webhookID)
+	if err != nil {
+		writeWebhookError(w, err, "Could not retrieve webhook")
+		return
+	}
+
+	if req.URL != nil {
+		webhookURL, err := parseWebhookURL(*req.URL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		webhook.URL = webhookURL
+	}
+	if req.Events != nil {
+		if err := validateWebhookEvents(*req.Events); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		webhook.Events = *req.Events
+	}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+
+	updated, err := s.db.UpdateUserWebhook(ctx, user.ID, webhook)
+	if err != nil {
+		writeWebhookError(w, err, "Could not update webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		http.Error(w, "Could not encode webhook", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleDeleteWebhook deletes a webhook and its delivery log
+func (s *Server) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID := chi.URLParam(r, "webhookID")
+	if webhookID == "" {
+		http.Error(w, "Webhook ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.DeleteUserWebhook(ctx, user.ID, webhookID); err != nil {
+		writeWebhookError(w, err, "Could not delete webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]string{
+		"message":    "Webhook deleted successfully",
+		"webhook_id": webhookID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Could not encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandlePingWebhook queues a ping event to a webhook and returns the delivery
+func (s *Server) HandlePingWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID := chi.URLParam(r, "webhookID")
+	if webhookID == "" {
+		http.Error(w, "Webhook ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := s.db.GetUserWebhook(ctx, user.ID, webhookID)
+	if err != nil {
+		writeWebhookError(w, err, "Could not retrieve webhook")
+		return
+	}
+
+	payload, err := json.Marshal(types.WebhookPayload{
+		Event:      types.WebhookEventPing,
+		OccurredAt: time.Now().UTC(),
+		Data:       map[string]string{"webhook_id": webhook.ID},
+	})
+	if err != nil {
+		http.Error(w, "Could not encode ping", http.StatusInternalServerError)
+		return
+	}
+
+	delivery, err := s.webhooks.Enqueue(ctx, &types.WebhookDelivery{
+		WebhookID: webhook.ID,
+		Event:     types.WebhookEventPing,
+		Payload:   payload,
+	})
+	if err != nil {
+		http.Error(w, "Could not queue ping", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(delivery); err != nil {
+		http.Error(w, "Could not encode delivery", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleGetWebhookDeliveries returns the delivery log of a webhook, newest first (limit query param, default 50)
+func (s *Server) HandleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID := chi.URLParam(r, "webhookID")
+	if webhookID == "" {
+		http.Error(w, "Webhook ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultWebhookDeliveryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit, expected a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxWebhookDeliveryLimit)
+	}
+
+	if _, err := s.db.GetUserWebhook(ctx, user.ID, webhookID); err != nil {
+		writeWebhookError(w, err, "Could not retrieve webhook")
+		return
+	}
+
+	deliveries, err := s.db.GetWebhookDeliveries(ctx, webhookID, limit)
+	if err != nil {
+		http.Error(w, "Could not retrieve webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(deliveries); err != nil {
+		http.Error(w, "Could not encode webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleReplayWebhookDelivery sends the payload of a logged delivery again as a new delivery
+func (s *Server) HandleReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := middleware.User(ctx)
+	if user == nil {
+		http.Error(w, "Could not get user from context", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID := chi.URLParam(r, "webhookID")
+	deliveryID := chi.URLParam(r, "deliveryID")
+	if webhookID == "" || deliveryID == "" {
+		http.Error(w, "Webhook ID and delivery ID cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.GetUserWebhook(ctx, user.ID, webhookID); err != nil {
+		writeWebhookError(w, err, "Could not retrieve webhook")
+		return
+	}
+
+	original, err := s.db.GetWebhookDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		var notFoundErr *types.WebhookDeliveryNotFoundError
+		if errors.As(err, &notFoundErr) {
+			http.Error(w, "Delivery not found for this webhook", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Could not retrieve webhook delivery", http.StatusInternalServerError)
+		return
+	}
+
+	delivery, err := s.webhooks.Replay(ctx, original)
+	if err != nil {
+		http.Error(w, "Could not queue replay", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(delivery); err != nil {
+		http.Error(w, "Could not encode delivery", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseWebhookURL checks that a webhook URL is an absolute http or https URL and does not name an
+// internal host. Hostnames are checked again by the dispatcher against the addresses they resolve to.
+func parseWebhookURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("webhook url must be an absolute http or https URL")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return "", fmt.Errorf("webhook url must not point to a loopback, private or internal address")
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !webhooks.PublicAddress(ip) {
+		return "", fmt.Errorf("webhook url must not point to a loopback, private or internal address")
+	}
+
+	return raw, nil
+}
+
+// validateWebhookEvents checks the events a webhook subscribes to, an empty list subscribes to every event
+func validateWebhookEvents(events []types.WebhookEvent) error {
+	for _, event := range events {
+		if !event.IsValid() {
+			return fmt.Errorf("invalid event %q, expected one of analysis.completed, alert.triggered, stock.added, stock.removed", event)
+		}
+	}
+	return nil
+}
+
+// writeWebhookError maps a missing webhook to 404 and anything else to 500 with message
+func writeWebhookError(w http.ResponseWriter, err error, message string) {
+	var notFoundErr *types.WebhookNotFoundError
+	if errors.As(err, &notFoundErr) {
+		http.Error(w, "Webhook not found or you don't have access to it", http.StatusNotFound)
+		return
+	}
+	http.Error(w, message, http.StatusInternalServerError)
+}
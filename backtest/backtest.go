@@ -0,0 +1,334 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/benchmark"
+	"github.com/ecetinerdem/forseer/indicators"
+	"github.com/ecetinerdem/forseer/performance"
+	"github.com/ecetinerdem/forseer/risk"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Defaults of the strategy parameters
+const (
+	DefaultFastPeriod = 50
+	DefaultSlowPeriod = 200
+	DefaultFrequency  = types.RebalanceMonthly
+)
+
+// minTradeFraction skips rebalance trades smaller than this share of the equity
+const minTradeFraction = 0.001
+
+// Run simulates a definition on daily split-adjusted bars keyed by symbol and ordered by date ascending.
+// Trades fill at the close of the day they are decided on; bars before the from date only warm up the
+// moving averages. The simulation records its deposits, buys and sells as a ledger, measured like a portfolio.
+// It returns ctx's error when cancelled.
+func Run(ctx context.Context, def types.BacktestDefinition, history map[string][]types.PriceBar) (*types.BacktestResult, error) {
+	sim := newSimulation(def, history)
+	if len(sim.dates) == 0 {
+		return nil, fmt.Errorf("no price history between %s and %s", def.From.Format(time.DateOnly), def.To.Format(time.DateOnly))
+	}
+
+	for d, date := range sim.dates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		sim.advance(date)
+
+		newPeriod := d > 0 && benchmark.NewPeriod(def.Strategy.Frequency, sim.dates[d-1], date)
+		if d == 0 && def.StartingCash > 0 {
+			sim.deposit(date, def.StartingCash)
+		}
+
+		switch def.Strategy.Type {
+		case types.BacktestSMACrossover:
+			sim.crossover(date)
+		case types.BacktestRebalance:
+			if d == 0 || newPeriod {
+				sim.rebalance(date)
+			}
+		case types.BacktestDCA:
+			if newPeriod && def.Strategy.Contribution > 0 {
+				sim.deposit(date, def.Strategy.Contribution)
+			}
+			if d == 0 || newPeriod {
+				sim.invest(date)
+			}
+		}
+	}
+
+	return sim.result()
+}
+
+// simulation holds the cash, positions and ledger of a backtest as it steps through the dates
+type simulation struct {
+	def     types.BacktestDefinition
+	history map[string][]types.PriceBar
+	dates   []time.Time
+
+	prices  map[string]float64 // Latest close on or before the current date
+	nextBar map[string]int
+	fast    map[string]map[time.Time]float64
+	slow    map[string]map[time.Time]float64
+
+	cash       float64
+	quantities map[string]float64
+	ledger     []types.Transaction
+}
+
+func newSimulation(def types.BacktestDefinition, history map[string][]types.PriceBar) *simulation {
+	sim := &simulation{
+		def:        def,
+		history:    history,
+		prices:     make(map[string]float64),
+		nextBar:    make(map[string]int),
+		fast:       make(map[string]map[time.Time]float64),
+		slow:       make(map[string]map[time.Time]float64),
+		quantities: make(map[string]float64),
+	}
+
+	seen := make(map[time.Time]bool)
+	for _, component := range def.Universe {
+		bars := history[component.Symbol]
+		for _, bar := range bars {
+			date := dateOf(bar.Date)
+			if !date.Before(def.From) && !date.After(def.To) && !seen[date] {
+				seen[date] = true
+				sim.dates = append(sim.dates, date)
+			}
+		}
+
+		if def.Strategy.Type == types.BacktestSMACrossover {
+			closes := make([]float64, len(bars))
+			for i, bar := range bars {
+				closes[i] = bar.Close
+			}
+			sim.fast[component.Symbol] = byDate(bars, indicators.SMA(closes, def.Strategy.FastPeriod))
+			sim.slow[component.Symbol] = byDate(bars, indicators.SMA(closes, def.Strategy.SlowPeriod))
+		}
+	}
+	sort.Slice(sim.dates, func(i, j int) bool { return sim.dates[i].Before(sim.dates[j]) })
+
+	return sim
+}
+
+// advance moves the prices to the closes of the date
+func (s *simulation) advance(date time.Time) {
+	for _, component := range s.def.Universe {
+		bars := s.history[component.Symbol]
+		i := s.nextBar[component.Symbol]
+		for ; i < len(bars) && !dateOf(bars[i].Date).After(date); i++ {
+			s.prices[component.Symbol] = bars[i].Close
+		}
+		s.nextBar[component.Symbol] = i
+	}
+}
+
+// equity is the cash plus the holdings at their latest close
+func (s *simulation) equity() float64 {
+	value := s.cash
+	for symbol, quantity := range s.quantities {
+		value += quantity * s.prices[symbol]
+	}
+	return value
+}
+
+// crossover holds every symbol whose fast SMA is above its slow SMA with a budget of its weight of the equity
+func (s *simulation) crossover(date time.Time) {
+	equity := s.equity()
+
+	// Exits first so their proceeds can fund entries of the same day
+	for _, component := range s.def.Universe {
+		fast, fastOK := s.fast[component.Symbol][date]
+		slow, slowOK := s.slow[component.Symbol][date]
+		if fastOK && slowOK && fast <= slow && s.quantities[component.Symbol] > 0 {
+			s.sell(date, component.Symbol, s.quantities[component.Symbol])
+		}
+	}
+
+	for _, component := range s.def.Universe {
+		fast, fastOK := s.fast[component.Symbol][date]
+		slow, slowOK := s.slow[component.Symbol][date]
+		if fastOK && slowOK && fast > slow && s.quantities[component.Symbol] == 0 {
+			s.buy(date, component.Symbol, component.Weight*equity)
+		}
+	}
+}
+
+// rebalance trades every symbol with a price back to its weight of the equity
+func (s *simulation) rebalance(date time.Time) {
+	equity := s.equity()
+	minTrade := equity * minTradeFraction
+
+	gaps := make(map[string]float64, len(s.def.Universe))
+	for _, component := range s.def.Universe {
+		price := s.prices[component.Symbol]
+		if price <= 0 {
+			continue
+		}
+		gaps[component.Symbol] = component.Weight*equity - s.quantities[component.Symbol]*price
+	}
+
+	for _, component := range s.def.Universe {
+		if gap := gaps[component.Symbol]; gap < -minTrade {
+			quantity := -gap / s.prices[component.Symbol]
+			if s.def.WholeShares {
+				quantity = math.Floor(quantity)
+			}
+			s.sell(date, component.Symbol, min(quantity, s.quantities[component.Symbol]))
+		}
+	}
+
+	for _, component := range s.def.Universe {
+		if gap := gaps[component.Symbol]; gap > minTrade {
+			s.buy(date, component.Symbol, gap)
+		}
+	}
+}
+
+// invest spends the cash at the weights, the share of symbols without a price yet stays in cash
+func (s *simulation) invest(date time.Time) {
+	cash := s.cash
+	for _, component := range s.def.Universe {
+		s.buy(date, component.Symbol, component.Weight*cash)
+	}
+}
+
+func (s *simulation) deposit(date time.Time, amount float64) {
+	s.cash += amount
+	s.record(types.Transaction{Type: types.TransactionDeposit, Amount: amount, TradeDate: date})
+}
+
+// buy spends up to budget of the cash, commission included, on the symbol at its latest close
+func (s *simulation) buy(date time.Time, symbol string, budget float64) {
+	price := s.prices[symbol]
+	budget = min(budget, s.cash)
+	if price <= 0 || budget <= 0 {
+		return
+	}
+
+	// The commission only falls with the quantity, so one reduction keeps the cost within the budget
+	quantity := budget / price
+	if fee := s.def.Commission.Commission(quantity, price); quantity*price+fee > budget {
+		quantity = (budget - fee) / price
+	}
+	if s.def.WholeShares {
+		quantity = math.Floor(quantity)
+	}
+	if quantity <= 0 {
+		return
+	}
+
+	fee := s.def.Commission.Commission(quantity, price)
+	s.cash -= quantity*price + fee
+	s.quantities[symbol] += quantity
+	s.record(types.Transaction{Type: types.TransactionBuy, Symbol: symbol, Quantity: quantity, Price: price, Fees: fee, TradeDate: date})
+}
+
+// sell sells quantity shares of the symbol at its latest close
+func (s *simulation) sell(date time.Time, symbol string, quantity float64) {
+	price := s.prices[symbol]
+	if price <= 0 || quantity <= 0 {
+		return
+	}
+
+	fee := s.def.Commission.Commission(quantity, price)
+	s.cash += quantity*price - fee
+	s.quantities[symbol] -= quantity
+	if s.quantities[symbol] < 1e-9 {
+		delete(s.quantities, symbol)
+	}
+	s.record(types.Transaction{Type: types.TransactionSell, Symbol: symbol, Quantity: quantity, Price: price, Fees: fee, TradeDate: date})
+}
+
+// record appends to the ledger, entries of a day keep the order they were made in
+func (s *simulation) record(tx types.Transaction) {
+	tx.CreatedAt = tx.TradeDate
+	tx.UpdatedAt = tx.TradeDate
+	s.ledger = append(s.ledger, tx)
+}
+
+// result measures the ledger like a portfolio over the simulated dates
+func (s *simulation) result() (*types.BacktestResult, error) {
+	if len(s.ledger) == 0 {
+		return nil, fmt.Errorf("the backtest had no cash to invest")
+	}
+
+	report, err := performance.Compute(s.ledger, s.history, s.dates[0], s.dates[len(s.dates)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	summary := types.BacktestSummary{
+		From:                   report.From,
+		To:                     report.To,
+		Days:                   report.Days,
+		StartingCash:           s.def.StartingCash,
+		Contributions:          report.NetContributions,
+		EndValue:               report.EndValue,
+		EndCash:                s.cash,
+		Gain:                   report.Gain,
+		TotalReturnPct:         report.TimeWeightedReturnPct,
+		AnnualizedReturnPct:    report.AnnualizedTimeWeightedReturnPct,
+		MoneyWeightedReturnPct: report.AnnualizedMoneyWeightedReturnPct,
+		Risk:                   risk.Measure(risk.SeriesReturns(report.Series), nil, report.EndValue, risk.Options{Confidence: 0.95}),
+	}
+
+	for _, tx := range s.ledger {
+		if tx.Type == types.TransactionBuy || tx.Type == types.TransactionSell {
+			summary.Trades++
+			summary.Commissions += tx.Fees
+		}
+	}
+
+	// Annualizing a few days can overflow, JSON has no infinities
+	summary.TotalReturnPct = finite(summary.TotalReturnPct)
+	summary.AnnualizedReturnPct = finite(summary.AnnualizedReturnPct)
+	if summary.MoneyWeightedReturnPct != nil {
+		clamped := finite(*summary.MoneyWeightedReturnPct)
+		summary.MoneyWeightedReturnPct = &clamped
+	}
+	summary.Risk.VolatilityPct = finite(summary.Risk.VolatilityPct)
+	summary.Risk.SharpeRatio = finite(summary.Risk.SharpeRatio)
+	summary.Risk.SortinoRatio = finite(summary.Risk.SortinoRatio)
+
+	return &types.BacktestResult{
+		EquityCurve: report.Series,
+		Trades:      s.ledger,
+		Summary:     summary,
+	}, nil
+}
+
+// finite clamps infinities to the largest float and NaN to zero
+func finite(value float64) float64 {
+	switch {
+	case math.IsNaN(value):
+		return 0
+	case math.IsInf(value, 1):
+		return math.MaxFloat64
+	case math.IsInf(value, -1):
+		return -math.MaxFloat64
+	}
+	return value
+}
+
+// byDate keys the defined values by the dates of their bars
+func byDate(bars []types.PriceBar, values []float64) map[time.Time]float64 {
+	keyed := make(map[time.Time]float64, len(bars))
+	for i, bar := range bars {
+		if !math.IsNaN(values[i]) {
+			keyed[dateOf(bar.Date)] = values[i]
+		}
+	}
+	return keyed
+}
+
+func dateOf(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,153 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+func TestBuyKeepsCommissionWithinBudget(t *testing.T) {
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		commission  types.CommissionModel
+		wholeShares bool
+		price       float64
+		budget      float64
+		wantBuy     bool
+	}{
+		{"no commission", types.CommissionModel{}, false, 100, 1000, true},
+		{"per trade", types.CommissionModel{PerTrade: 5}, false, 100, 1000, true},
+		{"per share", types.CommissionModel{PerShare: 0.5}, false, 10, 1000, true},
+		{"percentage", types.CommissionModel{Pct: 0.25}, false, 33.3, 1000, true},
+		{"minimum above the rest", types.CommissionModel{Pct: 0.1, Minimum: 7}, false, 100, 1000, true},
+		{"everything", types.CommissionModel{PerTrade: 1, PerShare: 0.01, Pct: 0.2, Minimum: 2}, false, 17.17, 523.45, true},
+		{"whole shares", types.CommissionModel{PerTrade: 5}, true, 99, 1000, true},
+		{"whole shares over budget", types.CommissionModel{PerTrade: 5}, true, 99, 100, false},
+		{"fee eats the budget", types.CommissionModel{Minimum: 50}, false, 10, 40, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := types.BacktestDefinition{
+				Universe:    []types.BenchmarkComponent{{Symbol: "AAA", Weight: 1}},
+				Commission:  tt.commission,
+				WholeShares: tt.wholeShares,
+			}
+			sim := newSimulation(def, nil)
+			sim.prices["AAA"] = tt.price
+			sim.cash = tt.budget
+
+			sim.buy(date, "AAA", tt.budget)
+
+			if !tt.wantBuy {
+				if len(sim.ledger) != 0 || sim.cash != tt.budget {
+					t.Fatalf("expected no buy, got ledger %v and cash %v", sim.ledger, sim.cash)
+				}
+				return
+			}
+			if len(sim.ledger) != 1 {
+				t.Fatalf("expected one buy, got %d ledger entries", len(sim.ledger))
+			}
+
+			buy := sim.ledger[0]
+			spent := buy.Quantity*buy.Price + buy.Fees
+			if spent > tt.budget+1e-9 {
+				t.Errorf("spent %v, over the budget of %v", spent, tt.budget)
+			}
+			if sim.cash < -1e-9 {
+				t.Errorf("cash went negative: %v", sim.cash)
+			}
+			if buy.Fees != tt.commission.Commission(buy.Quantity, buy.Price) {
+				t.Errorf("recorded fee %v, want %v", buy.Fees, tt.commission.Commission(buy.Quantity, buy.Price))
+			}
+			if tt.wholeShares && buy.Quantity != math.Floor(buy.Quantity) {
+				t.Errorf("bought a fraction of a share: %v", buy.Quantity)
+			}
+		})
+	}
+}
+
+func TestRunStaysWithinCashWithCommissions(t *testing.T) {
+	from := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	var bars []types.PriceBar
+	for d := range 400 {
+		bars = append(bars, types.PriceBar{Date: from.AddDate(0, 0, d), Close: 50 + 10*math.Sin(float64(d)/20)})
+	}
+	history := map[string][]types.PriceBar{"AAA": bars, "BBB": bars[10:]}
+	commission := types.CommissionModel{PerTrade: 1, Pct: 0.5, Minimum: 3}
+
+	tests := []struct {
+		name     string
+		strategy types.BacktestStrategy
+	}{
+		{"rebalance", types.BacktestStrategy{Type: types.BacktestRebalance, Frequency: types.RebalanceMonthly}},
+		{"dca", types.BacktestStrategy{Type: types.BacktestDCA, Frequency: types.RebalanceMonthly, Contribution: 250}},
+		{"sma crossover", types.BacktestStrategy{Type: types.BacktestSMACrossover, FastPeriod: 5, SlowPeriod: 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := types.BacktestDefinition{
+				Strategy:     tt.strategy,
+				Universe:     []types.BenchmarkComponent{{Symbol: "AAA", Weight: 0.6}, {Symbol: "BBB", Weight: 0.4}},
+				From:         from,
+				To:           from.AddDate(0, 0, 399),
+				StartingCash: 10000,
+				Commission:   commission,
+			}
+
+			result, err := Run(context.Background(), def, history)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+
+			cash, commissions := 0.0, 0.0
+			for _, tx := range result.Trades {
+				switch tx.Type {
+				case types.TransactionDeposit:
+					cash += tx.Amount
+				case types.TransactionBuy:
+					cash -= tx.Quantity*tx.Price + tx.Fees
+				case types.TransactionSell:
+					cash += tx.Quantity*tx.Price - tx.Fees
+				}
+				if cash < -1e-6 {
+					t.Fatalf("cash went negative to %v on %s", cash, tx.TradeDate.Format(time.DateOnly))
+				}
+				commissions += tx.Fees
+			}
+
+			if math.Abs(result.Summary.EndCash-cash) > 1e-6 {
+				t.Errorf("end cash %v, ledger leaves %v", result.Summary.EndCash, cash)
+			}
+			if math.Abs(result.Summary.Commissions-commissions) > 1e-6 {
+				t.Errorf("commissions %v, ledger has %v", result.Summary.Commissions, commissions)
+			}
+		})
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	from := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	def := types.BacktestDefinition{
+		Strategy:     types.BacktestStrategy{Type: types.BacktestRebalance, Frequency: types.RebalanceMonthly},
+		Universe:     []types.BenchmarkComponent{{Symbol: "AAA", Weight: 1}},
+		From:         from,
+		To:           from.AddDate(0, 0, 10),
+		StartingCash: 1000,
+	}
+	history := map[string][]types.PriceBar{"AAA": {{Date: from, Close: 10}, {Date: from.AddDate(0, 0, 1), Close: 11}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Run(ctx, def, history); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
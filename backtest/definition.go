@@ -0,0 +1,127 @@
+package backtest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// MaxSymbols limits the universe of a backtest
+const MaxSymbols = 20
+
+// MaxPeriod limits the moving averages of an SMA crossover, in bars
+const MaxPeriod = 500
+
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9.\-]{1,10}$`)
+
+// Normalize validates a definition and fills in its defaults: universe symbols are upper cased and their
+// weights scaled to sum to 1, equal weights when none are given.
+func Normalize(def *types.BacktestDefinition) error {
+	if len(def.Universe) == 0 {
+		return fmt.Errorf("a backtest needs at least one symbol")
+	}
+	if len(def.Universe) > MaxSymbols {
+		return fmt.Errorf("a backtest can have at most %d symbols", MaxSymbols)
+	}
+
+	weighted := false
+	for _, component := range def.Universe {
+		if component.Weight < 0 {
+			return fmt.Errorf("weight of %s cannot be negative", component.Symbol)
+		}
+		weighted = weighted || component.Weight > 0
+	}
+
+	seen := make(map[string]bool, len(def.Universe))
+	universe := make([]types.BenchmarkComponent, 0, len(def.Universe))
+	var total float64
+	for _, component := range def.Universe {
+		symbol := strings.ToUpper(strings.TrimSpace(component.Symbol))
+		if !symbolPattern.MatchString(symbol) {
+			return fmt.Errorf("invalid symbol %q", component.Symbol)
+		}
+		if seen[symbol] {
+			return fmt.Errorf("symbol %s is listed twice", symbol)
+		}
+		seen[symbol] = true
+
+		weight := component.Weight
+		if !weighted {
+			weight = 1
+		} else if weight == 0 {
+			return fmt.Errorf("weight of %s must be greater than zero", symbol)
+		}
+		total += weight
+		universe = append(universe, types.BenchmarkComponent{Symbol: symbol, Weight: weight})
+	}
+	for i := range universe {
+		universe[i].Weight /= total
+	}
+	def.Universe = universe
+
+	if def.From.IsZero() || def.To.IsZero() || !def.From.Before(def.To) {
+		return fmt.Errorf("a backtest needs a from date before its to date")
+	}
+	if def.To.After(time.Now().UTC()) {
+		return fmt.Errorf("the to date cannot be in the future")
+	}
+
+	if def.StartingCash < 0 {
+		return fmt.Errorf("starting cash cannot be negative")
+	}
+
+	commission := def.Commission
+	if commission.PerTrade < 0 || commission.PerShare < 0 || commission.Pct < 0 || commission.Minimum < 0 {
+		return fmt.Errorf("commissions cannot be negative")
+	}
+	if commission.Pct >= 100 {
+		return fmt.Errorf("the commission percentage must be below 100")
+	}
+
+	return normalizeStrategy(def)
+}
+
+func normalizeStrategy(def *types.BacktestDefinition) error {
+	strategy := &def.Strategy
+	if !strategy.Type.IsValid() {
+		return fmt.Errorf("invalid strategy type, expected sma_crossover, rebalance or dca")
+	}
+
+	switch strategy.Type {
+	case types.BacktestSMACrossover:
+		if strategy.FastPeriod == 0 {
+			strategy.FastPeriod = DefaultFastPeriod
+		}
+		if strategy.SlowPeriod == 0 {
+			strategy.SlowPeriod = DefaultSlowPeriod
+		}
+		if strategy.FastPeriod < 2 || strategy.SlowPeriod > MaxPeriod || strategy.FastPeriod >= strategy.SlowPeriod {
+			return fmt.Errorf("the fast period must be shorter than the slow period, both between 2 and %d bars", MaxPeriod)
+		}
+		strategy.Frequency, strategy.Contribution = "", 0
+
+	case types.BacktestRebalance, types.BacktestDCA:
+		if strategy.Frequency == "" {
+			strategy.Frequency = DefaultFrequency
+		}
+		if !strategy.Frequency.IsValid() {
+			return fmt.Errorf("invalid frequency, expected one of none, daily, monthly, quarterly, annually")
+		}
+		strategy.FastPeriod, strategy.SlowPeriod = 0, 0
+
+		if strategy.Type == types.BacktestRebalance {
+			strategy.Contribution = 0
+		} else if strategy.Contribution < 0 {
+			return fmt.Errorf("contribution cannot be negative")
+		}
+	}
+
+	if def.StartingCash == 0 && strategy.Contribution == 0 {
+		return fmt.Errorf("a backtest needs starting cash or a contribution to invest")
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Store is the persistence the runner needs
+type Store interface {
+	CreateBacktest(ctx context.Context, backtest *types.Backtest) (*types.Backtest, error)
+	StartBacktest(ctx context.Context, backtestID string) (bool, error)
+	FinishBacktest(ctx context.Context, backtest *types.Backtest) error
+	CancelBacktest(ctx context.Context, userID, backtestID string) (*types.Backtest, error)
+	FailInterruptedBacktests(ctx context.Context) error
+}
+
+// PriceHistory loads the split-adjusted bars of a symbol at an interval between from and to, either may be zero
+type PriceHistory func(ctx context.Context, symbol string, interval types.Interval, from, to time.Time) ([]types.PriceBar, error)
+
+// Runner runs backtests as background jobs, at most Workers at a time; the others wait queued.
+// A user has at most MaxPerUser backtests queued or running. Cancelling a job stops its simulation
+// at the next simulated day.
+type Runner struct {
+	store      Store
+	Workers    int
+	MaxPerUser int
+
+	ctx     context.Context
+	slots   chan struct{}
+	jobs    sync.WaitGroup
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	active  map[string]int // Unfinished backtests per user
+}
+
+func NewRunner(store Store) *Runner {
+	return &Runner{
+		store:      store,
+		Workers:    2,
+		MaxPerUser: 3,
+		ctx:        context.Background(),
+		cancels:    make(map[string]context.CancelFunc),
+		active:     make(map[string]int),
+	}
+}
+
+// Start lets jobs run until ctx is cancelled, jobs still queued or running then are marked failed.
+// Backtests a previous process left unfinished are marked failed.
+func (r *Runner) Start(ctx context.Context) {
+	if err := r.store.FailInterruptedBacktests(ctx); err != nil {
+		log.Printf("Backtest runner could not fail interrupted backtests: %v", err)
+	}
+
+	r.ctx = ctx
+	r.slots = make(chan struct{}, max(r.Workers, 1))
+}
+
+// Submit queues a backtest and runs it in the background with prices loaded from history.
+// The returned backtest is queued, its status and result are saved as the job progresses.
+// A user over MaxPerUser unfinished backtests gets a *types.BacktestLimitError.
+func (r *Runner) Submit(ctx context.Context, backtest *types.Backtest, history PriceHistory) (*types.Backtest, error) {
+	if r.slots == nil {
+		return nil, fmt.Errorf("backtest runner is not started")
+	}
+	if r.ctx.Err() != nil {
+		return nil, fmt.Errorf("backtest runner is shutting down")
+	}
+
+	r.mu.Lock()
+	if r.active[backtest.UserID] >= max(r.MaxPerUser, 1) {
+		r.mu.Unlock()
+		return nil, &types.BacktestLimitError{Limit: max(r.MaxPerUser, 1)}
+	}
+	r.active[backtest.UserID]++
+	r.mu.Unlock()
+
+	created, err := r.store.CreateBacktest(ctx, backtest)
+	if err != nil {
+		r.release(backtest.UserID)
+		return nil, err
+	}
+
+	jobCtx, cancel := context.WithCancel(r.ctx)
+	r.mu.Lock()
+	r.cancels[created.ID] = cancel
+	r.mu.Unlock()
+
+	r.jobs.Add(1)
+	go r.run(jobCtx, *created, history)
+
+	return created, nil
+}
+
+// Wait blocks until every job has stopped and recorded its status, call it after the Start context is cancelled
+func (r *Runner) Wait() {
+	r.jobs.Wait()
+}
+
+// release frees one of the user's unfinished backtests
+func (r *Runner) release(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active[userID]--; r.active[userID] <= 0 {
+		delete(r.active, userID)
+	}
+}
+
+// Cancel stops a queued or running backtest of the user and marks it cancelled.
+// A backtest that already finished returns a *types.BacktestFinishedError.
+func (r *Runner) Cancel(ctx context.Context, userID, backtestID string) (*types.Backtest, error) {
+	cancelled, err := r.store.CancelBacktest(ctx, userID, backtestID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if cancel, ok := r.cancels[backtestID]; ok {
+		cancel()
+	}
+	r.mu.Unlock()
+
+	return cancelled, nil
+}
+
+func (r *Runner) run(ctx context.Context, backtest types.Backtest, history PriceHistory) {
+	defer r.jobs.Done()
+	defer r.release(backtest.UserID)
+	defer func() {
+		r.mu.Lock()
+		if cancel, ok := r.cancels[backtest.ID]; ok {
+			cancel()
+			delete(r.cancels, backtest.ID)
+		}
+		r.mu.Unlock()
+	}()
+
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		// Cancelled while queued, already marked, unless the runner is shutting down
+		if r.ctx.Err() != nil {
+			r.interrupted(&backtest)
+			r.finish(ctx, &backtest)
+		}
+		return
+	}
+
+	started, err := r.store.StartBacktest(ctx, backtest.ID)
+	if err != nil {
+		log.Printf("Backtest %s could not be started: %v", backtest.ID, err)
+		return
+	}
+	if !started {
+		// Cancelled while it was queued
+		return
+	}
+
+	result, err := r.simulate(ctx, backtest.Definition, history)
+	switch {
+	case err == nil:
+		backtest.Status = types.BacktestCompleted
+		backtest.Result = result
+	case errors.Is(err, context.Canceled) && r.ctx.Err() != nil:
+		r.interrupted(&backtest)
+	case errors.Is(err, context.Canceled) && ctx.Err() != nil:
+		// Marked cancelled by Cancel
+		backtest.Status = types.BacktestCancelled
+	default:
+		backtest.Status = types.BacktestFailed
+		backtest.Error = err.Error()
+	}
+
+	r.finish(ctx, &backtest)
+}
+
+// interrupted marks a backtest stopped by the runner shutting down failed
+func (r *Runner) interrupted(backtest *types.Backtest) {
+	backtest.Status = types.BacktestFailed
+	backtest.Error = "interrupted by a server shutdown"
+}
+
+// finish records the final status of a backtest, even when shutting down so it is not left running
+func (r *Runner) finish(ctx context.Context, backtest *types.Backtest) {
+	if err := r.store.FinishBacktest(context.WithoutCancel(ctx), backtest); err != nil {
+		log.Printf("Backtest %s could not be finished: %v", backtest.ID, err)
+	}
+}
+
+// simulate loads the daily history of the universe and runs the definition on it
+func (r *Runner) simulate(ctx context.Context, def types.BacktestDefinition, history PriceHistory) (*types.BacktestResult, error) {
+	bars := make(map[string][]types.PriceBar, len(def.Universe))
+	for _, component := range def.Universe {
+		// The whole stored history, earlier bars warm up the moving averages
+		symbolBars, err := history(ctx, component.Symbol, types.IntervalDaily, time.Time{}, def.To.Add(24*time.Hour-time.Nanosecond))
+		if err != nil {
+			return nil, fmt.Errorf("could not load the price history of %s: %w", component.Symbol, err)
+		}
+		bars[component.Symbol] = symbolBars
+	}
+
+	return Run(ctx, def, bars)
+}
@@ -0,0 +1,249 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// fakeStore keeps backtests in memory, like the database only unfinished backtests change status
+type fakeStore struct {
+	mu        sync.Mutex
+	backtests map[string]types.Backtest
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{backtests: make(map[string]types.Backtest)}
+}
+
+func (s *fakeStore) get(backtestID string) types.Backtest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.backtests[backtestID]
+}
+
+func (s *fakeStore) CreateBacktest(ctx context.Context, backtest *types.Backtest) (*types.Backtest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := *backtest
+	created.ID = fmt.Sprintf("bt-%d", len(s.backtests)+1)
+	created.Status = types.BacktestQueued
+	s.backtests[created.ID] = created
+	return &created, nil
+}
+
+func (s *fakeStore) StartBacktest(ctx context.Context, backtestID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	backtest := s.backtests[backtestID]
+	if backtest.Status != types.BacktestQueued {
+		return false, nil
+	}
+	backtest.Status = types.BacktestRunning
+	s.backtests[backtestID] = backtest
+	return true, nil
+}
+
+func (s *fakeStore) FinishBacktest(ctx context.Context, backtest *types.Backtest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.backtests[backtest.ID].Status.IsFinished() {
+		s.backtests[backtest.ID] = *backtest
+	}
+	return nil
+}
+
+func (s *fakeStore) CancelBacktest(ctx context.Context, userID, backtestID string) (*types.Backtest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	backtest := s.backtests[backtestID]
+	if backtest.Status.IsFinished() {
+		return nil, &types.BacktestFinishedError{BacktestID: backtestID, Status: backtest.Status}
+	}
+	backtest.Status = types.BacktestCancelled
+	s.backtests[backtestID] = backtest
+	return &backtest, nil
+}
+
+func (s *fakeStore) FailInterruptedBacktests(ctx context.Context) error {
+	return nil
+}
+
+// blockingHistory signals started when a job loads its prices, then blocks until the job is stopped
+func blockingHistory(started chan<- struct{}) PriceHistory {
+	return func(ctx context.Context, symbol string, interval types.Interval, from, to time.Time) ([]types.PriceBar, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func testBacktest(userID string) *types.Backtest {
+	return &types.Backtest{
+		UserID: userID,
+		Definition: types.BacktestDefinition{
+			Strategy: types.BacktestStrategy{Type: types.BacktestRebalance, Frequency: types.RebalanceMonthly},
+			Universe: []types.BenchmarkComponent{{Symbol: "AAA", Weight: 1}},
+			To:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func TestRunnerStop(t *testing.T) {
+	// The first job runs and the second waits queued behind it on the single worker
+	tests := []struct {
+		name string
+		stop func(ctx context.Context, runner *Runner, shutdown context.CancelFunc, ids []string)
+		want map[string]types.BacktestStatus
+	}{
+		{
+			name: "cancel running",
+			stop: func(ctx context.Context, runner *Runner, shutdown context.CancelFunc, ids []string) {
+				runner.Cancel(ctx, "user", ids[0])
+				runner.Cancel(ctx, "user", ids[1])
+			},
+			want: map[string]types.BacktestStatus{"bt-1": types.BacktestCancelled, "bt-2": types.BacktestCancelled},
+		},
+		{
+			name: "cancel queued",
+			stop: func(ctx context.Context, runner *Runner, shutdown context.CancelFunc, ids []string) {
+				runner.Cancel(ctx, "user", ids[1])
+				shutdown()
+			},
+			want: map[string]types.BacktestStatus{"bt-1": types.BacktestFailed, "bt-2": types.BacktestCancelled},
+		},
+		{
+			name: "shutdown",
+			stop: func(ctx context.Context, runner *Runner, shutdown context.CancelFunc, ids []string) {
+				shutdown()
+			},
+			want: map[string]types.BacktestStatus{"bt-1": types.BacktestFailed, "bt-2": types.BacktestFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			runner := NewRunner(store)
+			runner.Workers = 1
+
+			ctx, shutdown := context.WithCancel(context.Background())
+			defer shutdown()
+			runner.Start(ctx)
+
+			started := make(chan struct{}, 2)
+			history := blockingHistory(started)
+
+			var ids []string
+			for i := range 2 {
+				created, err := runner.Submit(context.Background(), testBacktest("user"), history)
+				if err != nil {
+					t.Fatalf("Submit: %v", err)
+				}
+				ids = append(ids, created.ID)
+				if i == 0 {
+					<-started
+				}
+			}
+
+			tt.stop(context.Background(), runner, shutdown, ids)
+			shutdown()
+			runner.Wait()
+
+			for id, status := range tt.want {
+				finished := store.get(id)
+				if finished.Status != status {
+					t.Errorf("backtest %s finished %s, want %s", id, finished.Status, status)
+				}
+				if status == types.BacktestFailed && finished.Error == "" {
+					t.Errorf("backtest %s failed without an error", id)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerLimitsBacktestsPerUser(t *testing.T) {
+	store := newFakeStore()
+	runner := NewRunner(store)
+	runner.MaxPerUser = 2
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	runner.Start(ctx)
+
+	started := make(chan struct{}, 4)
+	history := blockingHistory(started)
+
+	submit := func(userID string) (*types.Backtest, error) {
+		return runner.Submit(context.Background(), testBacktest(userID), history)
+	}
+
+	tests := []struct {
+		name      string
+		userID    string
+		cancel    bool // Cancel the first backtest of the user before submitting
+		wantLimit bool
+	}{
+		{"first", "alice", false, false},
+		{"second", "alice", false, false},
+		{"over the limit", "alice", false, true},
+		{"another user", "bob", false, false},
+		{"after a cancel", "alice", true, false},
+	}
+
+	var first *types.Backtest
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cancel {
+				runner.Cancel(context.Background(), tt.userID, first.ID)
+				waitReleased(t, runner, tt.userID, 1)
+			}
+
+			created, err := submit(tt.userID)
+			var limitErr *types.BacktestLimitError
+			if tt.wantLimit {
+				if !errors.As(err, &limitErr) || limitErr.Limit != 2 {
+					t.Fatalf("expected a limit of 2, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Submit: %v", err)
+			}
+			if first == nil {
+				first = created
+			}
+		})
+	}
+
+	shutdown()
+	runner.Wait()
+
+	if _, err := submit("carol"); err == nil {
+		t.Error("expected Submit to fail after shutdown")
+	}
+}
+
+// waitReleased waits until the runner has stopped the user's job and released its slot
+func waitReleased(t *testing.T, runner *Runner, userID string, active int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		runner.mu.Lock()
+		released := runner.active[userID] <= active
+		runner.mu.Unlock()
+		if released {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("the backtests of %s were not released", userID)
+}
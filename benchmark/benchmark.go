@@ -0,0 +1,129 @@
+package benchmark
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/risk"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// MaxComponents limits the symbols of a composite benchmark
+const MaxComponents = 20
+
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9.\-]{1,10}$`)
+
+// Normalize validates benchmark components and scales their weights to sum to 1, heaviest first
+func Normalize(components []types.BenchmarkComponent) ([]types.BenchmarkComponent, error) {
+	if len(components) == 0 {
+		return nil, fmt.Errorf("a benchmark needs at least one component")
+	}
+	if len(components) > MaxComponents {
+		return nil, fmt.Errorf("a benchmark can have at most %d components", MaxComponents)
+	}
+
+	seen := make(map[string]bool, len(components))
+	normalized := make([]types.BenchmarkComponent, 0, len(components))
+	var total float64
+	for _, component := range components {
+		symbol := strings.ToUpper(strings.TrimSpace(component.Symbol))
+		if !symbolPattern.MatchString(symbol) {
+			return nil, fmt.Errorf("invalid benchmark symbol %q", component.Symbol)
+		}
+		if seen[symbol] {
+			return nil, fmt.Errorf("benchmark symbol %s is listed twice", symbol)
+		}
+		seen[symbol] = true
+
+		if component.Weight <= 0 {
+			return nil, fmt.Errorf("weight of %s must be greater than zero", symbol)
+		}
+		total += component.Weight
+		normalized = append(normalized, types.BenchmarkComponent{Symbol: symbol, Weight: component.Weight})
+	}
+
+	for i := range normalized {
+		normalized[i].Weight /= total
+	}
+	sort.SliceStable(normalized, func(i, j int) bool { return normalized[i].Weight > normalized[j].Weight })
+
+	return normalized, nil
+}
+
+// Returns simulates the daily returns of a benchmark from the daily bars of its components, keyed by symbol.
+// Only dates on which every component has a return are kept. The composite starts at its target weights,
+// lets them drift with prices and restores them at the close of the first day of each rebalance period.
+func Returns(components []types.BenchmarkComponent, rebalance types.RebalanceFrequency, history map[string][]types.PriceBar) []risk.Return {
+	if len(components) == 0 {
+		return nil
+	}
+
+	byDate := make([]map[time.Time]float64, len(components))
+	for i, component := range components {
+		byDate[i] = make(map[time.Time]float64)
+		for _, r := range risk.BarReturns(history[component.Symbol]) {
+			byDate[i][r.Date] = r.Value
+		}
+	}
+
+	var dates []time.Time
+	for date := range byDate[0] {
+		common := true
+		for _, returns := range byDate[1:] {
+			if _, ok := returns[date]; !ok {
+				common = false
+				break
+			}
+		}
+		if common {
+			dates = append(dates, date)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	holdings := make([]float64, len(components))
+	for i, component := range components {
+		holdings[i] = component.Weight
+	}
+
+	returns := make([]risk.Return, 0, len(dates))
+	for d, date := range dates {
+		var before, after float64
+		for i := range holdings {
+			before += holdings[i]
+			holdings[i] *= 1 + byDate[i][date]
+			after += holdings[i]
+		}
+		var from time.Time
+		if d > 0 {
+			from = dates[d-1]
+		}
+		returns = append(returns, risk.Return{Date: date, From: from, Value: after/before - 1})
+
+		if d > 0 && NewPeriod(rebalance, dates[d-1], date) {
+			for i, component := range components {
+				holdings[i] = component.Weight * after
+			}
+		}
+	}
+
+	return returns
+}
+
+// NewPeriod reports whether date starts a rebalance period that previous is not part of
+func NewPeriod(rebalance types.RebalanceFrequency, previous, date time.Time) bool {
+	switch rebalance {
+	case types.RebalanceDaily:
+		return true
+	case types.RebalanceMonthly:
+		return date.Year() != previous.Year() || date.Month() != previous.Month()
+	case types.RebalanceQuarterly:
+		return date.Year() != previous.Year() || (date.Month()-1)/3 != (previous.Month()-1)/3
+	case types.RebalanceAnnually:
+		return date.Year() != previous.Year()
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package corporate
+
+import (
+	"math"
+	"sort"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Adjust returns copies of bars with the splits among actions applied: bars before a split's ex-date are
+// scaled to the post-split share count, their prices divided and volumes multiplied by the ratio.
+// Series the provider does not adjust get their adjusted close scaled for dividends too, so returns read
+// from it include income the way they do for daily_adjusted series.
+func Adjust(bars []types.PriceBar, actions []types.CorporateAction) []types.PriceBar {
+	if len(bars) == 0 || len(actions) == 0 {
+		return bars
+	}
+
+	// Walk both newest first so every bar sees the factors of the actions after it
+	pending := append([]types.CorporateAction(nil), actions...)
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ExDate.After(pending[j].ExDate)
+	})
+
+	adjusted := make([]types.PriceBar, len(bars))
+	splitFactor, dividendFactor := 1.0, 1.0
+	next := 0
+
+	for i := len(bars) - 1; i >= 0; i-- {
+		bar := bars[i]
+
+		for next < len(pending) && bar.Date.Before(pending[next].ExDate) {
+			action := pending[next]
+			switch action.Type {
+			case types.CorporateActionSplit:
+				if action.Ratio > 0 {
+					splitFactor *= action.Ratio
+				}
+			case types.CorporateActionDividend:
+				// The bar is the last one before the ex-date, the dividend is a fraction of its close
+				if bar.Close > action.Amount {
+					dividendFactor *= 1 - action.Amount/bar.Close
+				}
+			}
+			next++
+		}
+
+		if splitFactor != 1 {
+			bar.Open /= splitFactor
+			bar.High /= splitFactor
+			bar.Low /= splitFactor
+			bar.Close /= splitFactor
+			bar.Volume = int64(math.Round(float64(bar.Volume) * splitFactor))
+		}
+
+		if bar.Interval != types.IntervalDailyAdjusted {
+			bar.AdjustedClose = bar.Close * dividendFactor
+		}
+
+		adjusted[i] = bar
+	}
+
+	return adjusted
+}
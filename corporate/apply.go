@@ -0,0 +1,188 @@
+package corporate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ecetinerdem/forseer/ledger"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Store is the persistence the applier needs
+type Store interface {
+	GetCorporateActions(ctx context.Context, symbol string) ([]types.CorporateAction, error)
+	GetSymbolPortfolioIDs(ctx context.Context, symbol string) ([]string, error)
+	GetPortfolioTransactions(ctx context.Context, portfolioID string, filter types.TransactionFilter) ([]types.Transaction, error)
+	ImportPortfolioTransactions(ctx context.Context, portfolioID string, transactions []types.Transaction, interval types.Interval, dryRun bool) ([]types.Transaction, int, error)
+}
+
+// Applier records the splits and dividends of a symbol in the ledgers holding it on the ex-date.
+// Splits rescale the open lots and holdings through the ledger, dividends add income and cash.
+type Applier struct {
+	store Store
+}
+
+func NewApplier(store Store) *Applier {
+	return &Applier{store: store}
+}
+
+// Apply records the entries the symbol's known actions call for and that are missing from the ledgers
+// that traded it. It is safe to run repeatedly, entries carry the action's ID as their external ID.
+// It returns the number of entries recorded; a failing ledger does not stop the others.
+func (a *Applier) Apply(ctx context.Context, symbol string) (int, error) {
+	actions, err := a.store.GetCorporateActions(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+	if len(actions) == 0 {
+		return 0, nil
+	}
+
+	portfolioIDs, err := a.store.GetSymbolPortfolioIDs(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	recorded := 0
+	var errs []error
+	for _, portfolioID := range portfolioIDs {
+		transactions, err := a.store.GetPortfolioTransactions(ctx, portfolioID, types.TransactionFilter{Symbol: symbol})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		entries := Entries(transactions, actions)
+		if len(entries) == 0 {
+			continue
+		}
+
+		imported, _, err := a.store.ImportPortfolioTransactions(ctx, portfolioID, entries, "", false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to record %s corporate actions in portfolio %s: %w", symbol, portfolioID, err))
+			continue
+		}
+		recorded += len(imported)
+	}
+
+	return recorded, errors.Join(errs...)
+}
+
+// ApplyRefreshed applies the actions of every refreshed symbol, it is registered as a scheduler refresh hook
+func (a *Applier) ApplyRefreshed(ctx context.Context, refreshed []types.TrackedSymbol) {
+	seen := make(map[string]bool, len(refreshed))
+	for _, tracked := range refreshed {
+		if seen[tracked.Symbol] {
+			continue
+		}
+		seen[tracked.Symbol] = true
+
+		recorded, err := a.Apply(ctx, tracked.Symbol)
+		if err != nil {
+			log.Printf("corporate actions: %s: %v", tracked.Symbol, err)
+		}
+		if recorded > 0 {
+			log.Printf("corporate actions: recorded %d entries for %s", recorded, tracked.Symbol)
+		}
+	}
+}
+
+// ExternalID is the external ID of the ledger entries recorded for an action
+func ExternalID(action types.CorporateAction) string {
+	return "corporate-action:" + action.ID
+}
+
+// Entries returns the split and dividend transactions a symbol's actions call for in its ledger, in ex-date
+// order. Actions already recorded, by the applier or by hand on the same date, and actions on dates the
+// ledger held no shares are skipped. Dividends pay the per share amount on the shares held before the ex-date.
+func Entries(transactions []types.Transaction, actions []types.CorporateAction) []types.Transaction {
+	working := append([]types.Transaction(nil), transactions...)
+	var entries []types.Transaction
+
+	for _, action := range actions {
+		if recorded(working, action) {
+			continue
+		}
+
+		held := heldBefore(working, action.Symbol, action.ExDate)
+		if held <= 0 {
+			continue
+		}
+
+		entry := types.Transaction{
+			Symbol:     action.Symbol,
+			TradeDate:  action.ExDate,
+			ExternalID: ExternalID(action),
+		}
+
+		switch action.Type {
+		case types.CorporateActionSplit:
+			if action.Ratio <= 0 {
+				continue
+			}
+			entry.Type = types.TransactionSplit
+			entry.SplitRatio = action.Ratio
+			entry.Notes = fmt.Sprintf("%s:1 split (%s)", strconv.FormatFloat(action.Ratio, 'f', -1, 64), action.Source)
+		case types.CorporateActionDividend:
+			if action.Amount <= 0 {
+				continue
+			}
+			entry.Type = types.TransactionDividend
+			entry.Amount = action.Amount * held
+			entry.Notes = fmt.Sprintf("Dividend of %s per share on %s shares (%s)",
+				strconv.FormatFloat(action.Amount, 'f', -1, 64), strconv.FormatFloat(held, 'f', -1, 64), action.Source)
+		default:
+			continue
+		}
+
+		working = append(working, entry)
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// recorded reports whether the ledger already has the entry of an action
+func recorded(transactions []types.Transaction, action types.CorporateAction) bool {
+	entryType := types.TransactionSplit
+	if action.Type == types.CorporateActionDividend {
+		entryType = types.TransactionDividend
+	}
+
+	externalID := ExternalID(action)
+	for _, tx := range transactions {
+		if tx.ExternalID == externalID {
+			return true
+		}
+		if tx.Symbol == action.Symbol && tx.Type == entryType && tx.TradeDate.Equal(action.ExDate) {
+			return true
+		}
+	}
+	return false
+}
+
+// heldBefore returns the shares of the symbol held at the close before the ex-date
+func heldBefore(transactions []types.Transaction, symbol string, exDate time.Time) float64 {
+	ordered := append([]types.Transaction(nil), transactions...)
+	ledger.Sort(ordered)
+
+	held := 0.0
+	for _, tx := range ordered {
+		if tx.Symbol != symbol || !tx.TradeDate.Before(exDate) {
+			continue
+		}
+		switch tx.Type {
+		case types.TransactionBuy:
+			held += tx.Quantity
+		case types.TransactionSell:
+			held -= tx.Quantity
+		case types.TransactionSplit:
+			held *= tx.SplitRatio
+		}
+	}
+	return held
+}
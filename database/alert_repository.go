@@ -0,0 +1,314 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type AlertRepo interface {
+	// Alert operations - all user-scoped
+	GetUserAlerts(ctx context.Context, userID string) ([]types.Alert, error)
+	GetUserAlert(ctx context.Context, userID, alertID string) (*types.Alert, error)
+	CreateUserAlert(ctx context.Context, userID string, alert *types.Alert) (*types.Alert, error)
+	UpdateUserAlert(ctx context.Context, userID string, alert *types.Alert) (*types.Alert, error)
+	DeleteUserAlert(ctx context.Context, userID, alertID string) error
+
+	// Trigger history
+	GetUserAlertTriggers(ctx context.Context, userID, alertID string, limit int) ([]types.AlertTrigger, error)
+
+	// Evaluation - used by the alerts evaluator across all users
+	GetEnabledAlerts(ctx context.Context) ([]types.Alert, error)
+	SaveAlertState(ctx context.Context, alertID string, conditionMet bool, evaluatedAt time.Time) error
+	RecordAlertTrigger(ctx context.Context, trigger *types.AlertTrigger) (*types.AlertTrigger, error)
+}
+
+// GetUserAlerts returns every alert of the user
+func (db *DB) GetUserAlerts(ctx context.Context, userID string) ([]types.Alert, error) {
+	query := alertSelect + `
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	return db.queryAlerts(ctx, query, userID)
+}
+
+// GetUserAlert retrieves an alert if it belongs to the user
+func (db *DB) GetUserAlert(ctx context.Context, userID, alertID string) (*types.Alert, error) {
+	query := alertSelect + `
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var alert types.Alert
+	err := scanAlert(db.QueryRowContext(ctx, query, alertID, userID), &alert)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.AlertNotFoundError{UserID: userID, AlertID: alertID}
+		}
+		return nil, fmt.Errorf("failed to get alert: %w", err)
+	}
+
+	return &alert, nil
+}
+
+// CreateUserAlert saves a new alert for the user
+func (db *DB) CreateUserAlert(ctx context.Context, userID string, alert *types.Alert) (*types.Alert, error) {
+	query := `
+		INSERT INTO alerts (user_id, portfolio_id, name, symbol, bar_interval, expression, cooldown_minutes, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING ` + alertColumns
+
+	var created types.Alert
+	err := scanAlert(db.QueryRowContext(ctx, query,
+		userID,
+		nullableString(alert.PortfolioID),
+		alert.Name,
+		alert.Symbol,
+		alert.Interval,
+		alert.Expression,
+		alert.CooldownMinutes,
+		alert.Enabled,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("could not save the alert: %w", err)
+	}
+
+	return &created, nil
+}
+
+// UpdateUserAlert saves the name, expression, cooldown and enabled flag of an alert.
+// Changing the expression resets the condition state so the new rule can fire on its first evaluation.
+func (db *DB) UpdateUserAlert(ctx context.Context, userID string, alert *types.Alert) (*types.Alert, error) {
+	query := `
+		UPDATE alerts
+		SET name = $1,
+			expression = $2,
+			cooldown_minutes = $3,
+			enabled = $4,
+			condition_met = CASE WHEN expression = $2 THEN condition_met ELSE FALSE END
+		WHERE id = $5 AND user_id = $6
+		RETURNING ` + alertColumns
+
+	var updated types.Alert
+	err := scanAlert(db.QueryRowContext(ctx, query,
+		alert.Name,
+		alert.Expression,
+		alert.CooldownMinutes,
+		alert.Enabled,
+		alert.ID,
+		userID,
+	), &updated)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.AlertNotFoundError{UserID: userID, AlertID: alert.ID}
+		}
+		return nil, fmt.Errorf("could not update the alert: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// DeleteUserAlert deletes an alert and its trigger history if it belongs to the user
+func (db *DB) DeleteUserAlert(ctx context.Context, userID, alertID string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM alerts WHERE id = $1 AND user_id = $2`, alertID, userID)
+	if err != nil {
+		return fmt.Errorf("could not delete alert: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &types.AlertNotFoundError{UserID: userID, AlertID: alertID}
+	}
+
+	return nil
+}
+
+// GetUserAlertTriggers returns the most recent triggers of the user, newest first.
+// An empty alertID returns the triggers of every alert.
+func (db *DB) GetUserAlertTriggers(ctx context.Context, userID, alertID string, limit int) ([]types.AlertTrigger, error) {
+	query := `
+		SELECT id, alert_id, user_id, name, symbol, COALESCE(portfolio_id::text, ''), expression, observed, triggered_at
+		FROM alert_triggers
+		WHERE user_id = $1 AND ($2 = '' OR alert_id::text = $2)
+		ORDER BY triggered_at DESC
+		LIMIT $3
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID, alertID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert triggers: %w", err)
+	}
+	defer rows.Close()
+
+	triggers := []types.AlertTrigger{}
+	for rows.Next() {
+		var trigger types.AlertTrigger
+		err := rows.Scan(
+			&trigger.ID,
+			&trigger.AlertID,
+			&trigger.UserID,
+			&trigger.Name,
+			&trigger.Symbol,
+			&trigger.PortfolioID,
+			&trigger.Expression,
+			&trigger.Observed,
+			&trigger.TriggeredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert trigger: %w", err)
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("alert trigger iteration error: %w", err)
+	}
+
+	return triggers, nil
+}
+
+// GetEnabledAlerts returns the enabled alerts of every user
+func (db *DB) GetEnabledAlerts(ctx context.Context) ([]types.Alert, error) {
+	query := alertSelect + `
+		WHERE enabled
+		ORDER BY created_at ASC
+	`
+
+	return db.queryAlerts(ctx, query)
+}
+
+// SaveAlertState records the result of an evaluation
+func (db *DB) SaveAlertState(ctx context.Context, alertID string, conditionMet bool, evaluatedAt time.Time) error {
+	query := `
+		UPDATE alerts
+		SET condition_met = $1, last_evaluated_at = $2
+		WHERE id = $3
+	`
+
+	if _, err := db.ExecContext(ctx, query, conditionMet, evaluatedAt, alertID); err != nil {
+		return fmt.Errorf("could not save alert state: %w", err)
+	}
+
+	return nil
+}
+
+// RecordAlertTrigger appends a trigger to the history and stamps the alert's last trigger time
+func (db *DB) RecordAlertTrigger(ctx context.Context, trigger *types.AlertTrigger) (*types.AlertTrigger, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO alert_triggers (alert_id, user_id, name, symbol, portfolio_id, expression, observed, triggered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	recorded := *trigger
+	err = tx.QueryRowContext(ctx, query,
+		trigger.AlertID,
+		trigger.UserID,
+		trigger.Name,
+		trigger.Symbol,
+		nullableString(trigger.PortfolioID),
+		trigger.Expression,
+		trigger.Observed,
+		trigger.TriggeredAt,
+	).Scan(&recorded.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not save alert trigger: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE alerts SET last_triggered_at = $1 WHERE id = $2`, trigger.TriggeredAt, trigger.AlertID)
+	if err != nil {
+		return nil, fmt.Errorf("could not update alert trigger time: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit alert trigger: %w", err)
+	}
+
+	return &recorded, nil
+}
+
+func (db *DB) queryAlerts(ctx context.Context, query string, args ...any) ([]types.Alert, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []types.Alert{}
+	for rows.Next() {
+		var alert types.Alert
+		if err := scanAlert(rows, &alert); err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("alert iteration error: %w", err)
+	}
+
+	return alerts, nil
+}
+
+const alertColumns = `id, user_id, COALESCE(portfolio_id::text, ''), name, symbol, bar_interval, expression,
+	cooldown_minutes, enabled, condition_met, last_evaluated_at, last_triggered_at, created_at, updated_at`
+
+// alertSelect selects alerts, callers append the WHERE clause
+const alertSelect = `
+	SELECT ` + alertColumns + `
+	FROM alerts
+`
+
+// scanAlert scans a row of alertColumns into alert
+func scanAlert(row rowScanner, alert *types.Alert) error {
+	var lastEvaluatedAt, lastTriggeredAt sql.NullTime
+
+	err := row.Scan(
+		&alert.ID,
+		&alert.UserID,
+		&alert.PortfolioID,
+		&alert.Name,
+		&alert.Symbol,
+		&alert.Interval,
+		&alert.Expression,
+		&alert.CooldownMinutes,
+		&alert.Enabled,
+		&alert.ConditionMet,
+		&lastEvaluatedAt,
+		&lastTriggeredAt,
+		&alert.CreatedAt,
+		&alert.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if lastEvaluatedAt.Valid {
+		alert.LastEvaluatedAt = &lastEvaluatedAt.Time
+	}
+	if lastTriggeredAt.Valid {
+		alert.LastTriggeredAt = &lastTriggeredAt.Time
+	}
+	return nil
+}
+
+// nullableString maps the empty string to NULL for optional foreign keys
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type AllocationRepo interface {
+	// Target operations - callers verify portfolio ownership
+	GetAllocationTargets(ctx context.Context, portfolioID string) ([]types.AllocationTarget, error)
+	SetAllocationTargets(ctx context.Context, portfolioID string, targets []types.AllocationTarget) ([]types.AllocationTarget, error)
+
+	// Classification operations - callers verify portfolio ownership
+	GetSymbolClassifications(ctx context.Context, portfolioID string) ([]types.SymbolClassification, error)
+	SetSymbolClassification(ctx context.Context, classification *types.SymbolClassification) (*types.SymbolClassification, error)
+}
+
+// GetAllocationTargets returns the allocation targets of a portfolio, heaviest first
+func (db *DB) GetAllocationTargets(ctx context.Context, portfolioID string) ([]types.AllocationTarget, error) {
+	return getAllocationTargets(ctx, db, portfolioID)
+}
+
+// SetAllocationTargets replaces every allocation target of a portfolio
+func (db *DB) SetAllocationTargets(ctx context.Context, portfolioID string, targets []types.AllocationTarget) ([]types.AllocationTarget, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM allocation_targets WHERE portfolio_id = $1`, portfolioID); err != nil {
+		return nil, fmt.Errorf("failed to clear allocation targets: %w", err)
+	}
+
+	for _, target := range targets {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO allocation_targets (portfolio_id, kind, key, weight, tolerance, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		`, portfolioID, target.Kind, target.Key, target.Weight, target.Tolerance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save allocation target %s: %w", target.Key, err)
+		}
+	}
+
+	saved, err := getAllocationTargets(ctx, tx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit allocation targets: %w", err)
+	}
+
+	return saved, nil
+}
+
+// GetSymbolClassifications returns the sector and asset class of every classified symbol of a portfolio
+func (db *DB) GetSymbolClassifications(ctx context.Context, portfolioID string) ([]types.SymbolClassification, error) {
+	query := `
+		SELECT portfolio_id, symbol, sector, asset_class, updated_at
+		FROM symbol_classifications
+		WHERE portfolio_id = $1
+		ORDER BY symbol ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query symbol classifications: %w", err)
+	}
+	defer rows.Close()
+
+	var classifications []types.SymbolClassification
+	for rows.Next() {
+		var c types.SymbolClassification
+		if err := rows.Scan(&c.PortfolioID, &c.Symbol, &c.Sector, &c.AssetClass, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan symbol classification: %w", err)
+		}
+		classifications = append(classifications, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("symbol classification iteration error: %w", err)
+	}
+
+	return classifications, nil
+}
+
+// SetSymbolClassification saves the sector and asset class of a symbol, replacing its previous classification
+func (db *DB) SetSymbolClassification(ctx context.Context, classification *types.SymbolClassification) (*types.SymbolClassification, error) {
+	query := `
+		INSERT INTO symbol_classifications (portfolio_id, symbol, sector, asset_class, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (portfolio_id, symbol) DO UPDATE
+		SET sector = EXCLUDED.sector, asset_class = EXCLUDED.asset_class
+		RETURNING portfolio_id, symbol, sector, asset_class, updated_at
+	`
+
+	var saved types.SymbolClassification
+	err := db.QueryRowContext(ctx, query,
+		classification.PortfolioID,
+		classification.Symbol,
+		classification.Sector,
+		classification.AssetClass,
+	).Scan(&saved.PortfolioID, &saved.Symbol, &saved.Sector, &saved.AssetClass, &saved.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("could not save symbol classification: %w", err)
+	}
+
+	return &saved, nil
+}
+
+func getAllocationTargets(ctx context.Context, q queryer, portfolioID string) ([]types.AllocationTarget, error) {
+	query := `
+		SELECT id, portfolio_id, kind, key, weight, tolerance, created_at, updated_at
+		FROM allocation_targets
+		WHERE portfolio_id = $1
+		ORDER BY weight DESC, key ASC
+	`
+
+	rows, err := q.QueryContext(ctx, query, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query allocation targets: %w", err)
+	}
+	defer rows.Close()
+
+	targets := []types.AllocationTarget{}
+	for rows.Next() {
+		var t types.AllocationTarget
+		if err := rows.Scan(&t.ID, &t.PortfolioID, &t.Kind, &t.Key, &t.Weight, &t.Tolerance, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan allocation target: %w", err)
+		}
+		targets = append(targets, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("allocation target iteration error: %w", err)
+	}
+
+	return targets, nil
+}
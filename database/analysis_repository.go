@@ -4,6 +4,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/ecetinerdem/forseer/types"
 )
@@ -14,12 +15,14 @@ type AnalysisRepo interface {
 	GetStockAnalysis(ctx context.Context, userID, stockID string) (*types.StockAnalysis, error)
 	GetUserStockAnalyses(ctx context.Context, userID string) ([]*types.StockAnalysis, error)
 	DeleteStockAnalysis(ctx context.Context, userID, analysisID string) error
+	GetPortfolioStockAnalyses(ctx context.Context, portfolioID string, from, to time.Time) ([]*types.StockAnalysis, error)
 
 	// Portfolio analysis methods
 	SavePortfolioAnalysis(ctx context.Context, analysis *types.PortfolioAnalysis) (*types.PortfolioAnalysis, error)
 	GetPortfolioAnalysis(ctx context.Context, userID, portfolioID string) (*types.PortfolioAnalysis, error)
 	GetUserPortfolioAnalyses(ctx context.Context, userID string) ([]*types.PortfolioAnalysis, error)
 	DeletePortfolioAnalysis(ctx context.Context, userID, analysisID string) error
+	GetPortfolioAnalyses(ctx context.Context, portfolioID string, from, to time.Time) ([]*types.PortfolioAnalysis, error)
 }
 
 // SaveStockAnalysis saves a stock analysis to the database
@@ -278,3 +281,91 @@ func (db *DB) DeletePortfolioAnalysis(ctx context.Context, userID, analysisID st
 
 	return nil
 }
+
+// GetPortfolioStockAnalyses retrieves the analyses of a portfolio's stocks generated in a date range, oldest first.
+// A zero from or to leaves that side of the range open.
+func (db *DB) GetPortfolioStockAnalyses(ctx context.Context, portfolioID string, from, to time.Time) ([]*types.StockAnalysis, error) {
+	query := `
+		SELECT sa.id, sa.stock_id, sa.symbol, sa.analysis, sa.generated_at, sa.created_at, sa.updated_at
+		FROM stock_analyses sa
+		INNER JOIN stocks s ON sa.stock_id = s.id
+		WHERE s.portfolio_id = $1
+		AND ($2::timestamptz IS NULL OR sa.generated_at >= $2)
+		AND ($3::timestamptz IS NULL OR sa.generated_at <= $3)
+		ORDER BY sa.generated_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, portfolioID, nullableTime(from), nullableTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var analyses []*types.StockAnalysis
+	for rows.Next() {
+		var analysis types.StockAnalysis
+		err := rows.Scan(
+			&analysis.ID,
+			&analysis.StockID,
+			&analysis.Symbol,
+			&analysis.Analysis,
+			&analysis.GeneratedAt,
+			&analysis.CreatedAt,
+			&analysis.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stock analysis: %w", err)
+		}
+		analyses = append(analyses, &analysis)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("stock analysis iteration error: %w", err)
+	}
+
+	return analyses, nil
+}
+
+// GetPortfolioAnalyses retrieves the analyses of a portfolio generated in a date range, oldest first.
+// A zero from or to leaves that side of the range open.
+func (db *DB) GetPortfolioAnalyses(ctx context.Context, portfolioID string, from, to time.Time) ([]*types.PortfolioAnalysis, error) {
+	query := `
+		SELECT id, portfolio_id, user_id, analysis, stock_count, generated_at, created_at, updated_at
+		FROM portfolio_analyses
+		WHERE portfolio_id = $1
+		AND ($2::timestamptz IS NULL OR generated_at >= $2)
+		AND ($3::timestamptz IS NULL OR generated_at <= $3)
+		ORDER BY generated_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, portfolioID, nullableTime(from), nullableTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query portfolio analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var analyses []*types.PortfolioAnalysis
+	for rows.Next() {
+		var analysis types.PortfolioAnalysis
+		err := rows.Scan(
+			&analysis.ID,
+			&analysis.PortfolioID,
+			&analysis.UserID,
+			&analysis.Analysis,
+			&analysis.StockCount,
+			&analysis.GeneratedAt,
+			&analysis.CreatedAt,
+			&analysis.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan portfolio analysis: %w", err)
+		}
+		analyses = append(analyses, &analysis)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("portfolio analysis iteration error: %w", err)
+	}
+
+	return analyses, nil
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type BacktestRepo interface {
+	// Backtest operations - all user-scoped
+	GetUserBacktests(ctx context.Context, userID string) ([]types.Backtest, error)
+	GetUserBacktest(ctx context.Context, userID, backtestID string) (*types.Backtest, error)
+	DeleteUserBacktest(ctx context.Context, userID, backtestID string) error
+	CancelBacktest(ctx context.Context, userID, backtestID string) (*types.Backtest, error)
+
+	// Jobs - used by the backtest runner
+	CreateBacktest(ctx context.Context, backtest *types.Backtest) (*types.Backtest, error)
+	StartBacktest(ctx context.Context, backtestID string) (bool, error)
+	FinishBacktest(ctx context.Context, backtest *types.Backtest) error
+	FailInterruptedBacktests(ctx context.Context) error
+}
+
+// GetUserBacktests returns every backtest of the user without its result, newest first
+func (db *DB) GetUserBacktests(ctx context.Context, userID string) ([]types.Backtest, error) {
+	query := `
+		SELECT ` + backtestColumns + `, NULL
+		FROM backtests
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backtests: %w", err)
+	}
+	defer rows.Close()
+
+	backtests := []types.Backtest{}
+	for rows.Next() {
+		var backtest types.Backtest
+		if err := scanBacktest(rows, &backtest); err != nil {
+			return nil, fmt.Errorf("failed to scan backtest: %w", err)
+		}
+		backtests = append(backtests, backtest)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("backtest iteration error: %w", err)
+	}
+
+	return backtests, nil
+}
+
+// GetUserBacktest retrieves a backtest with its result if it belongs to the user
+func (db *DB) GetUserBacktest(ctx context.Context, userID, backtestID string) (*types.Backtest, error) {
+	query := `
+		SELECT ` + backtestColumns + `, result
+		FROM backtests
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var backtest types.Backtest
+	err := scanBacktest(db.QueryRowContext(ctx, query, backtestID, userID), &backtest)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.BacktestNotFoundError{UserID: userID, BacktestID: backtestID}
+		}
+		return nil, fmt.Errorf("failed to get backtest: %w", err)
+	}
+
+	return &backtest, nil
+}
+
+// DeleteUserBacktest deletes a backtest if it belongs to the user
+func (db *DB) DeleteUserBacktest(ctx context.Context, userID, backtestID string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM backtests WHERE id = $1 AND user_id = $2`, backtestID, userID)
+	if err != nil {
+		return fmt.Errorf("could not delete backtest: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &types.BacktestNotFoundError{UserID: userID, BacktestID: backtestID}
+	}
+
+	return nil
+}
+
+// CancelBacktest marks a queued or running backtest of the user cancelled. A backtest that already
+// finished returns a *types.BacktestFinishedError.
+func (db *DB) CancelBacktest(ctx context.Context, userID, backtestID string) (*types.Backtest, error) {
+	query := `
+		UPDATE backtests
+		SET status = 'cancelled', finished_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status IN ('queued', 'running')
+		RETURNING ` + backtestColumns + `, NULL
+	`
+
+	var backtest types.Backtest
+	err := scanBacktest(db.QueryRowContext(ctx, query, backtestID, userID), &backtest)
+	if err == nil {
+		return &backtest, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("could not cancel backtest: %w", err)
+	}
+
+	existing, err := db.GetUserBacktest(ctx, userID, backtestID)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &types.BacktestFinishedError{BacktestID: backtestID, Status: existing.Status}
+}
+
+// CreateBacktest queues a backtest
+func (db *DB) CreateBacktest(ctx context.Context, backtest *types.Backtest) (*types.Backtest, error) {
+	definition, err := json.Marshal(backtest.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode backtest definition: %w", err)
+	}
+
+	query := `
+		INSERT INTO backtests (user_id, name, status, definition, created_at)
+		VALUES ($1, $2, 'queued', $3, NOW())
+		RETURNING ` + backtestColumns + `, NULL
+	`
+
+	var created types.Backtest
+	err = scanBacktest(db.QueryRowContext(ctx, query, backtest.UserID, backtest.Name, definition), &created)
+	if err != nil {
+		return nil, fmt.Errorf("could not save the backtest: %w", err)
+	}
+
+	return &created, nil
+}
+
+// StartBacktest marks a queued backtest running, it returns false when it is no longer queued
+func (db *DB) StartBacktest(ctx context.Context, backtestID string) (bool, error) {
+	result, err := db.ExecContext(ctx, `
+		UPDATE backtests
+		SET status = 'running', started_at = NOW()
+		WHERE id = $1 AND status = 'queued'
+	`, backtestID)
+	if err != nil {
+		return false, fmt.Errorf("could not start backtest: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// FinishBacktest saves the status, result and error of a backtest. A backtest cancelled meanwhile is left as is.
+// A result that cannot be encoded marks the backtest failed rather than leaving it running.
+func (db *DB) FinishBacktest(ctx context.Context, backtest *types.Backtest) error {
+	var result []byte
+	if backtest.Result != nil {
+		encoded, err := json.Marshal(backtest.Result)
+		if err != nil {
+			backtest.Status = types.BacktestFailed
+			backtest.Error = fmt.Sprintf("could not save the result: %v", err)
+			backtest.Result = nil
+		}
+		result = encoded
+	}
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE backtests
+		SET status = $1, result = $2, error = $3, finished_at = NOW()
+		WHERE id = $4 AND status IN ('queued', 'running')
+	`, backtest.Status, result, backtest.Error, backtest.ID)
+	if err != nil {
+		return fmt.Errorf("could not finish backtest: %w", err)
+	}
+
+	return nil
+}
+
+// FailInterruptedBacktests marks the backtests a previous process left queued or running failed
+func (db *DB) FailInterruptedBacktests(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE backtests
+		SET status = 'failed', error = 'interrupted by a server restart', finished_at = NOW()
+		WHERE status IN ('queued', 'running')
+	`)
+	if err != nil {
+		return fmt.Errorf("could not fail interrupted backtests: %w", err)
+	}
+
+	return nil
+}
+
+const backtestColumns = `id, user_id, name, status, definition, error, created_at, started_at, finished_at`
+
+// scanBacktest scans a row of backtestColumns followed by the result, NULL when it is not selected
+func scanBacktest(row rowScanner, backtest *types.Backtest) error {
+	var definition, result []byte
+	var startedAt, finishedAt sql.NullTime
+
+	err := row.Scan(
+		&backtest.ID,
+		&backtest.UserID,
+		&backtest.Name,
+		&backtest.Status,
+		&definition,
+		&backtest.Error,
+		&backtest.CreatedAt,
+		&startedAt,
+		&finishedAt,
+		&result,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(definition, &backtest.Definition); err != nil {
+		return fmt.Errorf("failed to decode backtest definition: %w", err)
+	}
+	if len(result) > 0 {
+		backtest.Result = &types.BacktestResult{}
+		if err := json.Unmarshal(result, backtest.Result); err != nil {
+			return fmt.Errorf("failed to decode backtest result: %w", err)
+		}
+	}
+	if startedAt.Valid {
+		backtest.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		backtest.FinishedAt = &finishedAt.Time
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type BenchmarkRepo interface {
+	// Benchmark operations - all user-scoped
+	GetUserBenchmarks(ctx context.Context, userID string) ([]types.Benchmark, error)
+	GetUserBenchmark(ctx context.Context, userID, benchmarkID string) (*types.Benchmark, error)
+	CreateUserBenchmark(ctx context.Context, userID string, benchmark *types.Benchmark) (*types.Benchmark, error)
+	UpdateUserBenchmark(ctx context.Context, userID, benchmarkID string, req *types.UpdateBenchmarkRequest) (*types.Benchmark, error)
+	DeleteUserBenchmark(ctx context.Context, userID, benchmarkID string) error
+}
+
+// GetUserBenchmarks returns every benchmark of the user with its components
+func (db *DB) GetUserBenchmarks(ctx context.Context, userID string) ([]types.Benchmark, error) {
+	query := benchmarkSelect + `
+		WHERE user_id = $1
+		ORDER BY name ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query benchmarks: %w", err)
+	}
+	defer rows.Close()
+
+	var benchmarks []types.Benchmark
+	for rows.Next() {
+		var benchmark types.Benchmark
+		if err := scanBenchmark(rows, &benchmark); err != nil {
+			return nil, fmt.Errorf("failed to scan benchmark: %w", err)
+		}
+		benchmarks = append(benchmarks, benchmark)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("benchmark iteration error: %w", err)
+	}
+
+	for i := range benchmarks {
+		components, err := getBenchmarkComponents(ctx, db, benchmarks[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		benchmarks[i].Components = components
+	}
+
+	return benchmarks, nil
+}
+
+// GetUserBenchmark retrieves a benchmark with its components if it belongs to the user
+func (db *DB) GetUserBenchmark(ctx context.Context, userID, benchmarkID string) (*types.Benchmark, error) {
+	return getUserBenchmark(ctx, db, userID, benchmarkID)
+}
+
+// CreateUserBenchmark saves a benchmark and its components for the user
+func (db *DB) CreateUserBenchmark(ctx context.Context, userID string, benchmark *types.Benchmark) (*types.Benchmark, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO benchmarks (user_id, name, rebalance, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING id
+	`
+
+	var benchmarkID string
+	if err := tx.QueryRowContext(ctx, query, userID, benchmark.Name, benchmark.Rebalance).Scan(&benchmarkID); err != nil {
+		if isUniqueViolation(err) {
+			return nil, &types.BenchmarkNameTakenError{Name: benchmark.Name}
+		}
+		return nil, fmt.Errorf("could not save the benchmark: %w", err)
+	}
+
+	if err := replaceBenchmarkComponents(ctx, tx, benchmarkID, benchmark.Components); err != nil {
+		return nil, err
+	}
+
+	created, err := getUserBenchmark(ctx, tx, userID, benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit benchmark: %w", err)
+	}
+
+	return created, nil
+}
+
+// UpdateUserBenchmark changes the given fields of a benchmark if it belongs to the user,
+// components are replaced as a whole
+func (db *DB) UpdateUserBenchmark(ctx context.Context, userID, benchmarkID string, req *types.UpdateBenchmarkRequest) (*types.Benchmark, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE benchmarks
+		SET name = COALESCE($1, name), rebalance = COALESCE($2, rebalance)
+		WHERE id = $3 AND user_id = $4
+		RETURNING id
+	`
+
+	var name, rebalance sql.NullString
+	if req.Name != nil {
+		name = sql.NullString{String: *req.Name, Valid: true}
+	}
+	if req.Rebalance != nil {
+		rebalance = sql.NullString{String: string(*req.Rebalance), Valid: true}
+	}
+
+	var id string
+	if err := tx.QueryRowContext(ctx, query, name, rebalance, benchmarkID, userID).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.BenchmarkNotFoundError{UserID: userID, BenchmarkID: benchmarkID}
+		}
+		if isUniqueViolation(err) {
+			return nil, &types.BenchmarkNameTakenError{Name: name.String}
+		}
+		return nil, fmt.Errorf("failed to update benchmark: %w", err)
+	}
+
+	if req.Components != nil {
+		if err := replaceBenchmarkComponents(ctx, tx, benchmarkID, req.Components); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := getUserBenchmark(ctx, tx, userID, benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit benchmark: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteUserBenchmark deletes a benchmark and its components if it belongs to the user
+func (db *DB) DeleteUserBenchmark(ctx context.Context, userID, benchmarkID string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM benchmarks WHERE id = $1 AND user_id = $2`, benchmarkID, userID)
+	if err != nil {
+		return fmt.Errorf("could not delete benchmark: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &types.BenchmarkNotFoundError{UserID: userID, BenchmarkID: benchmarkID}
+	}
+
+	return nil
+}
+
+func getUserBenchmark(ctx context.Context, q queryer, userID, benchmarkID string) (*types.Benchmark, error) {
+	query := benchmarkSelect + `
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var benchmark types.Benchmark
+	if err := scanBenchmark(q.QueryRowContext(ctx, query, benchmarkID, userID), &benchmark); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.BenchmarkNotFoundError{UserID: userID, BenchmarkID: benchmarkID}
+		}
+		return nil, fmt.Errorf("failed to get benchmark: %w", err)
+	}
+
+	components, err := getBenchmarkComponents(ctx, q, benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	benchmark.Components = components
+
+	return &benchmark, nil
+}
+
+// getBenchmarkComponents returns the components of a benchmark, heaviest first
+func getBenchmarkComponents(ctx context.Context, q queryer, benchmarkID string) ([]types.BenchmarkComponent, error) {
+	query := `
+		SELECT symbol, weight
+		FROM benchmark_components
+		WHERE benchmark_id = $1
+		ORDER BY weight DESC, symbol ASC
+	`
+
+	rows, err := q.QueryContext(ctx, query, benchmarkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query benchmark components: %w", err)
+	}
+	defer rows.Close()
+
+	var components []types.BenchmarkComponent
+	for rows.Next() {
+		var component types.BenchmarkComponent
+		if err := rows.Scan(&component.Symbol, &component.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan benchmark component: %w", err)
+		}
+		components = append(components, component)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("benchmark component iteration error: %w", err)
+	}
+
+	return components, nil
+}
+
+func replaceBenchmarkComponents(ctx context.Context, tx *sql.Tx, benchmarkID string, components []types.BenchmarkComponent) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM benchmark_components WHERE benchmark_id = $1`, benchmarkID); err != nil {
+		return fmt.Errorf("failed to clear benchmark components: %w", err)
+	}
+
+	for _, component := range components {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO benchmark_components (benchmark_id, symbol, weight)
+			VALUES ($1, $2, $3)
+		`, benchmarkID, component.Symbol, component.Weight)
+		if err != nil {
+			return fmt.Errorf("failed to save benchmark component %s: %w", component.Symbol, err)
+		}
+	}
+
+	return nil
+}
+
+// benchmarkSelect selects benchmarks without components, callers append the WHERE clause
+const benchmarkSelect = `
+	SELECT id, user_id, name, rebalance, created_at, updated_at
+	FROM benchmarks
+`
+
+// scanBenchmark scans a row produced by benchmarkSelect into benchmark
+func scanBenchmark(row rowScanner, benchmark *types.Benchmark) error {
+	return row.Scan(
+		&benchmark.ID,
+		&benchmark.UserID,
+		&benchmark.Name,
+		&benchmark.Rebalance,
+		&benchmark.CreatedAt,
+		&benchmark.UpdatedAt,
+	)
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type CorporateActionRepo interface {
+	GetCorporateActions(ctx context.Context, symbol string) ([]types.CorporateAction, error)
+	CreateCorporateAction(ctx context.Context, action *types.CorporateAction) (*types.CorporateAction, error)
+	SaveCorporateActions(ctx context.Context, symbol string, actions []types.CorporateAction) error
+	GetSymbolPortfolioIDs(ctx context.Context, symbol string) ([]string, error)
+}
+
+const corporateActionSelect = `
+	SELECT id, symbol, type, ex_date, ratio, amount, source, created_at
+	FROM corporate_actions
+`
+
+// GetCorporateActions returns the known splits and dividends of a symbol ordered by ex-date
+func (db *DB) GetCorporateActions(ctx context.Context, symbol string) ([]types.CorporateAction, error) {
+	query := corporateActionSelect + `
+		WHERE symbol = $1
+		ORDER BY ex_date ASC, type ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query corporate actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []types.CorporateAction
+	for rows.Next() {
+		var action types.CorporateAction
+		if err := scanCorporateAction(rows, &action); err != nil {
+			return nil, fmt.Errorf("failed to scan corporate action: %w", err)
+		}
+		actions = append(actions, action)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("corporate action iteration error: %w", err)
+	}
+
+	return actions, nil
+}
+
+// CreateCorporateAction stores an action entered by hand. An action of the same type and ex-date
+// already known for the symbol returns a *types.CorporateActionExistsError.
+func (db *DB) CreateCorporateAction(ctx context.Context, action *types.CorporateAction) (*types.CorporateAction, error) {
+	query := `
+		INSERT INTO corporate_actions (symbol, type, ex_date, ratio, amount, source, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, symbol, type, ex_date, ratio, amount, source, created_at
+	`
+
+	var created types.CorporateAction
+	err := scanCorporateAction(db.QueryRowContext(ctx, query,
+		action.Symbol,
+		action.Type,
+		action.ExDate,
+		action.Ratio,
+		action.Amount,
+		action.Source,
+	), &created)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, &types.CorporateActionExistsError{Symbol: action.Symbol, Type: action.Type, ExDate: action.ExDate}
+		}
+		return nil, fmt.Errorf("failed to create corporate action: %w", err)
+	}
+
+	return &created, nil
+}
+
+// SaveCorporateActions stores the actions a provider reported for a symbol.
+// Actions already known are kept as they are, so manual corrections are never overwritten.
+func (db *DB) SaveCorporateActions(ctx context.Context, symbol string, actions []types.CorporateAction) error {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := saveCorporateActions(ctx, tx, symbol, actions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit corporate actions: %w", err)
+	}
+
+	return nil
+}
+
+// saveCorporateActions inserts the actions of a symbol within an open transaction, skipping known ones
+func saveCorporateActions(ctx context.Context, tx *sql.Tx, symbol string, actions []types.CorporateAction) error {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO corporate_actions (symbol, type, ex_date, ratio, amount, source, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (symbol, type, ex_date) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare corporate action insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, action := range actions {
+		_, err := stmt.ExecContext(ctx, symbol, action.Type, action.ExDate, action.Ratio, action.Amount, action.Source)
+		if err != nil {
+			return fmt.Errorf("failed to save %s of %s on %s: %w", action.Type, symbol, action.ExDate.Format("2006-01-02"), err)
+		}
+	}
+
+	return nil
+}
+
+// GetSymbolPortfolioIDs returns the portfolios whose ledger has traded the symbol
+func (db *DB) GetSymbolPortfolioIDs(ctx context.Context, symbol string) ([]string, error) {
+	query := `
+		SELECT DISTINCT portfolio_id
+		FROM transactions
+		WHERE symbol = $1 AND type IN ('buy', 'sell')
+	`
+
+	rows, err := db.QueryContext(ctx, query, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query symbol portfolios: %w", err)
+	}
+	defer rows.Close()
+
+	var portfolioIDs []string
+	for rows.Next() {
+		var portfolioID string
+		if err := rows.Scan(&portfolioID); err != nil {
+			return nil, fmt.Errorf("failed to scan portfolio id: %w", err)
+		}
+		portfolioIDs = append(portfolioIDs, portfolioID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("symbol portfolio iteration error: %w", err)
+	}
+
+	return portfolioIDs, nil
+}
+
+func scanCorporateAction(row rowScanner, action *types.CorporateAction) error {
+	return row.Scan(
+		&action.ID,
+		&action.Symbol,
+		&action.Type,
+		&action.ExDate,
+		&action.Ratio,
+		&action.Amount,
+		&action.Source,
+		&action.CreatedAt,
+	)
+}
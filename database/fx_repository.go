@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type FXRepo interface {
+	GetFXRates(ctx context.Context, from, to string) ([]types.FXRate, time.Time, error)
+	SaveFXSeries(ctx context.Context, series *types.FXSeries, fetchedAt time.Time) error
+}
+
+// GetFXRates returns the stored daily rates of a currency pair ordered by date and when the pair was
+// last fetched from the provider. No rates and a zero time are returned when the pair was never fetched.
+func (db *DB) GetFXRates(ctx context.Context, from, to string) ([]types.FXRate, time.Time, error) {
+	var fetchedAt time.Time
+	err := db.QueryRowContext(ctx, `SELECT fetched_at FROM fx_pairs WHERE from_currency = $1 AND to_currency = $2`, from, to).Scan(&fetchedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, time.Time{}, fmt.Errorf("failed to get fx pair: %w", err)
+	}
+
+	query := `
+		SELECT from_currency, to_currency, rate_date, rate
+		FROM fx_rates
+		WHERE from_currency = $1 AND to_currency = $2
+		ORDER BY rate_date ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to query fx rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []types.FXRate
+	for rows.Next() {
+		var rate types.FXRate
+		if err := rows.Scan(&rate.From, &rate.To, &rate.Date, &rate.Rate); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to scan fx rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, time.Time{}, fmt.Errorf("fx rate iteration error: %w", err)
+	}
+
+	return rates, fetchedAt, nil
+}
+
+// SaveFXSeries stores the rates of the series and records when the pair was fetched.
+// Rates already stored are kept as they are, so valuations of past dates do not change.
+func (db *DB) SaveFXSeries(ctx context.Context, series *types.FXSeries, fetchedAt time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fx_rates (from_currency, to_currency, rate_date, rate, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (from_currency, to_currency, rate_date) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare fx rate insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, rate := range series.Rates {
+		if _, err := stmt.ExecContext(ctx, series.From, series.To, rate.Date, rate.Rate); err != nil {
+			return fmt.Errorf("failed to save %s/%s rate of %s: %w", series.From, series.To, rate.Date.Format("2006-01-02"), err)
+		}
+	}
+
+	query := `
+		INSERT INTO fx_pairs (from_currency, to_currency, last_refreshed, fetched_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (from_currency, to_currency) DO UPDATE
+		SET last_refreshed = EXCLUDED.last_refreshed, fetched_at = EXCLUDED.fetched_at
+	`
+
+	if _, err := tx.ExecContext(ctx, query, series.From, series.To, series.LastRefreshed, fetchedAt); err != nil {
+		return fmt.Errorf("failed to save fx pair: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fx rates: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type MarketDataCacheRepo interface {
+	GetCachedPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, time.Time, error)
+	SaveCachedPriceSeries(ctx context.Context, series *types.PriceSeries, fetchedAt time.Time) error
+}
+
+// GetCachedPriceSeries returns the stored series and when it was last fetched from the provider.
+// A nil series is returned when the series was never fetched.
+func (db *DB) GetCachedPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, time.Time, error) {
+	query := `
+		SELECT symbol, bar_interval, last_refreshed, fetched_at
+		FROM market_data_cache
+		WHERE symbol = $1 AND bar_interval = $2
+	`
+
+	var series types.PriceSeries
+	var fetchedAt time.Time
+	err := db.QueryRowContext(ctx, query, symbol, interval).Scan(
+		&series.Symbol,
+		&series.Interval,
+		&series.LastRefreshed,
+		&fetchedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, fmt.Errorf("failed to get cached series: %w", err)
+	}
+
+	bars, err := db.GetPriceHistory(ctx, symbol, interval, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	series.Bars = bars
+
+	return &series, fetchedAt, nil
+}
+
+// SaveCachedPriceSeries stores the series in the price history with the corporate actions it reports
+// and records when it was fetched
+func (db *DB) SaveCachedPriceSeries(ctx context.Context, series *types.PriceSeries, fetchedAt time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := savePriceBars(ctx, tx, series); err != nil {
+		return err
+	}
+
+	if err := saveCorporateActions(ctx, tx, series.Symbol, series.Actions); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO market_data_cache (symbol, bar_interval, last_refreshed, fetched_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (symbol, bar_interval) DO UPDATE
+		SET last_refreshed = EXCLUDED.last_refreshed, fetched_at = EXCLUDED.fetched_at
+	`
+
+	if _, err := tx.ExecContext(ctx, query, series.Symbol, series.Interval, series.LastRefreshed, fetchedAt); err != nil {
+		return fmt.Errorf("failed to save cache entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cached series: %w", err)
+	}
+
+	return nil
+}
@@ -3,103 +3,280 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/ecetinerdem/forseer/types"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type PortfolioRepo interface {
 	// Portfolio operations
+	GetUserPortfolios(ctx context.Context, userID string) ([]types.Portfolio, error)
 	GetUserPortfolio(ctx context.Context, userID string) (*types.Portfolio, error)
+	GetUserDefaultPortfolio(ctx context.Context, userID string) (*types.Portfolio, error)
+	GetPortfolio(ctx context.Context, portfolioID string) (*types.Portfolio, error)
 	CreateUserPortfolio(ctx context.Context, userID, portfolioName string) (*types.Portfolio, error)
-
-	// Stock operations - all user-scoped
-	GetUserStocks(ctx context.Context, userID string) ([]types.Stock, error)
+	RenamePortfolio(ctx context.Context, portfolioID, portfolioName string) (*types.Portfolio, error)
+	SetUserDefaultPortfolio(ctx context.Context, userID, portfolioID string) (*types.Portfolio, error)
+	SetPortfolioBaseCurrency(ctx context.Context, portfolioID, currency string) (*types.Portfolio, error)
+	DeleteUserPortfolio(ctx context.Context, userID, portfolioID string) error
+
+	// Stock operations - scoped to a portfolio, callers verify ownership with UserOwnsPortfolio
+	GetPortfolioStocks(ctx context.Context, portfolioID string) ([]types.Stock, error)
+	GetPortfolioStockByID(ctx context.Context, portfolioID, stockID string) (*types.Stock, error)
+	GetPortfolioStockBySymbol(ctx context.Context, portfolioID, stockSymbol string) (*types.Stock, error)
+	AddStockToPortfolio(ctx context.Context, portfolioID string, stock *types.Stock) (*types.Stock, error)
+	DeletePortfolioStockByID(ctx context.Context, portfolioID, stockID string, purge bool) error
+
+	// Stock lookup across all of the user's portfolios
 	GetUserStockByID(ctx context.Context, userID, stockID string) (*types.Stock, error)
-	GetUserStockBySymbol(ctx context.Context, userID, stockSymbol string) (*types.Stock, error)
-	AddStockToUserPortfolio(ctx context.Context, userID string, stock *types.Stock) (*types.Stock, error)
-	DeleteUserStockByID(ctx context.Context, userID, stockID string) error
+
+	// Symbols held across all portfolios or watched, for the price refresh scheduler
+	GetTrackedSymbols(ctx context.Context) ([]types.TrackedSymbol, error)
+	GetUserTrackedSymbols(ctx context.Context, userID string) ([]types.TrackedSymbol, error)
 
 	// Ownership validation
 	UserOwnsStock(ctx context.Context, userID, stockID string) (bool, error)
 	UserOwnsPortfolio(ctx context.Context, userID, portfolioID string) (bool, error)
 }
 
-// GetUserPortfolio retrieves the user's portfolio with all stocks
+// GetUserPortfolios returns every portfolio of the user, the default one first
+func (db *DB) GetUserPortfolios(ctx context.Context, userID string) ([]types.Portfolio, error) {
+	// Make sure the user has a default portfolio to list
+	if _, err := db.GetUserDefaultPortfolio(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	query := portfolioSelect + `
+		WHERE user_id = $1
+		ORDER BY is_default DESC, created_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query portfolios: %w", err)
+	}
+	defer rows.Close()
+
+	var portfolios []types.Portfolio
+	for rows.Next() {
+		var portfolio types.Portfolio
+		if err := scanPortfolio(rows, &portfolio); err != nil {
+			return nil, fmt.Errorf("failed to scan portfolio: %w", err)
+		}
+		portfolios = append(portfolios, portfolio)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("portfolio iteration error: %w", err)
+	}
+
+	return portfolios, nil
+}
+
+// GetUserPortfolio retrieves the user's default portfolio with all stocks
 func (db *DB) GetUserPortfolio(ctx context.Context, userID string) (*types.Portfolio, error) {
-	// First get or create portfolio
-	portfolio, err := db.getOrCreatePortfolio(ctx, userID)
+	portfolio, err := db.GetUserDefaultPortfolio(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get portfolio: %w", err)
 	}
 
+	return db.GetPortfolio(ctx, portfolio.ID)
+}
+
+// GetPortfolio retrieves a portfolio with all stocks
+func (db *DB) GetPortfolio(ctx context.Context, portfolioID string) (*types.Portfolio, error) {
+	query := portfolioSelect + `
+		WHERE id = $1
+	`
+
+	var portfolio types.Portfolio
+	if err := scanPortfolio(db.QueryRowContext(ctx, query, portfolioID), &portfolio); err != nil {
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+
 	// Get all stocks for this portfolio
-	stocks, err := db.GetUserStocks(ctx, userID)
+	stocks, err := db.GetPortfolioStocks(ctx, portfolioID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stocks: %w", err)
 	}
 
 	portfolio.Stocks = stocks
-	return portfolio, nil
+	return &portfolio, nil
 }
 
-// CreateUserPortfolio creates a new portfolio for the user
+// GetUserDefaultPortfolio returns the user's default portfolio, creating one if the user has none
+func (db *DB) GetUserDefaultPortfolio(ctx context.Context, userID string) (*types.Portfolio, error) {
+	query := portfolioSelect + `
+		WHERE user_id = $1
+		ORDER BY is_default DESC, created_at DESC
+		LIMIT 1
+	`
+
+	var portfolio types.Portfolio
+	err := scanPortfolio(db.QueryRowContext(ctx, query, userID), &portfolio)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Create default portfolio
+			return db.CreateUserPortfolio(ctx, userID, "My Portfolio")
+		}
+		return nil, fmt.Errorf("failed to query portfolio: %w", err)
+	}
+
+	return &portfolio, nil
+}
+
+// CreateUserPortfolio creates a new portfolio for the user.
+// The user's first portfolio becomes the default one.
 func (db *DB) CreateUserPortfolio(ctx context.Context, userID, portfolioName string) (*types.Portfolio, error) {
 	query := `
-		INSERT INTO portfolios (user_id, name, created_at, updated_at)
-		VALUES ($1, $2, NOW(), NOW())
-		RETURNING id, user_id, name, created_at, updated_at
-	`
+		INSERT INTO portfolios (user_id, name, is_default, created_at, updated_at)
+		VALUES ($1, $2, NOT EXISTS (SELECT 1 FROM portfolios WHERE user_id = $1 AND is_default), NOW(), NOW())
+		RETURNING ` + portfolioColumns
 
 	var portfolio types.Portfolio
-	err := db.QueryRowContext(ctx, query, userID, portfolioName).Scan(
-		&portfolio.ID,
-		&portfolio.UserID,
-		&portfolio.Name,
-		&portfolio.CreatedAt,
-		&portfolio.UpdatedAt,
-	)
+	err := scanPortfolio(db.QueryRowContext(ctx, query, userID, portfolioName), &portfolio)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, &types.PortfolioNameTakenError{Name: portfolioName}
+		}
 		return nil, fmt.Errorf("failed to create portfolio: %w", err)
 	}
 
 	return &portfolio, nil
 }
 
-// GetUserStocks returns all stocks belonging to the user
-func (db *DB) GetUserStocks(ctx context.Context, userID string) ([]types.Stock, error) {
+// RenamePortfolio changes the name of a portfolio
+func (db *DB) RenamePortfolio(ctx context.Context, portfolioID, portfolioName string) (*types.Portfolio, error) {
 	query := `
-		SELECT s.id, s.portfolio_id, s.symbol, s.month, s.open, s.high, s.low, s.close, s.volume, s.created_at, s.updated_at
-		FROM stocks s
-		INNER JOIN portfolios p ON s.portfolio_id = p.id
-		WHERE p.user_id = $1
+		UPDATE portfolios
+		SET name = $1
+		WHERE id = $2
+		RETURNING ` + portfolioColumns
+
+	var portfolio types.Portfolio
+	err := scanPortfolio(db.QueryRowContext(ctx, query, portfolioName, portfolioID), &portfolio)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, &types.PortfolioNameTakenError{Name: portfolioName}
+		}
+		return nil, fmt.Errorf("failed to rename portfolio: %w", err)
+	}
+
+	return &portfolio, nil
+}
+
+// SetUserDefaultPortfolio makes a portfolio the user's default one
+func (db *DB) SetUserDefaultPortfolio(ctx context.Context, userID, portfolioID string) (*types.Portfolio, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE portfolios SET is_default = FALSE WHERE user_id = $1 AND is_default AND id <> $2`, userID, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear default portfolio: %w", err)
+	}
+
+	query := `
+		UPDATE portfolios
+		SET is_default = TRUE
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + portfolioColumns
+
+	var portfolio types.Portfolio
+	err = scanPortfolio(tx.QueryRowContext(ctx, query, portfolioID, userID), &portfolio)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.PortfolioOwnershipError{UserID: userID, PortfolioID: portfolioID}
+		}
+		return nil, fmt.Errorf("failed to set default portfolio: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit default portfolio: %w", err)
+	}
+
+	return &portfolio, nil
+}
+
+// SetPortfolioBaseCurrency changes the currency a portfolio is valued in. The ledger keeps its own
+// currencies, so nothing else is rewritten.
+func (db *DB) SetPortfolioBaseCurrency(ctx context.Context, portfolioID, currency string) (*types.Portfolio, error) {
+	query := `
+		UPDATE portfolios
+		SET base_currency = $1
+		WHERE id = $2
+		RETURNING ` + portfolioColumns
+
+	var portfolio types.Portfolio
+	if err := scanPortfolio(db.QueryRowContext(ctx, query, currency, portfolioID), &portfolio); err != nil {
+		return nil, fmt.Errorf("failed to set base currency: %w", err)
+	}
+
+	return &portfolio, nil
+}
+
+// DeleteUserPortfolio deletes a portfolio with its stocks and transactions.
+// When the default portfolio is deleted the most recent remaining one becomes the default.
+func (db *DB) DeleteUserPortfolio(ctx context.Context, userID, portfolioID string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var wasDefault bool
+	err = tx.QueryRowContext(ctx, `DELETE FROM portfolios WHERE id = $1 AND user_id = $2 RETURNING is_default`, portfolioID, userID).Scan(&wasDefault)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &types.PortfolioOwnershipError{UserID: userID, PortfolioID: portfolioID}
+		}
+		return fmt.Errorf("could not delete portfolio: %w", err)
+	}
+
+	if wasDefault {
+		query := `
+			UPDATE portfolios
+			SET is_default = TRUE
+			WHERE id = (
+				SELECT id FROM portfolios WHERE user_id = $1 ORDER BY created_at DESC LIMIT 1
+			)
+		`
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("could not promote default portfolio: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit portfolio deletion: %w", err)
+	}
+
+	return nil
+}
+
+// GetPortfolioStocks returns all stocks of a portfolio
+func (db *DB) GetPortfolioStocks(ctx context.Context, portfolioID string) ([]types.Stock, error) {
+	query := stockSelect + `
+		WHERE s.portfolio_id = $1
 		ORDER BY s.created_at DESC
 	`
 
-	rows, err := db.QueryContext(ctx, query, userID)
+	rows, err := db.QueryContext(ctx, query, portfolioID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query user stocks: %w", err)
+		return nil, fmt.Errorf("failed to query portfolio stocks: %w", err)
 	}
 	defer rows.Close()
 
 	var stocks []types.Stock
 	for rows.Next() {
 		var stock types.Stock
-		err := rows.Scan(
-			&stock.ID,
-			&stock.PortfolioID,
-			&stock.Symbol,
-			&stock.Month,
-			&stock.Open,
-			&stock.High,
-			&stock.Low,
-			&stock.Close,
-			&stock.Volume,
-			&stock.CreatedAt,
-			&stock.UpdatedAt,
-		)
-		if err != nil {
+		if err := scanStock(rows, &stock); err != nil {
 			return nil, fmt.Errorf("failed to scan stock: %w", err)
 		}
 		stocks = append(stocks, stock)
@@ -108,29 +285,33 @@ func (db *DB) GetUserStocks(ctx context.Context, userID string) ([]types.Stock,
 	return stocks, nil
 }
 
-// GetUserStockByID retrieves a specific stock if it belongs to the user
+// GetPortfolioStockByID retrieves a specific stock if it belongs to the portfolio
+func (db *DB) GetPortfolioStockByID(ctx context.Context, portfolioID, stockID string) (*types.Stock, error) {
+	query := stockSelect + `
+		WHERE s.id = $1 AND s.portfolio_id = $2
+	`
+
+	var stock types.Stock
+	err := scanStock(db.QueryRowContext(ctx, query, stockID, portfolioID), &stock)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.StockNotFoundError{PortfolioID: portfolioID, StockID: stockID}
+		}
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+
+	return &stock, nil
+}
+
+// GetUserStockByID retrieves a specific stock if it belongs to any of the user's portfolios
 func (db *DB) GetUserStockByID(ctx context.Context, userID, stockID string) (*types.Stock, error) {
-	query := `
-		SELECT s.id, s.portfolio_id, s.symbol, s.month, s.open, s.high, s.low, s.close, s.volume, s.created_at, s.updated_at
-		FROM stocks s
-		INNER JOIN portfolios p ON s.portfolio_id = p.id
+	query := stockSelect + `
 		WHERE s.id = $1 AND p.user_id = $2
 	`
 
 	var stock types.Stock
-	err := db.QueryRowContext(ctx, query, stockID, userID).Scan(
-		&stock.ID,
-		&stock.PortfolioID,
-		&stock.Symbol,
-		&stock.Month,
-		&stock.Open,
-		&stock.High,
-		&stock.Low,
-		&stock.Close,
-		&stock.Volume,
-		&stock.CreatedAt,
-		&stock.UpdatedAt,
-	)
+	err := scanStock(db.QueryRowContext(ctx, query, stockID, userID), &stock)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -142,35 +323,18 @@ func (db *DB) GetUserStockByID(ctx context.Context, userID, stockID string) (*ty
 	return &stock, nil
 }
 
-// GetUserStockBySymbol retrieves a stock by symbol if it belongs to the user
-func (db *DB) GetUserStockBySymbol(ctx context.Context, userID, stockSymbol string) (*types.Stock, error) {
-	query := `
-		SELECT s.id, s.portfolio_id, s.symbol, s.month, s.open, s.high, s.low, s.close, s.volume, s.created_at, s.updated_at
-		FROM stocks s
-		INNER JOIN portfolios p ON s.portfolio_id = p.id
-		WHERE s.symbol = $1 AND p.user_id = $2
-		ORDER BY s.created_at DESC
-		LIMIT 1
+// GetPortfolioStockBySymbol retrieves a stock by symbol if it belongs to the portfolio
+func (db *DB) GetPortfolioStockBySymbol(ctx context.Context, portfolioID, stockSymbol string) (*types.Stock, error) {
+	query := stockSelect + `
+		WHERE s.symbol = $1 AND s.portfolio_id = $2
 	`
 
 	var stock types.Stock
-	err := db.QueryRowContext(ctx, query, stockSymbol, userID).Scan(
-		&stock.ID,
-		&stock.PortfolioID,
-		&stock.Symbol,
-		&stock.Month,
-		&stock.Open,
-		&stock.High,
-		&stock.Low,
-		&stock.Close,
-		&stock.Volume,
-		&stock.CreatedAt,
-		&stock.UpdatedAt,
-	)
+	err := scanStock(db.QueryRowContext(ctx, query, stockSymbol, portfolioID), &stock)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("no stock with symbol %s found for user %s", stockSymbol, userID)
+			return nil, fmt.Errorf("no stock with symbol %s found in portfolio %s", stockSymbol, portfolioID)
 		}
 		return nil, fmt.Errorf("failed to get stock by symbol: %w", err)
 	}
@@ -178,78 +342,134 @@ func (db *DB) GetUserStockBySymbol(ctx context.Context, userID, stockSymbol stri
 	return &stock, nil
 }
 
-// AddStockToUserPortfolio adds a stock to the user's portfolio
-func (db *DB) AddStockToUserPortfolio(ctx context.Context, userID string, stock *types.Stock) (*types.Stock, error) {
-	// First ensure user has a portfolio
-	portfolio, err := db.getOrCreatePortfolio(ctx, userID)
+// AddStockToPortfolio opens a holding by recording a buy of its quantity at its average cost.
+// Holdings are derived from the ledger, the resulting holding is returned.
+func (db *DB) AddStockToPortfolio(ctx context.Context, portfolioID string, stock *types.Stock) (*types.Stock, error) {
+	if _, err := db.RecordPortfolioTransaction(ctx, portfolioID, openingBuy(stock), stock.Interval); err != nil {
+		return nil, fmt.Errorf("could not record the buy: %w", err)
+	}
+
+	return db.GetPortfolioStockBySymbol(ctx, portfolioID, stock.Symbol)
+}
+
+// openingBuy is the buy that opens a holding of the stock's quantity at its average cost
+func openingBuy(stock *types.Stock) *types.Transaction {
+	return &types.Transaction{
+		Type:      types.TransactionBuy,
+		Symbol:    stock.Symbol,
+		Quantity:  stock.Quantity,
+		Price:     stock.AverageCost,
+		Currency:  stock.Currency,
+		TradeDate: stock.AcquiredAt,
+	}
+}
+
+// DeletePortfolioStockByID deletes a stock of the portfolio together with its buys. A stock whose ledger
+// holds other entries returns a *types.StockHistoryError, unless purge is set to erase its whole ledger,
+// realized sells, dividends and splits included.
+func (db *DB) DeletePortfolioStockByID(ctx context.Context, portfolioID, stockID string, purge bool) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user portfolio: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Insert the stock
-	query := `
-		INSERT INTO stocks (portfolio_id, symbol, month, open, high, low, close, volume, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
-		RETURNING id, portfolio_id, symbol, month, open, high, low, close, volume, created_at, updated_at
-	`
+	var symbol string
+	err = tx.QueryRowContext(ctx, `DELETE FROM stocks WHERE id = $1 AND portfolio_id = $2 RETURNING symbol`, stockID, portfolioID).Scan(&symbol)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &types.StockNotFoundError{PortfolioID: portfolioID, StockID: stockID}
+		}
+		return fmt.Errorf("could not delete stock: %w", err)
+	}
 
-	var newStock types.Stock
-	err = db.QueryRowContext(ctx, query,
-		portfolio.ID,
-		stock.Symbol,
-		stock.Month,
-		stock.Open,
-		stock.High,
-		stock.Low,
-		stock.Close,
-		stock.Volume,
-	).Scan(
-		&newStock.ID,
-		&newStock.PortfolioID,
-		&newStock.Symbol,
-		&newStock.Month,
-		&newStock.Open,
-		&newStock.High,
-		&newStock.Low,
-		&newStock.Close,
-		&newStock.Volume,
-		&newStock.CreatedAt,
-		&newStock.UpdatedAt,
-	)
+	if !purge {
+		var entries int
+		err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM transactions WHERE portfolio_id = $1 AND symbol = $2 AND type <> 'buy'`, portfolioID, symbol).Scan(&entries)
+		if err != nil {
+			return fmt.Errorf("could not count stock transactions: %w", err)
+		}
+		if entries > 0 {
+			return &types.StockHistoryError{Symbol: symbol, Entries: entries}
+		}
+	}
 
+	_, err = tx.ExecContext(ctx, `DELETE FROM transactions WHERE portfolio_id = $1 AND symbol = $2`, portfolioID, symbol)
 	if err != nil {
-		return nil, fmt.Errorf("could not save the stock: %w", err)
+		return fmt.Errorf("could not delete stock transactions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit stock deletion: %w", err)
 	}
 
-	return &newStock, nil
+	return nil
 }
 
-// DeleteUserStockByID deletes a stock if it belongs to the user
-func (db *DB) DeleteUserStockByID(ctx context.Context, userID, stockID string) error {
-	// Verify ownership and delete in one query
+// GetTrackedSymbols returns every distinct symbol and interval held across all portfolios, on a watchlist
+// or watched by an enabled alert
+func (db *DB) GetTrackedSymbols(ctx context.Context) ([]types.TrackedSymbol, error) {
 	query := `
-		DELETE FROM stocks 
-		WHERE id = $1 
-		AND portfolio_id IN (
-			SELECT id FROM portfolios WHERE user_id = $2
-		)
+		SELECT symbol, bar_interval FROM stocks
+		UNION
+		SELECT symbol, bar_interval FROM watchlist_entries
+		UNION
+		SELECT symbol, bar_interval FROM alerts WHERE enabled AND symbol <> ''
+		ORDER BY symbol, bar_interval
 	`
 
-	result, err := db.ExecContext(ctx, query, stockID, userID)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
-		return fmt.Errorf("could not delete stock: %w", err)
+		return nil, fmt.Errorf("failed to query tracked symbols: %w", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
+	var symbols []types.TrackedSymbol
+	for rows.Next() {
+		var symbol types.TrackedSymbol
+		if err := rows.Scan(&symbol.Symbol, &symbol.Interval); err != nil {
+			return nil, fmt.Errorf("failed to scan tracked symbol: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols, nil
+}
+
+// GetUserTrackedSymbols returns the distinct symbols and intervals the user holds, watches or has an
+// enabled alert on
+func (db *DB) GetUserTrackedSymbols(ctx context.Context, userID string) ([]types.TrackedSymbol, error) {
+	query := `
+		SELECT s.symbol, s.bar_interval
+		FROM stocks s
+		INNER JOIN portfolios p ON s.portfolio_id = p.id
+		WHERE p.user_id = $1
+		UNION
+		SELECT e.symbol, e.bar_interval
+		FROM watchlist_entries e
+		INNER JOIN watchlists w ON e.watchlist_id = w.id
+		WHERE w.user_id = $1
+		UNION
+		SELECT symbol, bar_interval FROM alerts WHERE user_id = $1 AND enabled AND symbol <> ''
+		ORDER BY 1, 2
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID)
 	if err != nil {
-		return fmt.Errorf("could not get rows affected: %w", err)
+		return nil, fmt.Errorf("failed to query user tracked symbols: %w", err)
 	}
+	defer rows.Close()
 
-	if rowsAffected == 0 {
-		return &types.StockOwnershipError{UserID: userID, StockID: stockID}
+	var symbols []types.TrackedSymbol
+	for rows.Next() {
+		var symbol types.TrackedSymbol
+		if err := rows.Scan(&symbol.Symbol, &symbol.Interval); err != nil {
+			return nil, fmt.Errorf("failed to scan tracked symbol: %w", err)
+		}
+		symbols = append(symbols, symbol)
 	}
 
-	return nil
+	return symbols, nil
 }
 
 // UserOwnsStock verifies if a user owns a specific stock
@@ -293,33 +513,84 @@ func (db *DB) UserOwnsPortfolio(ctx context.Context, userID, portfolioID string)
 	return true, nil
 }
 
-// getOrCreatePortfolio is a helper that ensures a user has a portfolio
-func (db *DB) getOrCreatePortfolio(ctx context.Context, userID string) (*types.Portfolio, error) {
-	// Try to get existing portfolio
-	query := `
-		SELECT id, user_id, name, created_at, updated_at
-		FROM portfolios
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT 1
-	`
+// portfolioColumns lists the columns read by scanPortfolio
+const portfolioColumns = `id, user_id, name, is_default, cost_basis_method, base_currency, created_at, updated_at`
 
-	var portfolio types.Portfolio
-	err := db.QueryRowContext(ctx, query, userID).Scan(
+// portfolioSelect selects portfolios, callers append the WHERE clause
+const portfolioSelect = `
+	SELECT ` + portfolioColumns + `
+	FROM portfolios
+`
+
+// scanPortfolio scans a row of portfolioColumns into portfolio
+func scanPortfolio(row rowScanner, portfolio *types.Portfolio) error {
+	return row.Scan(
 		&portfolio.ID,
 		&portfolio.UserID,
 		&portfolio.Name,
+		&portfolio.IsDefault,
+		&portfolio.CostBasisMethod,
+		&portfolio.BaseCurrency,
 		&portfolio.CreatedAt,
 		&portfolio.UpdatedAt,
 	)
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// stockSelect selects holdings together with the latest bar of their price history.
+// Callers append the WHERE clause, rows must be read with scanStock.
+const stockSelect = `
+	SELECT s.id, s.portfolio_id, s.symbol, s.bar_interval, ph.bar_date,
+		COALESCE(ph.open, 0), COALESCE(ph.high, 0), COALESCE(ph.low, 0), COALESCE(ph.close, 0), COALESCE(ph.volume, 0),
+		s.quantity, s.average_cost, s.currency, s.acquired_at, s.created_at, s.updated_at, p.base_currency
+	FROM stocks s
+	INNER JOIN portfolios p ON s.portfolio_id = p.id
+	LEFT JOIN LATERAL (
+		SELECT bar_date, open, high, low, close, volume
+		FROM price_history
+		WHERE symbol = s.symbol AND bar_interval = s.bar_interval
+		ORDER BY bar_date DESC
+		LIMIT 1
+	) ph ON TRUE
+`
 
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanStock scans a row produced by stockSelect into stock
+func scanStock(row rowScanner, stock *types.Stock) error {
+	var barDate sql.NullTime
+
+	err := row.Scan(
+		&stock.ID,
+		&stock.PortfolioID,
+		&stock.Symbol,
+		&stock.Interval,
+		&barDate,
+		&stock.Open,
+		&stock.High,
+		&stock.Low,
+		&stock.Close,
+		&stock.Volume,
+		&stock.Quantity,
+		&stock.AverageCost,
+		&stock.Currency,
+		&stock.AcquiredAt,
+		&stock.CreatedAt,
+		&stock.UpdatedAt,
+		&stock.BaseCurrency,
+	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// Create default portfolio
-			return db.CreateUserPortfolio(ctx, userID, "My Portfolio")
-		}
-		return nil, fmt.Errorf("failed to query portfolio: %w", err)
+		return err
 	}
 
-	return &portfolio, nil
+	stock.Date = barDate.Time
+	return nil
 }
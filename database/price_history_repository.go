@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type PriceHistoryRepo interface {
+	SavePriceSeries(ctx context.Context, series *types.PriceSeries) error
+	GetPriceHistory(ctx context.Context, symbol string, interval types.Interval, from, to time.Time) ([]types.PriceBar, error)
+}
+
+// SavePriceSeries upserts every bar of the series into the price history and stores the corporate actions it reports
+func (db *DB) SavePriceSeries(ctx context.Context, series *types.PriceSeries) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := savePriceBars(ctx, tx, series); err != nil {
+		return err
+	}
+
+	if err := saveCorporateActions(ctx, tx, series.Symbol, series.Actions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit price history: %w", err)
+	}
+
+	return nil
+}
+
+// savePriceBars upserts the bars of a series within an open transaction
+func savePriceBars(ctx context.Context, tx *sql.Tx, series *types.PriceSeries) error {
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO price_history (symbol, bar_interval, bar_date, open, high, low, close, adjusted_close, volume, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		ON CONFLICT (symbol, bar_interval, bar_date) DO UPDATE
+		SET open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low, close = EXCLUDED.close,
+			adjusted_close = EXCLUDED.adjusted_close, volume = EXCLUDED.volume
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare price history insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, bar := range series.Bars {
+		_, err := stmt.ExecContext(ctx,
+			series.Symbol,
+			series.Interval,
+			bar.Date,
+			bar.Open,
+			bar.High,
+			bar.Low,
+			bar.Close,
+			bar.AdjustedClose,
+			bar.Volume,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save price bar %s %s: %w", series.Symbol, bar.Date.Format("2006-01-02"), err)
+		}
+	}
+
+	return nil
+}
+
+// GetPriceHistory returns the bars of a symbol's price history ordered by date ascending.
+// A zero from or to leaves that side of the range open.
+func (db *DB) GetPriceHistory(ctx context.Context, symbol string, interval types.Interval, from, to time.Time) ([]types.PriceBar, error) {
+	query := `
+		SELECT symbol, bar_interval, bar_date, open, high, low, close, adjusted_close, volume
+		FROM price_history
+		WHERE symbol = $1 AND bar_interval = $2
+		AND ($3::timestamptz IS NULL OR bar_date >= $3)
+		AND ($4::timestamptz IS NULL OR bar_date <= $4)
+		ORDER BY bar_date ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, symbol, interval, nullableTime(from), nullableTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	var bars []types.PriceBar
+	for rows.Next() {
+		var bar types.PriceBar
+		err := rows.Scan(
+			&bar.Symbol,
+			&bar.Interval,
+			&bar.Date,
+			&bar.Open,
+			&bar.High,
+			&bar.Low,
+			&bar.Close,
+			&bar.AdjustedClose,
+			&bar.Volume,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price bar: %w", err)
+		}
+		bars = append(bars, bar)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("price history iteration error: %w", err)
+	}
+
+	return bars, nil
+}
+
+// nullableTime maps the zero time to NULL so it can be used as an open range bound
+func nullableTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
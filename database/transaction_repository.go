@@ -0,0 +1,449 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ecetinerdem/forseer/ledger"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type TransactionRepo interface {
+	// All operations are scoped to a portfolio, callers verify ownership with UserOwnsPortfolio
+	GetPortfolioTransactions(ctx context.Context, portfolioID string, filter types.TransactionFilter) ([]types.Transaction, error)
+	GetPortfolioLedger(ctx context.Context, portfolioID string) ([]types.Transaction, *types.Portfolio, error)
+	GetPortfolioTransactionByID(ctx context.Context, portfolioID, transactionID string) (*types.Transaction, error)
+	RecordPortfolioTransaction(ctx context.Context, portfolioID string, transaction *types.Transaction, interval types.Interval) (*types.Transaction, error)
+	DeletePortfolioTransactionByID(ctx context.Context, portfolioID, transactionID string, interval types.Interval) error
+	SetPortfolioCostBasisMethod(ctx context.Context, portfolioID string, method types.CostBasisMethod) (*types.Portfolio, error)
+	ImportPortfolioTransactions(ctx context.Context, portfolioID string, transactions []types.Transaction, interval types.Interval, dryRun bool) ([]types.Transaction, int, error)
+}
+
+// GetPortfolioTransactions returns the ledger of a portfolio ordered by trade date
+func (db *DB) GetPortfolioTransactions(ctx context.Context, portfolioID string, filter types.TransactionFilter) ([]types.Transaction, error) {
+	query := transactionSelect + `
+		WHERE portfolio_id = $1
+		AND ($2 = '' OR symbol = $2)
+		AND ($3 = '' OR type = $3)
+		AND ($4::timestamptz IS NULL OR trade_date >= $4)
+		AND ($5::timestamptz IS NULL OR trade_date <= $5)
+		ORDER BY trade_date ASC, created_at ASC
+	`
+
+	return queryTransactions(ctx, db, query, portfolioID, filter.Symbol, string(filter.Type), nullableTime(filter.From), nullableTime(filter.To))
+}
+
+// GetPortfolioLedger returns the whole ledger of a portfolio ordered by trade date, with the portfolio
+// settings it is read with. The portfolio's stocks are not loaded.
+func (db *DB) GetPortfolioLedger(ctx context.Context, portfolioID string) ([]types.Transaction, *types.Portfolio, error) {
+	var portfolio types.Portfolio
+	if err := scanPortfolio(db.QueryRowContext(ctx, portfolioSelect+` WHERE id = $1`, portfolioID), &portfolio); err != nil {
+		return nil, nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+
+	transactions, err := db.GetPortfolioTransactions(ctx, portfolioID, types.TransactionFilter{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return transactions, &portfolio, nil
+}
+
+// GetPortfolioTransactionByID retrieves a transaction if it belongs to the portfolio
+func (db *DB) GetPortfolioTransactionByID(ctx context.Context, portfolioID, transactionID string) (*types.Transaction, error) {
+	query := transactionSelect + `
+		WHERE id = $1 AND portfolio_id = $2
+	`
+
+	var transaction types.Transaction
+	err := scanTransaction(db.QueryRowContext(ctx, query, transactionID, portfolioID), &transaction)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.TransactionNotFoundError{PortfolioID: portfolioID, TransactionID: transactionID}
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	return &transaction, nil
+}
+
+// RecordPortfolioTransaction validates a transaction against the ledger of the portfolio, stores it
+// and updates the affected holding. Holdings opened by the transaction use the given interval.
+// Sells exceeding the held quantity return an *types.InsufficientHoldingsError.
+func (db *DB) RecordPortfolioTransaction(ctx context.Context, portfolioID string, transaction *types.Transaction, interval types.Interval) (*types.Transaction, error) {
+	if err := ledger.Validate(transaction); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recordTransaction(ctx, tx, portfolioID, transaction, interval); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// ImportPortfolioTransactions records a batch of transactions in one database transaction and updates
+// the affected holdings, opening new ones with the given interval. Transactions whose external ID is
+// already in the ledger are skipped. The whole batch is validated against the ledger first, so nothing
+// is written when any of it fails; a dry run validates without writing.
+// It returns the transactions that were (or would be) recorded and the number of duplicates skipped.
+func (db *DB) ImportPortfolioTransactions(ctx context.Context, portfolioID string, transactions []types.Transaction, interval types.Interval, dryRun bool) ([]types.Transaction, int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, portfolio, err := lockLedger(ctx, tx, portfolioID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		if t.ExternalID != "" {
+			seen[t.ExternalID] = true
+		}
+	}
+
+	// Keep the file order within a trade date by spacing the creation times
+	now := time.Now()
+	var imported []types.Transaction
+	duplicates := 0
+	for i, t := range transactions {
+		if t.ExternalID != "" && seen[t.ExternalID] {
+			duplicates++
+			continue
+		}
+		seen[t.ExternalID] = true
+
+		t.PortfolioID = portfolioID
+		t.CreatedAt = now.Add(time.Duration(i) * time.Microsecond)
+		ledger.ResolveCurrency(&t, append(existing, imported...), portfolio.BaseCurrency)
+		imported = append(imported, t)
+	}
+
+	book, err := ledger.Replay(append(existing, imported...), portfolio.CostBasisMethod)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if dryRun || len(imported) == 0 {
+		return imported, duplicates, nil
+	}
+
+	query := `
+		INSERT INTO transactions (portfolio_id, type, symbol, quantity, price, amount, fees, currency, split_ratio, lot_id, external_id, trade_date, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)
+		RETURNING id, created_at, updated_at
+	`
+
+	symbols := make(map[string]bool)
+	for i := range imported {
+		t := &imported[i]
+		err := tx.QueryRowContext(ctx, query,
+			t.PortfolioID,
+			t.Type,
+			t.Symbol,
+			t.Quantity,
+			t.Price,
+			t.Amount,
+			t.Fees,
+			t.Currency,
+			t.SplitRatio,
+			t.LotID,
+			t.ExternalID,
+			t.TradeDate,
+			t.Notes,
+			t.CreatedAt,
+		).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not save the transaction: %w", err)
+		}
+		symbols[t.Symbol] = true
+	}
+
+	for symbol := range symbols {
+		if err := syncHolding(ctx, tx, portfolioID, symbol, book, interval); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	return imported, duplicates, nil
+}
+
+// DeletePortfolioTransactionByID removes a transaction from the portfolio's ledger and updates the affected holding.
+// A holding reopened by deleting the sell that closed it uses the given interval, existing holdings keep theirs.
+// Deleting a buy that later sells depend on returns an *types.InsufficientHoldingsError.
+func (db *DB) DeletePortfolioTransactionByID(ctx context.Context, portfolioID, transactionID string, interval types.Interval) error {
+	transaction, err := db.GetPortfolioTransactionByID(ctx, portfolioID, transactionID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transactions, portfolio, err := lockLedger(ctx, tx, transaction.PortfolioID)
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]types.Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		if t.ID != transactionID {
+			remaining = append(remaining, t)
+		}
+	}
+
+	book, err := ledger.Replay(remaining, portfolio.CostBasisMethod)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transactions WHERE id = $1`, transactionID); err != nil {
+		return fmt.Errorf("could not delete transaction: %w", err)
+	}
+
+	if err := syncHolding(ctx, tx, transaction.PortfolioID, transaction.Symbol, book, interval); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction deletion: %w", err)
+	}
+
+	return nil
+}
+
+// SetPortfolioCostBasisMethod changes the lot matching method of a portfolio
+// and recomputes the cost basis of every holding with it
+func (db *DB) SetPortfolioCostBasisMethod(ctx context.Context, portfolioID string, method types.CostBasisMethod) (*types.Portfolio, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transactions, _, err := lockLedger(ctx, tx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := ledger.Replay(transactions, method)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE portfolios
+		SET cost_basis_method = $1
+		WHERE id = $2
+		RETURNING ` + portfolioColumns
+
+	var portfolio types.Portfolio
+	if err := scanPortfolio(tx.QueryRowContext(ctx, query, method, portfolioID), &portfolio); err != nil {
+		return nil, fmt.Errorf("could not update cost basis method: %w", err)
+	}
+
+	for symbol := range book.Positions {
+		if err := syncHolding(ctx, tx, portfolioID, symbol, book, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit cost basis method: %w", err)
+	}
+
+	return &portfolio, nil
+}
+
+// recordTransaction stores a transaction within tx after replaying it against the locked ledger of the
+// portfolio, then updates the affected holding. Callers validate the transaction.
+func recordTransaction(ctx context.Context, tx *sql.Tx, portfolioID string, transaction *types.Transaction, interval types.Interval) error {
+	transactions, portfolio, err := lockLedger(ctx, tx, portfolioID)
+	if err != nil {
+		return err
+	}
+
+	transaction.PortfolioID = portfolioID
+	transaction.CreatedAt = time.Now()
+	ledger.ResolveCurrency(transaction, transactions, portfolio.BaseCurrency)
+
+	book, err := ledger.Replay(append(transactions, *transaction), portfolio.CostBasisMethod)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO transactions (portfolio_id, type, symbol, quantity, price, amount, fees, currency, split_ratio, lot_id, trade_date, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRowContext(ctx, query,
+		transaction.PortfolioID,
+		transaction.Type,
+		transaction.Symbol,
+		transaction.Quantity,
+		transaction.Price,
+		transaction.Amount,
+		transaction.Fees,
+		transaction.Currency,
+		transaction.SplitRatio,
+		transaction.LotID,
+		transaction.TradeDate,
+		transaction.Notes,
+	).Scan(&transaction.ID, &transaction.CreatedAt, &transaction.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("could not save the transaction: %w", err)
+	}
+
+	return syncHolding(ctx, tx, portfolioID, transaction.Symbol, book, interval)
+}
+
+// lockLedger locks the portfolio row so concurrent writes cannot both pass validation,
+// then returns its transactions and the portfolio, whose cost basis method and base currency apply to them
+func lockLedger(ctx context.Context, tx *sql.Tx, portfolioID string) ([]types.Transaction, *types.Portfolio, error) {
+	var portfolio types.Portfolio
+	err := scanPortfolio(tx.QueryRowContext(ctx, portfolioSelect+` WHERE id = $1 FOR UPDATE`, portfolioID), &portfolio)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+
+	query := transactionSelect + `
+		WHERE portfolio_id = $1
+		ORDER BY trade_date ASC, created_at ASC
+	`
+
+	transactions, err := queryTransactions(ctx, tx, query, portfolioID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return transactions, &portfolio, nil
+}
+
+// syncHolding writes the ledger position of a symbol to the stocks table,
+// removing the holding once the position is closed
+func syncHolding(ctx context.Context, tx *sql.Tx, portfolioID, symbol string, book *ledger.Book, interval types.Interval) error {
+	if symbol == "" {
+		return nil
+	}
+
+	position, ok := book.Positions[symbol]
+	if !ok {
+		_, err := tx.ExecContext(ctx, `DELETE FROM stocks WHERE portfolio_id = $1 AND symbol = $2`, portfolioID, symbol)
+		if err != nil {
+			return fmt.Errorf("could not remove closed holding %s: %w", symbol, err)
+		}
+		return nil
+	}
+
+	if interval == "" {
+		interval = types.IntervalMonthly
+	}
+
+	query := `
+		INSERT INTO stocks (portfolio_id, symbol, bar_interval, quantity, average_cost, currency, acquired_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (portfolio_id, symbol) DO UPDATE
+		SET quantity = EXCLUDED.quantity, average_cost = EXCLUDED.average_cost, currency = EXCLUDED.currency,
+			acquired_at = EXCLUDED.acquired_at
+	`
+
+	_, err := tx.ExecContext(ctx, query,
+		portfolioID,
+		symbol,
+		interval,
+		position.Quantity,
+		position.AverageCost,
+		position.Currency,
+		position.AcquiredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("could not update holding %s: %w", symbol, err)
+	}
+
+	return nil
+}
+
+// queryer is satisfied by both *DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// queryTransactions runs a transactionSelect query and scans every row
+func queryTransactions(ctx context.Context, q queryer, query string, args ...any) ([]types.Transaction, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []types.Transaction
+	for rows.Next() {
+		var transaction types.Transaction
+		if err := scanTransaction(rows, &transaction); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("transaction iteration error: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// transactionSelect selects ledger entries, callers append the WHERE clause
+const transactionSelect = `
+	SELECT id, portfolio_id, type, symbol, quantity, price, amount, fees, currency, split_ratio, lot_id, external_id, trade_date, notes, created_at, updated_at
+	FROM transactions
+`
+
+// scanTransaction scans a row produced by transactionSelect into transaction
+func scanTransaction(row rowScanner, transaction *types.Transaction) error {
+	return row.Scan(
+		&transaction.ID,
+		&transaction.PortfolioID,
+		&transaction.Type,
+		&transaction.Symbol,
+		&transaction.Quantity,
+		&transaction.Price,
+		&transaction.Amount,
+		&transaction.Fees,
+		&transaction.Currency,
+		&transaction.SplitRatio,
+		&transaction.LotID,
+		&transaction.ExternalID,
+		&transaction.TradeDate,
+		&transaction.Notes,
+		&transaction.CreatedAt,
+		&transaction.UpdatedAt,
+	)
+}
@@ -0,0 +1,337 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ecetinerdem/forseer/ledger"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type WatchlistRepo interface {
+	// Watchlist operations - all user-scoped
+	GetUserWatchlists(ctx context.Context, userID string) ([]types.Watchlist, error)
+	GetUserWatchlist(ctx context.Context, userID, watchlistID string) (*types.Watchlist, error)
+	CreateUserWatchlist(ctx context.Context, userID, name string) (*types.Watchlist, error)
+	RenameUserWatchlist(ctx context.Context, userID, watchlistID, name string) (*types.Watchlist, error)
+	DeleteUserWatchlist(ctx context.Context, userID, watchlistID string) error
+
+	// Entry operations - callers verify ownership with UserOwnsWatchlist
+	GetWatchlistEntry(ctx context.Context, watchlistID, entryID string) (*types.WatchlistEntry, error)
+	AddWatchlistEntry(ctx context.Context, watchlistID string, entry *types.WatchlistEntry) (*types.WatchlistEntry, error)
+	DeleteWatchlistEntry(ctx context.Context, watchlistID, entryID string) error
+	PromoteWatchlistEntry(ctx context.Context, watchlistID, entryID, portfolioID string, stock *types.Stock) (*types.Stock, error)
+
+	// Ownership validation
+	UserOwnsWatchlist(ctx context.Context, userID, watchlistID string) (bool, error)
+}
+
+// GetUserWatchlists returns every watchlist of the user without entries
+func (db *DB) GetUserWatchlists(ctx context.Context, userID string) ([]types.Watchlist, error) {
+	query := watchlistSelect + `
+		WHERE user_id = $1
+		ORDER BY name ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlists: %w", err)
+	}
+	defer rows.Close()
+
+	var watchlists []types.Watchlist
+	for rows.Next() {
+		var watchlist types.Watchlist
+		if err := scanWatchlist(rows, &watchlist); err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist: %w", err)
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("watchlist iteration error: %w", err)
+	}
+
+	return watchlists, nil
+}
+
+// GetUserWatchlist retrieves a watchlist with its entries if it belongs to the user
+func (db *DB) GetUserWatchlist(ctx context.Context, userID, watchlistID string) (*types.Watchlist, error) {
+	query := watchlistSelect + `
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var watchlist types.Watchlist
+	err := scanWatchlist(db.QueryRowContext(ctx, query, watchlistID, userID), &watchlist)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.WatchlistNotFoundError{UserID: userID, WatchlistID: watchlistID}
+		}
+		return nil, fmt.Errorf("failed to get watchlist: %w", err)
+	}
+
+	entriesQuery := watchlistEntrySelect + `
+		WHERE e.watchlist_id = $1
+		ORDER BY e.symbol ASC
+	`
+
+	rows, err := db.QueryContext(ctx, entriesQuery, watchlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry types.WatchlistEntry
+		if err := scanWatchlistEntry(rows, &entry); err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist entry: %w", err)
+		}
+		watchlist.Entries = append(watchlist.Entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("watchlist entry iteration error: %w", err)
+	}
+
+	return &watchlist, nil
+}
+
+// CreateUserWatchlist creates a new watchlist for the user
+func (db *DB) CreateUserWatchlist(ctx context.Context, userID, name string) (*types.Watchlist, error) {
+	query := `
+		INSERT INTO watchlists (user_id, name, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW())
+		RETURNING id, user_id, name, created_at, updated_at
+	`
+
+	var watchlist types.Watchlist
+	err := scanWatchlist(db.QueryRowContext(ctx, query, userID, name), &watchlist)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, &types.WatchlistNameTakenError{Name: name}
+		}
+		return nil, fmt.Errorf("failed to create watchlist: %w", err)
+	}
+
+	return &watchlist, nil
+}
+
+// RenameUserWatchlist changes the name of a watchlist if it belongs to the user
+func (db *DB) RenameUserWatchlist(ctx context.Context, userID, watchlistID, name string) (*types.Watchlist, error) {
+	query := `
+		UPDATE watchlists
+		SET name = $1
+		WHERE id = $2 AND user_id = $3
+		RETURNING id, user_id, name, created_at, updated_at
+	`
+
+	var watchlist types.Watchlist
+	err := scanWatchlist(db.QueryRowContext(ctx, query, name, watchlistID, userID), &watchlist)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.WatchlistNotFoundError{UserID: userID, WatchlistID: watchlistID}
+		}
+		if isUniqueViolation(err) {
+			return nil, &types.WatchlistNameTakenError{Name: name}
+		}
+		return nil, fmt.Errorf("failed to rename watchlist: %w", err)
+	}
+
+	return &watchlist, nil
+}
+
+// DeleteUserWatchlist deletes a watchlist and its entries if it belongs to the user
+func (db *DB) DeleteUserWatchlist(ctx context.Context, userID, watchlistID string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM watchlists WHERE id = $1 AND user_id = $2`, watchlistID, userID)
+	if err != nil {
+		return fmt.Errorf("could not delete watchlist: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &types.WatchlistNotFoundError{UserID: userID, WatchlistID: watchlistID}
+	}
+
+	return nil
+}
+
+// GetWatchlistEntry retrieves an entry of a watchlist with the latest bar of its series
+func (db *DB) GetWatchlistEntry(ctx context.Context, watchlistID, entryID string) (*types.WatchlistEntry, error) {
+	query := watchlistEntrySelect + `
+		WHERE e.id = $1 AND e.watchlist_id = $2
+	`
+
+	var entry types.WatchlistEntry
+	err := scanWatchlistEntry(db.QueryRowContext(ctx, query, entryID, watchlistID), &entry)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.WatchlistEntryNotFoundError{WatchlistID: watchlistID, EntryID: entryID}
+		}
+		return nil, fmt.Errorf("failed to get watchlist entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// AddWatchlistEntry adds a symbol to a watchlist, prices are read from the price history
+func (db *DB) AddWatchlistEntry(ctx context.Context, watchlistID string, entry *types.WatchlistEntry) (*types.WatchlistEntry, error) {
+	query := `
+		INSERT INTO watchlist_entries (watchlist_id, symbol, bar_interval, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING id
+	`
+
+	var entryID string
+	err := db.QueryRowContext(ctx, query, watchlistID, entry.Symbol, entry.Interval, entry.Notes).Scan(&entryID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, &types.WatchlistEntryExistsError{Symbol: entry.Symbol}
+		}
+		return nil, fmt.Errorf("could not save the watchlist entry: %w", err)
+	}
+
+	return db.GetWatchlistEntry(ctx, watchlistID, entryID)
+}
+
+// DeleteWatchlistEntry removes an entry from a watchlist
+func (db *DB) DeleteWatchlistEntry(ctx context.Context, watchlistID, entryID string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM watchlist_entries WHERE id = $1 AND watchlist_id = $2`, entryID, watchlistID)
+	if err != nil {
+		return fmt.Errorf("could not delete watchlist entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &types.WatchlistEntryNotFoundError{WatchlistID: watchlistID, EntryID: entryID}
+	}
+
+	return nil
+}
+
+// PromoteWatchlistEntry opens a holding of the stock in the portfolio and removes the entry from the
+// watchlist in one database transaction, so neither happens without the other
+func (db *DB) PromoteWatchlistEntry(ctx context.Context, watchlistID, entryID, portfolioID string, stock *types.Stock) (*types.Stock, error) {
+	buy := openingBuy(stock)
+	if err := ledger.Validate(buy); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recordTransaction(ctx, tx, portfolioID, buy, stock.Interval); err != nil {
+		return nil, fmt.Errorf("could not record the buy: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM watchlist_entries WHERE id = $1 AND watchlist_id = $2`, entryID, watchlistID)
+	if err != nil {
+		return nil, fmt.Errorf("could not delete watchlist entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, &types.WatchlistEntryNotFoundError{WatchlistID: watchlistID, EntryID: entryID}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit watchlist promotion: %w", err)
+	}
+
+	return db.GetPortfolioStockBySymbol(ctx, portfolioID, stock.Symbol)
+}
+
+// UserOwnsWatchlist verifies if a user owns a specific watchlist
+func (db *DB) UserOwnsWatchlist(ctx context.Context, userID, watchlistID string) (bool, error) {
+	query := `
+		SELECT 1
+		FROM watchlists
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var exists int
+	err := db.QueryRowContext(ctx, query, watchlistID, userID).Scan(&exists)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check watchlist ownership: %w", err)
+	}
+
+	return true, nil
+}
+
+// watchlistSelect selects watchlists, callers append the WHERE clause
+const watchlistSelect = `
+	SELECT id, user_id, name, created_at, updated_at
+	FROM watchlists
+`
+
+// scanWatchlist scans a row produced by watchlistSelect into watchlist
+func scanWatchlist(row rowScanner, watchlist *types.Watchlist) error {
+	return row.Scan(
+		&watchlist.ID,
+		&watchlist.UserID,
+		&watchlist.Name,
+		&watchlist.CreatedAt,
+		&watchlist.UpdatedAt,
+	)
+}
+
+// watchlistEntrySelect selects watchlist entries together with the latest bar of their price history.
+// Callers append the WHERE clause, rows must be read with scanWatchlistEntry.
+const watchlistEntrySelect = `
+	SELECT e.id, e.watchlist_id, e.symbol, e.bar_interval, e.notes, ph.bar_date,
+		COALESCE(ph.open, 0), COALESCE(ph.high, 0), COALESCE(ph.low, 0), COALESCE(ph.close, 0), COALESCE(ph.volume, 0),
+		e.created_at, e.updated_at
+	FROM watchlist_entries e
+	LEFT JOIN LATERAL (
+		SELECT bar_date, open, high, low, close, volume
+		FROM price_history
+		WHERE symbol = e.symbol AND bar_interval = e.bar_interval
+		ORDER BY bar_date DESC
+		LIMIT 1
+	) ph ON TRUE
+`
+
+// scanWatchlistEntry scans a row produced by watchlistEntrySelect into entry
+func scanWatchlistEntry(row rowScanner, entry *types.WatchlistEntry) error {
+	var barDate sql.NullTime
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.WatchlistID,
+		&entry.Symbol,
+		&entry.Interval,
+		&entry.Notes,
+		&barDate,
+		&entry.Open,
+		&entry.High,
+		&entry.Low,
+		&entry.Close,
+		&entry.Volume,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	entry.Date = barDate.Time
+	return nil
+}
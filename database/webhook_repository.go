@@ -0,0 +1,464 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type WebhookRepo interface {
+	// Webhook operations - all user-scoped, secrets are only returned on creation
+	GetUserWebhooks(ctx context.Context, userID string) ([]types.Webhook, error)
+	GetUserWebhook(ctx context.Context, userID, webhookID string) (*types.Webhook, error)
+	CreateUserWebhook(ctx context.Context, userID string, webhook *types.Webhook) (*types.Webhook, error)
+	UpdateUserWebhook(ctx context.Context, userID string, webhook *types.Webhook) (*types.Webhook, error)
+	DeleteUserWebhook(ctx context.Context, userID, webhookID string) error
+
+	// Delivery log - callers verify ownership with GetUserWebhook
+	GetWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]types.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*types.WebhookDelivery, error)
+
+	// Dispatch - used by the webhooks dispatcher across all users
+	GetEventWebhooks(ctx context.Context, userID string, event types.WebhookEvent) ([]types.Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, delivery *types.WebhookDelivery) (*types.WebhookDelivery, error)
+	ClaimWebhookDelivery(ctx context.Context, deliveryID string) (*types.WebhookDelivery, *types.Webhook, error)
+	RecordWebhookAttempt(ctx context.Context, delivery *types.WebhookDelivery) error
+	GetDueWebhookDeliveries(ctx context.Context, limit int) ([]string, error)
+	ResetInFlightWebhookDeliveries(ctx context.Context) error
+}
+
+// GetUserWebhooks returns every webhook of the user
+func (db *DB) GetUserWebhooks(ctx context.Context, userID string) ([]types.Webhook, error) {
+	query := webhookSelect + `
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	webhooks, err := db.queryWebhooks(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// GetUserWebhook retrieves a webhook if it belongs to the user
+func (db *DB) GetUserWebhook(ctx context.Context, userID, webhookID string) (*types.Webhook, error) {
+	query := webhookSelect + `
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var webhook types.Webhook
+	err := scanWebhook(db.QueryRowContext(ctx, query, webhookID, userID), &webhook)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.WebhookNotFoundError{UserID: userID, WebhookID: webhookID}
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	webhook.Secret = ""
+	return &webhook, nil
+}
+
+// CreateUserWebhook registers a webhook for the user, the returned webhook carries its secret
+func (db *DB) CreateUserWebhook(ctx context.Context, userID string, webhook *types.Webhook) (*types.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (user_id, url, events, secret, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING ` + webhookColumns
+
+	var created types.Webhook
+	err := scanWebhook(db.QueryRowContext(ctx, query,
+		userID,
+		webhook.URL,
+		joinWebhookEvents(webhook.Events),
+		webhook.Secret,
+		webhook.Enabled,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("could not save the webhook: %w", err)
+	}
+
+	return &created, nil
+}
+
+// UpdateUserWebhook saves the URL, events and enabled flag of a webhook
+func (db *DB) UpdateUserWebhook(ctx context.Context, userID string, webhook *types.Webhook) (*types.Webhook, error) {
+	query := `
+		UPDATE webhooks
+		SET url = $1, events = $2, enabled = $3
+		WHERE id = $4 AND user_id = $5
+		RETURNING ` + webhookColumns
+
+	var updated types.Webhook
+	err := scanWebhook(db.QueryRowContext(ctx, query,
+		webhook.URL,
+		joinWebhookEvents(webhook.Events),
+		webhook.Enabled,
+		webhook.ID,
+		userID,
+	), &updated)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.WebhookNotFoundError{UserID: userID, WebhookID: webhook.ID}
+		}
+		return nil, fmt.Errorf("could not update the webhook: %w", err)
+	}
+
+	updated.Secret = ""
+	return &updated, nil
+}
+
+// DeleteUserWebhook deletes a webhook and its delivery log if it belongs to the user
+func (db *DB) DeleteUserWebhook(ctx context.Context, userID, webhookID string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, webhookID, userID)
+	if err != nil {
+		return fmt.Errorf("could not delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &types.WebhookNotFoundError{UserID: userID, WebhookID: webhookID}
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the most recent deliveries of a webhook, newest first
+func (db *DB) GetWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]types.WebhookDelivery, error) {
+	query := webhookDeliverySelect + `
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := db.QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	for rows.Next() {
+		var delivery types.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("webhook delivery iteration error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// GetWebhookDelivery retrieves a delivery of a webhook
+func (db *DB) GetWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*types.WebhookDelivery, error) {
+	query := webhookDeliverySelect + `
+		WHERE id = $1 AND webhook_id = $2
+	`
+
+	var delivery types.WebhookDelivery
+	err := scanWebhookDelivery(db.QueryRowContext(ctx, query, deliveryID, webhookID), &delivery)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &types.WebhookDeliveryNotFoundError{WebhookID: webhookID, DeliveryID: deliveryID}
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+// GetEventWebhooks returns the enabled webhooks of the user subscribed to event, with their secrets
+func (db *DB) GetEventWebhooks(ctx context.Context, userID string, event types.WebhookEvent) ([]types.Webhook, error) {
+	query := webhookSelect + `
+		WHERE user_id = $1 AND enabled
+		ORDER BY created_at ASC
+	`
+
+	webhooks, err := db.queryWebhooks(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed := webhooks[:0]
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	return subscribed, nil
+}
+
+// CreateWebhookDelivery queues a delivery for its first attempt
+func (db *DB) CreateWebhookDelivery(ctx context.Context, delivery *types.WebhookDelivery) (*types.WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, replay_of, created_at)
+		VALUES ($1, $2, $3, 'pending', NOW(), $4, NOW())
+		RETURNING ` + webhookDeliveryColumns
+
+	var created types.WebhookDelivery
+	err := scanWebhookDelivery(db.QueryRowContext(ctx, query,
+		delivery.WebhookID,
+		delivery.Event,
+		[]byte(delivery.Payload),
+		nullableString(delivery.ReplayOf),
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("could not save the webhook delivery: %w", err)
+	}
+
+	return &created, nil
+}
+
+// ClaimWebhookDelivery marks a due pending delivery as delivering and returns it with its webhook.
+// It returns nil when the delivery is not due, already claimed or its webhook is disabled,
+// so a delivery queued twice is only sent once.
+func (db *DB) ClaimWebhookDelivery(ctx context.Context, deliveryID string) (*types.WebhookDelivery, *types.Webhook, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET status = 'delivering'
+		FROM webhooks w
+		WHERE d.id = $1 AND d.status = 'pending' AND d.next_attempt_at <= NOW()
+		AND w.id = d.webhook_id AND w.enabled
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_code, d.last_error,
+			d.next_attempt_at, COALESCE(d.replay_of::text, ''), d.created_at, d.delivered_at,
+			w.id, w.user_id, w.url, w.events, w.secret, w.enabled, w.created_at, w.updated_at
+	`
+
+	var delivery types.WebhookDelivery
+	var webhook types.Webhook
+	var payload []byte
+	var events string
+	var nextAttemptAt, deliveredAt sql.NullTime
+
+	err := db.QueryRowContext(ctx, query, deliveryID).Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseCode,
+		&delivery.LastError,
+		&nextAttemptAt,
+		&delivery.ReplayOf,
+		&delivery.CreatedAt,
+		&deliveredAt,
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&events,
+		&webhook.Secret,
+		&webhook.Enabled,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+
+	delivery.Payload = payload
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	webhook.Events = splitWebhookEvents(events)
+
+	return &delivery, &webhook, nil
+}
+
+// RecordWebhookAttempt saves the outcome of an attempt held in delivery
+func (db *DB) RecordWebhookAttempt(ctx context.Context, delivery *types.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, response_code = $3, last_error = $4, next_attempt_at = $5, delivered_at = $6
+		WHERE id = $7
+	`
+
+	_, err := db.ExecContext(ctx, query,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseCode,
+		delivery.LastError,
+		delivery.NextAttemptAt,
+		delivery.DeliveredAt,
+		delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("could not record webhook attempt: %w", err)
+	}
+
+	return nil
+}
+
+// GetDueWebhookDeliveries returns the IDs of pending deliveries whose next attempt is due, oldest first
+func (db *DB) GetDueWebhookDeliveries(ctx context.Context, limit int) ([]string, error) {
+	query := `
+		SELECT id
+		FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at ASC
+		LIMIT $1
+	`
+
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ResetInFlightWebhookDeliveries returns deliveries interrupted by a shutdown to pending
+func (db *DB) ResetInFlightWebhookDeliveries(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = 'pending', next_attempt_at = NOW() WHERE status = 'delivering'`)
+	if err != nil {
+		return fmt.Errorf("could not reset in flight webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) queryWebhooks(ctx context.Context, query string, args ...any) ([]types.Webhook, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []types.Webhook{}
+	for rows.Next() {
+		var webhook types.Webhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("webhook iteration error: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+const webhookColumns = `id, user_id, url, events, secret, enabled, created_at, updated_at`
+
+// webhookSelect selects webhooks, callers append the WHERE clause
+const webhookSelect = `
+	SELECT ` + webhookColumns + `
+	FROM webhooks
+`
+
+// scanWebhook scans a row of webhookColumns into webhook
+func scanWebhook(row rowScanner, webhook *types.Webhook) error {
+	var events string
+
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&events,
+		&webhook.Secret,
+		&webhook.Enabled,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	webhook.Events = splitWebhookEvents(events)
+	return nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, response_code, last_error,
+	next_attempt_at, COALESCE(replay_of::text, ''), created_at, delivered_at`
+
+// webhookDeliverySelect selects webhook deliveries, callers append the WHERE clause
+const webhookDeliverySelect = `
+	SELECT ` + webhookDeliveryColumns + `
+	FROM webhook_deliveries
+`
+
+// scanWebhookDelivery scans a row of webhookDeliveryColumns into delivery
+func scanWebhookDelivery(row rowScanner, delivery *types.WebhookDelivery) error {
+	var payload []byte
+	var nextAttemptAt, deliveredAt sql.NullTime
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseCode,
+		&delivery.LastError,
+		&nextAttemptAt,
+		&delivery.ReplayOf,
+		&delivery.CreatedAt,
+		&deliveredAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	delivery.Payload = payload
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return nil
+}
+
+// joinWebhookEvents stores events as a comma separated list
+func joinWebhookEvents(events []types.WebhookEvent) string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	return strings.Join(names, ",")
+}
+
+func splitWebhookEvents(events string) []types.WebhookEvent {
+	list := []types.WebhookEvent{}
+	for _, name := range strings.Split(events, ",") {
+		if name != "" {
+			list = append(list, types.WebhookEvent(name))
+		}
+	}
+	return list
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Column names are shared by every format so a file can be swapped for another without remapping
+var (
+	HoldingColumns = []string{
+		"portfolio_id", "portfolio_name", "symbol", "interval", "quantity", "average_cost", "cost_basis",
+		"close", "market_value", "unrealized_gain", "unrealized_gain_pct", "weight", "acquired_at", "price_date",
+		"currency", "base_currency", "fx_rate",
+	}
+	TransactionColumns = []string{
+		"id", "portfolio_id", "portfolio_name", "trade_date", "type", "symbol", "quantity", "price",
+		"amount", "fees", "split_ratio", "lot_id", "external_id", "notes", "currency",
+	}
+	PriceColumns = []string{
+		"symbol", "interval", "date", "open", "high", "low", "close", "adjusted_close", "volume",
+	}
+	StockAnalysisColumns = []string{
+		"id", "portfolio_id", "portfolio_name", "stock_id", "symbol", "generated_at", "analysis",
+	}
+	PortfolioAnalysisColumns = []string{
+		"id", "portfolio_id", "portfolio_name", "generated_at", "stock_count", "analysis",
+	}
+)
+
+// HoldingRow is a valued holding in HoldingColumns order. The average cost and close are in the holding's
+// currency, the cost basis, market value and gain in the portfolio's base currency.
+func HoldingRow(portfolio *types.Portfolio, stock *types.Stock) []any {
+	return []any{
+		portfolio.ID, portfolio.Name, stock.Symbol, string(stock.Interval), stock.Quantity, stock.AverageCost,
+		stock.CostBasis, stock.Close, stock.MarketValue, stock.UnrealizedGain, stock.UnrealizedGainPct,
+		stock.Weight, dateOrNil(stock.AcquiredAt), barTime(stock.Interval, stock.Date),
+		stock.Currency, portfolio.BaseCurrency, stock.FXRate,
+	}
+}
+
+// TransactionRow is a ledger entry in TransactionColumns order
+func TransactionRow(portfolio *types.Portfolio, tx *types.Transaction) []any {
+	return []any{
+		tx.ID, portfolio.ID, portfolio.Name, dateOrNil(tx.TradeDate), string(tx.Type), tx.Symbol, tx.Quantity,
+		tx.Price, tx.Amount, tx.Fees, tx.SplitRatio, tx.LotID, tx.ExternalID, tx.Notes, tx.Currency,
+	}
+}
+
+// PriceRow is a price bar in PriceColumns order
+func PriceRow(bar *types.PriceBar) []any {
+	return []any{
+		bar.Symbol, string(bar.Interval), barTime(bar.Interval, bar.Date), bar.Open, bar.High, bar.Low,
+		bar.Close, bar.AdjustedClose, bar.Volume,
+	}
+}
+
+// StockAnalysisRow is a saved stock analysis in StockAnalysisColumns order
+func StockAnalysisRow(portfolio *types.Portfolio, analysis *types.StockAnalysis) []any {
+	return []any{
+		analysis.ID, portfolio.ID, portfolio.Name, analysis.StockID, analysis.Symbol, analysis.GeneratedAt, analysis.Analysis,
+	}
+}
+
+// PortfolioAnalysisRow is a saved portfolio analysis in PortfolioAnalysisColumns order
+func PortfolioAnalysisRow(portfolio *types.Portfolio, analysis *types.PortfolioAnalysis) []any {
+	return []any{
+		analysis.ID, portfolio.ID, portfolio.Name, analysis.GeneratedAt, analysis.StockCount, analysis.Analysis,
+	}
+}
+
+// barTime keeps the time of intraday bars and drops it from daily and longer ones
+func barTime(interval types.Interval, t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	if interval.IsIntraday() {
+		return t
+	}
+	return Date(t)
+}
+
+func dateOrNil(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return Date(t)
+}
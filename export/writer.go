@@ -0,0 +1,158 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl" // One JSON object per line, keyed by column name
+	FormatXLSX  Format = "xlsx"
+)
+
+func (f Format) IsValid() bool {
+	return f == FormatCSV || f == FormatJSONL || f == FormatXLSX
+}
+
+// ContentType is the MIME type of files in the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer streams the rows of one table. Every row has a value per column, values are strings, numbers,
+// booleans, time.Time (written as RFC 3339) or Date.
+type Writer interface {
+	WriteRow(values []any) error
+	Close() error // Flushes the output, it does not close the underlying writer
+}
+
+// Date is a calendar date, written as YYYY-MM-DD in every format
+type Date time.Time
+
+// NewWriter starts a table with the given columns in the format. The sheet name is only used by XLSX.
+func NewWriter(format Format, w io.Writer, sheet string, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w), columns: columns}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, sheet, columns)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer, record: make([]string, len(columns))}, nil
+}
+
+func (c *csvWriter) WriteRow(values []any) error {
+	for i, value := range values {
+		c.record[i] = formatText(value)
+	}
+	return c.writer.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func (j *jsonlWriter) WriteRow(values []any) error {
+	// Keep the column order of the other formats
+	object := make(orderedObject, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case Date:
+			value = time.Time(v).Format(time.DateOnly)
+		case time.Time:
+			value = v.Format(time.RFC3339)
+		}
+		object[i] = field{name: j.columns[i], value: value}
+	}
+	return j.encoder.Encode(object)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+type field struct {
+	name  string
+	value any
+}
+
+// orderedObject encodes as a JSON object with its fields in order
+type orderedObject []field
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, f := range o {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, name...)
+		buf = append(buf, ':')
+		buf = append(buf, value...)
+	}
+	return append(buf, '}'), nil
+}
+
+// formatText renders a value the same way in CSV and XLSX text cells
+func formatText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case Date:
+		return time.Time(v).Format(time.DateOnly)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,187 @@
+package fx
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/lots"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Table is the daily rate history of a currency pair
+type Table struct {
+	rates []types.FXRate
+}
+
+func NewTable(rates []types.FXRate) *Table {
+	sorted := append([]types.FXRate(nil), rates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+	return &Table{rates: sorted}
+}
+
+// Rate returns the rate of the date, or of the closest earlier date on weekends and holidays.
+// Dates before the history use its first rate. It reports false when the table is empty.
+func (t *Table) Rate(date time.Time) (float64, bool) {
+	if len(t.rates) == 0 {
+		return 0, false
+	}
+
+	i := sort.Search(len(t.rates), func(i int) bool {
+		return t.rates[i].Date.After(date)
+	})
+	if i == 0 {
+		return t.rates[0].Rate, true
+	}
+	return t.rates[i-1].Rate, true
+}
+
+// Converter converts amounts to a base currency at the rate of a given date
+type Converter struct {
+	Base   string
+	tables map[string]*Table
+}
+
+// NewConverter returns a converter without rates, it only converts the base currency and its subunits
+func NewConverter(base string) *Converter {
+	return &Converter{Base: base, tables: make(map[string]*Table)}
+}
+
+// quoteCurrency maps a currency to the one its rates are quoted in, with the factor from one to the other.
+// Pence are hundredths of a pound, an empty currency is the default one.
+func quoteCurrency(currency string) (string, float64) {
+	switch currency {
+	case "":
+		return types.DefaultCurrency, 1
+	case types.PenceCurrency:
+		return "GBP", 0.01
+	}
+	return currency, 1
+}
+
+// Rate returns the rate converting an amount in currency to the base currency at the date.
+// A *types.MissingFXRateError is returned when the converter has no rates for the currency.
+func (c *Converter) Rate(currency string, date time.Time) (float64, error) {
+	quote, factor := quoteCurrency(currency)
+	if quote == c.Base {
+		return factor, nil
+	}
+
+	table, ok := c.tables[quote]
+	if !ok {
+		return 0, &types.MissingFXRateError{From: quote, To: c.Base, Date: date}
+	}
+
+	rate, ok := table.Rate(date)
+	if !ok {
+		return 0, &types.MissingFXRateError{From: quote, To: c.Base, Date: date}
+	}
+
+	return rate * factor, nil
+}
+
+// Convert converts an amount in currency to the base currency at the rate of the date
+func (c *Converter) Convert(amount float64, currency string, date time.Time) (float64, error) {
+	rate, err := c.Rate(currency, date)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// Transactions returns copies of the transactions with their price, amount and fees in the base currency,
+// each converted at the rate of its trade date
+func (c *Converter) Transactions(transactions []types.Transaction) ([]types.Transaction, error) {
+	converted := make([]types.Transaction, len(transactions))
+	for i, tx := range transactions {
+		rate, err := c.Rate(tx.Currency, tx.TradeDate)
+		if err != nil {
+			return nil, err
+		}
+
+		tx.Price *= rate
+		tx.Amount *= rate
+		tx.Fees *= rate
+		tx.Currency = c.Base
+		converted[i] = tx
+	}
+	return converted, nil
+}
+
+// Bars returns copies of bars quoted in currency with their prices in the base currency,
+// each converted at the rate of its date
+func (c *Converter) Bars(bars []types.PriceBar, currency string) ([]types.PriceBar, error) {
+	converted := make([]types.PriceBar, len(bars))
+	for i, bar := range bars {
+		rate, err := c.Rate(currency, bar.Date)
+		if err != nil {
+			return nil, err
+		}
+
+		bar.Open *= rate
+		bar.High *= rate
+		bar.Low *= rate
+		bar.Close *= rate
+		bar.AdjustedClose *= rate
+		converted[i] = bar
+	}
+	return converted, nil
+}
+
+// CostBases returns the cost basis of the open lots of a ledger in the base currency per symbol, every lot
+// converted at the rate of its trade date like the gains of the lots are
+func (c *Converter) CostBases(transactions []types.Transaction, method types.CostBasisMethod) (map[string]float64, error) {
+	converted, err := c.Transactions(transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	matched, err := lots.Match(converted, method)
+	if err != nil {
+		return nil, err
+	}
+
+	costBases := make(map[string]float64)
+	for _, lot := range matched.Open {
+		costBases[lot.Symbol] += lot.CostBasis
+	}
+	return costBases, nil
+}
+
+// Holding sets the rates converting a holding to the base currency. Prices convert at the rate of the latest
+// bar's date. The cost converts lot by lot with the base currency cost bases of CostBases, holdings without
+// one convert at the rate of their acquisition date.
+func (c *Converter) Holding(stock *types.Stock, costBases map[string]float64) error {
+	priceDate := stock.Date
+	if priceDate.IsZero() {
+		priceDate = time.Now().UTC()
+	}
+
+	var err error
+	if stock.FXRate, err = c.Rate(stock.Currency, priceDate); err != nil {
+		return err
+	}
+
+	cost := stock.Quantity * stock.AverageCost
+	if base, ok := costBases[stock.Symbol]; ok && cost > 0 {
+		stock.CostFXRate = base / cost
+		return nil
+	}
+
+	stock.CostFXRate, err = c.Rate(stock.Currency, stock.AcquiredAt)
+	return err
+}
+
+// TransactionCurrencies returns the distinct currencies of the transactions
+func TransactionCurrencies(transactions []types.Transaction) []string {
+	seen := make(map[string]bool)
+	var currencies []string
+	for _, tx := range transactions {
+		if !seen[tx.Currency] {
+			seen[tx.Currency] = true
+			currencies = append(currencies, tx.Currency)
+		}
+	}
+	return currencies
+}
@@ -0,0 +1,111 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ecetinerdem/forseer/marketdata"
+	"github.com/ecetinerdem/forseer/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultTTL is how long the fetched rates of a currency pair stay fresh
+const DefaultTTL = 6 * time.Hour
+
+// Store is the Postgres-backed rate history. Stored rates are never overwritten,
+// so a valuation of a past date gives the same result every time it is run.
+type Store interface {
+	// GetFXRates returns the stored rates of a pair ordered by date and when it was last fetched
+	GetFXRates(ctx context.Context, from, to string) ([]types.FXRate, time.Time, error)
+	SaveFXSeries(ctx context.Context, series *types.FXSeries, fetchedAt time.Time) error
+}
+
+// Service serves exchange rates from the store, fetching a pair from the provider once it is stale.
+// Concurrent loads of the same pair are coalesced into a single upstream call.
+type Service struct {
+	provider marketdata.FXProvider
+	store    Store
+	ttl      time.Duration
+	group    singleflight.Group
+}
+
+func NewService(provider marketdata.FXProvider, store Store, ttl time.Duration) *Service {
+	return &Service{
+		provider: provider,
+		store:    store,
+		ttl:      ttl,
+	}
+}
+
+// Rates returns the rate history of a pair, units of to per unit of from
+func (s *Service) Rates(ctx context.Context, from, to string) (*Table, error) {
+	// The shared call must outlive any single caller, each caller stops waiting on its own context
+	result := s.group.DoChan(from+"/"+to, func() (any, error) {
+		return s.load(context.WithoutCancel(ctx), from, to)
+	})
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*Table), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load reads the pair from the store, refreshing it upstream when it is stale.
+// A failed refresh falls back to the stored rates, an outage only delays the latest ones.
+func (s *Service) load(ctx context.Context, from, to string) (*Table, error) {
+	rates, fetchedAt, err := s.store.GetFXRates(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(rates) > 0 && time.Since(fetchedAt) < s.ttl {
+		return NewTable(rates), nil
+	}
+
+	series, err := s.provider.GetFXSeries(ctx, from, to)
+	if err != nil {
+		if len(rates) > 0 {
+			log.Printf("Using stored %s/%s rates, refresh failed: %v", from, to, err)
+			return NewTable(rates), nil
+		}
+		return nil, fmt.Errorf("failed to fetch %s/%s rates: %w", from, to, err)
+	}
+
+	if err := s.store.SaveFXSeries(ctx, series, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to store %s/%s rates: %w", from, to, err)
+	}
+
+	// Read back what was kept, earlier stored rates win over revised ones
+	rates, _, err = s.store.GetFXRates(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTable(rates), nil
+}
+
+// Converter loads the rates converting each of the currencies to the base currency
+func (s *Service) Converter(ctx context.Context, base string, currencies ...string) (*Converter, error) {
+	converter := NewConverter(base)
+
+	for _, currency := range currencies {
+		quote, _ := quoteCurrency(currency)
+		if quote == base || converter.tables[quote] != nil {
+			continue
+		}
+
+		table, err := s.Rates(ctx, quote, base)
+		if err != nil {
+			return nil, err
+		}
+		converter.tables[quote] = table
+	}
+
+	return converter, nil
+}
@@ -0,0 +1,291 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/ledger"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+const (
+	defaultDateLayout   = "2006-01-02"
+	maxSymbolLength     = 10 // Matches the symbol columns of the schema
+	maxExternalIDLength = 255
+)
+
+// fields lists the columns each kind of import reads. Every field is looked up in a column named like it
+// unless the mapping names another column.
+var fields = map[types.ImportKind][]string{
+	types.ImportTransactions: {"type", "symbol", "quantity", "price", "amount", "fees", "split_ratio", "trade_date", "notes", "external_id", "currency"},
+	types.ImportHoldings:     {"symbol", "quantity", "average_cost", "acquired_at", "notes", "external_id", "currency"},
+}
+
+// aliases are column names commonly exported by brokers, used when a file has no column named like the field
+var aliases = map[string][]string{
+	"type":         {"action", "side"},
+	"symbol":       {"ticker"},
+	"quantity":     {"shares", "qty"},
+	"fees":         {"fee", "commission"},
+	"trade_date":   {"date"},
+	"average_cost": {"cost", "avg_cost", "cost_basis_per_share"},
+	"acquired_at":  {"date", "acquired"},
+	"external_id":  {"id", "transaction_id"},
+	"currency":     {"ccy", "currency_code"},
+}
+
+// Options configure how a file is read
+type Options struct {
+	Kind       types.ImportKind
+	Mapping    map[string]string // Field name to column header
+	DateLayout string            // Go time layout of date columns, defaults to YYYY-MM-DD
+}
+
+// Row is a parsed row ready to be recorded in the ledger
+type Row struct {
+	Line        int
+	Transaction types.Transaction
+}
+
+// Result is the outcome of parsing a file
+type Result struct {
+	Rows   []Row                  // Valid rows in file order
+	Errors []types.ImportRowError // Errors of every invalid row
+	Total  int                    // Data rows read, valid or not
+}
+
+// Parse reads a CSV file with a header line into ledger transactions. Every row is validated and all
+// errors are returned together; rows with errors are left out of the result.
+//
+// Rows without an external_id column get one derived from their content, so importing the same
+// file twice yields the same keys. Identical rows in one file are told apart by their occurrence.
+func Parse(r io.Reader, opts Options) *Result {
+	if !opts.Kind.IsValid() {
+		return &Result{Errors: []types.ImportRowError{{Message: fmt.Sprintf("invalid import kind %q, expected transactions or holdings", opts.Kind)}}}
+	}
+	if opts.DateLayout == "" {
+		opts.DateLayout = defaultDateLayout
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return &Result{Errors: []types.ImportRowError{{Message: "file is empty"}}}
+		}
+		return &Result{Errors: []types.ImportRowError{{Line: 1, Message: err.Error()}}}
+	}
+
+	columns, err := resolveColumns(header, opts)
+	if err != nil {
+		return &Result{Errors: []types.ImportRowError{{Line: 1, Message: err.Error()}}}
+	}
+
+	result := &Result{}
+	occurrences := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		result.Total++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				// Reading failed, the rest of the file cannot be trusted
+				result.Errors = append(result.Errors, types.ImportRowError{Message: err.Error()})
+				break
+			}
+			result.Errors = append(result.Errors, types.ImportRowError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		values := make(map[string]string, len(columns))
+		for field, index := range columns {
+			if index < len(record) {
+				values[field] = strings.TrimSpace(record[index])
+			}
+		}
+
+		transaction, fieldErrors := buildTransaction(values, opts)
+		if len(fieldErrors) > 0 {
+			for _, fieldErr := range fieldErrors {
+				fieldErr.Line = line
+				result.Errors = append(result.Errors, fieldErr)
+			}
+			continue
+		}
+
+		if err := ledger.Validate(transaction); err != nil {
+			result.Errors = append(result.Errors, types.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+
+		if transaction.ExternalID == "" {
+			key := contentKey(opts.Kind, values)
+			occurrences[key]++
+			transaction.ExternalID = fmt.Sprintf("csv:%s:%d", key, occurrences[key])
+		}
+
+		result.Rows = append(result.Rows, Row{Line: line, Transaction: *transaction})
+	}
+
+	return result
+}
+
+// resolveColumns maps every field of the import kind to its column index.
+// Fields missing from the file are left out, required ones are checked per row.
+func resolveColumns(header []string, opts Options) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = normalizeHeader(name)
+		if _, exists := index[name]; !exists {
+			index[name] = i
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, field := range fields[opts.Kind] {
+		known[field] = true
+	}
+	for field := range opts.Mapping {
+		if !known[field] {
+			return nil, fmt.Errorf("mapping names unknown field %q for a %s import", field, opts.Kind)
+		}
+	}
+
+	columns := make(map[string]int)
+	for _, field := range fields[opts.Kind] {
+		if column, ok := opts.Mapping[field]; ok {
+			i, found := index[normalizeHeader(column)]
+			if !found {
+				return nil, fmt.Errorf("column %q mapped to %s is not in the file", column, field)
+			}
+			columns[field] = i
+			continue
+		}
+
+		if i, found := index[field]; found {
+			columns[field] = i
+			continue
+		}
+		for _, alias := range aliases[field] {
+			if i, found := index[alias]; found {
+				columns[field] = i
+				break
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+// buildTransaction converts the values of a row into a transaction
+func buildTransaction(values map[string]string, opts Options) (*types.Transaction, []types.ImportRowError) {
+	var rowErrors []types.ImportRowError
+	fail := func(field, message string) {
+		rowErrors = append(rowErrors, types.ImportRowError{Field: field, Message: message})
+	}
+
+	number := func(field string) float64 {
+		raw := strings.NewReplacer("$", "", "£", "", "€", "", ",", "").Replace(values[field])
+		if raw == "" {
+			return 0
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			fail(field, fmt.Sprintf("invalid number %q", values[field]))
+		}
+		return value
+	}
+
+	date := func(field string, required bool) time.Time {
+		raw := values[field]
+		if raw == "" {
+			if required {
+				fail(field, "is required")
+			}
+			return time.Now().UTC().Truncate(24 * time.Hour)
+		}
+		parsed, err := time.Parse(opts.DateLayout, raw)
+		if err != nil {
+			fail(field, fmt.Sprintf("invalid date %q, expected layout %s", raw, opts.DateLayout))
+		}
+		return parsed
+	}
+
+	transaction := &types.Transaction{
+		Symbol:     strings.ToUpper(values["symbol"]),
+		Quantity:   number("quantity"),
+		Currency:   strings.ToUpper(values["currency"]),
+		Notes:      values["notes"],
+		ExternalID: values["external_id"],
+	}
+
+	if transaction.Currency != "" && !types.ValidCurrency(transaction.Currency) {
+		fail("currency", fmt.Sprintf("invalid currency %q, expected a three letter code such as USD", values["currency"]))
+	}
+
+	if len(transaction.Symbol) > maxSymbolLength {
+		fail("symbol", fmt.Sprintf("cannot be longer than %d characters", maxSymbolLength))
+	}
+	if len(transaction.ExternalID) > maxExternalIDLength {
+		fail("external_id", fmt.Sprintf("cannot be longer than %d characters", maxExternalIDLength))
+	}
+
+	switch opts.Kind {
+	case types.ImportHoldings:
+		transaction.Type = types.TransactionBuy
+		transaction.Price = number("average_cost")
+		transaction.TradeDate = date("acquired_at", false)
+		if values["average_cost"] == "" {
+			fail("average_cost", "is required")
+		}
+
+	case types.ImportTransactions:
+		transaction.Type = types.TransactionType(strings.ToLower(values["type"]))
+		transaction.Price = number("price")
+		transaction.Amount = number("amount")
+		transaction.Fees = number("fees")
+		transaction.SplitRatio = number("split_ratio")
+		transaction.TradeDate = date("trade_date", true)
+		if values["type"] == "" {
+			fail("type", "is required")
+		}
+	}
+
+	return transaction, rowErrors
+}
+
+// contentKey hashes the raw values of a row, so a row without a date keeps its key on later days
+func contentKey(kind types.ImportKind, values map[string]string) string {
+	content := []string{string(kind)}
+	for _, field := range fields[kind] {
+		// Currency came later, rows without one keep the key they had before
+		if field == "currency" && values[field] == "" {
+			continue
+		}
+		content = append(content, strings.ToLower(values[field]))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(content, "|")))
+	return hex.EncodeToString(sum[:12])
+}
+
+// normalizeHeader lowercases a column name, drops a byte order mark and turns spaces and dashes into underscores
+func normalizeHeader(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
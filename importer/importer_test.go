@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+func externalIDs(result *Result) []string {
+	ids := make([]string, len(result.Rows))
+	for i, row := range result.Rows {
+		ids[i] = row.Transaction.ExternalID
+	}
+	return ids
+}
+
+func TestParseExternalIDs(t *testing.T) {
+	const file = "type,symbol,quantity,price,trade_date\n" +
+		"buy,AAPL,10,150,2024-01-02\n" +
+		"buy,AAPL,10,150,2024-01-02\n" +
+		"sell,AAPL,5,160,2024-02-01\n"
+
+	first := Parse(strings.NewReader(file), Options{Kind: types.ImportTransactions})
+	second := Parse(strings.NewReader(file), Options{Kind: types.ImportTransactions})
+	if len(first.Errors) != 0 || len(first.Rows) != 3 {
+		t.Fatalf("expected 3 valid rows, got %d rows and errors %v", len(first.Rows), first.Errors)
+	}
+
+	ids := externalIDs(first)
+	if !reflect.DeepEqual(ids, externalIDs(second)) {
+		t.Errorf("the same file gave different keys: %v and %v", ids, externalIDs(second))
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if !strings.HasPrefix(id, "csv:") {
+			t.Errorf("derived key %q has no csv: prefix", id)
+		}
+		if seen[id] {
+			t.Errorf("identical rows share the key %q", id)
+		}
+		seen[id] = true
+	}
+
+	// The file's own ids win, and a reordered file keeps each row's key
+	reordered := "type,symbol,quantity,price,trade_date\n" +
+		"sell,AAPL,5,160,2024-02-01\n" +
+		"buy,AAPL,10,150,2024-01-02\n" +
+		"buy,AAPL,10,150,2024-01-02\n"
+	got := externalIDs(Parse(strings.NewReader(reordered), Options{Kind: types.ImportTransactions}))
+	if want := []string{ids[2], ids[0], ids[1]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reordered file keys %v, want %v", got, want)
+	}
+
+	withIDs := Parse(strings.NewReader("type,symbol,quantity,price,trade_date,id\nbuy,AAPL,10,150,2024-01-02,T-1\n"), Options{Kind: types.ImportTransactions})
+	if got := externalIDs(withIDs); !reflect.DeepEqual(got, []string{"T-1"}) {
+		t.Errorf("keys %v, want the file's own id", got)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		opts Options
+		want types.Transaction
+	}{
+		{
+			name: "field names",
+			file: "type,symbol,quantity,price,fees,trade_date,currency\nBUY,aapl,10,150.5,1,2024-01-02,usd\n",
+			opts: Options{Kind: types.ImportTransactions},
+			want: types.Transaction{Type: types.TransactionBuy, Symbol: "AAPL", Quantity: 10, Price: 150.5, Fees: 1, Currency: "USD", TradeDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "broker aliases",
+			file: "\ufeffAction,Ticker,Shares,Price,Commission,Date,CCY\nsell,MSFT,\"1,200\",$310.25,4.95,2024-03-04,EUR\n",
+			opts: Options{Kind: types.ImportTransactions},
+			want: types.Transaction{Type: types.TransactionSell, Symbol: "MSFT", Quantity: 1200, Price: 310.25, Fees: 4.95, Currency: "EUR", TradeDate: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "mapping wins over a column named like the field",
+			file: "Kind,Symbol,Units,Cost,When,Quantity\ndividend,KO,,0.48,2024-05-06,99\n",
+			opts: Options{Kind: types.ImportTransactions, Mapping: map[string]string{"type": "Kind", "amount": "Cost", "quantity": "Units", "trade_date": "When"}},
+			want: types.Transaction{Type: types.TransactionDividend, Symbol: "KO", Amount: 0.48, TradeDate: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "holdings",
+			file: "ticker,qty,avg_cost,acquired\nvti,3.5,210,2023-07-01\n",
+			opts: Options{Kind: types.ImportHoldings},
+			want: types.Transaction{Type: types.TransactionBuy, Symbol: "VTI", Quantity: 3.5, Price: 210, TradeDate: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "day first dates",
+			file: "type,symbol,quantity,price,trade_date\nbuy,AAPL,1,100,31/12/2023\n",
+			opts: Options{Kind: types.ImportTransactions, DateLayout: "02/01/2006"},
+			want: types.Transaction{Type: types.TransactionBuy, Symbol: "AAPL", Quantity: 1, Price: 100, TradeDate: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "month first dates",
+			file: "type,symbol,quantity,price,trade_date\nbuy,AAPL,1,100,1/2/2024\n",
+			opts: Options{Kind: types.ImportTransactions, DateLayout: "1/2/2006"},
+			want: types.Transaction{Type: types.TransactionBuy, Symbol: "AAPL", Quantity: 1, Price: 100, TradeDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Parse(strings.NewReader(tt.file), tt.opts)
+			if len(result.Errors) != 0 || len(result.Rows) != 1 {
+				t.Fatalf("expected one valid row, got %d rows and errors %v", len(result.Rows), result.Errors)
+			}
+
+			got := result.Rows[0].Transaction
+			got.ExternalID = ""
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		opts      Options
+		wantRows  int
+		wantTotal int
+		want      []types.ImportRowError
+	}{
+		{
+			name:      "invalid rows keep their line",
+			file:      "type,symbol,quantity,price,trade_date\nbuy,AAPL,10,150,2024-01-02\nbuy,AAPL,ten,150,2024-01-02\nbuy,AAPL,1,1,2024-13-01\nbuy,AAPL,1,1,2024-01-02\n",
+			opts:      Options{Kind: types.ImportTransactions},
+			wantRows:  2,
+			wantTotal: 4,
+			want: []types.ImportRowError{
+				{Line: 3, Field: "quantity", Message: `invalid number "ten"`},
+				{Line: 4, Field: "trade_date", Message: `invalid date "2024-13-01", expected layout 2006-01-02`},
+			},
+		},
+		{
+			name:      "line numbers count quoted newlines",
+			file:      "type,symbol,quantity,price,trade_date,notes\nbuy,AAPL,1,1,2024-01-02,\"two\nlines\"\nsell,AAPL,,1,2024-01-03,\n",
+			opts:      Options{Kind: types.ImportTransactions},
+			wantRows:  1,
+			wantTotal: 2,
+			want:      []types.ImportRowError{{Line: 4, Message: "sell transactions require a quantity greater than zero"}},
+		},
+		{
+			name:      "every field error of a row",
+			file:      "type,symbol,quantity,price,trade_date,currency\n,TOOLONGSYMBOL,1,1,,dollars\n",
+			opts:      Options{Kind: types.ImportTransactions},
+			wantTotal: 1,
+			want: []types.ImportRowError{
+				{Line: 2, Field: "currency", Message: `invalid currency "dollars", expected a three letter code such as USD`},
+				{Line: 2, Field: "symbol", Message: "cannot be longer than 10 characters"},
+				{Line: 2, Field: "trade_date", Message: "is required"},
+				{Line: 2, Field: "type", Message: "is required"},
+			},
+		},
+		{
+			name:      "holding without a cost",
+			file:      "symbol,quantity\nAAPL,1\n",
+			opts:      Options{Kind: types.ImportHoldings},
+			wantTotal: 1,
+			want:      []types.ImportRowError{{Line: 2, Field: "average_cost", Message: "is required"}},
+		},
+		{
+			name:      "date in another layout",
+			file:      "type,symbol,quantity,price,trade_date\nbuy,AAPL,1,1,2024-01-02\n",
+			opts:      Options{Kind: types.ImportTransactions, DateLayout: "02/01/2006"},
+			wantTotal: 1,
+			want:      []types.ImportRowError{{Line: 2, Field: "trade_date", Message: `invalid date "2024-01-02", expected layout 02/01/2006`}},
+		},
+		{
+			name: "mapped column missing",
+			file: "type,symbol\n",
+			opts: Options{Kind: types.ImportTransactions, Mapping: map[string]string{"quantity": "Units"}},
+			want: []types.ImportRowError{{Line: 1, Message: `column "Units" mapped to quantity is not in the file`}},
+		},
+		{
+			name: "mapping an unknown field",
+			file: "symbol,quantity,average_cost\n",
+			opts: Options{Kind: types.ImportHoldings, Mapping: map[string]string{"fees": "Fee"}},
+			want: []types.ImportRowError{{Line: 1, Message: `mapping names unknown field "fees" for a holdings import`}},
+		},
+		{
+			name: "empty file",
+			file: "",
+			opts: Options{Kind: types.ImportTransactions},
+			want: []types.ImportRowError{{Message: "file is empty"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Parse(strings.NewReader(tt.file), tt.opts)
+			if len(result.Rows) != tt.wantRows || result.Total != tt.wantTotal {
+				t.Errorf("got %d rows of %d, want %d of %d", len(result.Rows), result.Total, tt.wantRows, tt.wantTotal)
+			}
+			if !reflect.DeepEqual(result.Errors, tt.want) {
+				t.Errorf("errors %+v, want %+v", result.Errors, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// The functions below return one value per input, NaN while there are too few inputs to compute it
+
+// SMA is the simple moving average over period values
+func SMA(values []float64, period int) []float64 {
+	out := undefined(len(values))
+	if period < 1 || len(values) < period {
+		return out
+	}
+
+	var sum float64
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// EMA is the exponential moving average over period values, seeded with the SMA of the first ones.
+// Leading NaN values are skipped so an EMA can be taken of another indicator.
+func EMA(values []float64, period int) []float64 {
+	out := undefined(len(values))
+	start := 0
+	for start < len(values) && math.IsNaN(values[start]) {
+		start++
+	}
+	if period < 1 || len(values)-start < period {
+		return out
+	}
+
+	alpha := 2 / float64(period+1)
+	seed := start + period - 1
+
+	var sum float64
+	for _, v := range values[start : seed+1] {
+		sum += v
+	}
+	out[seed] = sum / float64(period)
+
+	for i := seed + 1; i < len(values); i++ {
+		out[i] = alpha*values[i] + (1-alpha)*out[i-1]
+	}
+	return out
+}
+
+// RSI is Wilder's relative strength index over period changes, between 0 and 100
+func RSI(values []float64, period int) []float64 {
+	out := undefined(len(values))
+	if period < 1 || len(values) <= period {
+		return out
+	}
+
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		gain += math.Max(change, 0)
+		loss += math.Max(-change, 0)
+	}
+	gain /= float64(period)
+	loss /= float64(period)
+	out[period] = relativeStrength(gain, loss)
+
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		gain = (gain*float64(period-1) + math.Max(change, 0)) / float64(period)
+		loss = (loss*float64(period-1) + math.Max(-change, 0)) / float64(period)
+		out[i] = relativeStrength(gain, loss)
+	}
+	return out
+}
+
+func relativeStrength(gain, loss float64) float64 {
+	if loss == 0 {
+		if gain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+// MACD returns the difference of the fast and slow EMAs, the signal EMA of that difference and the histogram
+func MACD(values []float64, fast, slow, signal int) (macd, signalLine, histogram []float64) {
+	fastEMA, slowEMA := EMA(values, fast), EMA(values, slow)
+
+	macd = undefined(len(values))
+	for i := range values {
+		if !math.IsNaN(fastEMA[i]) && !math.IsNaN(slowEMA[i]) {
+			macd[i] = fastEMA[i] - slowEMA[i]
+		}
+	}
+
+	signalLine = EMA(macd, signal)
+	histogram = undefined(len(values))
+	for i := range values {
+		if !math.IsNaN(signalLine[i]) {
+			histogram[i] = macd[i] - signalLine[i]
+		}
+	}
+	return macd, signalLine, histogram
+}
+
+// Bollinger returns the SMA over period values and the bands k population standard deviations above and below it
+func Bollinger(values []float64, period int, k float64) (middle, upper, lower []float64) {
+	middle = SMA(values, period)
+	upper, lower = undefined(len(values)), undefined(len(values))
+
+	for i := range values {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		var sum float64
+		for _, v := range values[i-period+1 : i+1] {
+			sum += (v - middle[i]) * (v - middle[i])
+		}
+		band := k * math.Sqrt(sum/float64(period))
+		upper[i], lower[i] = middle[i]+band, middle[i]-band
+	}
+	return middle, upper, lower
+}
+
+// ATR is Wilder's average true range over period bars
+func ATR(bars []types.PriceBar, period int) []float64 {
+	out := undefined(len(bars))
+	if period < 1 || len(bars) < period {
+		return out
+	}
+
+	trueRange := make([]float64, len(bars))
+	for i, bar := range bars {
+		trueRange[i] = bar.High - bar.Low
+		if i > 0 {
+			prev := bars[i-1].Close
+			trueRange[i] = math.Max(trueRange[i], math.Max(math.Abs(bar.High-prev), math.Abs(bar.Low-prev)))
+		}
+	}
+
+	var sum float64
+	for _, tr := range trueRange[:period] {
+		sum += tr
+	}
+	out[period-1] = sum / float64(period)
+
+	for i := period; i < len(bars); i++ {
+		out[i] = (out[i-1]*float64(period-1) + trueRange[i]) / float64(period)
+	}
+	return out
+}
+
+// OBV is the on-balance volume, the running total of volume signed by the direction of the close from the first bar
+func OBV(bars []types.PriceBar) []float64 {
+	out := make([]float64, len(bars))
+	for i := 1; i < len(bars); i++ {
+		out[i] = out[i-1]
+		switch {
+		case bars[i].Close > bars[i-1].Close:
+			out[i] += float64(bars[i].Volume)
+		case bars[i].Close < bars[i-1].Close:
+			out[i] -= float64(bars[i].Volume)
+		}
+	}
+	return out
+}
+
+// VWAP is the volume-weighted average of the typical price (high+low+close)/3 from the first bar.
+// Intraday bars restart it on every trading day. It is NaN until some volume has traded.
+func VWAP(bars []types.PriceBar, intraday bool) []float64 {
+	out := undefined(len(bars))
+
+	var priceVolume, volume float64
+	for i, bar := range bars {
+		if intraday && i > 0 && !sameDay(bar, bars[i-1]) {
+			priceVolume, volume = 0, 0
+		}
+
+		priceVolume += (bar.High + bar.Low + bar.Close) / 3 * float64(bar.Volume)
+		volume += float64(bar.Volume)
+		if volume > 0 {
+			out[i] = priceVolume / volume
+		}
+	}
+	return out
+}
+
+func sameDay(a, b types.PriceBar) bool {
+	ay, am, ad := a.Date.Date()
+	by, bm, bd := b.Date.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+func undefined(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
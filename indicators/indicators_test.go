@@ -0,0 +1,115 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+var nan = math.NaN()
+
+func assertSeries(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s has %d values, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("%s[%d] = %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestMovingAverages(t *testing.T) {
+	tests := []struct {
+		name   string
+		series func([]float64, int) []float64
+		values []float64
+		period int
+		want   []float64
+	}{
+		{"SMA", SMA, []float64{1, 2, 3, 4, 5}, 3, []float64{nan, nan, 2, 3, 4}},
+		{"SMA of one value", SMA, []float64{1, 2, 3}, 1, []float64{1, 2, 3}},
+		{"SMA longer than the input", SMA, []float64{1, 2}, 3, []float64{nan, nan}},
+		{"EMA", EMA, []float64{2, 4, 6, 8, 10, 4}, 3, []float64{nan, nan, 4, 6, 8, 6}},
+		{"EMA after leading NaN", EMA, []float64{nan, 2, 4, 6, 8}, 3, []float64{nan, nan, nan, 4, 6}},
+		{"EMA longer than the input", EMA, []float64{nan, 2, 4}, 3, []float64{nan, nan, nan}},
+		{"RSI", RSI, []float64{1, 2, 3, 2, 4, 3}, 2, []float64{nan, nan, 100, 50, 100 - 100.0/6, 50}},
+		{"RSI without changes", RSI, []float64{5, 5, 5}, 2, []float64{nan, nan, 50}},
+		{"RSI without losses", RSI, []float64{1, 2, 3, 4}, 2, []float64{nan, nan, 100, 100}},
+		{"RSI longer than the input", RSI, []float64{1, 2}, 2, []float64{nan, nan}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, tt.name, tt.series(tt.values, tt.period), tt.want)
+		})
+	}
+}
+
+func TestMACD(t *testing.T) {
+	// Fast EMA 3, 5, 7, 9, 17/3 and slow EMA 4, 6, 8, 6 from the third value
+	macd, signal, histogram := MACD([]float64{2, 4, 6, 8, 10, 4}, 2, 3, 2)
+
+	assertSeries(t, "macd", macd, []float64{nan, nan, 1, 1, 1, -1.0 / 3})
+	assertSeries(t, "signal", signal, []float64{nan, nan, nan, 1, 1, 1.0 / 9})
+	assertSeries(t, "histogram", histogram, []float64{nan, nan, nan, 0, 0, -4.0 / 9})
+}
+
+func TestBollinger(t *testing.T) {
+	middle, upper, lower := Bollinger([]float64{2, 4, 4, 8}, 2, 2)
+
+	assertSeries(t, "middle", middle, []float64{nan, 3, 4, 6})
+	assertSeries(t, "upper", upper, []float64{nan, 5, 4, 10})
+	assertSeries(t, "lower", lower, []float64{nan, 1, 4, 2})
+}
+
+func TestBarIndicators(t *testing.T) {
+	bar := func(high, low, close float64, volume int64) types.PriceBar {
+		return types.PriceBar{High: high, Low: low, Close: close, Volume: volume}
+	}
+	// True ranges 2, 3, 1 and 4.5 for the gap up from the previous close
+	bars := []types.PriceBar{
+		bar(10, 8, 9, 100),
+		bar(12, 9, 11, 200),
+		bar(11, 10, 10.5, 300),
+		bar(15, 14, 14.5, 400),
+		bar(15, 14, 14.5, 500),
+	}
+
+	assertSeries(t, "ATR", ATR(bars, 2), []float64{nan, 2.5, 1.75, 3.125, 2.0625})
+	assertSeries(t, "ATR longer than the input", ATR(bars[:1], 2), []float64{nan})
+	assertSeries(t, "OBV", OBV(bars), []float64{0, 200, -100, 300, 300})
+}
+
+func TestVWAP(t *testing.T) {
+	at := func(day, hour int, high, low, close float64, volume int64) types.PriceBar {
+		return types.PriceBar{Date: time.Date(2024, 1, day, hour, 30, 0, 0, time.UTC), High: high, Low: low, Close: close, Volume: volume}
+	}
+	// Typical prices 10, 20, 30 and 40
+	bars := []types.PriceBar{
+		at(2, 9, 12, 6, 12, 100),
+		at(2, 10, 20, 20, 20, 300),
+		at(3, 9, 30, 30, 30, 0),
+		at(3, 10, 40, 40, 40, 100),
+	}
+
+	tests := []struct {
+		name     string
+		bars     []types.PriceBar
+		intraday bool
+		want     []float64
+	}{
+		{"daily", bars, false, []float64{10, 17.5, 17.5, 22}},
+		{"intraday restarts every day", bars, true, []float64{10, 17.5, nan, 40}},
+		{"no volume yet", bars[2:], false, []float64{nan, 40}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, tt.name, VWAP(tt.bars, tt.intraday), tt.want)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package indicators
+
+import (
+	"math"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Options sets the periods of the indicators
+type Options struct {
+	SMAPeriods      []int
+	EMAPeriods      []int
+	RSIPeriod       int
+	MACDFast        int
+	MACDSlow        int
+	MACDSignal      int
+	BollingerPeriod int
+	BollingerStdDev float64
+	ATRPeriod       int
+}
+
+// DefaultOptions are the usual settings: 20, 50 and 200 bar SMAs, 12 and 26 bar EMAs, 14 bar RSI and ATR,
+// a 12/26/9 MACD and 20 bar Bollinger Bands two standard deviations wide
+func DefaultOptions() Options {
+	return Options{
+		SMAPeriods:      []int{20, 50, 200},
+		EMAPeriods:      []int{12, 26},
+		RSIPeriod:       14,
+		MACDFast:        12,
+		MACDSlow:        26,
+		MACDSignal:      9,
+		BollingerPeriod: 20,
+		BollingerStdDev: 2,
+		ATRPeriod:       14,
+	}
+}
+
+// Compute returns the indicators of a price history ordered by date ascending, with points from the from date on.
+// Earlier bars only warm the indicators up, a zero from date keeps every point.
+func Compute(bars []types.PriceBar, from time.Time, opts Options) *types.IndicatorReport {
+	report := &types.IndicatorReport{From: from}
+	if len(bars) > 0 {
+		report.Symbol = bars[0].Symbol
+		report.Interval = bars[0].Interval
+		report.To = bars[len(bars)-1].Date
+	}
+
+	start := 0
+	for start < len(bars) && bars[start].Date.Before(from) {
+		start++
+	}
+	report.Bars = len(bars) - start
+	if report.From.IsZero() && report.Bars > 0 {
+		report.From = bars[start].Date
+	}
+
+	closes := make([]float64, len(bars))
+	for i, bar := range bars {
+		closes[i] = bar.Close
+	}
+
+	for _, period := range opts.SMAPeriods {
+		report.SMA = append(report.SMA, types.IndicatorSeries{Period: period, Points: points(bars, SMA(closes, period), start)})
+	}
+	for _, period := range opts.EMAPeriods {
+		report.EMA = append(report.EMA, types.IndicatorSeries{Period: period, Points: points(bars, EMA(closes, period), start)})
+	}
+
+	report.RSI = types.IndicatorSeries{Period: opts.RSIPeriod, Points: points(bars, RSI(closes, opts.RSIPeriod), start)}
+	report.ATR = types.IndicatorSeries{Period: opts.ATRPeriod, Points: points(bars, ATR(bars, opts.ATRPeriod), start)}
+
+	report.MACD = types.MACDSeries{Fast: opts.MACDFast, Slow: opts.MACDSlow, Signal: opts.MACDSignal, Points: []types.MACDPoint{}}
+	macd, signal, histogram := MACD(closes, opts.MACDFast, opts.MACDSlow, opts.MACDSignal)
+	for i := start; i < len(bars); i++ {
+		if !math.IsNaN(histogram[i]) {
+			report.MACD.Points = append(report.MACD.Points, types.MACDPoint{
+				Date: bars[i].Date, MACD: macd[i], Signal: signal[i], Histogram: histogram[i],
+			})
+		}
+	}
+
+	report.Bollinger = types.BollingerSeries{Period: opts.BollingerPeriod, StdDev: opts.BollingerStdDev, Points: []types.BollingerPoint{}}
+	middle, upper, lower := Bollinger(closes, opts.BollingerPeriod, opts.BollingerStdDev)
+	for i := start; i < len(bars); i++ {
+		if !math.IsNaN(middle[i]) {
+			report.Bollinger.Points = append(report.Bollinger.Points, types.BollingerPoint{
+				Date: bars[i].Date, Middle: middle[i], Upper: upper[i], Lower: lower[i],
+			})
+		}
+	}
+
+	// OBV and VWAP accumulate, they start with the range
+	inRange := bars[start:]
+	report.OBV = points(inRange, OBV(inRange), 0)
+	report.VWAP = points(inRange, VWAP(inRange, report.Interval.IsIntraday()), 0)
+
+	if report.Bars > 0 {
+		report.Latest = latest(report, bars[len(bars)-1])
+	}
+
+	return report
+}
+
+// latest reads the values of the report at the last bar
+func latest(report *types.IndicatorReport, bar types.PriceBar) *types.IndicatorSnapshot {
+	snapshot := &types.IndicatorSnapshot{
+		Date:  bar.Date,
+		Close: bar.Close,
+		SMA:   []types.IndicatorValue{},
+		EMA:   []types.IndicatorValue{},
+	}
+
+	for _, series := range report.SMA {
+		if value := lastValue(series, bar.Date); value != nil {
+			snapshot.SMA = append(snapshot.SMA, *value)
+		}
+	}
+	for _, series := range report.EMA {
+		if value := lastValue(series, bar.Date); value != nil {
+			snapshot.EMA = append(snapshot.EMA, *value)
+		}
+	}
+	snapshot.RSI = lastValue(report.RSI, bar.Date)
+	snapshot.ATR = lastValue(report.ATR, bar.Date)
+
+	if n := len(report.MACD.Points); n > 0 && report.MACD.Points[n-1].Date.Equal(bar.Date) {
+		point := report.MACD.Points[n-1]
+		snapshot.MACD = &point
+	}
+	if n := len(report.Bollinger.Points); n > 0 && report.Bollinger.Points[n-1].Date.Equal(bar.Date) {
+		point := report.Bollinger.Points[n-1]
+		snapshot.Bollinger = &point
+	}
+	if n := len(report.OBV); n > 0 {
+		snapshot.OBV = &report.OBV[n-1].Value
+	}
+	if n := len(report.VWAP); n > 0 && report.VWAP[n-1].Date.Equal(bar.Date) {
+		snapshot.VWAP = &report.VWAP[n-1].Value
+	}
+
+	return snapshot
+}
+
+// lastValue returns the value of a series at the date, nil when it has none there
+func lastValue(series types.IndicatorSeries, date time.Time) *types.IndicatorValue {
+	n := len(series.Points)
+	if n == 0 || !series.Points[n-1].Date.Equal(date) {
+		return nil
+	}
+	return &types.IndicatorValue{Period: series.Period, Value: series.Points[n-1].Value}
+}
+
+// points pairs the defined values from index start on with the dates of their bars
+func points(bars []types.PriceBar, values []float64, start int) []types.IndicatorPoint {
+	out := []types.IndicatorPoint{}
+	for i := start; i < len(bars); i++ {
+		if !math.IsNaN(values[i]) {
+			out = append(out, types.IndicatorPoint{Date: bars[i].Date, Value: values[i]})
+		}
+	}
+	return out
+}
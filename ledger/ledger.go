@@ -0,0 +1,177 @@
+package ledger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/lots"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Position is an open holding derived from the ledger, valued at average cost
+type Position struct {
+	Symbol      string
+	Quantity    float64
+	CostBasis   float64
+	AcquiredAt  time.Time // Acquisition date of the oldest open lot
+	AverageCost float64
+	Currency    string // Currency the symbol is traded in
+}
+
+// Book is the state of a portfolio after replaying its ledger
+type Book struct {
+	Positions map[string]*Position
+	Cash      float64 // Sums entries of every currency, convert the ledger first to get a single currency balance
+}
+
+// Validate checks that a transaction has the fields its type requires
+func Validate(tx *types.Transaction) error {
+	if !tx.Type.IsValid() {
+		return fmt.Errorf("invalid transaction type %q", tx.Type)
+	}
+
+	if tx.LotID != "" && tx.Type != types.TransactionSell {
+		return fmt.Errorf("only sell transactions can name a lot")
+	}
+
+	if tx.Currency != "" && !types.ValidCurrency(tx.Currency) {
+		return fmt.Errorf("invalid currency %q, expected a three letter code such as USD", tx.Currency)
+	}
+
+	if tx.Quantity < 0 || tx.Price < 0 || tx.Amount < 0 || tx.Fees < 0 || tx.SplitRatio < 0 {
+		return fmt.Errorf("transaction amounts cannot be negative")
+	}
+
+	switch tx.Type {
+	case types.TransactionBuy, types.TransactionSell:
+		if tx.Symbol == "" {
+			return fmt.Errorf("%s transactions require a symbol", tx.Type)
+		}
+		if tx.Quantity <= 0 {
+			return fmt.Errorf("%s transactions require a quantity greater than zero", tx.Type)
+		}
+	case types.TransactionDividend:
+		if tx.Symbol == "" {
+			return fmt.Errorf("dividend transactions require a symbol")
+		}
+		if tx.Amount <= 0 {
+			return fmt.Errorf("dividend transactions require an amount greater than zero")
+		}
+	case types.TransactionSplit:
+		if tx.Symbol == "" {
+			return fmt.Errorf("split transactions require a symbol")
+		}
+		if tx.SplitRatio <= 0 {
+			return fmt.Errorf("split transactions require a split ratio greater than zero")
+		}
+	case types.TransactionFee:
+		if tx.Amount <= 0 {
+			return fmt.Errorf("fee transactions require an amount greater than zero")
+		}
+	case types.TransactionDeposit, types.TransactionWithdrawal:
+		if tx.Symbol != "" {
+			return fmt.Errorf("%s transactions cannot have a symbol", tx.Type)
+		}
+		if tx.Amount <= 0 {
+			return fmt.Errorf("%s transactions require an amount greater than zero", tx.Type)
+		}
+	}
+
+	return nil
+}
+
+// ResolveCurrency fills in the currency of a transaction that does not name one. Entries of a symbol use
+// the currency of its earlier trades in the ledger, or its listing's currency when it was never traded.
+// Cash entries use the portfolio's base currency.
+func ResolveCurrency(tx *types.Transaction, ledger []types.Transaction, baseCurrency string) {
+	if tx.Currency != "" {
+		return
+	}
+
+	if tx.Symbol == "" {
+		tx.Currency = baseCurrency
+		return
+	}
+
+	tx.Currency = types.ListingCurrency(tx.Symbol)
+	for _, t := range ledger {
+		if t.Symbol == tx.Symbol && (t.Type == types.TransactionBuy || t.Type == types.TransactionSell) {
+			tx.Currency = t.Currency
+			break
+		}
+	}
+}
+
+// Sort orders transactions by trade date, then by the time they were recorded
+func Sort(transactions []types.Transaction) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		if !transactions[i].TradeDate.Equal(transactions[j].TradeDate) {
+			return transactions[i].TradeDate.Before(transactions[j].TradeDate)
+		}
+		return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+	})
+}
+
+// Replay applies the transactions in trade date order and returns the resulting positions and cash.
+// Sells are matched against lots with the given method, which decides the cost basis of what remains.
+// A sell of more shares than held at its trade date returns an *types.InsufficientHoldingsError and
+// a trade in another currency than the symbol's earlier trades a *types.CurrencyMismatchError.
+func Replay(transactions []types.Transaction, method types.CostBasisMethod) (*Book, error) {
+	ordered := append([]types.Transaction(nil), transactions...)
+	Sort(ordered)
+
+	currencies := make(map[string]string)
+	for _, tx := range ordered {
+		if tx.Type != types.TransactionBuy && tx.Type != types.TransactionSell {
+			continue
+		}
+		currency, ok := currencies[tx.Symbol]
+		if !ok {
+			currencies[tx.Symbol] = tx.Currency
+			continue
+		}
+		if tx.Currency != currency {
+			return nil, &types.CurrencyMismatchError{Symbol: tx.Symbol, Currency: tx.Currency, Expected: currency}
+		}
+	}
+
+	matched, err := lots.Match(ordered, method)
+	if err != nil {
+		return nil, err
+	}
+
+	book := &Book{Positions: make(map[string]*Position)}
+
+	for _, lot := range matched.Open {
+		position, ok := book.Positions[lot.Symbol]
+		if !ok {
+			position = &Position{Symbol: lot.Symbol, AcquiredAt: lot.AcquiredAt, Currency: currencies[lot.Symbol]}
+			book.Positions[lot.Symbol] = position
+		}
+		position.Quantity += lot.Quantity
+		position.CostBasis += lot.CostBasis
+		if lot.AcquiredAt.Before(position.AcquiredAt) {
+			position.AcquiredAt = lot.AcquiredAt
+		}
+	}
+
+	for _, position := range book.Positions {
+		position.AverageCost = position.CostBasis / position.Quantity
+	}
+
+	for _, tx := range ordered {
+		switch tx.Type {
+		case types.TransactionBuy:
+			book.Cash -= tx.Quantity*tx.Price + tx.Fees
+		case types.TransactionSell:
+			book.Cash += tx.Quantity*tx.Price - tx.Fees
+		case types.TransactionDividend, types.TransactionDeposit:
+			book.Cash += tx.Amount
+		case types.TransactionFee, types.TransactionWithdrawal:
+			book.Cash -= tx.Amount
+		}
+	}
+
+	return book, nil
+}
@@ -0,0 +1,192 @@
+package ledger
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func buy(id string, d int, quantity, price, fees float64) types.Transaction {
+	return types.Transaction{ID: id, Type: types.TransactionBuy, Symbol: "AAA", Quantity: quantity, Price: price, Fees: fees, Currency: "USD", TradeDate: day(d)}
+}
+
+func sell(d int, quantity, price float64) types.Transaction {
+	return types.Transaction{Type: types.TransactionSell, Symbol: "AAA", Quantity: quantity, Price: price, Currency: "USD", TradeDate: day(d)}
+}
+
+func TestReplay(t *testing.T) {
+	sellLot := sell(4, 5, 300)
+	sellLot.LotID = "b2"
+
+	tests := []struct {
+		name         string
+		transactions []types.Transaction
+		method       types.CostBasisMethod
+		want         *Position // Position in AAA, nil when none is open
+		wantCash     float64
+	}{
+		{
+			name:         "buy with fees",
+			transactions: []types.Transaction{buy("b1", 1, 10, 100, 5)},
+			want:         &Position{Quantity: 10, CostBasis: 1005, AverageCost: 100.5, AcquiredAt: day(1)},
+			wantCash:     -1005,
+		},
+		{
+			name:         "fifo sells the oldest lot first",
+			transactions: []types.Transaction{buy("b1", 1, 10, 100, 0), buy("b2", 2, 10, 200, 0), sell(3, 15, 300)},
+			method:       types.CostBasisFIFO,
+			want:         &Position{Quantity: 5, CostBasis: 1000, AverageCost: 200, AcquiredAt: day(2)},
+			wantCash:     1500,
+		},
+		{
+			name:         "lifo sells the newest lot first",
+			transactions: []types.Transaction{buy("b1", 1, 10, 100, 0), buy("b2", 2, 10, 200, 0), sell(3, 15, 300)},
+			method:       types.CostBasisLIFO,
+			want:         &Position{Quantity: 5, CostBasis: 500, AverageCost: 100, AcquiredAt: day(1)},
+			wantCash:     1500,
+		},
+		{
+			name:         "average pools the cost",
+			transactions: []types.Transaction{buy("b1", 1, 10, 100, 0), buy("b2", 2, 10, 200, 0), sell(3, 15, 300)},
+			method:       types.CostBasisAverage,
+			want:         &Position{Quantity: 5, CostBasis: 750, AverageCost: 150, AcquiredAt: day(2)},
+			wantCash:     1500,
+		},
+		{
+			name:         "specific lot",
+			transactions: []types.Transaction{buy("b1", 1, 10, 100, 0), buy("b2", 2, 10, 200, 0), sellLot},
+			method:       types.CostBasisSpecific,
+			want:         &Position{Quantity: 15, CostBasis: 2000, AverageCost: 2000.0 / 15, AcquiredAt: day(1)},
+			wantCash:     -1500,
+		},
+		{
+			name:         "replayed in trade date order",
+			transactions: []types.Transaction{sell(3, 10, 300), buy("b2", 2, 10, 200, 0), buy("b1", 1, 10, 100, 0)},
+			method:       types.CostBasisFIFO,
+			want:         &Position{Quantity: 10, CostBasis: 2000, AverageCost: 200, AcquiredAt: day(2)},
+			wantCash:     0,
+		},
+		{
+			name: "split",
+			transactions: []types.Transaction{
+				buy("b1", 1, 10, 100, 0),
+				{Type: types.TransactionSplit, Symbol: "AAA", SplitRatio: 2, TradeDate: day(2)},
+				sell(3, 4, 60),
+			},
+			method:   types.CostBasisFIFO,
+			want:     &Position{Quantity: 16, CostBasis: 800, AverageCost: 50, AcquiredAt: day(1)},
+			wantCash: -760,
+		},
+		{
+			name:         "sold out",
+			transactions: []types.Transaction{buy("b1", 1, 10, 100, 0), sell(2, 10, 120)},
+			method:       types.CostBasisFIFO,
+			wantCash:     200,
+		},
+		{
+			name: "cash entries",
+			transactions: []types.Transaction{
+				{Type: types.TransactionDeposit, Amount: 5000, TradeDate: day(1)},
+				buy("b1", 2, 10, 100, 0),
+				{Type: types.TransactionDividend, Symbol: "AAA", Amount: 25, TradeDate: day(3)},
+				{Type: types.TransactionFee, Amount: 10, TradeDate: day(4)},
+				{Type: types.TransactionWithdrawal, Amount: 500, TradeDate: day(5)},
+			},
+			want:     &Position{Quantity: 10, CostBasis: 1000, AverageCost: 100, AcquiredAt: day(2)},
+			wantCash: 3515,
+		},
+		{
+			name:         "rounding left by a sell",
+			transactions: []types.Transaction{buy("b1", 1, 0.3, 100, 0), sell(2, 0.1, 100), sell(3, 0.2, 100)},
+			method:       types.CostBasisFIFO,
+			wantCash:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book, err := Replay(tt.transactions, tt.method)
+			if err != nil {
+				t.Fatalf("Replay: %v", err)
+			}
+
+			if !near(book.Cash, tt.wantCash) {
+				t.Errorf("cash %v, want %v", book.Cash, tt.wantCash)
+			}
+
+			got := book.Positions["AAA"]
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected no position, got %+v", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("expected a position")
+			}
+			if !near(got.Quantity, tt.want.Quantity) || !near(got.CostBasis, tt.want.CostBasis) || !near(got.AverageCost, tt.want.AverageCost) {
+				t.Errorf("position %v shares costing %v (%v each), want %v shares costing %v (%v each)",
+					got.Quantity, got.CostBasis, got.AverageCost, tt.want.Quantity, tt.want.CostBasis, tt.want.AverageCost)
+			}
+			if !got.AcquiredAt.Equal(tt.want.AcquiredAt) {
+				t.Errorf("acquired at %v, want %v", got.AcquiredAt, tt.want.AcquiredAt)
+			}
+			if got.Currency != "USD" {
+				t.Errorf("currency %q, want USD", got.Currency)
+			}
+		})
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	unknownLot := sell(2, 1, 100)
+	unknownLot.LotID = "missing"
+
+	euroBuy := buy("b2", 2, 1, 100, 0)
+	euroBuy.Currency = "EUR"
+
+	insufficient := func(err error) bool {
+		var target *types.InsufficientHoldingsError
+		return errors.As(err, &target)
+	}
+
+	tests := []struct {
+		name         string
+		transactions []types.Transaction
+		method       types.CostBasisMethod
+		check        func(error) bool
+	}{
+		{"sell without a buy", []types.Transaction{sell(1, 1, 100)}, types.CostBasisFIFO, insufficient},
+		{"sell before the buy", []types.Transaction{buy("b1", 2, 10, 100, 0), sell(1, 1, 100)}, types.CostBasisFIFO, insufficient},
+		{"oversell", []types.Transaction{buy("b1", 1, 10, 100, 0), sell(2, 11, 100)}, types.CostBasisFIFO, insufficient},
+		{"dust after selling out", []types.Transaction{buy("b1", 1, 10, 100, 0), sell(2, 10, 100), sell(3, 1e-12, 100)}, types.CostBasisFIFO, insufficient},
+		{"dust after selling out on average", []types.Transaction{buy("b1", 1, 10, 100, 0), sell(2, 10, 100), sell(3, 1e-12, 100)}, types.CostBasisAverage, insufficient},
+		{"unknown lot", []types.Transaction{buy("b1", 1, 10, 100, 0), unknownLot}, types.CostBasisSpecific, func(err error) bool {
+			var target *types.InvalidLotError
+			return errors.As(err, &target)
+		}},
+		{"other currency", []types.Transaction{buy("b1", 1, 10, 100, 0), euroBuy}, types.CostBasisFIFO, func(err error) bool {
+			var target *types.CurrencyMismatchError
+			return errors.As(err, &target) && target.Expected == "USD" && target.Currency == "EUR"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Replay(tt.transactions, tt.method); !tt.check(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
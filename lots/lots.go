@@ -0,0 +1,267 @@
+package lots
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// quantityEpsilon absorbs rounding when comparing share quantities
+const quantityEpsilon = 1e-9
+
+// Result holds the open lots and realized gains of a ledger
+type Result struct {
+	Open     []types.Lot
+	Realized []types.RealizedLot
+}
+
+// Match replays buys, sells and splits in trade date order and matches every sell against open lots
+// using the given method. Transactions must already be sorted, see ledger.Sort.
+// A sell of more shares than held returns an *types.InsufficientHoldingsError.
+func Match(transactions []types.Transaction, method types.CostBasisMethod) (*Result, error) {
+	open := make(map[string][]*types.Lot)
+	var symbols []string
+	result := &Result{}
+
+	for _, tx := range transactions {
+		switch tx.Type {
+		case types.TransactionBuy:
+			if _, ok := open[tx.Symbol]; !ok {
+				symbols = append(symbols, tx.Symbol)
+			}
+			open[tx.Symbol] = append(open[tx.Symbol], &types.Lot{
+				ID:               tx.ID,
+				Symbol:           tx.Symbol,
+				AcquiredAt:       tx.TradeDate,
+				OriginalQuantity: tx.Quantity,
+				Quantity:         tx.Quantity,
+				CostPerShare:     (tx.Quantity*tx.Price + tx.Fees) / tx.Quantity,
+			})
+
+		case types.TransactionSell:
+			realized, err := sell(open[tx.Symbol], tx, method)
+			if err != nil {
+				return nil, err
+			}
+			result.Realized = append(result.Realized, realized...)
+			open[tx.Symbol] = removeClosed(open[tx.Symbol])
+
+		case types.TransactionSplit:
+			for _, lot := range open[tx.Symbol] {
+				lot.Quantity *= tx.SplitRatio
+				lot.OriginalQuantity *= tx.SplitRatio
+				lot.CostPerShare /= tx.SplitRatio
+			}
+		}
+	}
+
+	for _, symbol := range symbols {
+		for _, lot := range open[symbol] {
+			lot.CostBasis = lot.Quantity * lot.CostPerShare
+			result.Open = append(result.Open, *lot)
+		}
+	}
+
+	return result, nil
+}
+
+// sell closes lots against a sell transaction, reducing them in place
+func sell(lots []*types.Lot, tx types.Transaction, method types.CostBasisMethod) ([]types.RealizedLot, error) {
+	held := 0.0
+	totalCost := 0.0
+	for _, lot := range lots {
+		held += lot.Quantity
+		totalCost += lot.Quantity * lot.CostPerShare
+	}
+
+	// Nothing open cannot be sold, even a quantity within the rounding tolerance
+	if len(lots) == 0 || tx.Quantity > held+quantityEpsilon {
+		return nil, &types.InsufficientHoldingsError{
+			Symbol:    tx.Symbol,
+			Held:      held,
+			Requested: tx.Quantity,
+			TradeDate: tx.TradeDate,
+		}
+	}
+
+	var order []*types.Lot
+	switch {
+	case method == types.CostBasisSpecific && tx.LotID != "":
+		lot := findLot(lots, tx.LotID)
+		if lot == nil {
+			return nil, &types.InvalidLotError{LotID: tx.LotID, Symbol: tx.Symbol, Reason: "lot is not open"}
+		}
+		if tx.Quantity > lot.Quantity+quantityEpsilon {
+			return nil, &types.InvalidLotError{LotID: tx.LotID, Symbol: tx.Symbol, Reason: "lot holds fewer shares than sold"}
+		}
+		order = []*types.Lot{lot}
+
+	case method == types.CostBasisLIFO:
+		for i := len(lots) - 1; i >= 0; i-- {
+			order = append(order, lots[i])
+		}
+
+	default:
+		// Average cost pools the lots, holding periods are still consumed first in first out
+		if method == types.CostBasisAverage {
+			for _, lot := range lots {
+				lot.CostPerShare = totalCost / held
+			}
+		}
+		order = lots
+	}
+
+	proceedsPerShare := (tx.Quantity*tx.Price - tx.Fees) / tx.Quantity
+	remaining := tx.Quantity
+
+	var realized []types.RealizedLot
+	for _, lot := range order {
+		if remaining <= quantityEpsilon {
+			break
+		}
+
+		take := min(lot.Quantity, remaining)
+		costBasis := take * lot.CostPerShare
+		proceeds := take * proceedsPerShare
+
+		realized = append(realized, types.RealizedLot{
+			LotID:       lot.ID,
+			SellID:      tx.ID,
+			Symbol:      tx.Symbol,
+			AcquiredAt:  lot.AcquiredAt,
+			SoldAt:      tx.TradeDate,
+			Quantity:    take,
+			CostBasis:   costBasis,
+			Proceeds:    proceeds,
+			Gain:        proceeds - costBasis,
+			Term:        Term(lot.AcquiredAt, tx.TradeDate),
+			HoldingDays: holdingDays(lot.AcquiredAt, tx.TradeDate),
+		})
+
+		lot.Quantity -= take
+		remaining -= take
+	}
+
+	return realized, nil
+}
+
+func findLot(lots []*types.Lot, id string) *types.Lot {
+	for _, lot := range lots {
+		if lot.ID == id {
+			return lot
+		}
+	}
+	return nil
+}
+
+func removeClosed(lots []*types.Lot) []*types.Lot {
+	kept := lots[:0]
+	for _, lot := range lots {
+		if lot.Quantity > quantityEpsilon {
+			kept = append(kept, lot)
+		}
+	}
+	return kept
+}
+
+// Term classifies a holding period, positions held for more than one year are long term
+func Term(acquiredAt, at time.Time) types.HoldingTerm {
+	if at.After(acquiredAt.AddDate(1, 0, 0)) {
+		return types.LongTerm
+	}
+	return types.ShortTerm
+}
+
+func holdingDays(acquiredAt, at time.Time) int {
+	return int(at.Sub(acquiredAt).Hours() / 24)
+}
+
+// RealizedBetween returns the realized lots sold within the range, a zero bound leaves that side open
+func (r *Result) RealizedBetween(from, to time.Time) []types.RealizedLot {
+	var realized []types.RealizedLot
+	for _, lot := range r.Realized {
+		if !from.IsZero() && lot.SoldAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && lot.SoldAt.After(to) {
+			continue
+		}
+		realized = append(realized, lot)
+	}
+	return realized
+}
+
+// Report values the open lots at the given prices, keyed by symbol, and summarizes gains per symbol.
+// Lots without a price are reported at cost.
+func Report(open []types.Lot, realized []types.RealizedLot, prices map[string]float64, asOf time.Time) *types.PnLReport {
+	report := &types.PnLReport{AsOf: asOf}
+	holdings := make(map[string]*types.HoldingPnL)
+
+	holding := func(symbol string) *types.HoldingPnL {
+		if h, ok := holdings[symbol]; ok {
+			return h
+		}
+		h := &types.HoldingPnL{Symbol: symbol}
+		holdings[symbol] = h
+		return h
+	}
+
+	for _, lot := range open {
+		lot.Term = Term(lot.AcquiredAt, asOf)
+		lot.HoldingDays = holdingDays(lot.AcquiredAt, asOf)
+		lot.MarketPrice = lot.CostPerShare
+		if price, ok := prices[lot.Symbol]; ok && price > 0 {
+			lot.MarketPrice = price
+		}
+		lot.MarketValue = lot.Quantity * lot.MarketPrice
+		lot.UnrealizedGain = lot.MarketValue - lot.CostBasis
+		if lot.CostBasis > 0 {
+			lot.UnrealizedGainPct = lot.UnrealizedGain / lot.CostBasis * 100
+		}
+
+		h := holding(lot.Symbol)
+		h.Quantity += lot.Quantity
+		h.CostBasis += lot.CostBasis
+		h.MarketValue += lot.MarketValue
+		h.UnrealizedGain += lot.UnrealizedGain
+		if lot.Term == types.LongTerm {
+			h.LongTermUnrealized += lot.UnrealizedGain
+		} else {
+			h.ShortTermUnrealized += lot.UnrealizedGain
+		}
+		h.Lots = append(h.Lots, lot)
+	}
+
+	for _, lot := range realized {
+		h := holding(lot.Symbol)
+		h.RealizedGain += lot.Gain
+		if lot.Term == types.LongTerm {
+			h.LongTermRealized += lot.Gain
+		} else {
+			h.ShortTermRealized += lot.Gain
+		}
+	}
+
+	symbols := make([]string, 0, len(holdings))
+	for symbol := range holdings {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	report.Holdings = make([]types.HoldingPnL, 0, len(symbols))
+	for _, symbol := range symbols {
+		h := holdings[symbol]
+		report.TotalCostBasis += h.CostBasis
+		report.TotalMarketValue += h.MarketValue
+		report.UnrealizedGain += h.UnrealizedGain
+		report.ShortTermUnrealized += h.ShortTermUnrealized
+		report.LongTermUnrealized += h.LongTermUnrealized
+		report.RealizedGain += h.RealizedGain
+		report.ShortTermRealized += h.ShortTermRealized
+		report.LongTermRealized += h.LongTermRealized
+		report.Holdings = append(report.Holdings, *h)
+	}
+
+	return report
+}
@@ -1,12 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/ecetinerdem/forseer/alerts"
 	"github.com/ecetinerdem/forseer/api"
+	"github.com/ecetinerdem/forseer/backtest"
+	"github.com/ecetinerdem/forseer/corporate"
 	"github.com/ecetinerdem/forseer/database"
+	"github.com/ecetinerdem/forseer/fx"
+	"github.com/ecetinerdem/forseer/marketdata"
+	"github.com/ecetinerdem/forseer/scheduler"
+	"github.com/ecetinerdem/forseer/types"
+	"github.com/ecetinerdem/forseer/webhooks"
 	"github.com/joho/godotenv"
 )
 
@@ -31,9 +45,98 @@ func main() {
 
 	database.RunMigrations(db)
 
-	server := api.NewServer(db, openAIAPIKey)
+	// Background jobs stop and the server drains its requests on interrupt or termination
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Offline environments can serve market data and exchange rates from fixture files instead of Alpha Vantage
+	var marketData marketdata.MarketDataProvider
+	var fxRates marketdata.FXProvider
+	if fixturesDir := os.Getenv("MARKET_DATA_FIXTURES_DIR"); fixturesDir != "" {
+		log.Println("Using market data fixtures from", fixturesDir)
+		fixtures := marketdata.NewFixtureProvider(fixturesDir)
+		marketData, fxRates = fixtures, fixtures
+	} else {
+		clientConfig := marketdata.DefaultClientConfig(os.Getenv("ALPHAVENTAGE_API_KEY"))
+		clientConfig.CallsPerMinute = envInt("ALPHAVENTAGE_CALLS_PER_MINUTE", clientConfig.CallsPerMinute)
+		clientConfig.CallsPerDay = envInt("ALPHAVENTAGE_CALLS_PER_DAY", clientConfig.CallsPerDay)
+		alphaVantage := marketdata.NewAlphaVantageProvider(marketdata.NewAlphaVantageClient(clientConfig))
+		// Full daily and intraday histories need a premium API key
+		alphaVantage.FullHistory = os.Getenv("ALPHAVENTAGE_FULL_HISTORY") == "true"
+		marketData, fxRates = alphaVantage, alphaVantage
+	}
+
+	// Shared cache in front of the provider, every fetched series is stored in the price history.
+	// The cache is itself a MarketDataProvider, the server does not know it is there.
+	cachedMarketData := marketdata.NewCachedProvider(marketData, db, envInt("MARKET_DATA_CACHE_SIZE", 512), marketdata.DefaultTTLs())
+
+	// Exchange rates are stored as they are fetched, so past valuations can be reproduced
+	fxService := fx.NewService(fxRates, db, fx.DefaultTTL)
+
+	// Keep every held symbol fresh in the background, schedules run in server local time
+	refreshSchedules := os.Getenv("PRICE_REFRESH_SCHEDULES")
+	if refreshSchedules == "" {
+		refreshSchedules = "all=30 22 * * 1-5"
+	}
+
+	refreshJobs, err := scheduler.ParseJobs(refreshSchedules)
+	if err != nil {
+		log.Fatal("Invalid PRICE_REFRESH_SCHEDULES: ", err)
+	}
+
+	// Signed event pushes to user webhooks, failed deliveries are retried in the background
+	webhookDispatcher := webhooks.NewDispatcher(db)
+	webhookDispatcher.Start(ctx)
+
+	refreshScheduler := scheduler.New(cachedMarketData, db, refreshJobs)
+
+	// Splits and dividends of refreshed symbols are recorded in the ledgers holding them before alerts run
+	corporateActions := corporate.NewApplier(db)
+	refreshScheduler.OnRefreshed(corporateActions.ApplyRefreshed)
+
+	alertEvaluator := alerts.NewEvaluator(db, fxService)
+	alertEvaluator.OnTriggered(func(ctx context.Context, trigger *types.AlertTrigger) {
+		webhookDispatcher.Publish(ctx, trigger.UserID, types.WebhookEventAlertTriggered, trigger)
+	})
+	refreshScheduler.OnRefreshed(alertEvaluator.Evaluate)
+	refreshScheduler.Start(ctx)
+
+	// Backtests run in the background, jobs interrupted by a shutdown or a restart are marked failed
+	backtestRunner := backtest.NewRunner(db)
+	backtestRunner.Workers = envInt("BACKTEST_WORKERS", backtestRunner.Workers)
+	backtestRunner.MaxPerUser = envInt("BACKTEST_MAX_PER_USER", backtestRunner.MaxPerUser)
+	backtestRunner.Start(ctx)
+
+	server := api.NewServer(db, openAIAPIKey, cachedMarketData, fxService, corporateActions, backtestRunner, refreshScheduler, webhookDispatcher)
 	PORT := os.Getenv("PORT")
+	httpServer := &http.Server{Addr: ":" + PORT, Handler: server.Router}
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Println("Server shutdown error: ", err)
+		}
+	}()
+
 	log.Println("Server starting on the designated port")
-	log.Fatal(http.ListenAndServe(":"+PORT, server.Router))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-drained
+	backtestRunner.Wait()
+	webhookDispatcher.Wait()
+	log.Println("Server stopped")
+}
 
+// envInt reads a positive integer environment variable, falling back to def when unset or invalid
+func envInt(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
 }
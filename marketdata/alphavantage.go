@@ -0,0 +1,71 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+const alphaVantageBaseURL = "https://www.alphavantage.co/query"
+
+// AlphaVantageProvider fetches market data from the Alpha Vantage API through a rate-limited client.
+// Daily and intraday series are the latest 100 bars unless FullHistory is set, the full history of those
+// series is a premium feature the free API key is refused. Stored history still grows with every refresh.
+type AlphaVantageProvider struct {
+	client      *AlphaVantageClient
+	FullHistory bool
+}
+
+func NewAlphaVantageProvider(client *AlphaVantageClient) *AlphaVantageProvider {
+	return &AlphaVantageProvider{client: client}
+}
+
+// GetPriceSeries fetches the price history of the given symbol and interval
+func (a *AlphaVantageProvider) GetPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error) {
+	outputSize := "compact"
+	if a.FullHistory {
+		outputSize = "full"
+	}
+
+	params, err := seriesParams(symbol, interval, outputSize)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := a.client.Query(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeSeries(body, interval)
+}
+
+// seriesParams builds the Alpha Vantage query for a symbol's time series at the given interval,
+// outputSize (compact or full) applies to daily and intraday series
+func seriesParams(symbol string, interval types.Interval, outputSize string) (url.Values, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+
+	switch interval {
+	case types.IntervalMonthly:
+		params.Set("function", "TIME_SERIES_MONTHLY")
+	case types.IntervalWeekly:
+		params.Set("function", "TIME_SERIES_WEEKLY")
+	case types.IntervalDaily:
+		params.Set("function", "TIME_SERIES_DAILY")
+		params.Set("outputsize", outputSize)
+	case types.IntervalDailyAdjusted:
+		params.Set("function", "TIME_SERIES_DAILY_ADJUSTED")
+		params.Set("outputsize", outputSize)
+	case types.Interval1Min, types.Interval5Min, types.Interval15Min, types.Interval60Min:
+		params.Set("function", "TIME_SERIES_INTRADAY")
+		params.Set("interval", string(interval))
+		params.Set("outputsize", outputSize)
+	default:
+		return nil, fmt.Errorf("unsupported interval %q", interval)
+	}
+
+	return params, nil
+}
@@ -0,0 +1,155 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// SeriesStore is the Postgres-backed layer of the cache, shared by every server instance
+type SeriesStore interface {
+	// GetCachedPriceSeries returns the stored series and when it was fetched, or a nil series if none is stored
+	GetCachedPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, time.Time, error)
+	SaveCachedPriceSeries(ctx context.Context, series *types.PriceSeries, fetchedAt time.Time) error
+}
+
+// DefaultTTLs returns how long a fetched series stays fresh for each interval
+func DefaultTTLs() map[types.Interval]time.Duration {
+	return map[types.Interval]time.Duration{
+		types.Interval1Min:          time.Minute,
+		types.Interval5Min:          5 * time.Minute,
+		types.Interval15Min:         15 * time.Minute,
+		types.Interval60Min:         time.Hour,
+		types.IntervalDaily:         6 * time.Hour,
+		types.IntervalDailyAdjusted: 6 * time.Hour,
+		types.IntervalWeekly:        24 * time.Hour,
+		types.IntervalMonthly:       24 * time.Hour,
+	}
+}
+
+// CachedProvider is a MarketDataProvider that serves series from an in-process LRU and the
+// Postgres store while they are fresh, and coalesces concurrent fetches of the same series
+// into a single upstream call. Every fetched series is persisted to the store, which keeps serving
+// an expired series while the upstream provider fails.
+type CachedProvider struct {
+	provider MarketDataProvider
+	store    SeriesStore
+	lru      *lruCache
+	ttls     map[types.Interval]time.Duration
+	group    singleflight.Group
+}
+
+func NewCachedProvider(provider MarketDataProvider, store SeriesStore, size int, ttls map[types.Interval]time.Duration) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		store:    store,
+		lru:      newLRUCache(size),
+		ttls:     ttls,
+	}
+}
+
+// GetPriceSeries returns a fresh series from the cache, fetching it upstream when it is missing or expired.
+// An expired series is returned when it cannot be fetched.
+func (c *CachedProvider) GetPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error) {
+	key := cacheKey(symbol, interval)
+
+	if series, fetchedAt, ok := c.lru.get(key); ok && c.isFresh(interval, fetchedAt) {
+		return series, nil
+	}
+
+	// The shared call must outlive any single caller, each caller stops waiting on its own context
+	result := c.group.DoChan(key, func() (any, error) {
+		return c.fetch(context.WithoutCancel(ctx), symbol, interval)
+	})
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*types.PriceSeries), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Refresh fetches the series upstream even if the cached copy is still fresh, used by the background scheduler
+func (c *CachedProvider) Refresh(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error) {
+	key := cacheKey(symbol, interval)
+
+	result := c.group.DoChan("refresh|"+key, func() (any, error) {
+		return c.fetchUpstream(context.WithoutCancel(ctx), symbol, interval)
+	})
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*types.PriceSeries), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch checks the Postgres store before calling the upstream provider.
+// A stored series that expired is still served when the upstream call fails.
+func (c *CachedProvider) fetch(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error) {
+	key := cacheKey(symbol, interval)
+
+	series, fetchedAt, err := c.store.GetCachedPriceSeries(ctx, symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	if series != nil && c.isFresh(interval, fetchedAt) {
+		c.lru.put(key, series, fetchedAt)
+		return series, nil
+	}
+
+	fetched, err := c.fetchUpstream(ctx, symbol, interval)
+	if err != nil {
+		if series != nil {
+			log.Printf("Using stored %s series for %s, refresh failed: %v", interval, symbol, err)
+			return series, nil
+		}
+		return nil, err
+	}
+
+	return fetched, nil
+}
+
+// fetchUpstream calls the provider and stores the series in both cache layers
+func (c *CachedProvider) fetchUpstream(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error) {
+	key := cacheKey(symbol, interval)
+
+	series, err := c.provider.GetPriceSeries(ctx, symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchedAt := time.Now()
+	if err := c.store.SaveCachedPriceSeries(ctx, series, fetchedAt); err != nil {
+		return nil, fmt.Errorf("failed to store %s series for %s: %w", interval, symbol, err)
+	}
+
+	c.lru.put(key, series, fetchedAt)
+	// Providers may canonicalize the symbol, cache under the returned name too
+	if returnedKey := cacheKey(series.Symbol, interval); returnedKey != key {
+		c.lru.put(returnedKey, series, fetchedAt)
+	}
+
+	return series, nil
+}
+
+func (c *CachedProvider) isFresh(interval types.Interval, fetchedAt time.Time) bool {
+	return time.Since(fetchedAt) < c.ttls[interval]
+}
+
+func cacheKey(symbol string, interval types.Interval) string {
+	return strings.ToUpper(symbol) + "|" + string(interval)
+}
@@ -0,0 +1,81 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+type fakeSeriesStore struct {
+	series    *types.PriceSeries
+	fetchedAt time.Time
+}
+
+func (s *fakeSeriesStore) GetCachedPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, time.Time, error) {
+	return s.series, s.fetchedAt, nil
+}
+
+func (s *fakeSeriesStore) SaveCachedPriceSeries(ctx context.Context, series *types.PriceSeries, fetchedAt time.Time) error {
+	s.series, s.fetchedAt = series, fetchedAt
+	return nil
+}
+
+type fakeProvider struct {
+	series *types.PriceSeries
+	err    error
+	calls  int
+}
+
+func (p *fakeProvider) GetPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error) {
+	p.calls++
+	return p.series, p.err
+}
+
+func TestCachedProviderGetPriceSeries(t *testing.T) {
+	stored := &types.PriceSeries{Symbol: "IBM", Interval: types.IntervalDaily, Bars: []types.PriceBar{{Close: 1}}}
+	fetched := &types.PriceSeries{Symbol: "IBM", Interval: types.IntervalDaily, Bars: []types.PriceBar{{Close: 2}}}
+	rateLimited := &RateLimitError{Message: "slow down", RetryAfter: time.Minute}
+
+	tests := []struct {
+		name      string
+		stored    *types.PriceSeries
+		storedAge time.Duration
+		upstream  *fakeProvider
+		want      *types.PriceSeries
+		wantCalls int
+		wantErr   error
+	}{
+		{"fresh stored series", stored, time.Hour, &fakeProvider{series: fetched}, stored, 0, nil},
+		{"expired stored series is refreshed", stored, 7 * time.Hour, &fakeProvider{series: fetched}, fetched, 1, nil},
+		{"nothing stored", nil, 0, &fakeProvider{series: fetched}, fetched, 1, nil},
+		{"expired stored series served when the refresh fails", stored, 7 * time.Hour, &fakeProvider{err: rateLimited}, stored, 1, nil},
+		{"nothing stored and the fetch fails", nil, 0, &fakeProvider{err: rateLimited}, nil, 1, rateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeSeriesStore{series: tt.stored, fetchedAt: time.Now().Add(-tt.storedAge)}
+			cache := NewCachedProvider(tt.upstream, store, 8, DefaultTTLs())
+
+			got, err := cache.GetPriceSeries(context.Background(), "IBM", types.IntervalDaily)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPriceSeries: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got series closing at %v, want %v", got.Bars[0].Close, tt.want.Bars[0].Close)
+			}
+			if tt.upstream.calls != tt.wantCalls {
+				t.Errorf("upstream called %d times, want %d", tt.upstream.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ClientConfig configures the Alpha Vantage client limits
+type ClientConfig struct {
+	APIKey         string
+	CallsPerMinute int
+	CallsPerDay    int
+	QueueSize      int
+	MaxWait        time.Duration // Longest a queued call may wait for the rate limiter
+}
+
+// DefaultClientConfig matches the Alpha Vantage free tier
+func DefaultClientConfig(apiKey string) ClientConfig {
+	return ClientConfig{
+		APIKey:         apiKey,
+		CallsPerMinute: 5,
+		CallsPerDay:    25,
+		QueueSize:      50,
+		MaxWait:        30 * time.Second,
+	}
+}
+
+// ClientStatus reports the client's current limiter state
+type ClientStatus struct {
+	QueueLength    int       `json:"queue_length"`
+	QuotaRemaining int       `json:"quota_remaining"`
+	QuotaResetAt   time.Time `json:"quota_reset_at"`
+}
+
+// AlphaVantageClient is the outbound client for the Alpha Vantage API.
+// Calls are queued and run one at a time, spaced by a token bucket limiter and counted against a daily quota.
+type AlphaVantageClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	limiter    *TokenBucket
+	quota      *DailyQuota
+	queue      chan *clientRequest
+	maxWait    time.Duration
+}
+
+type clientRequest struct {
+	ctx    context.Context
+	params url.Values
+	result chan clientResult
+}
+
+type clientResult struct {
+	body []byte
+	err  error
+}
+
+// errorPayload holds the fields Alpha Vantage returns with a 200 status instead of data
+type errorPayload struct {
+	Note         string `json:"Note"`
+	Information  string `json:"Information"`
+	ErrorMessage string `json:"Error Message"`
+}
+
+func NewAlphaVantageClient(config ClientConfig) *AlphaVantageClient {
+	c := &AlphaVantageClient{
+		apiKey:  config.APIKey,
+		baseURL: alphaVantageBaseURL,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		limiter: NewTokenBucket(config.CallsPerMinute, time.Minute),
+		quota:   NewDailyQuota(config.CallsPerDay),
+		queue:   make(chan *clientRequest, config.QueueSize),
+		maxWait: config.MaxWait,
+	}
+	go c.run()
+	return c
+}
+
+// Query queues a call with the given query params and waits for its response body
+func (c *AlphaVantageClient) Query(ctx context.Context, params url.Values) ([]byte, error) {
+	req := &clientRequest{
+		ctx:    ctx,
+		params: params,
+		result: make(chan clientResult, 1),
+	}
+
+	select {
+	case c.queue <- req:
+	default:
+		return nil, &QueueFullError{Size: cap(c.queue)}
+	}
+
+	select {
+	case result := <-req.result:
+		return result.body, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Status returns the current queue length and remaining daily quota
+func (c *AlphaVantageClient) Status() ClientStatus {
+	return ClientStatus{
+		QueueLength:    len(c.queue),
+		QuotaRemaining: c.quota.Remaining(),
+		QuotaResetAt:   c.quota.ResetAt(),
+	}
+}
+
+// run processes queued calls one at a time
+func (c *AlphaVantageClient) run() {
+	for req := range c.queue {
+		if req.ctx.Err() != nil {
+			continue
+		}
+
+		body, err := c.do(req.ctx, req.params)
+		req.result <- clientResult{body: body, err: err}
+	}
+}
+
+func (c *AlphaVantageClient) do(ctx context.Context, params url.Values) ([]byte, error) {
+	// Calls run one at a time, so the token and quota checked here are still there after the wait.
+	// Both are only taken once the wait is over, a cancelled call uses neither.
+	wait := c.limiter.Delay()
+	if wait > c.maxWait {
+		return nil, &RateLimitError{Message: "per-minute call limit reached", RetryAfter: wait}
+	}
+
+	if c.quota.Remaining() <= 0 {
+		return nil, &QuotaExceededError{Message: "daily call limit used up", ResetAt: c.quota.ResetAt()}
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	if !c.limiter.Take() {
+		return nil, &RateLimitError{Message: "per-minute call limit reached", RetryAfter: c.limiter.Delay()}
+	}
+	if !c.quota.Take() {
+		return nil, &QuotaExceededError{Message: "daily call limit used up", ResetAt: c.quota.ResetAt()}
+	}
+
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("apikey", c.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting stock data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Alpha Vantage API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// FX calls name a currency pair instead of a symbol
+	symbol := params.Get("symbol")
+	if symbol == "" && params.Has("from_symbol") {
+		symbol = params.Get("from_symbol") + "/" + params.Get("to_symbol")
+	}
+
+	if err := c.checkErrorPayload(body, symbol); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// checkErrorPayload turns the error payloads Alpha Vantage returns with a 200 status into typed errors
+func (c *AlphaVantageClient) checkErrorPayload(body []byte, symbol string) error {
+	var payload errorPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Not a JSON object, let the series decoder report it
+		return nil
+	}
+
+	switch {
+	case payload.Note != "":
+		return &RateLimitError{Message: payload.Note, RetryAfter: time.Minute}
+	case payload.Information != "":
+		return c.informationError(payload.Information, symbol)
+	case payload.ErrorMessage != "":
+		return &InvalidSymbolError{Symbol: symbol, Message: payload.ErrorMessage}
+	}
+
+	return nil
+}
+
+// informationError classifies an "Information" payload. Alpha Vantage uses it for the daily limit, for
+// calls made too fast and for premium endpoints and parameters; only the daily limit stops calls for the
+// rest of the day. Every message advertises the premium plans and premium replies can name a daily series,
+// so only the wording of each case is matched.
+func (c *AlphaVantageClient) informationError(message, symbol string) error {
+	lower := strings.ToLower(message)
+
+	switch {
+	case strings.Contains(lower, "premium endpoint") || strings.Contains(lower, "premium feature"):
+		return &UnsupportedRequestError{Symbol: symbol, Message: message}
+	case strings.Contains(lower, "per day"):
+		c.quota.Exhaust()
+		return &QuotaExceededError{Message: message, ResetAt: c.quota.ResetAt()}
+	case strings.Contains(lower, "rate limit") || strings.Contains(lower, "per second") ||
+		strings.Contains(lower, "per minute") || strings.Contains(lower, "sparingly"):
+		return &RateLimitError{Message: message, RetryAfter: time.Minute}
+	}
+
+	return &UnsupportedRequestError{Symbol: symbol, Message: message}
+}
@@ -0,0 +1,83 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Information messages as Alpha Vantage sends them
+const (
+	dailyLimitMessage = "We have detected your API key as demo and our standard API rate limit is 25 requests per day. " +
+		"Please subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly remove all daily rate limits."
+	burstMessage = "Thank you for using Alpha Vantage! Please consider spreading out your free API requests more sparingly (1 request per second). " +
+		"You may subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to lift the free key rate limit."
+	premiumParamMessage = "Thank you for using Alpha Vantage! The **outputsize=full** parameter value is a premium feature for the TIME_SERIES_DAILY endpoint. " +
+		"You may subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly unlock all premium features of this endpoint."
+	premiumEndpointMessage = "Thank you for using Alpha Vantage! This is a premium endpoint. " +
+		"You may subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly unlock all premium endpoints"
+)
+
+func TestQueryErrorPayloads(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       map[string]string
+		check         func(error) bool
+		wantExhausted bool
+	}{
+		{"daily limit", map[string]string{"Information": dailyLimitMessage}, func(err error) bool {
+			var target *QuotaExceededError
+			return errors.As(err, &target)
+		}, true},
+		{"burst", map[string]string{"Information": burstMessage}, func(err error) bool {
+			var target *RateLimitError
+			return errors.As(err, &target)
+		}, false},
+		{"note", map[string]string{"Note": "Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute."}, func(err error) bool {
+			var target *RateLimitError
+			return errors.As(err, &target)
+		}, false},
+		{"premium parameter on a daily series", map[string]string{"Information": premiumParamMessage}, func(err error) bool {
+			var target *UnsupportedRequestError
+			return errors.As(err, &target) && target.Symbol == "IBM"
+		}, false},
+		{"premium endpoint", map[string]string{"Information": premiumEndpointMessage}, func(err error) bool {
+			var target *UnsupportedRequestError
+			return errors.As(err, &target)
+		}, false},
+		{"invalid symbol", map[string]string{"Error Message": "Invalid API call. Please retry or visit the documentation for TIME_SERIES_DAILY."}, func(err error) bool {
+			var target *InvalidSymbolError
+			return errors.As(err, &target) && target.Symbol == "IBM"
+		}, false},
+		{"data", map[string]string{"Meta Data": "IBM"}, func(err error) bool { return err == nil }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(tt.payload)
+			}))
+			defer server.Close()
+
+			config := DefaultClientConfig("demo")
+			config.MaxWait = time.Second
+			client := NewAlphaVantageClient(config)
+			client.baseURL = server.URL
+
+			params := url.Values{"function": {"TIME_SERIES_DAILY"}, "symbol": {"IBM"}}
+			_, err := client.Query(context.Background(), params)
+			if !tt.check(err) {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if exhausted := client.Status().QuotaRemaining == 0; exhausted != tt.wantExhausted {
+				t.Errorf("quota exhausted %v, want %v", exhausted, tt.wantExhausted)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package marketdata
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitError is returned when a call cannot be made within the per-minute rate limit,
+// or when Alpha Vantage answers with a "Note" payload telling us the limit was hit
+type RateLimitError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("market data rate limit reached: %s", e.Message)
+}
+
+// QuotaExceededError is returned when the daily call quota is used up,
+// or when Alpha Vantage answers with an "Information" payload about its daily limit
+type QuotaExceededError struct {
+	Message string
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("market data daily quota exceeded: %s", e.Message)
+}
+
+// QueueFullError is returned when too many calls are already waiting for the rate limiter
+type QueueFullError struct {
+	Size int
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("market data request queue is full (%d pending)", e.Size)
+}
+
+// InvalidSymbolError is returned when Alpha Vantage answers with an "Error Message" payload
+type InvalidSymbolError struct {
+	Symbol  string
+	Message string
+}
+
+func (e *InvalidSymbolError) Error() string {
+	return fmt.Sprintf("invalid market data request for symbol %s: %s", e.Symbol, e.Message)
+}
+
+// UnsupportedRequestError is returned when Alpha Vantage answers with an "Information" payload refusing
+// the call itself, such as a premium endpoint requested with a free API key
+type UnsupportedRequestError struct {
+	Symbol  string
+	Message string
+}
+
+func (e *UnsupportedRequestError) Error() string {
+	return fmt.Sprintf("market data request for symbol %s is not available: %s", e.Symbol, e.Message)
+}
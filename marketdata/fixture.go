@@ -0,0 +1,33 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// FixtureProvider serves market data from JSON files on disk, for tests and offline development.
+// Each series is read from <dir>/<SYMBOL>_<interval>.json in the Alpha Vantage response format.
+type FixtureProvider struct {
+	dir string
+}
+
+func NewFixtureProvider(dir string) *FixtureProvider {
+	return &FixtureProvider{dir: dir}
+}
+
+// GetPriceSeries reads the price history of the given symbol and interval from its fixture file
+func (f *FixtureProvider) GetPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error) {
+	path := filepath.Join(f.dir, fmt.Sprintf("%s_%s.json", strings.ToUpper(symbol), interval))
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s fixture for symbol %s: %w", interval, symbol, err)
+	}
+
+	return decodeSeries(content, interval)
+}
@@ -0,0 +1,85 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// FXProvider fetches the daily exchange rate history of a currency pair from an external source
+type FXProvider interface {
+	GetFXSeries(ctx context.Context, from, to string) (*types.FXSeries, error)
+}
+
+// GetFXSeries fetches the full daily rate history of a currency pair with FX_DAILY
+func (a *AlphaVantageProvider) GetFXSeries(ctx context.Context, from, to string) (*types.FXSeries, error) {
+	params := url.Values{}
+	params.Set("function", "FX_DAILY")
+	params.Set("from_symbol", from)
+	params.Set("to_symbol", to)
+	params.Set("outputsize", "full")
+
+	body, err := a.client.Query(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeFXSeries(body, from, to)
+}
+
+// GetFXSeries reads the rate history of a currency pair from <dir>/FX_<FROM>_<TO>.json in the FX_DAILY format
+func (f *FixtureProvider) GetFXSeries(ctx context.Context, from, to string) (*types.FXSeries, error) {
+	path := filepath.Join(f.dir, fmt.Sprintf("FX_%s_%s.json", strings.ToUpper(from), strings.ToUpper(to)))
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read fx fixture for %s/%s: %w", from, to, err)
+	}
+
+	return decodeFXSeries(content, from, to)
+}
+
+// decodeFXSeries decodes an Alpha Vantage FX_DAILY response into a rate series ordered by date
+func decodeFXSeries(body []byte, from, to string) (*types.FXSeries, error) {
+	var response types.AlphaVentageFXDailyResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error decoding fx series: %w", err)
+	}
+
+	if len(response.TimeSeries) == 0 {
+		return nil, fmt.Errorf("no %s/%s exchange rates returned", from, to)
+	}
+
+	series := &types.FXSeries{
+		From:          from,
+		To:            to,
+		LastRefreshed: response.MetaData.LastRefreshed,
+		Rates:         make([]types.FXRate, 0, len(response.TimeSeries)),
+	}
+
+	for date, data := range response.TimeSeries {
+		rateDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate date %q for %s/%s: %w", date, from, to, err)
+		}
+		if data.Close <= 0 {
+			continue
+		}
+
+		series.Rates = append(series.Rates, types.FXRate{From: from, To: to, Date: rateDate, Rate: data.Close})
+	}
+
+	sort.Slice(series.Rates, func(i, j int) bool {
+		return series.Rates[i].Date.Before(series.Rates[j].Date)
+	})
+
+	return series, nil
+}
@@ -0,0 +1,66 @@
+package marketdata
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// lruCache is a fixed size in-process cache of price series, evicting the least recently used entry
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	series    *types.PriceSeries
+	fetchedAt time.Time
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, capacity),
+	}
+}
+
+func (c *lruCache) get(key string) (*types.PriceSeries, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	c.order.MoveToFront(element)
+	entry := element.Value.(*lruEntry)
+	return entry.series, entry.fetchedAt, true
+}
+
+func (c *lruCache) put(key string, series *types.PriceSeries, fetchedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		entry := element.Value.(*lruEntry)
+		entry.series = series
+		entry.fetchedAt = fetchedAt
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, series: series, fetchedAt: fetchedAt})
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
@@ -0,0 +1,175 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// MarketDataProvider fetches stock price data from an external source
+type MarketDataProvider interface {
+	GetPriceSeries(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error)
+}
+
+// decodeSeries decodes an Alpha Vantage time series response for the given interval into a price series
+func decodeSeries(body []byte, interval types.Interval) (*types.PriceSeries, error) {
+	switch interval {
+	case types.IntervalMonthly:
+		var response types.AlphaVentageStockResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("error decoding monthly series: %w", err)
+		}
+		return buildSeries(response.MetaData.Symbol, interval, response.MetaData.LastRefreshed, response.MetaData.TimeZone, unadjusted(response.TimeSeries))
+
+	case types.IntervalWeekly:
+		var response types.AlphaVentageWeeklyResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("error decoding weekly series: %w", err)
+		}
+		return buildSeries(response.MetaData.Symbol, interval, response.MetaData.LastRefreshed, response.MetaData.TimeZone, unadjusted(response.TimeSeries))
+
+	case types.IntervalDaily:
+		var response types.AlphaVentageDailyResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("error decoding daily series: %w", err)
+		}
+		return buildSeries(response.MetaData.Symbol, interval, response.MetaData.LastRefreshed, response.MetaData.TimeZone, unadjusted(response.TimeSeries))
+
+	case types.IntervalDailyAdjusted:
+		var response types.AlphaVentageDailyAdjustedResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("error decoding adjusted daily series: %w", err)
+		}
+
+		bars := make(map[string]types.AdjustedTimeSeriesData, len(response.TimeSeries))
+		for date, data := range response.TimeSeries {
+			bars[date] = data
+		}
+		return buildSeries(response.MetaData.Symbol, interval, response.MetaData.LastRefreshed, response.MetaData.TimeZone, bars)
+
+	case types.Interval1Min, types.Interval5Min, types.Interval15Min, types.Interval60Min:
+		var response types.AlphaVentageIntradayResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("error decoding intraday series: %w", err)
+		}
+		return buildSeries(response.MetaData.Symbol, interval, response.MetaData.LastRefreshed, response.MetaData.TimeZone, unadjusted(response.TimeSeries))
+	}
+
+	return nil, fmt.Errorf("unsupported interval %q", interval)
+}
+
+// unadjusted widens plain OHLCV data to the adjusted shape, using the close as the adjusted close
+func unadjusted(timeSeries map[string]types.TimeSeriesData) map[string]types.AdjustedTimeSeriesData {
+	bars := make(map[string]types.AdjustedTimeSeriesData, len(timeSeries))
+	for date, data := range timeSeries {
+		bars[date] = types.AdjustedTimeSeriesData{
+			Open:             data.Open,
+			High:             data.High,
+			Low:              data.Low,
+			Close:            data.Close,
+			AdjustedClose:    data.Close,
+			Volume:           data.Volume,
+			SplitCoefficient: 1,
+		}
+	}
+	return bars
+}
+
+// buildSeries converts Alpha Vantage bars keyed by date into a price series ordered by date
+func buildSeries(symbol string, interval types.Interval, lastRefreshed, timeZone string, timeSeries map[string]types.AdjustedTimeSeriesData) (*types.PriceSeries, error) {
+	if len(timeSeries) == 0 {
+		return nil, fmt.Errorf("no %s price data returned for symbol %s", interval, symbol)
+	}
+
+	// Intraday timestamps are exchange local times, dates are kept as UTC midnight
+	location := time.UTC
+	layout := "2006-01-02"
+	if interval.IsIntraday() {
+		layout = "2006-01-02 15:04:05"
+		if loaded, err := time.LoadLocation(timeZone); err == nil {
+			location = loaded
+		}
+	}
+
+	series := &types.PriceSeries{
+		Symbol:        symbol,
+		Interval:      interval,
+		LastRefreshed: lastRefreshed,
+		Bars:          make([]types.PriceBar, 0, len(timeSeries)),
+	}
+
+	for date, data := range timeSeries {
+		barDate, err := time.ParseInLocation(layout, date, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bar date %q for symbol %s: %w", date, symbol, err)
+		}
+
+		series.Bars = append(series.Bars, types.PriceBar{
+			Symbol:        symbol,
+			Interval:      interval,
+			Date:          barDate,
+			Open:          data.Open,
+			High:          data.High,
+			Low:           data.Low,
+			Close:         data.Close,
+			AdjustedClose: data.AdjustedClose,
+			Volume:        data.Volume,
+		})
+	}
+
+	sort.Slice(series.Bars, func(i, j int) bool {
+		return series.Bars[i].Date.Before(series.Bars[j].Date)
+	})
+
+	series.Actions = corporateActions(symbol, timeSeries)
+
+	return series, nil
+}
+
+// corporateActions reads the splits and dividends reported on the bars of an adjusted series, ordered by ex-date.
+// Unadjusted series report a split coefficient of 1 and no dividends, so they carry none.
+func corporateActions(symbol string, timeSeries map[string]types.AdjustedTimeSeriesData) []types.CorporateAction {
+	var actions []types.CorporateAction
+	for date, data := range timeSeries {
+		if data.SplitCoefficient == 1 && data.DividendAmount == 0 {
+			continue
+		}
+
+		exDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+
+		if data.SplitCoefficient > 0 && data.SplitCoefficient != 1 {
+			actions = append(actions, types.CorporateAction{
+				Symbol: symbol,
+				Type:   types.CorporateActionSplit,
+				ExDate: exDate,
+				Ratio:  data.SplitCoefficient,
+				Source: types.CorporateActionProvider,
+			})
+		}
+		if data.DividendAmount > 0 {
+			actions = append(actions, types.CorporateAction{
+				Symbol: symbol,
+				Type:   types.CorporateActionDividend,
+				ExDate: exDate,
+				Amount: data.DividendAmount,
+				Source: types.CorporateActionProvider,
+			})
+		}
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		if !actions[i].ExDate.Equal(actions[j].ExDate) {
+			return actions[i].ExDate.Before(actions[j].ExDate)
+		}
+		return actions[i].Type < actions[j].Type
+	})
+
+	return actions
+}
@@ -0,0 +1,121 @@
+package marketdata
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket is a token bucket rate limiter refilling capacity tokens every period
+type TokenBucket struct {
+	mu         sync.Mutex
+	capacity   float64
+	tokens     float64
+	refillRate float64 // tokens per second
+	last       time.Time
+}
+
+func NewTokenBucket(capacity int, period time.Duration) *TokenBucket {
+	return &TokenBucket{
+		capacity:   float64(capacity),
+		tokens:     float64(capacity),
+		refillRate: float64(capacity) / period.Seconds(),
+		last:       time.Now(),
+	}
+}
+
+// Delay returns how long until a token is available, without taking it
+func (b *TokenBucket) Delay() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.refillRate * float64(time.Second))
+}
+
+// Take takes a token, returning false when none is available yet
+func (b *TokenBucket) Take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *TokenBucket) refill() {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.refillRate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
+
+// DailyQuota counts calls against a limit that resets at midnight UTC
+type DailyQuota struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+	day   time.Time
+}
+
+func NewDailyQuota(limit int) *DailyQuota {
+	return &DailyQuota{
+		limit: limit,
+		day:   today(),
+	}
+}
+
+// Take counts one call against the quota, returning false when the quota is used up
+func (q *DailyQuota) Take() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.resetIfNewDay()
+	if q.used >= q.limit {
+		return false
+	}
+
+	q.used++
+	return true
+}
+
+// Exhaust marks the quota as used up until the next reset, used when the provider reports it first
+func (q *DailyQuota) Exhaust() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.resetIfNewDay()
+	q.used = q.limit
+}
+
+// Remaining returns the number of calls left today
+func (q *DailyQuota) Remaining() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.resetIfNewDay()
+	return q.limit - q.used
+}
+
+// ResetAt returns when the quota is next reset
+func (q *DailyQuota) ResetAt() time.Time {
+	return today().Add(24 * time.Hour)
+}
+
+func (q *DailyQuota) resetIfNewDay() {
+	if current := today(); current.After(q.day) {
+		q.day = current
+		q.used = 0
+	}
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import "context"
+
+const (
+	portfolioKey key = "portfolio"
+)
+
+// WithPortfolioID stores the ID of the portfolio a request is scoped to
+func WithPortfolioID(ctx context.Context, portfolioID string) context.Context {
+	return context.WithValue(ctx, portfolioKey, portfolioID)
+}
+
+// PortfolioID returns the ID of the portfolio a request is scoped to, empty when unscoped
+func PortfolioID(ctx context.Context) string {
+	portfolioID, _ := ctx.Value(portfolioKey).(string)
+	return portfolioID
+}
@@ -0,0 +1,248 @@
+package montecarlo
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ecetinerdem/forseer/risk"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// MinObservations is the fewest historical daily returns a projection draws from
+const MinObservations = 60
+
+// daysPerMonth is the number of daily returns drawn for each simulated month
+const daysPerMonth = risk.TradingDays / 12
+
+// blockSize is the number of paths drawn from one random stream. Blocks are shared out to the workers,
+// the stream of a block only depends on the seed so results do not depend on the number of workers.
+const blockSize = 250
+
+// Options configure a projection
+type Options struct {
+	Years        int
+	Paths        int
+	Method       types.ProjectionMethod
+	Contribution float64                  // Added at the end of every contribution period
+	Frequency    types.RebalanceFrequency // Of the contributions: monthly, quarterly or annually
+	Seed         int64
+	Target       *float64 // Optional value whose probability of being reached is measured
+	Workers      int      // Defaults to the number of CPUs
+}
+
+// Project simulates paths of a value compounding daily returns drawn from the historical ones and
+// returns the percentile bands of the paths at the end of every month from start.
+// It returns ctx's error when cancelled.
+func Project(ctx context.Context, value float64, returns []float64, start time.Time, opts Options) (*types.ProjectionReport, error) {
+	if len(returns) < MinObservations {
+		return nil, &types.InsufficientHistoryError{Observations: len(returns), Required: MinObservations}
+	}
+
+	report := &types.ProjectionReport{
+		Method:       opts.Method,
+		Paths:        opts.Paths,
+		Years:        opts.Years,
+		Seed:         opts.Seed,
+		StartValue:   value,
+		Contribution: opts.Contribution,
+		Frequency:    opts.Frequency,
+		Observations: len(returns),
+		Target:       opts.Target,
+	}
+
+	avg, sd := meanStddev(returns)
+	report.ExpectedReturnPct = (math.Pow(1+avg, risk.TradingDays) - 1) * 100
+	report.VolatilityPct = sd * math.Sqrt(risk.TradingDays) * 100
+
+	months := opts.Years * 12
+	every := contributionMonths(opts.Frequency)
+	draw := drawer(opts.Method, returns)
+
+	// values[m][p] is the value of path p at the end of month m+1
+	values := make([][]float64, months)
+	for m := range values {
+		values[m] = make([]float64, opts.Paths)
+	}
+
+	simulate := func(block int) {
+		rng := rand.New(rand.NewPCG(uint64(opts.Seed), uint64(block)))
+		for p := block * blockSize; p < min((block+1)*blockSize, opts.Paths); p++ {
+			v := value
+			for m := range months {
+				for range daysPerMonth {
+					v *= draw(rng)
+				}
+				if (m+1)%every == 0 {
+					v += opts.Contribution
+				}
+				values[m][p] = v
+			}
+		}
+	}
+
+	if err := runBlocks(ctx, (opts.Paths+blockSize-1)/blockSize, opts.Workers, simulate); err != nil {
+		return nil, err
+	}
+
+	contributed := value
+	report.Bands = make([]types.ProjectionBand, months)
+	for m := range months {
+		if (m+1)%every == 0 {
+			contributed += opts.Contribution
+		}
+
+		sorted := values[m]
+		sort.Float64s(sorted)
+		report.Bands[m] = types.ProjectionBand{
+			Month:       m + 1,
+			Date:        start.AddDate(0, m+1, 0),
+			Contributed: contributed,
+			P5:          percentile(sorted, 0.05),
+			P25:         percentile(sorted, 0.25),
+			P50:         percentile(sorted, 0.50),
+			P75:         percentile(sorted, 0.75),
+			P95:         percentile(sorted, 0.95),
+			Mean:        mean(sorted),
+		}
+	}
+
+	if months > 0 {
+		final := values[months-1]
+		report.LossProbabilityPct = share(final, func(v float64) bool { return v < contributed }) * 100
+		if opts.Target != nil {
+			probability := share(final, func(v float64) bool { return v >= *opts.Target }) * 100
+			report.TargetProbabilityPct = &probability
+		}
+	}
+
+	return report, nil
+}
+
+// runBlocks runs simulate for every block, on a pool of workers when there is more than one block
+func runBlocks(ctx context.Context, blocks, workers int, simulate func(block int)) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, blocks)
+
+	if workers <= 1 {
+		for block := range blocks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			simulate(block)
+		}
+		return ctx.Err()
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for block := range queue {
+				simulate(block)
+			}
+		}()
+	}
+
+	for block := 0; block < blocks && ctx.Err() == nil; block++ {
+		select {
+		case queue <- block:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// drawer returns a function drawing the growth factor of one simulated day
+func drawer(method types.ProjectionMethod, returns []float64) func(rng *rand.Rand) float64 {
+	if method == types.ProjectionNormal {
+		logs := make([]float64, len(returns))
+		for i, r := range returns {
+			logs[i] = math.Log1p(r)
+		}
+		mu, sigma := meanStddev(logs)
+		return func(rng *rand.Rand) float64 {
+			return math.Exp(mu + sigma*rng.NormFloat64())
+		}
+	}
+
+	growth := make([]float64, len(returns))
+	for i, r := range returns {
+		growth[i] = 1 + r
+	}
+	return func(rng *rand.Rand) float64 {
+		return growth[rng.IntN(len(growth))]
+	}
+}
+
+// contributionMonths is the number of months between contributions
+func contributionMonths(frequency types.RebalanceFrequency) int {
+	switch frequency {
+	case types.RebalanceQuarterly:
+		return 3
+	case types.RebalanceAnnually:
+		return 12
+	}
+	return 1
+}
+
+// percentile interpolates the q quantile of sorted values
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+func share(values []float64, match func(float64) bool) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	count := 0
+	for _, v := range values {
+		if match(v) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(values))
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// meanStddev returns the mean and sample standard deviation of values
+func meanStddev(values []float64) (float64, float64) {
+	avg := mean(values)
+	if len(values) < 2 {
+		return avg, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += (v - avg) * (v - avg)
+	}
+	return avg, math.Sqrt(sum / float64(len(values)-1))
+}
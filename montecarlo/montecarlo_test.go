@@ -0,0 +1,108 @@
+package montecarlo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// testReturns is a deterministic history of daily returns around a small positive drift
+func testReturns(n int) []float64 {
+	returns := make([]float64, n)
+	for i := range returns {
+		returns[i] = 0.0003 + 0.01*math.Sin(float64(i))
+	}
+	return returns
+}
+
+func TestProjectSameBandsForAnyWorkerCount(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	returns := testReturns(500)
+	target := 15000.0
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"bootstrap", Options{Years: 5, Paths: 1000, Method: types.ProjectionBootstrap, Frequency: types.RebalanceMonthly, Seed: 42}},
+		{"normal", Options{Years: 5, Paths: 1000, Method: types.ProjectionNormal, Frequency: types.RebalanceMonthly, Seed: 42}},
+		{"contributions and target", Options{Years: 3, Paths: 777, Method: types.ProjectionBootstrap, Frequency: types.RebalanceQuarterly, Contribution: 100, Seed: -7, Target: &target}},
+		{"fewer paths than a block", Options{Years: 2, Paths: 100, Method: types.ProjectionBootstrap, Frequency: types.RebalanceAnnually, Seed: 1 << 53}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Workers = 1
+			want, err := Project(context.Background(), 10000, returns, start, opts)
+			if err != nil {
+				t.Fatalf("Project with 1 worker: %v", err)
+			}
+
+			for _, workers := range []int{2, 3, 8} {
+				opts.Workers = workers
+				got, err := Project(context.Background(), 10000, returns, start, opts)
+				if err != nil {
+					t.Fatalf("Project with %d workers: %v", workers, err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Project with %d workers differs from 1 worker", workers)
+				}
+			}
+		})
+	}
+}
+
+func TestProjectSeedChangesBands(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	returns := testReturns(500)
+	opts := Options{Years: 1, Paths: 500, Method: types.ProjectionBootstrap, Frequency: types.RebalanceMonthly, Workers: 2}
+
+	opts.Seed = 1
+	first, err := Project(context.Background(), 10000, returns, start, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Seed = 2
+	second, err := Project(context.Background(), 10000, returns, start, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reflect.DeepEqual(first.Bands, second.Bands) {
+		t.Error("different seeds gave the same bands")
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		returns []float64
+		check   func(error) bool
+	}{
+		{"too little history", context.Background(), testReturns(MinObservations - 1), func(err error) bool {
+			var historyErr *types.InsufficientHistoryError
+			return errors.As(err, &historyErr) && historyErr.Required == MinObservations
+		}},
+		{"cancelled", cancelled, testReturns(500), func(err error) bool { return errors.Is(err, context.Canceled) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Years: 10, Paths: 10000, Method: types.ProjectionBootstrap, Frequency: types.RebalanceMonthly, Workers: 2}
+			_, err := Project(tt.ctx, 10000, tt.returns, time.Now(), opts)
+			if !tt.check(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package performance
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/ledger"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+const day = 24 * time.Hour
+
+// Series values the portfolio at the close of every day it has a transaction or a holding has a bar, up to and
+// including the to date (open when zero). Holdings are valued at their latest close, or at their latest trade
+// price before their first bar. Sell proceeds and dividends stay in the portfolio as cash, deposits and
+// withdrawals are the money flows. A buy or fee the cash does not cover counts as a deposit of the shortfall,
+// so ledgers that only record trades are measured against the money put into them.
+func Series(transactions []types.Transaction, history map[string][]types.PriceBar, to time.Time) []types.PerformancePoint {
+	ordered := append([]types.Transaction(nil), transactions...)
+	ledger.Sort(ordered)
+
+	dates := make(map[time.Time]bool)
+	for _, tx := range ordered {
+		dates[dateOf(tx.TradeDate)] = true
+	}
+	if len(dates) == 0 {
+		return nil
+	}
+	first := dateOf(ordered[0].TradeDate)
+	for _, bars := range history {
+		for _, bar := range bars {
+			if date := dateOf(bar.Date); !date.Before(first) {
+				dates[date] = true
+			}
+		}
+	}
+
+	timeline := make([]time.Time, 0, len(dates))
+	for date := range dates {
+		if to.IsZero() || !date.After(to) {
+			timeline = append(timeline, date)
+		}
+	}
+	sort.Slice(timeline, func(i, j int) bool { return timeline[i].Before(timeline[j]) })
+
+	quantities := make(map[string]float64)
+	prices := make(map[string]float64)
+	nextBar := make(map[string]int)
+	cash := 0.0
+	next := 0
+
+	points := make([]types.PerformancePoint, 0, len(timeline))
+	for _, date := range timeline {
+		flow := 0.0
+
+		// Cash spent beyond the balance is money brought into the portfolio
+		spend := func(amount float64) {
+			cash -= amount
+			if cash < 0 {
+				flow -= cash
+				cash = 0
+			}
+		}
+
+		for ; next < len(ordered) && !dateOf(ordered[next].TradeDate).After(date); next++ {
+			tx := ordered[next]
+			switch tx.Type {
+			case types.TransactionBuy:
+				quantities[tx.Symbol] += tx.Quantity
+				prices[tx.Symbol] = tx.Price
+				spend(tx.Quantity*tx.Price + tx.Fees)
+			case types.TransactionSell:
+				quantities[tx.Symbol] -= tx.Quantity
+				prices[tx.Symbol] = tx.Price
+				cash += tx.Quantity*tx.Price - tx.Fees
+				if cash < 0 {
+					spend(0)
+				}
+			case types.TransactionSplit:
+				quantities[tx.Symbol] *= tx.SplitRatio
+				prices[tx.Symbol] /= tx.SplitRatio
+			case types.TransactionDividend:
+				cash += tx.Amount
+			case types.TransactionFee:
+				spend(tx.Amount)
+			case types.TransactionDeposit:
+				cash += tx.Amount
+				flow += tx.Amount
+			case types.TransactionWithdrawal:
+				// Withdrawing more than the balance takes the shortfall out of money brought in the same way
+				cash -= tx.Amount
+				flow -= tx.Amount
+				if cash < 0 {
+					flow -= cash
+					cash = 0
+				}
+			}
+		}
+
+		value := cash
+		for symbol, quantity := range quantities {
+			bars := history[symbol]
+			i := nextBar[symbol]
+			for ; i < len(bars) && !dateOf(bars[i].Date).After(date); i++ {
+				prices[symbol] = bars[i].Close
+			}
+			nextBar[symbol] = i
+			value += quantity * prices[symbol]
+		}
+
+		points = append(points, types.PerformancePoint{Date: date, Value: value, NetFlow: flow})
+	}
+
+	for i := range points {
+		previous := 0.0
+		if i > 0 {
+			previous = points[i-1].Value
+		}
+		// Flows are invested at the start of the day
+		if invested := previous + points[i].NetFlow; invested > 0 {
+			points[i].Return = points[i].Value/invested - 1
+		}
+	}
+
+	return points
+}
+
+// Compute measures the returns of a ledger between from and to, either bound may be zero to leave it open.
+// The value at the close before from is treated as invested at from. A ledger without values in the period
+// returns a *types.NoPerformanceError.
+func Compute(transactions []types.Transaction, history map[string][]types.PriceBar, from, to time.Time) (*types.PerformanceReport, error) {
+	series := Series(transactions, history, to)
+	if len(series) == 0 {
+		return nil, &types.NoPerformanceError{Reason: "portfolio has no transactions"}
+	}
+
+	from = dateOf(from)
+	if from.IsZero() || from.Before(series[0].Date) {
+		from = series[0].Date
+	}
+
+	report := &types.PerformanceReport{From: from}
+
+	start := sort.Search(len(series), func(i int) bool { return !series[i].Date.Before(from) })
+	if start == len(series) {
+		return nil, &types.NoPerformanceError{Reason: fmt.Sprintf("no portfolio values after %s", from.Format(time.DateOnly))}
+	}
+	if start > 0 {
+		report.StartValue = series[start-1].Value
+	}
+	inRange := series[start:]
+
+	end := inRange[len(inRange)-1]
+	report.To = end.Date
+	report.EndValue = end.Value
+	report.Days = int(end.Date.Sub(from) / day)
+
+	growth := 1.0
+	flows := []CashFlow{{Date: from, Amount: -report.StartValue}}
+	for _, point := range inRange {
+		growth *= 1 + point.Return
+		report.NetContributions += point.NetFlow
+		if point.NetFlow != 0 {
+			flows = append(flows, CashFlow{Date: point.Date, Amount: -point.NetFlow})
+		}
+	}
+	flows = append(flows, CashFlow{Date: end.Date, Amount: end.Value})
+
+	report.Gain = report.EndValue - report.StartValue - report.NetContributions
+	report.TimeWeightedReturnPct = (growth - 1) * 100
+	report.AnnualizedTimeWeightedReturnPct = Annualize(growth-1, report.Days) * 100
+
+	if rate, err := XIRR(flows); err == nil {
+		annualized := rate * 100
+		cumulative := (math.Pow(1+rate, float64(report.Days)/365) - 1) * 100
+		report.AnnualizedMoneyWeightedReturnPct = &annualized
+		report.MoneyWeightedReturnPct = &cumulative
+	}
+
+	for _, tx := range transactions {
+		date := dateOf(tx.TradeDate)
+		if date.Before(from) || date.After(end.Date) {
+			continue
+		}
+		switch tx.Type {
+		case types.TransactionDividend:
+			report.Income += tx.Amount
+		case types.TransactionFee:
+			report.Fees += tx.Amount
+		case types.TransactionBuy, types.TransactionSell:
+			report.Fees += tx.Fees
+		}
+	}
+
+	report.Series = inRange
+	return report, nil
+}
+
+// Annualize converts a cumulative return over a number of days, as a fraction, to a yearly rate
+func Annualize(cumulative float64, days int) float64 {
+	if days <= 0 || cumulative <= -1 {
+		return cumulative
+	}
+	return math.Pow(1+cumulative, 365/float64(days)) - 1
+}
+
+func dateOf(t time.Time) time.Time {
+	return t.UTC().Truncate(day)
+}
@@ -0,0 +1,261 @@
+package performance
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) < 1e-6
+}
+
+func TestXIRR(t *testing.T) {
+	tests := []struct {
+		name    string
+		flows   []CashFlow
+		want    float64
+		wantErr bool
+	}{
+		{
+			name:  "one year",
+			flows: []CashFlow{{date(2021, 1, 1), -1000}, {date(2022, 1, 1), 1100}},
+			want:  0.1,
+		},
+		{
+			name: "spreadsheet example",
+			flows: []CashFlow{
+				{date(2008, 1, 1), -10000},
+				{date(2008, 3, 1), 2750},
+				{date(2008, 10, 30), 4250},
+				{date(2009, 2, 15), 3250},
+				{date(2009, 4, 1), 2750},
+			},
+			want: 0.3733625335,
+		},
+		{
+			name:  "flows out of order",
+			flows: []CashFlow{{date(2023, 1, 1), 1210}, {date(2021, 1, 1), -1000}},
+			want:  0.1,
+		},
+		{
+			// Newton's first step lands below -100%, bisection finds the rate
+			name:  "near total loss",
+			flows: []CashFlow{{date(2021, 1, 1), -1000}, {date(2022, 1, 1), 10}},
+			want:  -0.99,
+		},
+		{
+			name:  "large gain",
+			flows: []CashFlow{{date(2021, 1, 1), -1}, {date(2022, 1, 1), 1000}},
+			want:  999,
+		},
+		{
+			name:    "only payments",
+			flows:   []CashFlow{{date(2021, 1, 1), -1000}, {date(2022, 1, 1), -100}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := XIRR(tt.flows)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got rate %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !closeTo(got, tt.want) {
+				t.Errorf("rate %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	buy := func(on time.Time, quantity, price, fees float64) types.Transaction {
+		return types.Transaction{Type: types.TransactionBuy, Symbol: "AAPL", Quantity: quantity, Price: price, Fees: fees, TradeDate: on}
+	}
+	sell := func(on time.Time, quantity, price float64) types.Transaction {
+		return types.Transaction{Type: types.TransactionSell, Symbol: "AAPL", Quantity: quantity, Price: price, TradeDate: on}
+	}
+	deposit := func(on time.Time, amount float64) types.Transaction {
+		return types.Transaction{Type: types.TransactionDeposit, Amount: amount, TradeDate: on}
+	}
+	bars := func(closes map[time.Time]float64) map[string][]types.PriceBar {
+		var series []types.PriceBar
+		for _, on := range []time.Time{date(2021, 1, 1), date(2022, 1, 1), date(2022, 1, 2), date(2023, 1, 1)} {
+			if price, ok := closes[on]; ok {
+				series = append(series, types.PriceBar{Symbol: "AAPL", Date: on, Close: price})
+			}
+		}
+		return map[string][]types.PriceBar{"AAPL": series}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []types.Transaction
+		history      map[string][]types.PriceBar
+		from         time.Time
+
+		wantStart, wantEnd, wantContributions, wantGain, wantFees float64
+		wantDays                                                  int
+		wantTWR, wantMWR                                          float64
+	}{
+		{
+			name:              "buy without a deposit",
+			transactions:      []types.Transaction{buy(date(2021, 1, 1), 10, 100, 0)},
+			history:           bars(map[time.Time]float64{date(2022, 1, 1): 110, date(2023, 1, 1): 121}),
+			wantEnd:           1210,
+			wantContributions: 1000,
+			wantGain:          210,
+			wantDays:          730,
+			wantTWR:           21,
+			wantMWR:           21,
+		},
+		{
+			name:              "fees are part of the shortfall",
+			transactions:      []types.Transaction{buy(date(2021, 1, 1), 10, 100, 10)},
+			history:           bars(map[time.Time]float64{date(2022, 1, 1): 111}),
+			wantEnd:           1110,
+			wantContributions: 1010,
+			wantGain:          100,
+			wantFees:          10,
+			wantDays:          365,
+			wantTWR:           1110.0/1010*100 - 100,
+			wantMWR:           1110.0/1010*100 - 100,
+		},
+		{
+			name: "sale proceeds pay for the next buy",
+			transactions: []types.Transaction{
+				buy(date(2021, 1, 1), 10, 100, 0),
+				sell(date(2022, 1, 1), 10, 110),
+				buy(date(2022, 1, 2), 5, 200, 0),
+			},
+			history:           bars(map[time.Time]float64{date(2023, 1, 1): 220}),
+			wantEnd:           1200,
+			wantContributions: 1000,
+			wantGain:          200,
+			wantDays:          730,
+			wantTWR:           20,
+			wantMWR:           20,
+		},
+		{
+			// The second deposit misses the first year's gain and takes the second year's loss
+			name: "mid-period deposit",
+			transactions: []types.Transaction{
+				deposit(date(2021, 1, 1), 1000),
+				buy(date(2021, 1, 1), 10, 100, 0),
+				deposit(date(2022, 1, 2), 1000),
+			},
+			history:           bars(map[time.Time]float64{date(2022, 1, 1): 110, date(2023, 1, 1): 99}),
+			wantEnd:           1990,
+			wantContributions: 2000,
+			wantGain:          -10,
+			wantDays:          730,
+			wantTWR:           (1.1*1990/2100 - 1) * 100,
+			wantMWR:           -0.6669038379,
+		},
+		{
+			// The split halves the price before the first bar, so the value does not drop
+			name: "split",
+			transactions: []types.Transaction{
+				buy(date(2021, 1, 1), 10, 100, 0),
+				{Type: types.TransactionSplit, Symbol: "AAPL", SplitRatio: 2, TradeDate: date(2022, 1, 1)},
+			},
+			history:           bars(map[time.Time]float64{date(2023, 1, 1): 55}),
+			wantEnd:           1100,
+			wantContributions: 1000,
+			wantGain:          100,
+			wantDays:          730,
+			wantTWR:           10,
+			wantMWR:           10,
+		},
+		{
+			// The close before from is the starting investment
+			name:         "from inside the series",
+			transactions: []types.Transaction{buy(date(2021, 1, 1), 10, 100, 0)},
+			history:      bars(map[time.Time]float64{date(2022, 1, 1): 110, date(2023, 1, 1): 121}),
+			from:         date(2022, 1, 2),
+			wantStart:    1100,
+			wantEnd:      1210,
+			wantGain:     110,
+			wantDays:     364,
+			wantTWR:      10,
+			wantMWR:      10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Compute(tt.transactions, tt.history, tt.from, time.Time{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			checks := []struct {
+				name      string
+				got, want float64
+			}{
+				{"start value", report.StartValue, tt.wantStart},
+				{"end value", report.EndValue, tt.wantEnd},
+				{"net contributions", report.NetContributions, tt.wantContributions},
+				{"gain", report.Gain, tt.wantGain},
+				{"fees", report.Fees, tt.wantFees},
+				{"time weighted return", report.TimeWeightedReturnPct, tt.wantTWR},
+			}
+			for _, check := range checks {
+				if !closeTo(check.got, check.want) {
+					t.Errorf("%s %v, want %v", check.name, check.got, check.want)
+				}
+			}
+
+			if report.Days != tt.wantDays {
+				t.Errorf("days %d, want %d", report.Days, tt.wantDays)
+			}
+			if report.MoneyWeightedReturnPct == nil {
+				t.Fatalf("money weighted return missing")
+			}
+			if !closeTo(*report.MoneyWeightedReturnPct, tt.wantMWR) {
+				t.Errorf("money weighted return %v, want %v", *report.MoneyWeightedReturnPct, tt.wantMWR)
+			}
+			if want := Annualize(tt.wantTWR/100, tt.wantDays) * 100; !closeTo(report.AnnualizedTimeWeightedReturnPct, want) {
+				t.Errorf("annualized time weighted return %v, want %v", report.AnnualizedTimeWeightedReturnPct, want)
+			}
+		})
+	}
+}
+
+func TestComputeNothingToMeasure(t *testing.T) {
+	transactions := []types.Transaction{{Type: types.TransactionDeposit, Amount: 1000, TradeDate: date(2021, 1, 1)}}
+
+	tests := []struct {
+		name         string
+		transactions []types.Transaction
+		from, to     time.Time
+	}{
+		{name: "empty ledger"},
+		{name: "from after the last value", transactions: transactions, from: date(2021, 1, 2)},
+		{name: "to before the first value", transactions: transactions, to: date(2020, 12, 31)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compute(tt.transactions, nil, tt.from, tt.to)
+			var noPerformance *types.NoPerformanceError
+			if !errors.As(err, &noPerformance) {
+				t.Errorf("expected a NoPerformanceError, got %v", err)
+			}
+		})
+	}
+}
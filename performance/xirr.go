@@ -0,0 +1,90 @@
+package performance
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CashFlow is money paid (negative) or received (positive) by the investor
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// XIRR returns the annual rate at which the cash flows have a net present value of zero, as a fraction.
+// Newton's method is tried first and bisection is the fallback when it does not converge.
+func XIRR(flows []CashFlow) (float64, error) {
+	var hasIn, hasOut bool
+	for _, flow := range flows {
+		hasIn = hasIn || flow.Amount > 0
+		hasOut = hasOut || flow.Amount < 0
+	}
+	if !hasIn || !hasOut {
+		return 0, fmt.Errorf("cash flows need both a payment and a receipt")
+	}
+
+	first := flows[0].Date
+	for _, flow := range flows {
+		if flow.Date.Before(first) {
+			first = flow.Date
+		}
+	}
+
+	years := make([]float64, len(flows))
+	for i, flow := range flows {
+		years[i] = flow.Date.Sub(first).Hours() / 24 / 365
+	}
+
+	npv := func(rate float64) (value, derivative float64) {
+		for i, flow := range flows {
+			discount := math.Pow(1+rate, years[i])
+			value += flow.Amount / discount
+			derivative -= years[i] * flow.Amount / (discount * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	rate := 0.1
+	for range 100 {
+		value, derivative := npv(rate)
+		if derivative == 0 || math.IsNaN(value) {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-10 {
+			return next, nil
+		}
+		rate = next
+	}
+
+	// Bisection between a total loss and a bound grown until the sign changes
+	low, high := -0.999999, 1.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	for lowValue*highValue > 0 {
+		if high > 1e6 {
+			return 0, fmt.Errorf("cash flows have no rate of return")
+		}
+		high *= 10
+		highValue, _ = npv(high)
+	}
+
+	for range 200 {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if math.Abs(midValue) < 1e-9 || high-low < 1e-12 {
+			return mid, nil
+		}
+		if lowValue*midValue < 0 {
+			high = mid
+		} else {
+			low, lowValue = mid, midValue
+		}
+	}
+
+	return (low + high) / 2, nil
+}
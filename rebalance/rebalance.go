@@ -0,0 +1,302 @@
+package rebalance
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// DefaultTolerance is the band of targets set without one
+const DefaultTolerance = 0.05
+
+// Weights of a target set may miss 1 by this much
+const weightSlack = 0.0001
+
+// Holding is a position the plan can trade, Sector and AssetClass come from the symbol's classification
+type Holding struct {
+	Symbol     string
+	Quantity   float64
+	Price      float64
+	Sector     string
+	AssetClass string
+}
+
+// Options tune a plan
+type Options struct {
+	Cash          float64 // Available to buy with before any sell
+	MinTradeValue float64 // Smaller trades are skipped
+	NoSells       bool    // Only buy with the available cash, e.g. to avoid realizing gains
+	WholeShares   bool    // Round quantities down to whole shares
+}
+
+// Targets validates a set of targets, which must share one kind, and returns them ready to be saved.
+// Symbols are upper cased and a missing tolerance is DefaultTolerance.
+func Targets(inputs []types.AllocationTargetInput) ([]types.AllocationTarget, error) {
+	if len(inputs) == 0 {
+		return []types.AllocationTarget{}, nil
+	}
+
+	kind := inputs[0].Kind
+	if !kind.IsValid() {
+		return nil, fmt.Errorf("invalid target kind %q, expected symbol, sector or asset_class", kind)
+	}
+
+	seen := make(map[string]bool, len(inputs))
+	targets := make([]types.AllocationTarget, 0, len(inputs))
+	var total float64
+
+	for _, input := range inputs {
+		if input.Kind != kind {
+			return nil, fmt.Errorf("all targets must have the same kind, found %s and %s", kind, input.Kind)
+		}
+
+		key := strings.TrimSpace(input.Key)
+		if kind == types.AllocationSymbol {
+			key = strings.ToUpper(key)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("target key cannot be empty")
+		}
+		if seen[strings.ToLower(key)] {
+			return nil, fmt.Errorf("target %s is listed twice", key)
+		}
+		seen[strings.ToLower(key)] = true
+
+		if input.Weight < 0 || input.Weight > 1 {
+			return nil, fmt.Errorf("weight of %s must be between 0 and 1", key)
+		}
+
+		tolerance := DefaultTolerance
+		if input.Tolerance != nil {
+			tolerance = *input.Tolerance
+		}
+		if tolerance < 0 || tolerance >= 1 {
+			return nil, fmt.Errorf("tolerance of %s must be between 0 and 1", key)
+		}
+
+		total += input.Weight
+		targets = append(targets, types.AllocationTarget{Kind: kind, Key: key, Weight: input.Weight, Tolerance: tolerance})
+	}
+
+	if math.Abs(total-1) > weightSlack {
+		return nil, fmt.Errorf("target weights must sum to 1, they sum to %g", total)
+	}
+
+	return targets, nil
+}
+
+// group is the holdings an allocation covers
+type group struct {
+	key      string
+	target   float64
+	tol      float64
+	targeted bool
+	holdings []*Holding
+	value    float64
+	change   float64 // Value to buy (positive) or sell (negative)
+	traded   float64 // Value actually bought or sold
+}
+
+// Plan proposes the trades that bring every allocation drifting outside its band back to its target.
+// Holdings not covered by a target have a target of zero. Unclassified holdings are left alone under sector or
+// asset class targets, weights are measured against the classified value and cash. Symbol targets the portfolio
+// does not hold are bought at their price in prices.
+func Plan(holdings []Holding, targets []types.AllocationTarget, prices map[string]float64, opts Options) *types.RebalancePlan {
+	plan := &types.RebalancePlan{
+		Cash:          opts.Cash,
+		NoSells:       opts.NoSells,
+		MinTradeValue: opts.MinTradeValue,
+		Allocations:   []types.AllocationDrift{},
+		Trades:        []types.RebalanceTrade{},
+		Skipped:       []types.RebalanceTrade{},
+		Notes:         []string{},
+	}
+	if len(targets) == 0 {
+		return plan
+	}
+	plan.Kind = targets[0].Kind
+
+	groups := make(map[string]*group)
+	var order []*group
+	for _, target := range targets {
+		g := &group{key: target.Key, target: target.Weight, tol: target.Tolerance, targeted: true}
+		groups[strings.ToLower(target.Key)] = g
+		order = append(order, g)
+	}
+
+	plan.TotalValue = opts.Cash
+	for i := range holdings {
+		h := &holdings[i]
+		value := h.Quantity * h.Price
+
+		// No target can hold unclassified value, it stays out of the weights
+		key := keyOf(plan.Kind, h)
+		if key == "" {
+			plan.Notes = append(plan.Notes, fmt.Sprintf("%s has no %s, classify it to include it", h.Symbol, label(plan.Kind)))
+			continue
+		}
+		plan.TotalValue += value
+
+		g, ok := groups[strings.ToLower(key)]
+		if !ok {
+			g = &group{key: key}
+			groups[strings.ToLower(key)] = g
+			order = append(order, g)
+		}
+		g.holdings = append(g.holdings, h)
+		g.value += value
+	}
+
+	if plan.TotalValue <= 0 {
+		plan.Notes = append(plan.Notes, "portfolio has no value to rebalance")
+		return plan
+	}
+
+	for _, g := range order {
+		drift := g.value/plan.TotalValue - g.target
+		if math.Abs(drift) > g.tol {
+			g.change = g.target*plan.TotalValue - g.value
+		}
+	}
+
+	// Sells first, what they raise funds the buys
+	cash := opts.Cash
+	for _, g := range order {
+		if g.change >= 0 {
+			continue
+		}
+		if opts.NoSells {
+			plan.Notes = append(plan.Notes, fmt.Sprintf("%s is overweight, no sells mode leaves it", g.key))
+			continue
+		}
+		for _, trade := range spread(g, g.change, opts) {
+			cash += keep(plan, g, trade, opts)
+		}
+	}
+
+	var wanted float64
+	for _, g := range order {
+		if g.change > 0 {
+			wanted += g.change
+		}
+	}
+	scale := 1.0
+	if wanted > cash {
+		scale = math.Max(cash, 0) / wanted
+		plan.Notes = append(plan.Notes, fmt.Sprintf("buys are scaled to %.0f%% of what the targets need, the cash available is %.2f", scale*100, math.Max(cash, 0)))
+	}
+
+	for _, g := range order {
+		if g.change <= 0 {
+			continue
+		}
+		if len(g.holdings) == 0 {
+			price := prices[strings.ToUpper(g.key)]
+			if plan.Kind != types.AllocationSymbol || price <= 0 {
+				plan.Notes = append(plan.Notes, fmt.Sprintf("%s is underweight but the portfolio holds nothing in it to buy", g.key))
+				continue
+			}
+			g.holdings = []*Holding{{Symbol: strings.ToUpper(g.key), Price: price}}
+		}
+		for _, trade := range spread(g, g.change*scale, opts) {
+			cash += keep(plan, g, trade, opts)
+		}
+	}
+
+	// Drop the rounding left over from spending exactly what was raised
+	if math.Abs(cash) < 1e-6 {
+		cash = 0
+	}
+	plan.CashAfter = cash
+
+	for _, g := range order {
+		current := g.value / plan.TotalValue
+		plan.Allocations = append(plan.Allocations, types.AllocationDrift{
+			Key:             g.key,
+			TargetWeight:    g.target,
+			Tolerance:       g.tol,
+			CurrentWeight:   current,
+			Drift:           current - g.target,
+			WithinBand:      math.Abs(current-g.target) <= g.tol,
+			ProjectedWeight: (g.value + g.traded) / plan.TotalValue,
+		})
+	}
+	sort.SliceStable(plan.Allocations, func(i, j int) bool {
+		return plan.Allocations[i].TargetWeight > plan.Allocations[j].TargetWeight
+	})
+
+	return plan
+}
+
+// spread splits the value to trade in a group across its holdings in proportion to their value,
+// or evenly when none has a value yet
+func spread(g *group, change float64, opts Options) []types.RebalanceTrade {
+	trades := make([]types.RebalanceTrade, 0, len(g.holdings))
+	for _, h := range g.holdings {
+		if h.Price <= 0 {
+			continue
+		}
+
+		share := 1 / float64(len(g.holdings))
+		if g.value > 0 {
+			share = h.Quantity * h.Price / g.value
+		}
+
+		quantity := math.Abs(change) * share / h.Price
+		side := types.TradeBuy
+		if change < 0 {
+			side = types.TradeSell
+			quantity = math.Min(quantity, h.Quantity)
+		}
+		if opts.WholeShares {
+			quantity = math.Floor(quantity)
+		}
+
+		trades = append(trades, types.RebalanceTrade{
+			Symbol:   h.Symbol,
+			Key:      g.key,
+			Side:     side,
+			Quantity: quantity,
+			Price:    h.Price,
+			Value:    quantity * h.Price,
+		})
+	}
+	return trades
+}
+
+// keep adds a trade to the plan, or to the skipped trades when it is below the minimum size,
+// and returns the cash it raises (negative for buys)
+func keep(plan *types.RebalancePlan, g *group, trade types.RebalanceTrade, opts Options) float64 {
+	if trade.Quantity <= 0 || trade.Value < opts.MinTradeValue {
+		plan.Skipped = append(plan.Skipped, trade)
+		return 0
+	}
+
+	plan.Trades = append(plan.Trades, trade)
+	if trade.Side == types.TradeSell {
+		g.traded -= trade.Value
+		return trade.Value
+	}
+	g.traded += trade.Value
+	return -trade.Value
+}
+
+func keyOf(kind types.AllocationKind, h *Holding) string {
+	switch kind {
+	case types.AllocationSector:
+		return h.Sector
+	case types.AllocationAssetClass:
+		return h.AssetClass
+	}
+	return h.Symbol
+}
+
+func label(kind types.AllocationKind) string {
+	if kind == types.AllocationAssetClass {
+		return "asset class"
+	}
+	return string(kind)
+}
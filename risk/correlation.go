@@ -0,0 +1,178 @@
+package risk
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Pair holds the statistics of two return series on their common dates
+type Pair struct {
+	Observations int
+	Correlation  float64
+	Covariance   float64 // Daily
+	Valid        bool    // False with fewer than two common dates or a constant series
+}
+
+// Correlate measures every pair of the return series, the result is indexed like the series
+func Correlate(series [][]Return) [][]Pair {
+	byDate := make([]map[time.Time]float64, len(series))
+	for i, returns := range series {
+		byDate[i] = make(map[time.Time]float64, len(returns))
+		for _, r := range returns {
+			byDate[i][r.Date] = r.Value
+		}
+	}
+
+	pairs := make([][]Pair, len(series))
+	for i := range pairs {
+		pairs[i] = make([]Pair, len(series))
+	}
+
+	for i := range series {
+		for j := i; j < len(series); j++ {
+			var xs, ys []float64
+			for _, r := range series[i] {
+				if y, ok := byDate[j][r.Date]; ok {
+					xs = append(xs, r.Value)
+					ys = append(ys, y)
+				}
+			}
+			pair := measurePair(xs, ys)
+			pairs[i][j], pairs[j][i] = pair, pair
+		}
+	}
+
+	return pairs
+}
+
+func measurePair(xs, ys []float64) Pair {
+	pair := Pair{Observations: len(xs)}
+	if len(xs) < 2 {
+		return pair
+	}
+
+	mx, my := mean(xs), mean(ys)
+	var sxy, sxx, syy float64
+	for i := range xs {
+		sxy += (xs[i] - mx) * (ys[i] - my)
+		sxx += (xs[i] - mx) * (xs[i] - mx)
+		syy += (ys[i] - my) * (ys[i] - my)
+	}
+
+	pair.Covariance = sxy / float64(len(xs)-1)
+	if sxx == 0 || syy == 0 {
+		return pair
+	}
+
+	// Rounding can push a perfect correlation slightly past 1
+	pair.Correlation = math.Max(-1, math.Min(1, sxy/math.Sqrt(sxx*syy)))
+	pair.Valid = true
+	return pair
+}
+
+// Clusters groups the series linked by a correlation at or above the threshold, directly or through other
+// members. Only groups of at least two are returned, largest first, with members in index order.
+func Clusters(pairs [][]Pair, threshold float64) [][]int {
+	parent := make([]int, len(pairs))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range pairs {
+		for j := i + 1; j < len(pairs); j++ {
+			if pairs[i][j].Valid && pairs[i][j].Correlation >= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	groups := make(map[int][]int)
+	for i := range pairs {
+		root := find(i)
+		groups[root] = append(groups[root], i)
+	}
+
+	var clusters [][]int
+	for _, members := range groups {
+		if len(members) > 1 {
+			clusters = append(clusters, members)
+		}
+	}
+	sort.Slice(clusters, func(a, b int) bool {
+		if len(clusters[a]) != len(clusters[b]) {
+			return len(clusters[a]) > len(clusters[b])
+		}
+		return clusters[a][0] < clusters[b][0]
+	})
+
+	return clusters
+}
+
+// CorrelationReport measures the correlations of holdings' returns and clusters them, the returns and
+// holdings are indexed alike
+func CorrelationReport(holdings []types.Stock, returns [][]Return, threshold float64) *types.CorrelationReport {
+	pairs := Correlate(returns)
+
+	report := &types.CorrelationReport{
+		Threshold:    threshold,
+		Symbols:      make([]string, len(holdings)),
+		Correlation:  make([][]*float64, len(holdings)),
+		Covariance:   make([][]*float64, len(holdings)),
+		Observations: make([][]int, len(holdings)),
+		Clusters:     []types.CorrelationCluster{},
+	}
+
+	for i, stock := range holdings {
+		report.Symbols[i] = stock.Symbol
+		report.Correlation[i] = make([]*float64, len(holdings))
+		report.Covariance[i] = make([]*float64, len(holdings))
+		report.Observations[i] = make([]int, len(holdings))
+
+		for j := range holdings {
+			pair := pairs[i][j]
+			report.Observations[i][j] = pair.Observations
+			if pair.Observations < 2 {
+				continue
+			}
+			covariance := pair.Covariance * TradingDays
+			report.Covariance[i][j] = &covariance
+			if pair.Valid {
+				correlation := pair.Correlation
+				report.Correlation[i][j] = &correlation
+			}
+		}
+	}
+
+	for _, members := range Clusters(pairs, threshold) {
+		cluster := types.CorrelationCluster{}
+		var sum float64
+		var count int
+		for a, i := range members {
+			cluster.Symbols = append(cluster.Symbols, holdings[i].Symbol)
+			cluster.Weight += holdings[i].Weight
+			for _, j := range members[a+1:] {
+				if pairs[i][j].Valid {
+					sum += pairs[i][j].Correlation
+					count++
+				}
+			}
+		}
+		if count > 0 {
+			cluster.AverageCorrelation = sum / float64(count)
+		}
+		report.Clusters = append(report.Clusters, cluster)
+	}
+
+	return report
+}
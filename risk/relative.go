@@ -0,0 +1,94 @@
+package risk
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/performance"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Align pairs the returns with the benchmark returns on the benchmark's dates. Returns on dates the benchmark
+// does not have, like a trade recorded on a weekend, are compounded into the next benchmark date.
+func Align(returns, benchmark []Return) (aligned, benchmarkAligned []Return) {
+	byDate := make(map[time.Time]float64, len(benchmark))
+	for _, r := range benchmark {
+		byDate[r.Date] = r.Value
+	}
+
+	ordered := append([]Return(nil), returns...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Date.Before(ordered[j].Date) })
+
+	growth, compounding := 1.0, false
+	var from time.Time
+	for _, r := range ordered {
+		if !compounding {
+			from, compounding = r.From, true
+		}
+		growth *= 1 + r.Value
+		b, ok := byDate[r.Date]
+		if !ok {
+			continue
+		}
+		aligned = append(aligned, Return{Date: r.Date, From: from, Value: growth - 1})
+		benchmarkAligned = append(benchmarkAligned, Return{Date: r.Date, From: from, Value: b})
+		growth, compounding = 1, false
+	}
+
+	return aligned, benchmarkAligned
+}
+
+// Compare measures returns against benchmark returns on their common dates, see Align.
+// The from date starts the period used to annualize the cumulative returns.
+func Compare(returns, benchmark []Return, from time.Time, opts Options) types.BenchmarkComparison {
+	portfolio, bench := Align(returns, benchmark)
+	comparison := types.BenchmarkComparison{From: from, Observations: len(portfolio)}
+	if len(portfolio) == 0 {
+		return comparison
+	}
+
+	comparison.To = portfolio[len(portfolio)-1].Date
+	if from.IsZero() {
+		comparison.From = portfolio[0].Date
+	}
+
+	active := make([]float64, len(portfolio))
+	portfolioValues := make([]float64, len(portfolio))
+	benchmarkValues := make([]float64, len(portfolio))
+	portfolioGrowth, benchmarkGrowth := 1.0, 1.0
+	comparison.Series = make([]types.BenchmarkPoint, len(portfolio))
+
+	for i := range portfolio {
+		portfolioValues[i], benchmarkValues[i] = portfolio[i].Value, bench[i].Value
+		active[i] = portfolio[i].Value - bench[i].Value
+		portfolioGrowth *= 1 + portfolio[i].Value
+		benchmarkGrowth *= 1 + bench[i].Value
+		comparison.Series[i] = types.BenchmarkPoint{
+			Date:            portfolio[i].Date,
+			PortfolioReturn: portfolioGrowth - 1,
+			BenchmarkReturn: benchmarkGrowth - 1,
+		}
+	}
+
+	days := int(comparison.To.Sub(comparison.From).Hours() / 24)
+	comparison.PortfolioReturnPct = (portfolioGrowth - 1) * 100
+	comparison.BenchmarkReturnPct = (benchmarkGrowth - 1) * 100
+	comparison.ExcessReturnPct = comparison.PortfolioReturnPct - comparison.BenchmarkReturnPct
+	comparison.PortfolioAnnualizedReturnPct = performance.Annualize(portfolioGrowth-1, days) * 100
+	comparison.BenchmarkAnnualizedReturnPct = performance.Annualize(benchmarkGrowth-1, days) * 100
+
+	comparison.Beta = Beta(portfolio, bench)
+	if comparison.Beta != nil {
+		dailyRiskFree := opts.RiskFreeRate / TradingDays
+		alpha := mean(portfolioValues) - dailyRiskFree - *comparison.Beta*(mean(benchmarkValues)-dailyRiskFree)
+		comparison.AlphaPct = alpha * TradingDays * 100
+	}
+
+	if trackingError := stddev(active) * math.Sqrt(TradingDays); trackingError > 0 {
+		comparison.TrackingErrorPct = trackingError * 100
+		comparison.InformationRatio = mean(active) * TradingDays / trackingError
+	}
+
+	return comparison
+}
@@ -0,0 +1,242 @@
+package risk
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// TradingDays annualizes daily figures
+const TradingDays = 252
+
+// Return is the return of one day as a fraction, 0.01 is 1%
+type Return struct {
+	Date  time.Time
+	From  time.Time // Date of the close the return is measured from, zero when unknown
+	Value float64
+}
+
+// Options configure a measurement
+type Options struct {
+	RiskFreeRate float64 // Annual, as a fraction
+	Confidence   float64 // Of the value at risk, e.g. 0.95
+}
+
+// BarReturns computes daily returns from the adjusted closes of bars ordered by date
+func BarReturns(bars []types.PriceBar) []Return {
+	var returns []Return
+	for i := 1; i < len(bars); i++ {
+		previous, current := closeOf(bars[i-1]), closeOf(bars[i])
+		if previous <= 0 {
+			continue
+		}
+		returns = append(returns, Return{Date: bars[i].Date, From: bars[i-1].Date, Value: current/previous - 1})
+	}
+	return returns
+}
+
+// SeriesReturns takes the daily time-weighted returns of a portfolio value series, skipping its first day
+// which only holds the money put in
+func SeriesReturns(points []types.PerformancePoint) []Return {
+	var returns []Return
+	for i := 1; i < len(points); i++ {
+		returns = append(returns, Return{Date: points[i].Date, From: points[i-1].Date, Value: points[i].Return})
+	}
+	return returns
+}
+
+// Between keeps the returns dated within from and to, either bound may be zero
+func Between(returns []Return, from, to time.Time) []Return {
+	var kept []Return
+	for _, r := range returns {
+		if (from.IsZero() || !r.Date.Before(from)) && (to.IsZero() || !r.Date.After(to)) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// Measure computes the risk of the returns. Beta is measured against the benchmark returns on the dates both
+// have, value at risk amounts are taken of value.
+func Measure(returns, benchmark []Return, value float64, opts Options) types.RiskMetrics {
+	metrics := types.RiskMetrics{Observations: len(returns)}
+	if len(returns) < 2 {
+		metrics.ValueAtRisk.Confidence = opts.Confidence
+		return metrics
+	}
+
+	values := make([]float64, len(returns))
+	for i, r := range returns {
+		values[i] = r.Value
+	}
+
+	avg, sd := mean(values), stddev(values)
+	dailyRiskFree := opts.RiskFreeRate / TradingDays
+
+	metrics.VolatilityPct = sd * math.Sqrt(TradingDays) * 100
+	if sd > 0 {
+		metrics.SharpeRatio = (avg - dailyRiskFree) / sd * math.Sqrt(TradingDays)
+	}
+	if downside := downsideDeviation(values, dailyRiskFree); downside > 0 {
+		metrics.SortinoRatio = (avg - dailyRiskFree) / downside * math.Sqrt(TradingDays)
+	}
+
+	metrics.MaxDrawdown = MaxDrawdown(returns)
+	metrics.Beta = Beta(returns, benchmark)
+	metrics.ValueAtRisk = VaR(values, value, opts.Confidence)
+
+	return metrics
+}
+
+// MaxDrawdown finds the largest fall of the growth of the returns from a running peak,
+// and the first date the peak was regained after it
+func MaxDrawdown(returns []Return) types.Drawdown {
+	var drawdown types.Drawdown
+	if len(returns) == 0 {
+		return drawdown
+	}
+
+	wealth := make([]float64, len(returns))
+	growth := 1.0
+	for i, r := range returns {
+		growth *= 1 + r.Value
+		wealth[i] = growth
+	}
+
+	// The growth starts at the close the first return is measured from
+	peak, peakAt := 1.0, returns[0].From
+	if peakAt.IsZero() {
+		peakAt = returns[0].Date
+	}
+	worstPeak, trough := 0.0, -1
+	for i, w := range wealth {
+		if w >= peak {
+			peak, peakAt = w, returns[i].Date
+			continue
+		}
+		if depth := (peak - w) / peak * 100; depth > drawdown.DepthPct {
+			drawdown = types.Drawdown{DepthPct: depth, PeakAt: peakAt, TroughAt: returns[i].Date}
+			worstPeak, trough = peak, i
+		}
+	}
+
+	if trough < 0 {
+		return drawdown
+	}
+	for i := trough + 1; i < len(wealth); i++ {
+		if wealth[i] >= worstPeak {
+			recovered := returns[i].Date
+			drawdown.RecoveredAt = &recovered
+			break
+		}
+	}
+
+	return drawdown
+}
+
+// Beta is the covariance of the returns with the benchmark over the benchmark's variance,
+// nil with fewer than two common dates
+func Beta(returns, benchmark []Return) *float64 {
+	byDate := make(map[time.Time]float64, len(benchmark))
+	for _, r := range benchmark {
+		byDate[r.Date] = r.Value
+	}
+
+	var xs, ys []float64
+	for _, r := range returns {
+		if b, ok := byDate[r.Date]; ok {
+			xs = append(xs, b)
+			ys = append(ys, r.Value)
+		}
+	}
+	if len(xs) < 2 {
+		return nil
+	}
+
+	mx, my := mean(xs), mean(ys)
+	var covariance, variance float64
+	for i := range xs {
+		covariance += (xs[i] - mx) * (ys[i] - my)
+		variance += (xs[i] - mx) * (xs[i] - mx)
+	}
+	if variance == 0 {
+		return nil
+	}
+
+	beta := covariance / variance
+	return &beta
+}
+
+// VaR computes the one-day historical and parametric value at risk and conditional value at risk
+func VaR(values []float64, value, confidence float64) types.ValueAtRisk {
+	v := types.ValueAtRisk{Confidence: confidence}
+	if len(values) == 0 {
+		return v
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	// Worst returns in the tail, at least one
+	tail := int(math.Floor(float64(len(sorted)) * (1 - confidence)))
+	tail = max(tail, 1)
+	v.HistoricalVaRPct = math.Max(-sorted[tail-1], 0) * 100
+	v.HistoricalCVaRPct = math.Max(-mean(sorted[:tail]), 0) * 100
+
+	avg, sd := mean(values), stddev(values)
+	z := math.Sqrt2 * math.Erfinv(2*confidence-1)
+	density := math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+	v.ParametricVaRPct = math.Max(z*sd-avg, 0) * 100
+	v.ParametricCVaRPct = math.Max(sd*density/(1-confidence)-avg, 0) * 100
+
+	v.HistoricalVaR = value * v.HistoricalVaRPct / 100
+	v.HistoricalCVaR = value * v.HistoricalCVaRPct / 100
+	v.ParametricVaR = value * v.ParametricVaRPct / 100
+	v.ParametricCVaR = value * v.ParametricCVaRPct / 100
+
+	return v
+}
+
+func closeOf(bar types.PriceBar) float64 {
+	if bar.AdjustedClose > 0 {
+		return bar.AdjustedClose
+	}
+	return bar.Close
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stddev is the sample standard deviation
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	avg := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - avg) * (v - avg)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// downsideDeviation only counts returns below the target
+func downsideDeviation(values []float64, target float64) float64 {
+	var sum float64
+	for _, v := range values {
+		if v < target {
+			sum += (v - target) * (v - target)
+		}
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron-like schedule.
+// It accepts the standard five fields (minute hour day-of-month month day-of-week) with
+// *, lists, ranges and steps, the @hourly, @daily, @weekly and @monthly shorthands,
+// and "@every <duration>" for fixed intervals.
+type Schedule struct {
+	spec   string
+	every  time.Duration
+	minute fieldSet
+	hour   fieldSet
+	dom    fieldSet
+	month  fieldSet
+	dow    fieldSet
+	anyDom bool
+	anyDow bool
+}
+
+// fieldSet marks the allowed values of a cron field
+type fieldSet map[int]bool
+
+var shorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule parses a cron-like schedule spec
+func ParseSchedule(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || every < time.Minute {
+			return nil, fmt.Errorf("invalid schedule %q: @every needs a duration of at least 1m", spec)
+		}
+		return &Schedule{spec: spec, every: every}, nil
+	}
+
+	expr := spec
+	if expanded, ok := shorthands[spec]; ok {
+		expr = expanded
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields", spec)
+	}
+
+	s := &Schedule{spec: spec}
+	var err error
+
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q minute: %w", spec, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q hour: %w", spec, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q day of month: %w", spec, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q month: %w", spec, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q day of week: %w", spec, err)
+	}
+
+	// 7 is an alias for Sunday
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.anyDom = fields[2] == "*"
+	s.anyDow = fields[4] == "*"
+
+	return s, nil
+}
+
+// String returns the spec the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time matching the schedule strictly after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	// Any valid schedule matches within a few years, the bound guards against impossible dates like Feb 30
+	limit := next.AddDate(5, 0, 0)
+
+	for next.Before(limit) {
+		if !s.month[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.dayMatches(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.hour[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.minute[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows cron semantics: when both day fields are restricted either may match
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom[t.Day()]
+	dowMatch := s.dow[int(t.Weekday())]
+
+	switch {
+	case s.anyDom && s.anyDow:
+		return true
+	case s.anyDom:
+		return dowMatch
+	case s.anyDow:
+		return domMatch
+	}
+	return domMatch || dowMatch
+}
+
+// parseField parses a comma separated list of values, ranges and steps within [min, max]
+func parseField(field string, min, max int) (fieldSet, error) {
+	set := make(fieldSet)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if base, stepValue, ok := strings.Cut(part, "/"); ok {
+			parsed, err := strconv.Atoi(stepValue)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid step %q", part)
+			}
+			step = parsed
+			part = base
+		}
+
+		low, high := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			from, to, _ := strings.Cut(part, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+			if high, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			low, high = value, value
+			if step > 1 {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			set[value] = true
+		}
+	}
+
+	return set, nil
+}
@@ -0,0 +1,311 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ecetinerdem/forseer/marketdata"
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Refresher fetches a series upstream regardless of how fresh the cached copy is
+type Refresher interface {
+	Refresh(ctx context.Context, symbol string, interval types.Interval) (*types.PriceSeries, error)
+}
+
+// SymbolSource lists the symbols that must be kept fresh
+type SymbolSource interface {
+	GetTrackedSymbols(ctx context.Context) ([]types.TrackedSymbol, error)
+}
+
+// RefreshHook is called after a refresh run with the series that were refreshed successfully
+type RefreshHook func(ctx context.Context, refreshed []types.TrackedSymbol)
+
+// Job refreshes the tracked symbols of some intervals on a schedule
+type Job struct {
+	Intervals []types.Interval // Empty matches every interval
+	Schedule  *Schedule
+}
+
+func (j Job) matches(interval types.Interval) bool {
+	if len(j.Intervals) == 0 {
+		return true
+	}
+	for _, candidate := range j.Intervals {
+		if candidate == interval {
+			return true
+		}
+	}
+	return false
+}
+
+func (j Job) name() string {
+	if len(j.Intervals) == 0 {
+		return "all"
+	}
+	names := make([]string, len(j.Intervals))
+	for i, interval := range j.Intervals {
+		names[i] = string(interval)
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseJobs parses schedules of the form "interval[,interval]=cron;..." where "all" matches every interval,
+// e.g. "daily,daily_adjusted=30 22 * * 1-5;monthly=0 6 1 * *"
+func ParseJobs(spec string) ([]Job, error) {
+	var jobs []Job
+
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		intervalList, cron, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid refresh schedule %q, expected interval=cron", entry)
+		}
+
+		schedule, err := ParseSchedule(cron)
+		if err != nil {
+			return nil, err
+		}
+
+		job := Job{Schedule: schedule}
+		for _, name := range strings.Split(intervalList, ",") {
+			interval := types.Interval(strings.TrimSpace(name))
+			if interval == "all" {
+				job.Intervals = nil
+				break
+			}
+			if !interval.IsValid() {
+				return nil, fmt.Errorf("invalid interval %q in refresh schedule", interval)
+			}
+			job.Intervals = append(job.Intervals, interval)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// SymbolStatus is the refresh state of one tracked series
+type SymbolStatus struct {
+	Symbol              string         `json:"symbol"`
+	Interval            types.Interval `json:"interval"`
+	LastRefreshAt       *time.Time     `json:"last_refresh_at,omitempty"`
+	LastAttemptAt       time.Time      `json:"last_attempt_at"`
+	LastError           string         `json:"last_error,omitempty"`
+	ConsecutiveFailures int            `json:"consecutive_failures"`
+}
+
+// JobStatus is the state of one scheduled job
+type JobStatus struct {
+	Intervals string     `json:"intervals"`
+	Schedule  string     `json:"schedule"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt time.Time  `json:"next_run_at"`
+}
+
+// Status is a snapshot of the scheduler state
+type Status struct {
+	Jobs    []JobStatus    `json:"jobs"`
+	Symbols []SymbolStatus `json:"symbols"`
+}
+
+// Scheduler periodically refreshes every tracked symbol. Runs are sequential and go through the
+// rate-limited market data client, so a refresh never bursts past the provider limits.
+type Scheduler struct {
+	refresher Refresher
+	symbols   SymbolSource
+	jobs      []Job
+
+	runMu sync.Mutex // Serializes refresh runs of different jobs
+	hooks []RefreshHook
+
+	mu       sync.Mutex
+	statuses map[string]*SymbolStatus
+	jobStats []JobStatus
+}
+
+func New(refresher Refresher, symbols SymbolSource, jobs []Job) *Scheduler {
+	jobStats := make([]JobStatus, len(jobs))
+	for i, job := range jobs {
+		jobStats[i] = JobStatus{Intervals: job.name(), Schedule: job.Schedule.String()}
+	}
+
+	return &Scheduler{
+		refresher: refresher,
+		symbols:   symbols,
+		jobs:      jobs,
+		statuses:  make(map[string]*SymbolStatus),
+		jobStats:  jobStats,
+	}
+}
+
+// OnRefreshed registers a hook called after every refresh run, it must be called before Start
+func (s *Scheduler) OnRefreshed(hook RefreshHook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+// Start runs every job on its schedule until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	for i := range s.jobs {
+		go s.loop(ctx, i)
+	}
+}
+
+// Status returns a snapshot of the job schedules and per symbol refresh state
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		Jobs:    append([]JobStatus(nil), s.jobStats...),
+		Symbols: make([]SymbolStatus, 0, len(s.statuses)),
+	}
+	for _, symbolStatus := range s.statuses {
+		status.Symbols = append(status.Symbols, *symbolStatus)
+	}
+	sort.Slice(status.Symbols, func(i, j int) bool {
+		if status.Symbols[i].Symbol != status.Symbols[j].Symbol {
+			return status.Symbols[i].Symbol < status.Symbols[j].Symbol
+		}
+		return status.Symbols[i].Interval < status.Symbols[j].Interval
+	})
+
+	return status
+}
+
+func (s *Scheduler) loop(ctx context.Context, index int) {
+	job := s.jobs[index]
+
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Refresh schedule %q never fires, stopping job", job.Schedule)
+			return
+		}
+
+		s.mu.Lock()
+		s.jobStats[index].NextRunAt = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, job)
+
+		now := time.Now()
+		s.mu.Lock()
+		s.jobStats[index].LastRunAt = &now
+		s.mu.Unlock()
+	}
+}
+
+// run refreshes every tracked symbol matching the job, one at a time, then calls the hooks
+// with the symbols that were refreshed
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	tracked, err := s.symbols.GetTrackedSymbols(ctx)
+	if err != nil {
+		log.Printf("Price refresh could not list tracked symbols: %v", err)
+		return
+	}
+
+	var refreshed []types.TrackedSymbol
+	defer func() {
+		if len(refreshed) == 0 || ctx.Err() != nil {
+			return
+		}
+		for _, hook := range s.hooks {
+			hook(ctx, refreshed)
+		}
+	}()
+
+	for i, symbol := range tracked {
+		if !job.matches(symbol.Interval) {
+			continue
+		}
+
+		err := s.refresh(ctx, symbol)
+		s.record(symbol, err)
+		if err == nil {
+			refreshed = append(refreshed, symbol)
+		}
+
+		var quotaErr *marketdata.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			// Nothing else can be fetched today, mark the rest as skipped
+			for _, skipped := range tracked[i+1:] {
+				if job.matches(skipped.Interval) {
+					s.record(skipped, err)
+				}
+			}
+			log.Printf("Price refresh stopped, daily quota exceeded until %s", quotaErr.ResetAt.Format(time.RFC3339))
+			return
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// refresh fetches one series, waiting out a single rate limit rejection before giving up
+func (s *Scheduler) refresh(ctx context.Context, symbol types.TrackedSymbol) error {
+	_, err := s.refresher.Refresh(ctx, symbol.Symbol, symbol.Interval)
+
+	var rateLimitErr *marketdata.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		timer := time.NewTimer(rateLimitErr.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		_, err = s.refresher.Refresh(ctx, symbol.Symbol, symbol.Interval)
+	}
+
+	return err
+}
+
+func (s *Scheduler) record(symbol types.TrackedSymbol, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := symbol.Symbol + "|" + string(symbol.Interval)
+	status, ok := s.statuses[key]
+	if !ok {
+		status = &SymbolStatus{Symbol: symbol.Symbol, Interval: symbol.Interval}
+		s.statuses[key] = status
+	}
+
+	now := time.Now()
+	status.LastAttemptAt = now
+
+	if err != nil {
+		status.LastError = err.Error()
+		status.ConsecutiveFailures++
+		return
+	}
+
+	status.LastRefreshAt = &now
+	status.LastError = ""
+	status.ConsecutiveFailures = 0
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -63,9 +64,10 @@ func NewOpenAIService(apiKey string) *OpenAIService {
 	}
 }
 
-// AnalyzeStock analyzes a single stock and provides insights
-func (o *OpenAIService) AnalyzeStock(ctx context.Context, stock *types.Stock) (*types.StockAnalysis, error) {
-	prompt := o.buildStockAnalysisPrompt(stock)
+// AnalyzeStock analyzes a single stock and its price history and provides insights.
+// The risk metrics and technical indicators of the holding are optional.
+func (o *OpenAIService) AnalyzeStock(ctx context.Context, stock *types.Stock, history []types.PriceBar, risk *types.RiskMetrics, indicators *types.IndicatorReport) (*types.StockAnalysis, error) {
+	prompt := o.buildStockAnalysisPrompt(stock, history, risk, indicators)
 
 	analysis, err := o.getCompletion(ctx, prompt)
 	if err != nil {
@@ -80,13 +82,21 @@ func (o *OpenAIService) AnalyzeStock(ctx context.Context, stock *types.Stock) (*
 	}, nil
 }
 
-// AnalyzePortfolio analyzes an entire portfolio and provides insights
-func (o *OpenAIService) AnalyzePortfolio(ctx context.Context, portfolio *types.Portfolio) (*types.PortfolioAnalysis, error) {
+// PortfolioMetrics are the figures computed for a portfolio analysis, each of them is optional
+type PortfolioMetrics struct {
+	Performance  *types.PerformanceReport
+	Risk         *types.RiskReport
+	Correlations *types.CorrelationReport
+	Indicators   map[string]*types.IndicatorReport // Keyed by symbol
+}
+
+// AnalyzePortfolio analyzes an entire portfolio and the price history of its holdings, keyed by symbol
+func (o *OpenAIService) AnalyzePortfolio(ctx context.Context, portfolio *types.Portfolio, history map[string][]types.PriceBar, metrics PortfolioMetrics) (*types.PortfolioAnalysis, error) {
 	if len(portfolio.Stocks) == 0 {
 		return nil, fmt.Errorf("portfolio has no stocks to analyze")
 	}
 
-	prompt := o.buildPortfolioAnalysisPrompt(portfolio)
+	prompt := o.buildPortfolioAnalysisPrompt(portfolio, history, metrics)
 
 	analysis, err := o.getCompletion(ctx, prompt)
 	if err != nil {
@@ -161,43 +171,109 @@ func (o *OpenAIService) getCompletion(ctx context.Context, prompt string) (strin
 }
 
 // buildStockAnalysisPrompt creates a detailed prompt for single stock analysis
-func (o *OpenAIService) buildStockAnalysisPrompt(stock *types.Stock) string {
+func (o *OpenAIService) buildStockAnalysisPrompt(stock *types.Stock, history []types.PriceBar, risk *types.RiskMetrics, indicators *types.IndicatorReport) string {
 	return fmt.Sprintf(`
 Please analyze the following stock data and provide a comprehensive analysis:
 
 Stock Symbol: %s
-Month: %s
-Open Price: $%.2f
-High Price: $%.2f
-Low Price: $%.2f
-Close Price: $%.2f
+Date: %s
+Open Price: %s
+High Price: %s
+Low Price: %s
+Close Price: %s
 Volume: %d
 
+Position: %.4f shares at an average cost of %s (acquired %s)
+Market Value: %s
+Unrealized Gain: %s (%.2f%%)
+%s%s
+%s
 Please provide analysis covering:
 1. Price Performance: Analyze the price movement (open vs close, high vs low)
-2. Volatility Assessment: Comment on the price volatility based on the high-low range
+2. Volatility Assessment: Comment on the price volatility based on the high-low range and the measured volatility when given
 3. Volume Analysis: Interpret the trading volume significance
-4. Technical Indicators: Basic technical analysis (price trends, support/resistance if applicable)
-5. Risk Assessment: Identify potential risks based on the data
+4. Technical Indicators: Interpret the computed indicators when given (trend, momentum, volatility bands, volume); do not estimate indicator values that are not given
+5. Risk Assessment: Identify potential risks based on the data, referring to the computed risk metrics when given
 6. Recommendations: Provide actionable insights or recommendations
 
 Please format your response in clear sections and be specific about the data points you're referencing.
-`, stock.Symbol, stock.Month, stock.Open, stock.High, stock.Low, stock.Close, stock.Volume)
+`, stock.Symbol, stock.Date.Format("2006-01-02"),
+		formatAmount(stock.Open, stock.Currency), formatAmount(stock.High, stock.Currency),
+		formatAmount(stock.Low, stock.Currency), formatAmount(stock.Close, stock.Currency), stock.Volume,
+		stock.Quantity, formatAmount(stock.AverageCost, stock.Currency), stock.AcquiredAt.Format("2006-01-02"),
+		formatAmount(stock.MarketValue, stock.BaseCurrency), formatAmount(stock.UnrealizedGain, stock.BaseCurrency), stock.UnrealizedGainPct,
+		buildRiskSection("Risk", risk, stock.BaseCurrency), buildIndicatorSection(indicators), buildPriceHistorySection(history))
+}
+
+// formatAmount formats an amount with its currency code, e.g. 1234.56 EUR. Amounts without a currency are in USD.
+func formatAmount(amount float64, currency string) string {
+	if currency == "" {
+		currency = types.DefaultCurrency
+	}
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// buildPriceHistorySection lists the most recent bars of a price history, oldest first
+func buildPriceHistorySection(history []types.PriceBar) string {
+	if len(history) == 0 {
+		return ""
+	}
+
+	const maxBars = 24
+	if len(history) > maxBars {
+		history = history[len(history)-maxBars:]
+	}
+
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf("Price History (%s, last %d bars, in the listing currency):\n", history[0].Interval, len(history)))
+	for _, bar := range history {
+		section.WriteString(fmt.Sprintf("%s  O: %.2f  H: %.2f  L: %.2f  C: %.2f  V: %d\n",
+			bar.Date.Format("2006-01-02"), bar.Open, bar.High, bar.Low, bar.Close, bar.Volume))
+	}
+
+	return section.String()
+}
+
+// buildCloseHistoryLine summarizes the recent closes of a holding for the portfolio prompt
+func buildCloseHistoryLine(history []types.PriceBar) string {
+	if len(history) == 0 {
+		return ""
+	}
+
+	const maxBars = 12
+	if len(history) > maxBars {
+		history = history[len(history)-maxBars:]
+	}
+
+	closes := make([]string, 0, len(history))
+	for _, bar := range history {
+		closes = append(closes, fmt.Sprintf("%.2f", bar.Close))
+	}
+
+	return fmt.Sprintf("   Recent %s closes (oldest first): %s\n", history[0].Interval, strings.Join(closes, ", "))
 }
 
-// buildPortfolioAnalysisPrompt creates a detailed prompt for portfolio analysis
-func (o *OpenAIService) buildPortfolioAnalysisPrompt(portfolio *types.Portfolio) string {
+// buildPortfolioAnalysisPrompt creates a detailed prompt for portfolio analysis.
+// The portfolio must already be valued so weights and gains are filled in.
+func (o *OpenAIService) buildPortfolioAnalysisPrompt(portfolio *types.Portfolio, history map[string][]types.PriceBar, metrics PortfolioMetrics) string {
 	var stocksData strings.Builder
 	stocksData.WriteString("Portfolio Stocks:\n\n")
 
-	totalValue := 0.0
 	for i, stock := range portfolio.Stocks {
 		stocksData.WriteString(fmt.Sprintf(`%d. %s (%s):
-   Open: $%.2f, High: $%.2f, Low: $%.2f, Close: $%.2f
+   Open: %s, High: %s, Low: %s, Close: %s
    Volume: %d
-   
-`, i+1, stock.Symbol, stock.Month, stock.Open, stock.High, stock.Low, stock.Close, stock.Volume))
-		totalValue += stock.Close
+   Position: %.4f shares at an average cost of %s (acquired %s)
+   Market Value: %s, Weight: %.1f%%, Unrealized Gain: %s (%.2f%%)
+`, i+1, stock.Symbol, stock.Date.Format("2006-01-02"),
+			formatAmount(stock.Open, stock.Currency), formatAmount(stock.High, stock.Currency),
+			formatAmount(stock.Low, stock.Currency), formatAmount(stock.Close, stock.Currency), stock.Volume,
+			stock.Quantity, formatAmount(stock.AverageCost, stock.Currency), stock.AcquiredAt.Format("2006-01-02"),
+			formatAmount(stock.MarketValue, portfolio.BaseCurrency), stock.Weight*100,
+			formatAmount(stock.UnrealizedGain, portfolio.BaseCurrency), stock.UnrealizedGainPct))
+		stocksData.WriteString(buildCloseHistoryLine(history[stock.Symbol]))
+		stocksData.WriteString(buildIndicatorLine(metrics.Indicators[stock.Symbol]))
+		stocksData.WriteString("\n")
 	}
 
 	return fmt.Sprintf(`
@@ -205,20 +281,252 @@ Please analyze the following investment portfolio and provide a comprehensive an
 
 Portfolio Name: %s
 Number of Stocks: %d
-Total Portfolio Close Value: $%.2f
-
+Base Currency: %s
+Total Market Value: %s
+Total Cost Basis: %s
+Unrealized Gain: %s (%.2f%%)
+%s%s%s
 %s
 
 Please provide analysis covering:
-1. Portfolio Diversification: Analyze the spread across different stocks
-2. Overall Performance: Comment on the general performance of the portfolio
-3. Risk Assessment: Identify portfolio risks and volatility
+1. Portfolio Diversification: Analyze the spread across different stocks, grounded in the measured correlations when given
+2. Overall Performance: Comment on the general performance of the portfolio, using the measured returns when given
+3. Risk Assessment: Identify portfolio risks and volatility, referring to the computed risk metrics when given
 4. Sector Analysis: If you can identify sectors from the stock symbols, provide sector insights
-5. Performance Leaders and Laggards: Identify best and worst performing stocks
+5. Performance Leaders and Laggards: Identify best and worst performing stocks, referring to their computed technical indicators when given
 6. Portfolio Balance: Comment on the portfolio composition
 7. Recommendations: Provide specific recommendations for portfolio optimization
 8. Risk Management: Suggest risk management strategies
 
 Please format your response in clear sections with specific data references and actionable insights.
-`, portfolio.Name, len(portfolio.Stocks), totalValue, stocksData.String())
+`, portfolio.Name, len(portfolio.Stocks), portfolio.BaseCurrency,
+		formatAmount(portfolio.TotalValue, portfolio.BaseCurrency), formatAmount(portfolio.TotalCost, portfolio.BaseCurrency),
+		formatAmount(portfolio.UnrealizedGain, portfolio.BaseCurrency), portfolio.UnrealizedGainPct, buildPerformanceSection(metrics.Performance), buildPortfolioRiskSection(metrics.Risk, portfolio.BaseCurrency),
+		buildCorrelationSection(metrics.Correlations), stocksData.String())
+}
+
+// buildPerformanceSection describes the measured returns of a portfolio, it is empty without a report
+func buildPerformanceSection(performance *types.PerformanceReport) string {
+	if performance == nil {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf(`
+Performance from %s to %s (%d days):
+   Time-Weighted Return: %.2f%% (%.2f%% annualized)
+`, performance.From.Format("2006-01-02"), performance.To.Format("2006-01-02"), performance.Days,
+		performance.TimeWeightedReturnPct, performance.AnnualizedTimeWeightedReturnPct))
+
+	if performance.MoneyWeightedReturnPct != nil {
+		section.WriteString(fmt.Sprintf("   Money-Weighted Return (XIRR): %.2f%% (%.2f%% annualized)\n",
+			*performance.MoneyWeightedReturnPct, *performance.AnnualizedMoneyWeightedReturnPct))
+	}
+
+	currency := performance.Currency
+	section.WriteString(fmt.Sprintf(`   Start Value: %s, End Value: %s
+   Net Contributions: %s, Dividend Income: %s, Fees: %s, Gain: %s
+`, formatAmount(performance.StartValue, currency), formatAmount(performance.EndValue, currency),
+		formatAmount(performance.NetContributions, currency), formatAmount(performance.Income, currency),
+		formatAmount(performance.Fees, currency), formatAmount(performance.Gain, currency)))
+
+	return section.String()
+}
+
+// buildPortfolioRiskSection describes the risk of a portfolio and of its holdings, it is empty without a report
+func buildPortfolioRiskSection(risk *types.RiskReport, currency string) string {
+	if risk == nil {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString(buildRiskSection(fmt.Sprintf("Portfolio Risk from %s to %s against %s",
+		risk.From.Format("2006-01-02"), risk.To.Format("2006-01-02"), risk.Benchmark), &risk.Portfolio, currency))
+
+	for _, holding := range risk.Holdings {
+		section.WriteString(fmt.Sprintf("   %s: Volatility %.2f%%, Sharpe %.2f, Max Drawdown %.2f%%, 1-day VaR %.2f%%",
+			holding.Symbol, holding.Risk.VolatilityPct, holding.Risk.SharpeRatio,
+			holding.Risk.MaxDrawdown.DepthPct, holding.Risk.ValueAtRisk.HistoricalVaRPct))
+		if holding.Risk.Beta != nil {
+			section.WriteString(fmt.Sprintf(", Beta %.2f", *holding.Risk.Beta))
+		}
+		section.WriteString("\n")
+	}
+
+	return section.String()
+}
+
+// buildRiskSection describes risk metrics under a title with value at risk amounts in currency,
+// it is empty without metrics or with too few returns
+func buildRiskSection(title string, risk *types.RiskMetrics, currency string) string {
+	if risk == nil || risk.Observations < 2 {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf(`
+%s (%d daily returns):
+   Annualized Volatility: %.2f%%
+   Sharpe Ratio: %.2f, Sortino Ratio: %.2f
+`, title, risk.Observations, risk.VolatilityPct, risk.SharpeRatio, risk.SortinoRatio))
+
+	// No drawdown has no dates to report
+	drawdown := risk.MaxDrawdown
+	if drawdown.DepthPct == 0 || drawdown.PeakAt.IsZero() {
+		section.WriteString(fmt.Sprintf("   Max Drawdown: %.2f%%\n", drawdown.DepthPct))
+	} else {
+		section.WriteString(fmt.Sprintf("   Max Drawdown: %.2f%% from %s to %s\n",
+			drawdown.DepthPct, drawdown.PeakAt.Format("2006-01-02"), drawdown.TroughAt.Format("2006-01-02")))
+	}
+
+	if risk.Beta != nil {
+		section.WriteString(fmt.Sprintf("   Beta: %.2f\n", *risk.Beta))
+	}
+
+	v := risk.ValueAtRisk
+	section.WriteString(fmt.Sprintf(`   1-day VaR at %.0f%%: %.2f%% historical (%s), %.2f%% parametric (%s)
+   1-day CVaR at %.0f%%: %.2f%% historical (%s), %.2f%% parametric (%s)
+`, v.Confidence*100, v.HistoricalVaRPct, formatAmount(v.HistoricalVaR, currency), v.ParametricVaRPct, formatAmount(v.ParametricVaR, currency),
+		v.Confidence*100, v.HistoricalCVaRPct, formatAmount(v.HistoricalCVaR, currency), v.ParametricCVaRPct, formatAmount(v.ParametricCVaR, currency)))
+
+	return section.String()
+}
+
+// buildCorrelationSection describes the correlated clusters and the most correlated pairs of holdings,
+// it is empty without a report
+func buildCorrelationSection(correlations *types.CorrelationReport) string {
+	if correlations == nil || len(correlations.Symbols) < 2 {
+		return ""
+	}
+
+	type pair struct {
+		a, b        string
+		correlation float64
+	}
+
+	var pairs []pair
+	var sum float64
+	for i := range correlations.Symbols {
+		for j := i + 1; j < len(correlations.Symbols); j++ {
+			if c := correlations.Correlation[i][j]; c != nil {
+				pairs = append(pairs, pair{correlations.Symbols[i], correlations.Symbols[j], *c})
+				sum += *c
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].correlation > pairs[j].correlation })
+
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf(`
+Return Correlations from %s to %s:
+   Average Pairwise Correlation: %.2f
+`, correlations.From.Format("2006-01-02"), correlations.To.Format("2006-01-02"), sum/float64(len(pairs))))
+
+	const maxPairs = 5
+	section.WriteString("   Most Correlated Pairs:")
+	for i, p := range pairs[:min(len(pairs), maxPairs)] {
+		if i > 0 {
+			section.WriteString(",")
+		}
+		section.WriteString(fmt.Sprintf(" %s/%s %.2f", p.a, p.b, p.correlation))
+	}
+	section.WriteString("\n")
+
+	if len(correlations.Clusters) == 0 {
+		section.WriteString(fmt.Sprintf("   No holdings are correlated at %.2f or above\n", correlations.Threshold))
+	}
+	for _, cluster := range correlations.Clusters {
+		section.WriteString(fmt.Sprintf("   Correlated Cluster (%.2f or above): %s, average correlation %.2f, %.1f%% of the portfolio\n",
+			correlations.Threshold, strings.Join(cluster.Symbols, ", "), cluster.AverageCorrelation, cluster.Weight*100))
+	}
+
+	return section.String()
+}
+
+// buildIndicatorSection lists the technical indicators at the last bar, it is empty without a report
+func buildIndicatorSection(indicators *types.IndicatorReport) string {
+	if indicators == nil || indicators.Latest == nil {
+		return ""
+	}
+
+	latest := indicators.Latest
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf(`
+Technical Indicators (computed on %s bars at the close of %s, in the listing currency):
+   Close: %.2f
+`, indicators.Interval, latest.Date.Format("2006-01-02"), latest.Close))
+
+	if line := movingAverages("SMA", latest.SMA); line != "" {
+		section.WriteString("   " + line + "\n")
+	}
+	if line := movingAverages("EMA", latest.EMA); line != "" {
+		section.WriteString("   " + line + "\n")
+	}
+	if latest.RSI != nil {
+		section.WriteString(fmt.Sprintf("   RSI %d: %.2f\n", latest.RSI.Period, latest.RSI.Value))
+	}
+	if latest.MACD != nil {
+		section.WriteString(fmt.Sprintf("   MACD %d/%d/%d: %.4f, Signal %.4f, Histogram %.4f\n",
+			indicators.MACD.Fast, indicators.MACD.Slow, indicators.MACD.Signal,
+			latest.MACD.MACD, latest.MACD.Signal, latest.MACD.Histogram))
+	}
+	if latest.Bollinger != nil {
+		section.WriteString(fmt.Sprintf("   Bollinger Bands %d, %.1f std dev: Upper %.2f, Middle %.2f, Lower %.2f\n",
+			indicators.Bollinger.Period, indicators.Bollinger.StdDev,
+			latest.Bollinger.Upper, latest.Bollinger.Middle, latest.Bollinger.Lower))
+	}
+	if latest.ATR != nil {
+		section.WriteString(fmt.Sprintf("   ATR %d: %.2f\n", latest.ATR.Period, latest.ATR.Value))
+	}
+	if latest.OBV != nil {
+		section.WriteString(fmt.Sprintf("   OBV since %s: %.0f\n", indicators.From.Format("2006-01-02"), *latest.OBV))
+	}
+	if latest.VWAP != nil {
+		if indicators.Interval.IsIntraday() {
+			section.WriteString(fmt.Sprintf("   VWAP of the day: %.2f\n", *latest.VWAP))
+		} else {
+			section.WriteString(fmt.Sprintf("   VWAP since %s: %.2f\n", indicators.From.Format("2006-01-02"), *latest.VWAP))
+		}
+	}
+
+	return section.String()
+}
+
+// buildIndicatorLine summarizes the trend and momentum indicators of a holding for the portfolio prompt
+func buildIndicatorLine(indicators *types.IndicatorReport) string {
+	if indicators == nil || indicators.Latest == nil {
+		return ""
+	}
+
+	latest := indicators.Latest
+	parts := []string{}
+	if line := movingAverages("SMA", latest.SMA); line != "" {
+		parts = append(parts, line)
+	}
+	if latest.RSI != nil {
+		parts = append(parts, fmt.Sprintf("RSI %d: %.2f", latest.RSI.Period, latest.RSI.Value))
+	}
+	if latest.MACD != nil {
+		parts = append(parts, fmt.Sprintf("MACD Histogram: %.4f", latest.MACD.Histogram))
+	}
+	if latest.ATR != nil {
+		parts = append(parts, fmt.Sprintf("ATR %d: %.2f", latest.ATR.Period, latest.ATR.Value))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("   Indicators (%s, %s): %s\n", indicators.Interval, latest.Date.Format("2006-01-02"), strings.Join(parts, ", "))
+}
+
+// movingAverages formats the latest values of moving averages, e.g. SMA 20: 101.50, SMA 50: 98.20
+func movingAverages(name string, values []types.IndicatorValue) string {
+	formatted := make([]string, 0, len(values))
+	for _, v := range values {
+		formatted = append(formatted, fmt.Sprintf("%s %d: %.2f", name, v.Period, v.Value))
+	}
+	return strings.Join(formatted, ", ")
 }
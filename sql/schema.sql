@@ -28,22 +28,313 @@ CREATE TABLE IF NOT EXISTS portfolios (
     UNIQUE(user_id, name)
 );
 
--- Create stocks table with proper foreign key relationship
-CREATE TABLE IF NOT EXISTS stocks (
-    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+-- Create price history table holding the full series returned by the market data provider
+CREATE TABLE IF NOT EXISTS price_history (
     symbol VARCHAR(10) NOT NULL,
-    month VARCHAR(7) NOT NULL, -- Format: YYYY-MM
+    bar_interval VARCHAR(20) NOT NULL,
+    bar_date TIMESTAMP WITH TIME ZONE NOT NULL,
     open DECIMAL(15,4) NOT NULL,
     high DECIMAL(15,4) NOT NULL,
     low DECIMAL(15,4) NOT NULL,
     close DECIMAL(15,4) NOT NULL,
+    adjusted_close DECIMAL(15,4) NOT NULL,
     volume BIGINT NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (symbol, bar_interval, bar_date)
+);
+
+ALTER TABLE price_history ADD COLUMN IF NOT EXISTS adjusted_close DECIMAL(15,4) NOT NULL DEFAULT 0;
+
+-- Create market data cache table recording when each series was last fetched from the provider
+-- The bars themselves live in price_history
+CREATE TABLE IF NOT EXISTS market_data_cache (
+    symbol VARCHAR(10) NOT NULL,
+    bar_interval VARCHAR(20) NOT NULL,
+    last_refreshed VARCHAR(30) NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    PRIMARY KEY (symbol, bar_interval)
+);
+
+-- Create stocks table with proper foreign key relationship
+-- Holdings only reference a symbol's series, prices are read from price_history
+CREATE TABLE IF NOT EXISTS stocks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    symbol VARCHAR(10) NOT NULL,
+    bar_interval VARCHAR(20) NOT NULL DEFAULT 'monthly',
+    quantity DECIMAL(20,6) NOT NULL DEFAULT 0,
+    average_cost DECIMAL(15,4) NOT NULL DEFAULT 0,
+    acquired_at DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     
-    -- Prevent duplicate stocks per portfolio (same symbol and month)
-    UNIQUE(portfolio_id, symbol, month)
+    -- Prevent duplicate stocks per portfolio
+    UNIQUE(portfolio_id, symbol)
+);
+
+-- Upgrade stocks tables created before price_history existed.
+-- The copied monthly prices are dropped, they are refetched into price_history.
+DROP VIEW IF EXISTS user_stocks;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS bar_interval VARCHAR(20) NOT NULL DEFAULT 'monthly';
+ALTER TABLE stocks DROP CONSTRAINT IF EXISTS stocks_portfolio_id_symbol_month_key;
+ALTER TABLE stocks DROP COLUMN IF EXISTS month;
+ALTER TABLE stocks DROP COLUMN IF EXISTS open;
+ALTER TABLE stocks DROP COLUMN IF EXISTS high;
+ALTER TABLE stocks DROP COLUMN IF EXISTS low;
+ALTER TABLE stocks DROP COLUMN IF EXISTS close;
+ALTER TABLE stocks DROP COLUMN IF EXISTS volume;
+-- Merge the old per-month rows of a symbol into one holding before it becomes unique, keeping the first one added
+DELETE FROM stocks s
+USING stocks earlier
+WHERE s.portfolio_id = earlier.portfolio_id AND s.symbol = earlier.symbol
+AND (earlier.created_at, earlier.id) < (s.created_at, s.id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stocks_portfolio_symbol ON stocks(portfolio_id, symbol);
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS quantity DECIMAL(20,6) NOT NULL DEFAULT 0;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS average_cost DECIMAL(15,4) NOT NULL DEFAULT 0;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS acquired_at DATE NOT NULL DEFAULT CURRENT_DATE;
+
+-- Create transactions table, the ledger that holdings are derived from
+CREATE TABLE IF NOT EXISTS transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    symbol VARCHAR(10) NOT NULL DEFAULT '',
+    quantity DECIMAL(20,6) NOT NULL DEFAULT 0,
+    price DECIMAL(15,4) NOT NULL DEFAULT 0,
+    amount DECIMAL(15,4) NOT NULL DEFAULT 0,
+    fees DECIMAL(15,4) NOT NULL DEFAULT 0,
+    split_ratio DECIMAL(15,6) NOT NULL DEFAULT 0,
+    lot_id VARCHAR(36) NOT NULL DEFAULT '',
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    trade_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS lot_id VARCHAR(36) NOT NULL DEFAULT '';
+
+-- Import key of transactions loaded from files, re-importing a row with the same key is a no-op
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS external_id VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Lot matching method used for realized gains and the cost basis of holdings
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS cost_basis_method VARCHAR(20) NOT NULL DEFAULT 'fifo';
+
+-- Default portfolio used by the routes that do not name a portfolio
+-- Users without one keep using their most recently created portfolio
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS is_default BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE portfolios p SET is_default = TRUE
+WHERE p.id = (SELECT id FROM portfolios WHERE user_id = p.user_id ORDER BY created_at DESC LIMIT 1)
+AND NOT EXISTS (SELECT 1 FROM portfolios d WHERE d.user_id = p.user_id AND d.is_default);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_portfolios_user_default ON portfolios(user_id) WHERE is_default;
+
+-- Currencies, prices and amounts are recorded in the currency of the listing and converted to the
+-- portfolio's base currency when valued. Existing rows were all entered in USD.
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS base_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+-- Backfill an opening buy for holdings created before the ledger existed
+INSERT INTO transactions (portfolio_id, type, symbol, quantity, price, trade_date, notes)
+SELECT s.portfolio_id, 'buy', s.symbol, s.quantity, s.average_cost, s.acquired_at, 'Opening balance'
+FROM stocks s
+WHERE s.quantity > 0
+AND NOT EXISTS (
+    SELECT 1 FROM transactions t
+    WHERE t.portfolio_id = s.portfolio_id AND t.symbol = s.symbol
+);
+
+-- Create watchlists, symbols tracked without being held
+CREATE TABLE IF NOT EXISTS watchlists (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    UNIQUE(user_id, name)
+);
+
+-- Watchlist entries reference a symbol's series like holdings do, prices are read from price_history
+CREATE TABLE IF NOT EXISTS watchlist_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    watchlist_id UUID NOT NULL REFERENCES watchlists(id) ON DELETE CASCADE,
+    symbol VARCHAR(10) NOT NULL,
+    bar_interval VARCHAR(20) NOT NULL DEFAULT 'monthly',
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    UNIQUE(watchlist_id, symbol)
+);
+
+-- Create alerts, rules evaluated whenever prices refresh. An alert watches either a symbol's series or a portfolio.
+-- condition_met keeps the result of the last evaluation so a condition that stays true fires once.
+CREATE TABLE IF NOT EXISTS alerts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    portfolio_id UUID REFERENCES portfolios(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    symbol VARCHAR(10) NOT NULL DEFAULT '',
+    bar_interval VARCHAR(20) NOT NULL DEFAULT 'daily',
+    expression TEXT NOT NULL,
+    cooldown_minutes INTEGER NOT NULL DEFAULT 60 CHECK (cooldown_minutes >= 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    condition_met BOOLEAN NOT NULL DEFAULT FALSE,
+    last_evaluated_at TIMESTAMP WITH TIME ZONE,
+    last_triggered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    CHECK ((symbol <> '') <> (portfolio_id IS NOT NULL))
+);
+
+-- Alert triggers keep the history of every alert firing with the values it saw
+CREATE TABLE IF NOT EXISTS alert_triggers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    alert_id UUID NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    symbol VARCHAR(10) NOT NULL DEFAULT '',
+    portfolio_id UUID,
+    expression TEXT NOT NULL,
+    observed TEXT NOT NULL DEFAULT '',
+    triggered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create webhooks, user endpoints receiving HMAC-SHA256 signed event payloads.
+-- events is a comma separated list, empty subscribes to every event.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    events TEXT NOT NULL DEFAULT '',
+    secret VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Webhook deliveries log every event sent to a webhook with the outcome of its last attempt.
+-- Pending deliveries are retried at next_attempt_at, replays are new deliveries of the same payload.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_code INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    replay_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create benchmarks, a single symbol or a weighted composite portfolios are compared against.
+-- Composites are brought back to their target weights on the rebalance schedule.
+CREATE TABLE IF NOT EXISTS benchmarks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    rebalance VARCHAR(20) NOT NULL DEFAULT 'none',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    UNIQUE(user_id, name)
+);
+
+-- Benchmark components hold the target weight of each symbol, weights of a benchmark sum to 1
+CREATE TABLE IF NOT EXISTS benchmark_components (
+    benchmark_id UUID NOT NULL REFERENCES benchmarks(id) ON DELETE CASCADE,
+    symbol VARCHAR(10) NOT NULL,
+    weight DECIMAL(10, 8) NOT NULL CHECK (weight > 0 AND weight <= 1),
+
+    PRIMARY KEY (benchmark_id, symbol)
+);
+
+-- Create allocation targets, the weight a portfolio aims for per symbol, sector or asset class.
+-- All targets of a portfolio share one kind and their weights sum to 1, tolerance is the allowed drift either way.
+CREATE TABLE IF NOT EXISTS allocation_targets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    key VARCHAR(100) NOT NULL,
+    weight DECIMAL(10, 8) NOT NULL CHECK (weight >= 0 AND weight <= 1),
+    tolerance DECIMAL(10, 8) NOT NULL DEFAULT 0 CHECK (tolerance >= 0 AND tolerance < 1),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    UNIQUE(portfolio_id, kind, key)
+);
+
+-- Symbol classifications hold the user's sector and asset class of a symbol in a portfolio.
+-- They are kept apart from holdings so they survive a position being closed and reopened.
+CREATE TABLE IF NOT EXISTS symbol_classifications (
+    portfolio_id UUID NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    symbol VARCHAR(10) NOT NULL,
+    sector VARCHAR(100) NOT NULL DEFAULT '',
+    asset_class VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (portfolio_id, symbol)
+);
+
+-- FX rates hold the daily close of a currency pair, units of to_currency per unit of from_currency.
+-- A stored rate is never overwritten so past valuations stay reproducible, refetches only add new dates.
+CREATE TABLE IF NOT EXISTS fx_rates (
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate DECIMAL(20, 10) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (from_currency, to_currency, rate_date)
+);
+
+-- FX pairs record when each pair was last fetched from the provider
+CREATE TABLE IF NOT EXISTS fx_pairs (
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    last_refreshed VARCHAR(30) NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    PRIMARY KEY (from_currency, to_currency)
+);
+
+-- Corporate actions hold the splits and dividends of a symbol, read from adjusted daily series or entered by an admin.
+-- Stored bars stay as traded, splits are applied to them when history is read.
+CREATE TABLE IF NOT EXISTS corporate_actions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    symbol VARCHAR(10) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('split', 'dividend')),
+    ex_date DATE NOT NULL,
+    ratio DECIMAL(20, 10) NOT NULL DEFAULT 0 CHECK (ratio >= 0),
+    amount DECIMAL(15, 6) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    source VARCHAR(10) NOT NULL DEFAULT 'provider',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    UNIQUE (symbol, type, ex_date)
+);
+
+-- Backtests are strategy simulations run as background jobs. The definition and the result are stored as JSON,
+-- jobs left queued or running by a restart are marked failed.
+CREATE TABLE IF NOT EXISTS backtests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    definition JSONB NOT NULL,
+    result JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
 );
 
 -- Create indexes for better query performance
@@ -51,9 +342,24 @@ CREATE INDEX IF NOT EXISTS idx_portfolios_user_id ON portfolios(user_id);
 CREATE INDEX IF NOT EXISTS idx_stocks_portfolio_id ON stocks(portfolio_id);
 CREATE INDEX IF NOT EXISTS idx_stocks_symbol ON stocks(symbol);
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_price_history_symbol_interval ON price_history(symbol, bar_interval, bar_date DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_portfolio_date ON transactions(portfolio_id, trade_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_external_id ON transactions(portfolio_id, external_id) WHERE external_id <> '';
+CREATE INDEX IF NOT EXISTS idx_watchlists_user_id ON watchlists(user_id);
+CREATE INDEX IF NOT EXISTS idx_watchlist_entries_watchlist_id ON watchlist_entries(watchlist_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_user_id ON alerts(user_id);
+CREATE INDEX IF NOT EXISTS idx_alert_triggers_alert_id ON alert_triggers(alert_id, triggered_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_triggers_user_id ON alert_triggers(user_id, triggered_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_benchmarks_user_id ON benchmarks(user_id);
+CREATE INDEX IF NOT EXISTS idx_allocation_targets_portfolio_id ON allocation_targets(portfolio_id);
+CREATE INDEX IF NOT EXISTS idx_backtests_user_id ON backtests(user_id, created_at DESC);
 
 -- Create a view that combines user, portfolio, and stock data for easy queries
-CREATE OR REPLACE VIEW user_stocks AS
+-- Stock prices come from the latest bar of each holding's price history
+CREATE VIEW user_stocks AS
 SELECT 
     u.id as user_id,
     u.email as user_email,
@@ -62,17 +368,29 @@ SELECT
     p.name as portfolio_name,
     s.id as stock_id,
     s.symbol,
-    s.month,
-    s.open,
-    s.high,
-    s.low,
-    s.close,
-    s.volume,
+    s.bar_interval,
+    s.quantity,
+    s.average_cost,
+    s.currency,
+    s.acquired_at,
+    ph.bar_date,
+    ph.open,
+    ph.high,
+    ph.low,
+    ph.close,
+    ph.volume,
     s.created_at as stock_created_at,
     s.updated_at as stock_updated_at
 FROM users u
 LEFT JOIN portfolios p ON u.id = p.user_id
-LEFT JOIN stocks s ON p.id = s.portfolio_id;
+LEFT JOIN stocks s ON p.id = s.portfolio_id
+LEFT JOIN LATERAL (
+    SELECT bar_date, open, high, low, close, volume
+    FROM price_history
+    WHERE symbol = s.symbol AND bar_interval = s.bar_interval
+    ORDER BY bar_date DESC
+    LIMIT 1
+) ph ON TRUE;
 
 -- Create function to update the updated_at column
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -84,21 +402,79 @@ END;
 $$ language 'plpgsql';
 
 -- Create triggers to automatically update the updated_at column
+-- Triggers are dropped first so the migration can be re-run on every startup
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
 CREATE TRIGGER update_users_updated_at 
     BEFORE UPDATE ON users 
     FOR EACH ROW 
     EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_portfolios_updated_at ON portfolios;
 CREATE TRIGGER update_portfolios_updated_at 
     BEFORE UPDATE ON portfolios 
     FOR EACH ROW 
     EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_stocks_updated_at ON stocks;
 CREATE TRIGGER update_stocks_updated_at 
     BEFORE UPDATE ON stocks 
     FOR EACH ROW 
     EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_price_history_updated_at ON price_history;
+CREATE TRIGGER update_price_history_updated_at 
+    BEFORE UPDATE ON price_history 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_transactions_updated_at ON transactions;
+CREATE TRIGGER update_transactions_updated_at 
+    BEFORE UPDATE ON transactions 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_watchlists_updated_at ON watchlists;
+CREATE TRIGGER update_watchlists_updated_at 
+    BEFORE UPDATE ON watchlists 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_watchlist_entries_updated_at ON watchlist_entries;
+CREATE TRIGGER update_watchlist_entries_updated_at 
+    BEFORE UPDATE ON watchlist_entries 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_alerts_updated_at ON alerts;
+CREATE TRIGGER update_alerts_updated_at 
+    BEFORE UPDATE ON alerts 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_webhooks_updated_at ON webhooks;
+CREATE TRIGGER update_webhooks_updated_at 
+    BEFORE UPDATE ON webhooks 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_benchmarks_updated_at ON benchmarks;
+CREATE TRIGGER update_benchmarks_updated_at 
+    BEFORE UPDATE ON benchmarks 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_allocation_targets_updated_at ON allocation_targets;
+CREATE TRIGGER update_allocation_targets_updated_at 
+    BEFORE UPDATE ON allocation_targets 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_symbol_classifications_updated_at ON symbol_classifications;
+CREATE TRIGGER update_symbol_classifications_updated_at 
+    BEFORE UPDATE ON symbol_classifications 
+    FOR EACH ROW 
+    EXECUTE FUNCTION update_updated_at_column();
+
 -- Sample data migration (optional - for testing)
 -- This creates a sample user and portfolio structure
 -- Remove this section in production
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Alert is a user defined rule evaluated whenever prices refresh.
+// Symbol alerts watch one series, portfolio alerts watch the value of a portfolio.
+type Alert struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"user_id"`
+	Name            string     `json:"name"`
+	Symbol          string     `json:"symbol,omitempty"`
+	PortfolioID     string     `json:"portfolio_id,omitempty"`
+	Interval        Interval   `json:"interval"` // Series the rule reads, symbol alerts only
+	Expression      string     `json:"expression"`
+	CooldownMinutes int        `json:"cooldown_minutes"`
+	Enabled         bool       `json:"enabled"`
+	ConditionMet    bool       `json:"condition_met"` // Result of the last evaluation, alerts fire when it turns true
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// AlertTrigger records an alert firing
+type AlertTrigger struct {
+	ID          string    `json:"id"`
+	AlertID     string    `json:"alert_id"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	Symbol      string    `json:"symbol,omitempty"`
+	PortfolioID string    `json:"portfolio_id,omitempty"`
+	Expression  string    `json:"expression"`
+	Observed    string    `json:"observed"` // Values the rule saw, e.g. "close = 148.20"
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+// CreateAlertRequest represents the request body to create an alert.
+// The subject can be given in the expression ("AAPL close < 150", "portfolio drawdown > 10%")
+// or through the symbol and portfolio_id fields.
+type CreateAlertRequest struct {
+	Name            string   `json:"name"`
+	Symbol          string   `json:"symbol"`
+	PortfolioID     string   `json:"portfolio_id"`
+	Interval        Interval `json:"interval"` // Defaults to daily
+	Expression      string   `json:"expression"`
+	CooldownMinutes *int     `json:"cooldown_minutes"` // Defaults to 60
+}
+
+// UpdateAlertRequest represents the request body to update an alert, missing fields are left unchanged
+type UpdateAlertRequest struct {
+	Name            *string `json:"name"`
+	Expression      *string `json:"expression"`
+	CooldownMinutes *int    `json:"cooldown_minutes"`
+	Enabled         *bool   `json:"enabled"`
+}
+
+// AlertNotFoundError is returned when an alert does not exist or belongs to another user
+type AlertNotFoundError struct {
+	UserID  string
+	AlertID string
+}
+
+func (e *AlertNotFoundError) Error() string {
+	return fmt.Sprintf("alert %s not found for user %s", e.AlertID, e.UserID)
+}
@@ -0,0 +1,103 @@
+package types
+
+import "time"
+
+// AllocationKind is what allocation targets are set on
+type AllocationKind string
+
+const (
+	AllocationSymbol     AllocationKind = "symbol"
+	AllocationSector     AllocationKind = "sector"
+	AllocationAssetClass AllocationKind = "asset_class"
+)
+
+func (k AllocationKind) IsValid() bool {
+	return k == AllocationSymbol || k == AllocationSector || k == AllocationAssetClass
+}
+
+// AllocationTarget is the weight a portfolio aims for in a symbol, sector or asset class
+type AllocationTarget struct {
+	ID          string         `json:"id"`
+	PortfolioID string         `json:"portfolio_id"`
+	Kind        AllocationKind `json:"kind"`
+	Key         string         `json:"key"`       // Symbol, sector or asset class name
+	Weight      float64        `json:"weight"`    // Share of the portfolio value, 0-1
+	Tolerance   float64        `json:"tolerance"` // Allowed drift either way, 0.05 keeps a 0.30 target within 0.25-0.35
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// AllocationTargetInput is a target of a SetAllocationTargetsRequest
+type AllocationTargetInput struct {
+	Kind      AllocationKind `json:"kind"`
+	Key       string         `json:"key"`
+	Weight    float64        `json:"weight"`
+	Tolerance *float64       `json:"tolerance"` // Defaults to 0.05
+}
+
+// SetAllocationTargetsRequest represents the request body replacing every target of a portfolio,
+// an empty list removes them
+type SetAllocationTargetsRequest struct {
+	Targets []AllocationTargetInput `json:"targets"`
+}
+
+// SymbolClassification is the sector and asset class the user assigned to a symbol of a portfolio
+type SymbolClassification struct {
+	PortfolioID string    `json:"portfolio_id"`
+	Symbol      string    `json:"symbol"`
+	Sector      string    `json:"sector"`
+	AssetClass  string    `json:"asset_class"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SetClassificationRequest represents the request body to classify a symbol
+type SetClassificationRequest struct {
+	Sector     string `json:"sector"`
+	AssetClass string `json:"asset_class"`
+}
+
+// TradeSide is the direction of a proposed trade
+type TradeSide string
+
+const (
+	TradeBuy  TradeSide = "buy"
+	TradeSell TradeSide = "sell"
+)
+
+// AllocationDrift compares the current weight of a target with the band around it
+type AllocationDrift struct {
+	Key             string  `json:"key"`
+	TargetWeight    float64 `json:"target_weight"`
+	Tolerance       float64 `json:"tolerance"`
+	CurrentWeight   float64 `json:"current_weight"`
+	Drift           float64 `json:"drift"` // Current less target weight
+	WithinBand      bool    `json:"within_band"`
+	ProjectedWeight float64 `json:"projected_weight"` // After the proposed trades
+}
+
+// RebalanceTrade is a trade proposed to bring an allocation back within its band
+type RebalanceTrade struct {
+	Symbol   string    `json:"symbol"`
+	Key      string    `json:"key"` // Allocation the trade belongs to
+	Side     TradeSide `json:"side"`
+	Quantity float64   `json:"quantity"`
+	Price    float64   `json:"price"` // Latest close
+	Value    float64   `json:"value"`
+}
+
+// RebalancePlan holds the trades that bring a portfolio's allocations back within their bands.
+// Trades smaller than the minimum trade value are listed as skipped, nothing is executed.
+type RebalancePlan struct {
+	PortfolioID   string            `json:"portfolio_id"`
+	Kind          AllocationKind    `json:"kind"`
+	Currency      string            `json:"currency"`    // Base currency of the portfolio, every value is in it
+	TotalValue    float64           `json:"total_value"` // Classified holdings plus cash
+	Cash          float64           `json:"cash"`        // Available before the trades
+	CashAfter     float64           `json:"cash_after"`
+	NoSells       bool              `json:"no_sells"`
+	MinTradeValue float64           `json:"min_trade_value"`
+	Allocations   []AllocationDrift `json:"allocations"`
+	Trades        []RebalanceTrade  `json:"trades"`
+	Skipped       []RebalanceTrade  `json:"skipped"`
+	Notes         []string          `json:"notes"` // Allocations that could not be acted on and why
+}
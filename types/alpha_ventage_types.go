@@ -1,8 +1,70 @@
 package types
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AlphaVentageStockResponse is the TIME_SERIES_MONTHLY response
 type AlphaVentageStockResponse struct {
-	MetaData   MetaData               `json:"Meta Data"`
-	TimeSeries map[string]MonthlyData `json:"Monthly Time Series"`
+	MetaData   MetaData                  `json:"Meta Data"`
+	TimeSeries map[string]TimeSeriesData `json:"Monthly Time Series"`
+}
+
+// AlphaVentageWeeklyResponse is the TIME_SERIES_WEEKLY response
+type AlphaVentageWeeklyResponse struct {
+	MetaData   MetaData                  `json:"Meta Data"`
+	TimeSeries map[string]TimeSeriesData `json:"Weekly Time Series"`
+}
+
+// AlphaVentageDailyResponse is the TIME_SERIES_DAILY response
+type AlphaVentageDailyResponse struct {
+	MetaData   MetaData                  `json:"Meta Data"`
+	TimeSeries map[string]TimeSeriesData `json:"Time Series (Daily)"`
+}
+
+// AlphaVentageDailyAdjustedResponse is the TIME_SERIES_DAILY_ADJUSTED response
+type AlphaVentageDailyAdjustedResponse struct {
+	MetaData   MetaData                          `json:"Meta Data"`
+	TimeSeries map[string]AdjustedTimeSeriesData `json:"Time Series (Daily)"`
+}
+
+// AlphaVentageIntradayResponse is the TIME_SERIES_INTRADAY response.
+// The series key depends on the requested interval, e.g. "Time Series (5min)".
+type AlphaVentageIntradayResponse struct {
+	MetaData   IntradayMetaData
+	TimeSeries map[string]TimeSeriesData
+}
+
+func (r *AlphaVentageIntradayResponse) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if metaData, ok := raw["Meta Data"]; ok {
+		if err := json.Unmarshal(metaData, &r.MetaData); err != nil {
+			return fmt.Errorf("invalid intraday meta data: %w", err)
+		}
+	}
+
+	for key, value := range raw {
+		if strings.HasPrefix(key, "Time Series (") {
+			if err := json.Unmarshal(value, &r.TimeSeries); err != nil {
+				return fmt.Errorf("invalid intraday time series: %w", err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// AlphaVentageFXDailyResponse is the FX_DAILY response
+type AlphaVentageFXDailyResponse struct {
+	MetaData   FXMetaData                  `json:"Meta Data"`
+	TimeSeries map[string]FXTimeSeriesData `json:"Time Series FX (Daily)"`
 }
 
 type MetaData struct {
@@ -12,10 +74,46 @@ type MetaData struct {
 	TimeZone      string `json:"4. Time Zone"`
 }
 
-type MonthlyData struct {
-	Open   float64 `json:"1. open"`
-	High   float64 `json:"2. high"`
-	Low    float64 `json:"3. low"`
-	Close  float64 `json:"4. close"`
-	Volume int64   `json:"5. volume"`
+type IntradayMetaData struct {
+	Information   string `json:"1. Information"`
+	Symbol        string `json:"2. Symbol"`
+	LastRefreshed string `json:"3. Last Refreshed"`
+	Interval      string `json:"4. Interval"`
+	OutputSize    string `json:"5. Output Size"`
+	TimeZone      string `json:"6. Time Zone"`
+}
+
+type FXMetaData struct {
+	Information   string `json:"1. Information"`
+	FromSymbol    string `json:"2. From Symbol"`
+	ToSymbol      string `json:"3. To Symbol"`
+	OutputSize    string `json:"4. Output Size"`
+	LastRefreshed string `json:"5. Last Refreshed"`
+	TimeZone      string `json:"6. Time Zone"`
+}
+
+type FXTimeSeriesData struct {
+	Open  float64 `json:"1. open,string"`
+	High  float64 `json:"2. high,string"`
+	Low   float64 `json:"3. low,string"`
+	Close float64 `json:"4. close,string"`
+}
+
+type TimeSeriesData struct {
+	Open   float64 `json:"1. open,string"`
+	High   float64 `json:"2. high,string"`
+	Low    float64 `json:"3. low,string"`
+	Close  float64 `json:"4. close,string"`
+	Volume int64   `json:"5. volume,string"`
+}
+
+type AdjustedTimeSeriesData struct {
+	Open             float64 `json:"1. open,string"`
+	High             float64 `json:"2. high,string"`
+	Low              float64 `json:"3. low,string"`
+	Close            float64 `json:"4. close,string"`
+	AdjustedClose    float64 `json:"5. adjusted close,string"`
+	Volume           int64   `json:"6. volume,string"`
+	DividendAmount   float64 `json:"7. dividend amount,string"`
+	SplitCoefficient float64 `json:"8. split coefficient,string"`
 }
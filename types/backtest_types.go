@@ -0,0 +1,156 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// BacktestStrategyType identifies the rules a backtest trades by
+type BacktestStrategyType string
+
+const (
+	BacktestSMACrossover BacktestStrategyType = "sma_crossover" // Hold a symbol while its fast SMA is above its slow SMA
+	BacktestRebalance    BacktestStrategyType = "rebalance"     // Hold the target weights, restored every period
+	BacktestDCA          BacktestStrategyType = "dca"           // Invest a fixed contribution at the target weights every period
+)
+
+func (t BacktestStrategyType) IsValid() bool {
+	return t == BacktestSMACrossover || t == BacktestRebalance || t == BacktestDCA
+}
+
+// BacktestStrategy defines the rules of a backtest, the fields used depend on its type
+type BacktestStrategy struct {
+	Type         BacktestStrategyType `json:"type"`
+	FastPeriod   int                  `json:"fast_period,omitempty"`  // sma_crossover, defaults to 50 bars
+	SlowPeriod   int                  `json:"slow_period,omitempty"`  // sma_crossover, defaults to 200 bars
+	Frequency    RebalanceFrequency   `json:"frequency,omitempty"`    // rebalance and dca, defaults to monthly
+	Contribution float64              `json:"contribution,omitempty"` // dca, cash added at the start of every period after the first
+}
+
+// CommissionModel prices a trade: a fixed fee, a fee per share and a percentage of the traded amount,
+// with a minimum per trade
+type CommissionModel struct {
+	PerTrade float64 `json:"per_trade"`
+	PerShare float64 `json:"per_share"`
+	Pct      float64 `json:"pct"` // Of the traded amount, 0.1 is 0.1%
+	Minimum  float64 `json:"minimum"`
+}
+
+// Commission returns the fee of trading quantity shares at price
+func (m CommissionModel) Commission(quantity, price float64) float64 {
+	if quantity <= 0 {
+		return 0
+	}
+	return max(m.PerTrade+m.PerShare*quantity+m.Pct/100*quantity*price, m.Minimum)
+}
+
+// BacktestDefinition is what a backtest simulates. Universe weights are scaled to sum to 1;
+// SMA crossovers give every symbol in it a slot of its weight.
+type BacktestDefinition struct {
+	Strategy     BacktestStrategy     `json:"strategy"`
+	Universe     []BenchmarkComponent `json:"universe"`
+	From         time.Time            `json:"from"`
+	To           time.Time            `json:"to"`
+	StartingCash float64              `json:"starting_cash"`
+	Commission   CommissionModel      `json:"commission"`
+	WholeShares  bool                 `json:"whole_shares"`
+}
+
+// BacktestStatus is the state of a backtest job
+type BacktestStatus string
+
+const (
+	BacktestQueued    BacktestStatus = "queued"
+	BacktestRunning   BacktestStatus = "running"
+	BacktestCompleted BacktestStatus = "completed"
+	BacktestFailed    BacktestStatus = "failed"
+	BacktestCancelled BacktestStatus = "cancelled"
+)
+
+// IsFinished reports whether a backtest in this state will not change anymore
+func (s BacktestStatus) IsFinished() bool {
+	return s == BacktestCompleted || s == BacktestFailed || s == BacktestCancelled
+}
+
+// BacktestSummary holds the figures of a finished backtest, amounts are in the listing currency of the universe
+type BacktestSummary struct {
+	From                   time.Time   `json:"from"`
+	To                     time.Time   `json:"to"`
+	Days                   int         `json:"days"`
+	StartingCash           float64     `json:"starting_cash"`
+	Contributions          float64     `json:"contributions"` // Starting cash included
+	EndValue               float64     `json:"end_value"`
+	EndCash                float64     `json:"end_cash"`
+	Gain                   float64     `json:"gain"`
+	TotalReturnPct         float64     `json:"total_return_pct"` // Time-weighted
+	AnnualizedReturnPct    float64     `json:"annualized_return_pct"`
+	MoneyWeightedReturnPct *float64    `json:"money_weighted_return_pct,omitempty"` // Annualized XIRR
+	Risk                   RiskMetrics `json:"risk"`
+	Trades                 int         `json:"trades"`
+	Commissions            float64     `json:"commissions"`
+}
+
+// BacktestResult holds the equity curve, the ledger and the summary of a finished backtest
+type BacktestResult struct {
+	EquityCurve []PerformancePoint `json:"equity_curve"`
+	Trades      []Transaction      `json:"trades"` // Deposits, buys and sells in the order they were made
+	Summary     BacktestSummary    `json:"summary"`
+}
+
+// Backtest is a strategy simulation run as a background job
+type Backtest struct {
+	ID         string             `json:"id"`
+	UserID     string             `json:"user_id"`
+	Name       string             `json:"name"`
+	Status     BacktestStatus     `json:"status"`
+	Definition BacktestDefinition `json:"definition"`
+	Result     *BacktestResult    `json:"result,omitempty"` // Only returned for a single completed backtest
+	Error      string             `json:"error,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+// CreateBacktestRequest represents the request body to start a backtest, e.g.
+// {"strategy": {"type": "sma_crossover", "fast_period": 20, "slow_period": 50}, "universe": [{"symbol": "SPY"}],
+// "from": "2020-01-01", "to": "2024-12-31", "starting_cash": 10000, "commission": {"per_trade": 1}}.
+// Universe weights are relative and default to equal weights.
+type CreateBacktestRequest struct {
+	Name         string               `json:"name"`
+	Strategy     BacktestStrategy     `json:"strategy"`
+	Universe     []BenchmarkComponent `json:"universe"`
+	From         string               `json:"from"` // YYYY-MM-DD
+	To           string               `json:"to"`   // YYYY-MM-DD, defaults to today
+	StartingCash float64              `json:"starting_cash"`
+	Commission   CommissionModel      `json:"commission"`
+	WholeShares  bool                 `json:"whole_shares"`
+}
+
+// BacktestNotFoundError is returned when a backtest does not exist or belongs to another user
+type BacktestNotFoundError struct {
+	UserID     string
+	BacktestID string
+}
+
+func (e *BacktestNotFoundError) Error() string {
+	return fmt.Sprintf("backtest %s not found for user %s", e.BacktestID, e.UserID)
+}
+
+// BacktestLimitError is returned when a user already has as many unfinished backtests as allowed
+type BacktestLimitError struct {
+	Limit int
+}
+
+func (e *BacktestLimitError) Error() string {
+	return fmt.Sprintf("at most %d backtests can be queued or running at a time, wait for one to finish or cancel one", e.Limit)
+}
+
+// BacktestFinishedError is returned when cancelling a backtest that already finished
+type BacktestFinishedError struct {
+	BacktestID string
+	Status     BacktestStatus
+}
+
+func (e *BacktestFinishedError) Error() string {
+	return fmt.Sprintf("backtest %s already %s", e.BacktestID, e.Status)
+}
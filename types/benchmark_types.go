@@ -0,0 +1,108 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// RebalanceFrequency is how often a composite benchmark is brought back to its target weights
+type RebalanceFrequency string
+
+const (
+	RebalanceNone      RebalanceFrequency = "none" // Buy and hold, weights drift with prices
+	RebalanceDaily     RebalanceFrequency = "daily"
+	RebalanceMonthly   RebalanceFrequency = "monthly"
+	RebalanceQuarterly RebalanceFrequency = "quarterly"
+	RebalanceAnnually  RebalanceFrequency = "annually"
+)
+
+func (f RebalanceFrequency) IsValid() bool {
+	switch f {
+	case RebalanceNone, RebalanceDaily, RebalanceMonthly, RebalanceQuarterly, RebalanceAnnually:
+		return true
+	}
+	return false
+}
+
+// BenchmarkComponent is a symbol of a benchmark with its target weight, 0-1
+type BenchmarkComponent struct {
+	Symbol string  `json:"symbol"`
+	Weight float64 `json:"weight"`
+}
+
+// Benchmark is a single symbol or a weighted composite a portfolio is compared against
+type Benchmark struct {
+	ID         string               `json:"id"`
+	UserID     string               `json:"user_id"`
+	Name       string               `json:"name"`
+	Rebalance  RebalanceFrequency   `json:"rebalance"`
+	Components []BenchmarkComponent `json:"components"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+// CreateBenchmarkRequest represents the request body to define a benchmark.
+// Weights are relative, e.g. 60 and 40, they are scaled to sum to 1.
+type CreateBenchmarkRequest struct {
+	Name       string               `json:"name"`
+	Rebalance  RebalanceFrequency   `json:"rebalance"` // Defaults to none
+	Components []BenchmarkComponent `json:"components"`
+}
+
+// UpdateBenchmarkRequest represents the request body to change a benchmark, missing fields are kept
+type UpdateBenchmarkRequest struct {
+	Name       *string              `json:"name"`
+	Rebalance  *RebalanceFrequency  `json:"rebalance"`
+	Components []BenchmarkComponent `json:"components"` // Replaces every component when given
+}
+
+// BenchmarkNotFoundError is returned when a benchmark does not exist or belongs to another user
+type BenchmarkNotFoundError struct {
+	UserID      string
+	BenchmarkID string
+}
+
+func (e *BenchmarkNotFoundError) Error() string {
+	return fmt.Sprintf("benchmark %s not found for user %s", e.BenchmarkID, e.UserID)
+}
+
+// BenchmarkNameTakenError is returned when the user already has a benchmark with the name
+type BenchmarkNameTakenError struct {
+	Name string
+}
+
+func (e *BenchmarkNameTakenError) Error() string {
+	return fmt.Sprintf("a benchmark named %q already exists", e.Name)
+}
+
+// BenchmarkPoint is the growth of a portfolio and its benchmark since the start of a comparison
+type BenchmarkPoint struct {
+	Date            time.Time `json:"date"`
+	PortfolioReturn float64   `json:"portfolio_return"` // Cumulative, as a fraction
+	BenchmarkReturn float64   `json:"benchmark_return"`
+}
+
+// BenchmarkComparison holds the returns of a portfolio and a benchmark over the dates both have a return.
+// Alpha, tracking error and information ratio are annualized from the daily returns.
+type BenchmarkComparison struct {
+	PortfolioID  string               `json:"portfolio_id"`
+	Benchmark    string               `json:"benchmark"` // Name of a saved benchmark or the symbol
+	Rebalance    RebalanceFrequency   `json:"rebalance"`
+	Components   []BenchmarkComponent `json:"components"`
+	From         time.Time            `json:"from"`
+	To           time.Time            `json:"to"`
+	Observations int                  `json:"observations"`
+
+	PortfolioReturnPct           float64 `json:"portfolio_return_pct"`
+	BenchmarkReturnPct           float64 `json:"benchmark_return_pct"`
+	ExcessReturnPct              float64 `json:"excess_return_pct"`
+	PortfolioAnnualizedReturnPct float64 `json:"portfolio_annualized_return_pct"`
+	BenchmarkAnnualizedReturnPct float64 `json:"benchmark_annualized_return_pct"`
+
+	Beta             *float64 `json:"beta,omitempty"`
+	AlphaPct         float64  `json:"alpha_pct"` // Jensen's alpha
+	TrackingErrorPct float64  `json:"tracking_error_pct"`
+	InformationRatio float64  `json:"information_ratio"`
+
+	Series []BenchmarkPoint `json:"series,omitempty"`
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// CorporateActionType identifies a split or a cash dividend of a symbol
+type CorporateActionType string
+
+const (
+	CorporateActionSplit    CorporateActionType = "split"
+	CorporateActionDividend CorporateActionType = "dividend"
+)
+
+func (t CorporateActionType) IsValid() bool {
+	return t == CorporateActionSplit || t == CorporateActionDividend
+}
+
+// CorporateActionSource records where an action came from
+type CorporateActionSource string
+
+const (
+	CorporateActionProvider CorporateActionSource = "provider" // Read from an adjusted daily series
+	CorporateActionManual   CorporateActionSource = "manual"   // Entered by an admin
+)
+
+// CorporateAction is a split or dividend of a symbol taking effect on its ex-date.
+// Bars before the ex-date of a split are scaled to the post-split share count when history is read.
+type CorporateAction struct {
+	ID        string                `json:"id"`
+	Symbol    string                `json:"symbol"`
+	Type      CorporateActionType   `json:"type"`
+	ExDate    time.Time             `json:"ex_date"`
+	Ratio     float64               `json:"ratio,omitempty"`  // New shares per old share, 4 for a 4:1 split
+	Amount    float64               `json:"amount,omitempty"` // Dividend per share in the listing currency
+	Source    CorporateActionSource `json:"source"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+// CreateCorporateActionRequest represents the request body to enter a split or dividend by hand
+type CreateCorporateActionRequest struct {
+	Symbol string              `json:"symbol"`
+	Type   CorporateActionType `json:"type"`
+	ExDate string              `json:"ex_date"` // YYYY-MM-DD
+	Ratio  float64             `json:"ratio"`
+	Amount float64             `json:"amount"`
+}
+
+// CorporateActionSync is the outcome of storing a symbol's actions and recording them in the ledgers holding it
+type CorporateActionSync struct {
+	Symbol   string            `json:"symbol"`
+	Actions  []CorporateAction `json:"actions"`
+	Recorded int               `json:"recorded"` // Split and dividend entries added to ledgers
+}
+
+// CorporateActionExistsError is returned when an action of the same type and ex-date is already known for a symbol
+type CorporateActionExistsError struct {
+	Symbol string
+	Type   CorporateActionType
+	ExDate time.Time
+}
+
+func (e *CorporateActionExistsError) Error() string {
+	return fmt.Sprintf("a %s of %s on %s is already recorded", e.Type, e.Symbol, e.ExDate.Format("2006-01-02"))
+}
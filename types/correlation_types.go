@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// CorrelationCluster is a group of holdings whose returns move together, each linked to another
+// of the group by a correlation at or above the report threshold
+type CorrelationCluster struct {
+	Symbols            []string `json:"symbols"`
+	AverageCorrelation float64  `json:"average_correlation"` // Over every pair of the cluster with enough common dates
+	Weight             float64  `json:"weight"`              // Combined share of the portfolio market value, 0-1
+}
+
+// CorrelationReport holds the pairwise statistics of the daily returns of a portfolio's holdings.
+// Rows and columns of the matrices follow Symbols, each pair is measured on the dates both symbols have
+// a return, pairs with fewer than two common dates are null.
+type CorrelationReport struct {
+	PortfolioID  string               `json:"portfolio_id"`
+	From         time.Time            `json:"from"`
+	To           time.Time            `json:"to"`
+	Threshold    float64              `json:"threshold"`
+	Symbols      []string             `json:"symbols"`
+	Correlation  [][]*float64         `json:"correlation"`
+	Covariance   [][]*float64         `json:"covariance"` // Annualized
+	Observations [][]int              `json:"observations"`
+	Clusters     []CorrelationCluster `json:"clusters"`
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the currency of portfolios, holdings and transactions that do not name one
+const DefaultCurrency = "USD"
+
+// PenceCurrency is the quote currency of London listings, hundredths of a pound sterling.
+// It is valid on holdings and transactions but not as a portfolio's base currency.
+const PenceCurrency = "GBX"
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ValidCurrency reports whether code looks like an ISO 4217 currency code, e.g. USD, EUR or GBP
+func ValidCurrency(code string) bool {
+	return currencyPattern.MatchString(code)
+}
+
+// listingCurrencies maps the exchange suffix of Alpha Vantage symbols to the currency they are quoted in
+var listingCurrencies = map[string]string{
+	"LON": PenceCurrency, // London Stock Exchange
+	"DEX": "EUR",         // XETRA
+	"FRK": "EUR",         // Frankfurt
+	"TRT": "CAD",         // Toronto
+	"TRV": "CAD",         // TSX Venture
+	"BSE": "INR",         // Bombay
+	"SHH": "CNY",         // Shanghai
+	"SHZ": "CNY",         // Shenzhen
+}
+
+// ListingCurrency returns the currency a symbol is quoted in from its exchange suffix, e.g. GBX for VOD.LON
+// and EUR for SAP.DEX. Symbols without a known suffix are US listings.
+func ListingCurrency(symbol string) string {
+	if i := strings.LastIndex(symbol, "."); i >= 0 {
+		if currency, ok := listingCurrencies[strings.ToUpper(symbol[i+1:])]; ok {
+			return currency
+		}
+	}
+	return DefaultCurrency
+}
+
+// FXRate is the daily close of a currency pair, units of To per unit of From
+type FXRate struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Date time.Time `json:"date"`
+	Rate float64   `json:"rate"`
+}
+
+// FXSeries is the daily rate history of a currency pair returned by an FX provider, ordered by date ascending
+type FXSeries struct {
+	From          string   `json:"from"`
+	To            string   `json:"to"`
+	LastRefreshed string   `json:"last_refreshed"`
+	Rates         []FXRate `json:"rates"`
+}
+
+// SetBaseCurrencyRequest represents the request body to change the base currency of a portfolio
+type SetBaseCurrencyRequest struct {
+	BaseCurrency string `json:"base_currency"`
+}
+
+// MissingFXRateError is returned when an amount cannot be converted because no rate of the pair is known
+type MissingFXRateError struct {
+	From string
+	To   string
+	Date time.Time
+}
+
+func (e *MissingFXRateError) Error() string {
+	return fmt.Sprintf("no %s/%s exchange rate known for %s", e.From, e.To, e.Date.Format("2006-01-02"))
+}
+
+// CurrencyMismatchError is returned when a trade of a symbol is recorded in another currency than its earlier trades
+type CurrencyMismatchError struct {
+	Symbol   string
+	Currency string
+	Expected string
+}
+
+func (e *CurrencyMismatchError) Error() string {
+	return fmt.Sprintf("%s is traded in %s, cannot record a trade in %s", e.Symbol, e.Expected, e.Currency)
+}
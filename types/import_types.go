@@ -0,0 +1,41 @@
+package types
+
+import "fmt"
+
+// ImportKind selects how the rows of an imported file are read
+type ImportKind string
+
+const (
+	ImportTransactions ImportKind = "transactions" // One ledger entry per row
+	ImportHoldings     ImportKind = "holdings"     // One open position per row, recorded as a buy
+)
+
+func (k ImportKind) IsValid() bool {
+	return k == ImportTransactions || k == ImportHoldings
+}
+
+// ImportRowError is a validation error of one row, Line is the 1-based line in the file (0 for the whole file)
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ImportRowError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ImportReport is the outcome of an import. Nothing is written when there are errors or on a dry run.
+type ImportReport struct {
+	Kind          ImportKind       `json:"kind"`
+	DryRun        bool             `json:"dry_run"`
+	Rows          int              `json:"rows"`
+	Imported      int              `json:"imported"`   // Rows written, or that would be written on a dry run
+	Duplicates    int              `json:"duplicates"` // Rows skipped because they were imported before
+	Errors        []ImportRowError `json:"errors"`
+	PendingPrices []string         `json:"pending_prices,omitempty"` // Symbols whose prices will be fetched by the next refresh
+	Transactions  []Transaction    `json:"transactions"`
+}
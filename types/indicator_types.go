@@ -0,0 +1,85 @@
+package types
+
+import "time"
+
+// IndicatorPoint is the value of an indicator at the close of a bar
+type IndicatorPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+}
+
+// IndicatorSeries is an indicator computed over a number of bars
+type IndicatorSeries struct {
+	Period int              `json:"period"`
+	Points []IndicatorPoint `json:"points"`
+}
+
+// MACDPoint is the difference of the fast and slow EMAs, its signal EMA and their difference
+type MACDPoint struct {
+	Date      time.Time `json:"date"`
+	MACD      float64   `json:"macd"`
+	Signal    float64   `json:"signal"`
+	Histogram float64   `json:"histogram"`
+}
+
+type MACDSeries struct {
+	Fast   int         `json:"fast"`
+	Slow   int         `json:"slow"`
+	Signal int         `json:"signal"`
+	Points []MACDPoint `json:"points"`
+}
+
+// BollingerPoint is the moving average of the close with bands a number of standard deviations around it
+type BollingerPoint struct {
+	Date   time.Time `json:"date"`
+	Middle float64   `json:"middle"`
+	Upper  float64   `json:"upper"`
+	Lower  float64   `json:"lower"`
+}
+
+type BollingerSeries struct {
+	Period int              `json:"period"`
+	StdDev float64          `json:"std_dev"`
+	Points []BollingerPoint `json:"points"`
+}
+
+// IndicatorValue is the latest value of an indicator computed over a number of bars
+type IndicatorValue struct {
+	Period int     `json:"period"`
+	Value  float64 `json:"value"`
+}
+
+// IndicatorSnapshot holds the indicator values at the last bar, an indicator is missing
+// while there are too few bars to compute it
+type IndicatorSnapshot struct {
+	Date      time.Time        `json:"date"`
+	Close     float64          `json:"close"`
+	SMA       []IndicatorValue `json:"sma"`
+	EMA       []IndicatorValue `json:"ema"`
+	RSI       *IndicatorValue  `json:"rsi,omitempty"`
+	MACD      *MACDPoint       `json:"macd,omitempty"`
+	Bollinger *BollingerPoint  `json:"bollinger,omitempty"`
+	ATR       *IndicatorValue  `json:"atr,omitempty"`
+	OBV       *float64         `json:"obv,omitempty"`
+	VWAP      *float64         `json:"vwap,omitempty"`
+}
+
+// IndicatorReport holds the technical indicators of a symbol over its stored price history, in the listing currency.
+// Bars before From only warm the indicators up; OBV and VWAP accumulate from the first bar of the range,
+// VWAP restarting every day for intraday intervals.
+type IndicatorReport struct {
+	Symbol    string             `json:"symbol"`
+	Interval  Interval           `json:"interval"`
+	From      time.Time          `json:"from"`
+	To        time.Time          `json:"to"`
+	Bars      int                `json:"bars"` // Bars in the range
+	SMA       []IndicatorSeries  `json:"sma"`
+	EMA       []IndicatorSeries  `json:"ema"`
+	RSI       IndicatorSeries    `json:"rsi"`
+	MACD      MACDSeries         `json:"macd"`
+	Bollinger BollingerSeries    `json:"bollinger"`
+	ATR       IndicatorSeries    `json:"atr"`
+	OBV       []IndicatorPoint   `json:"obv"`
+	VWAP      []IndicatorPoint   `json:"vwap"`
+	Latest    *IndicatorSnapshot `json:"latest,omitempty"` // Missing without bars in the range
+}
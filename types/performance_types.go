@@ -0,0 +1,48 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// PerformancePoint is the value of a portfolio at the close of a day
+type PerformancePoint struct {
+	Date    time.Time `json:"date"`
+	Value   float64   `json:"value"`    // Holdings at their close plus cash
+	NetFlow float64   `json:"net_flow"` // Money added to (positive) or taken from the portfolio that day
+	Return  float64   `json:"return"`   // Time-weighted return of the day as a fraction, 0.01 is 1%
+}
+
+// PerformanceReport holds the returns of a portfolio over a date range.
+// Time-weighted returns ignore the timing of deposits and withdrawals, money-weighted returns (XIRR) include it.
+type PerformanceReport struct {
+	PortfolioID      string    `json:"portfolio_id"`
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	Days             int       `json:"days"`
+	Currency         string    `json:"currency"` // Base currency of the portfolio, every value is in it
+	StartValue       float64   `json:"start_value"`
+	EndValue         float64   `json:"end_value"`
+	NetContributions float64   `json:"net_contributions"` // Deposits less withdrawals, buys not covered by cash count as deposits
+	Income           float64   `json:"income"`            // Dividends received
+	Fees             float64   `json:"fees"`              // Trade commissions and fee transactions
+	Gain             float64   `json:"gain"`              // End value less start value and net contributions
+
+	TimeWeightedReturnPct           float64 `json:"time_weighted_return_pct"`
+	AnnualizedTimeWeightedReturnPct float64 `json:"annualized_time_weighted_return_pct"`
+
+	// Missing when the cash flows have no internal rate of return
+	MoneyWeightedReturnPct           *float64 `json:"money_weighted_return_pct,omitempty"`
+	AnnualizedMoneyWeightedReturnPct *float64 `json:"annualized_money_weighted_return_pct,omitempty"` // XIRR
+
+	Series []PerformancePoint `json:"series,omitempty"`
+}
+
+// NoPerformanceError is returned when a ledger has no values to measure over the requested period
+type NoPerformanceError struct {
+	Reason string
+}
+
+func (e *NoPerformanceError) Error() string {
+	return fmt.Sprintf("no performance to measure: %s", e.Reason)
+}
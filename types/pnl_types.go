@@ -0,0 +1,109 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// CostBasisMethod selects which lots a sell is matched against
+type CostBasisMethod string
+
+const (
+	CostBasisFIFO     CostBasisMethod = "fifo"
+	CostBasisLIFO     CostBasisMethod = "lifo"
+	CostBasisSpecific CostBasisMethod = "specific" // Sells name their lot, sells without one fall back to FIFO
+	CostBasisAverage  CostBasisMethod = "average"
+)
+
+func (m CostBasisMethod) IsValid() bool {
+	switch m {
+	case CostBasisFIFO, CostBasisLIFO, CostBasisSpecific, CostBasisAverage:
+		return true
+	}
+	return false
+}
+
+// HoldingTerm classifies a holding period for tax purposes
+type HoldingTerm string
+
+const (
+	ShortTerm HoldingTerm = "short_term"
+	LongTerm  HoldingTerm = "long_term" // Held for more than one year
+)
+
+// Lot is an open tax lot, the remainder of a single buy
+type Lot struct {
+	ID               string      `json:"id"` // ID of the buy transaction that opened the lot
+	Symbol           string      `json:"symbol"`
+	AcquiredAt       time.Time   `json:"acquired_at"`
+	OriginalQuantity float64     `json:"original_quantity"`
+	Quantity         float64     `json:"quantity"`
+	CostPerShare     float64     `json:"cost_per_share"` // Includes fees, adjusted for splits
+	CostBasis        float64     `json:"cost_basis"`
+	Term             HoldingTerm `json:"term"`
+	HoldingDays      int         `json:"holding_days"`
+
+	// Filled in when a market price is known
+	MarketPrice       float64 `json:"market_price"`
+	MarketValue       float64 `json:"market_value"`
+	UnrealizedGain    float64 `json:"unrealized_gain"`
+	UnrealizedGainPct float64 `json:"unrealized_gain_pct"`
+}
+
+// RealizedLot is the part of a lot closed by a sell
+type RealizedLot struct {
+	LotID       string      `json:"lot_id"`
+	SellID      string      `json:"sell_id"`
+	Symbol      string      `json:"symbol"`
+	AcquiredAt  time.Time   `json:"acquired_at"`
+	SoldAt      time.Time   `json:"sold_at"`
+	Quantity    float64     `json:"quantity"`
+	CostBasis   float64     `json:"cost_basis"`
+	Proceeds    float64     `json:"proceeds"` // Net of the sell's fees
+	Gain        float64     `json:"gain"`
+	Term        HoldingTerm `json:"term"`
+	HoldingDays int         `json:"holding_days"`
+}
+
+// HoldingPnL summarizes the realized and unrealized gains of one symbol
+type HoldingPnL struct {
+	Symbol              string  `json:"symbol"`
+	Quantity            float64 `json:"quantity"`
+	CostBasis           float64 `json:"cost_basis"`
+	MarketValue         float64 `json:"market_value"`
+	UnrealizedGain      float64 `json:"unrealized_gain"`
+	ShortTermUnrealized float64 `json:"short_term_unrealized"`
+	LongTermUnrealized  float64 `json:"long_term_unrealized"`
+	RealizedGain        float64 `json:"realized_gain"`
+	ShortTermRealized   float64 `json:"short_term_realized"`
+	LongTermRealized    float64 `json:"long_term_realized"`
+	Lots                []Lot   `json:"lots,omitempty"`
+}
+
+// PnLReport is the gain and loss report of a portfolio
+type PnLReport struct {
+	PortfolioID         string          `json:"portfolio_id"`
+	Method              CostBasisMethod `json:"method"`
+	Currency            string          `json:"currency"` // Base currency of the portfolio, every amount is in it
+	AsOf                time.Time       `json:"as_of"`
+	Holdings            []HoldingPnL    `json:"holdings"`
+	TotalCostBasis      float64         `json:"total_cost_basis"`
+	TotalMarketValue    float64         `json:"total_market_value"`
+	UnrealizedGain      float64         `json:"unrealized_gain"`
+	ShortTermUnrealized float64         `json:"short_term_unrealized"`
+	LongTermUnrealized  float64         `json:"long_term_unrealized"`
+	RealizedGain        float64         `json:"realized_gain"`
+	ShortTermRealized   float64         `json:"short_term_realized"`
+	LongTermRealized    float64         `json:"long_term_realized"`
+}
+
+// InvalidLotError is returned when a specific-lot sell names a lot that cannot cover it
+type InvalidLotError struct {
+	LotID  string
+	Symbol string
+	Reason string
+}
+
+func (e *InvalidLotError) Error() string {
+	return fmt.Sprintf("invalid lot %s for %s: %s", e.LotID, e.Symbol, e.Reason)
+}
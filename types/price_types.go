@@ -0,0 +1,68 @@
+package types
+
+import "time"
+
+// Interval identifies the bar size of a price series
+type Interval string
+
+const (
+	Interval1Min          Interval = "1min"
+	Interval5Min          Interval = "5min"
+	Interval15Min         Interval = "15min"
+	Interval60Min         Interval = "60min"
+	IntervalDaily         Interval = "daily"
+	IntervalDailyAdjusted Interval = "daily_adjusted"
+	IntervalWeekly        Interval = "weekly"
+	IntervalMonthly       Interval = "monthly"
+)
+
+func (i Interval) IsValid() bool {
+	switch i {
+	case Interval1Min, Interval5Min, Interval15Min, Interval60Min,
+		IntervalDaily, IntervalDailyAdjusted, IntervalWeekly, IntervalMonthly:
+		return true
+	}
+	return false
+}
+
+// IsIntraday reports whether bars of this interval are shorter than a trading day
+func (i Interval) IsIntraday() bool {
+	return i == Interval1Min || i == Interval5Min || i == Interval15Min || i == Interval60Min
+}
+
+// PriceBar is a single OHLCV bar of a symbol's price history
+type PriceBar struct {
+	Symbol        string    `json:"symbol"`
+	Interval      Interval  `json:"interval"`
+	Date          time.Time `json:"date"`
+	Open          float64   `json:"open"`
+	High          float64   `json:"high"`
+	Low           float64   `json:"low"`
+	Close         float64   `json:"close"`
+	AdjustedClose float64   `json:"adjusted_close"` // Equals Close for unadjusted intervals
+	Volume        int64     `json:"volume"`
+}
+
+// PriceSeries is the full price history returned by a market data provider, ordered by date ascending.
+// Bars are stored as traded, splits are applied when history is read.
+type PriceSeries struct {
+	Symbol        string            `json:"symbol"`
+	Interval      Interval          `json:"interval"`
+	LastRefreshed string            `json:"last_refreshed"`
+	Bars          []PriceBar        `json:"bars"`
+	Actions       []CorporateAction `json:"actions,omitempty"` // Splits and dividends reported by adjusted series
+}
+
+// Latest returns the most recent bar of the series, or nil if the series is empty
+func (s *PriceSeries) Latest() *PriceBar {
+	if len(s.Bars) == 0 {
+		return nil
+	}
+	return &s.Bars[len(s.Bars)-1]
+}
+
+// TrackedSymbol is a symbol and interval whose price history is kept fresh
+type TrackedSymbol struct {
+	Symbol   string   `json:"symbol"`
+	Interval Interval `json:"interval"`
+}
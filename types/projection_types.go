@@ -0,0 +1,65 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProjectionMethod is how simulated daily returns are drawn from the historical ones
+type ProjectionMethod string
+
+const (
+	ProjectionBootstrap ProjectionMethod = "bootstrap" // Resample historical days
+	ProjectionNormal    ProjectionMethod = "normal"    // Sample a normal distribution fitted to historical log returns
+)
+
+func (m ProjectionMethod) IsValid() bool {
+	return m == ProjectionBootstrap || m == ProjectionNormal
+}
+
+// ProjectionBand holds percentiles of the simulated portfolio value at the end of a month of the projection
+type ProjectionBand struct {
+	Month       int       `json:"month"`
+	Date        time.Time `json:"date"`
+	Contributed float64   `json:"contributed"` // Start value plus the contributions made so far
+	P5          float64   `json:"p5"`
+	P25         float64   `json:"p25"`
+	P50         float64   `json:"p50"`
+	P75         float64   `json:"p75"`
+	P95         float64   `json:"p95"`
+	Mean        float64   `json:"mean"`
+}
+
+// ProjectionReport is a Monte Carlo projection of a portfolio's value in its base currency. Holdings keep their
+// current weights, rebalanced daily, and returns are drawn from their common daily returns in the listing
+// currency. The seed reproduces the same paths.
+type ProjectionReport struct {
+	PortfolioID          string             `json:"portfolio_id"`
+	Currency             string             `json:"currency"`
+	Method               ProjectionMethod   `json:"method"`
+	Paths                int                `json:"paths"`
+	Years                int                `json:"years"`
+	Seed                 int64              `json:"seed"`
+	StartValue           float64            `json:"start_value"`
+	Contribution         float64            `json:"contribution"`
+	Frequency            RebalanceFrequency `json:"frequency"` // Of the contributions
+	HistoryFrom          time.Time          `json:"history_from"`
+	HistoryTo            time.Time          `json:"history_to"`
+	Observations         int                `json:"observations"`        // Historical daily returns drawn from
+	ExpectedReturnPct    float64            `json:"expected_return_pct"` // Annualized mean of the historical returns
+	VolatilityPct        float64            `json:"volatility_pct"`      // Annualized
+	Bands                []ProjectionBand   `json:"bands"`
+	LossProbabilityPct   float64            `json:"loss_probability_pct"` // Paths ending below the money put in
+	Target               *float64           `json:"target,omitempty"`
+	TargetProbabilityPct *float64           `json:"target_probability_pct,omitempty"` // Paths ending at or above the target
+}
+
+// InsufficientHistoryError is returned when holdings share too few daily returns to project from
+type InsufficientHistoryError struct {
+	Observations int
+	Required     int
+}
+
+func (e *InsufficientHistoryError) Error() string {
+	return fmt.Sprintf("holdings share %d daily returns, at least %d are needed", e.Observations, e.Required)
+}
@@ -0,0 +1,55 @@
+package types
+
+import "time"
+
+// Drawdown is the largest fall from a peak, RecoveredAt is missing while the peak has not been regained
+type Drawdown struct {
+	DepthPct    float64    `json:"depth_pct"`
+	PeakAt      time.Time  `json:"peak_at"`
+	TroughAt    time.Time  `json:"trough_at"`
+	RecoveredAt *time.Time `json:"recovered_at,omitempty"`
+}
+
+// ValueAtRisk is the one-day loss not exceeded at the confidence level (VaR) and the average loss
+// beyond it (CVaR), as a share of the value and as an amount of it
+type ValueAtRisk struct {
+	Confidence        float64 `json:"confidence"` // e.g. 0.95
+	HistoricalVaRPct  float64 `json:"historical_var_pct"`
+	HistoricalCVaRPct float64 `json:"historical_cvar_pct"`
+	ParametricVaRPct  float64 `json:"parametric_var_pct"` // Assumes normally distributed returns
+	ParametricCVaRPct float64 `json:"parametric_cvar_pct"`
+	HistoricalVaR     float64 `json:"historical_var"`
+	HistoricalCVaR    float64 `json:"historical_cvar"`
+	ParametricVaR     float64 `json:"parametric_var"`
+	ParametricCVaR    float64 `json:"parametric_cvar"`
+}
+
+// RiskMetrics are measured on the daily returns of a holding or a portfolio
+type RiskMetrics struct {
+	Observations  int         `json:"observations"`   // Daily returns measured
+	VolatilityPct float64     `json:"volatility_pct"` // Annualized
+	SharpeRatio   float64     `json:"sharpe_ratio"`
+	SortinoRatio  float64     `json:"sortino_ratio"`
+	MaxDrawdown   Drawdown    `json:"max_drawdown"`
+	Beta          *float64    `json:"beta,omitempty"` // Missing when the benchmark has too few common dates
+	ValueAtRisk   ValueAtRisk `json:"value_at_risk"`
+}
+
+// HoldingRisk is the risk of a single holding of a portfolio
+type HoldingRisk struct {
+	Symbol      string      `json:"symbol"`
+	MarketValue float64     `json:"market_value"`
+	Weight      float64     `json:"weight"`
+	Risk        RiskMetrics `json:"risk"`
+}
+
+// RiskReport holds the risk of a portfolio and of each of its holdings over a date range
+type RiskReport struct {
+	PortfolioID     string        `json:"portfolio_id"`
+	From            time.Time     `json:"from"`
+	To              time.Time     `json:"to"`
+	Benchmark       string        `json:"benchmark"`
+	RiskFreeRatePct float64       `json:"risk_free_rate_pct"` // Annual
+	Portfolio       RiskMetrics   `json:"portfolio"`
+	Holdings        []HoldingRisk `json:"holdings"`
+}
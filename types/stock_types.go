@@ -6,21 +6,51 @@ type Portfolio struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"`
 	Name      string    `json:"name"`
+	IsDefault bool      `json:"is_default"` // Used by the /portfolio routes that do not name a portfolio
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Stocks    []Stock   `json:"stocks,omitempty"` // Optional for when you want to include stocks
+
+	CostBasisMethod CostBasisMethod `json:"cost_basis_method"`
+	BaseCurrency    string          `json:"base_currency"` // Currency the portfolio is valued and reported in
+
+	// Computed by the valuation package in the base currency, not stored
+	TotalValue        float64 `json:"total_value"`
+	TotalCost         float64 `json:"total_cost"`
+	UnrealizedGain    float64 `json:"unrealized_gain"`
+	UnrealizedGainPct float64 `json:"unrealized_gain_pct"`
 }
 
+// Stock is a holding in a portfolio. Its prices are not stored on the holding itself,
+// they come from the latest bar of the symbol's price history for the holding's interval.
+// Prices and the average cost are in the holding's currency, computed values in the portfolio's base currency.
 type Stock struct {
 	ID          string    `json:"id"`
 	PortfolioID string    `json:"portfolio_id"`
 	Symbol      string    `json:"symbol"`
-	Month       string    `json:"month"` // Format: YYYY-MM
-	Open        float64   `json:"open"`  // Changed to float64 for decimal values
+	Interval    Interval  `json:"interval"`
+	Date        time.Time `json:"date"` // Date of the latest price bar
+	Open        float64   `json:"open"`
 	High        float64   `json:"high"`
 	Low         float64   `json:"low"`
 	Close       float64   `json:"close"`
-	Volume      int64     `json:"volume"` // Changed to int64 for bigint
+	Volume      int64     `json:"volume"`
+	Quantity    float64   `json:"quantity"`
+	AverageCost float64   `json:"average_cost"` // Average purchase price per share
+	Currency    string    `json:"currency"`     // Currency the symbol is quoted and traded in
+	AcquiredAt  time.Time `json:"acquired_at"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Conversion to the portfolio's base currency, 1 when the holding is in the base currency
+	BaseCurrency string  `json:"base_currency"`
+	FXRate       float64 `json:"fx_rate"`      // Rate of the latest bar's date, converts prices
+	CostFXRate   float64 `json:"cost_fx_rate"` // Converts the average cost, blends the rates of the open lots' trade dates
+
+	// Computed by the valuation package, not stored
+	MarketValue       float64 `json:"market_value"`
+	CostBasis         float64 `json:"cost_basis"`
+	UnrealizedGain    float64 `json:"unrealized_gain"`
+	UnrealizedGainPct float64 `json:"unrealized_gain_pct"`
+	Weight            float64 `json:"weight"` // Share of the portfolio market value, 0-1
 }
@@ -0,0 +1,104 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+type TransactionType string
+
+const (
+	TransactionBuy        TransactionType = "buy"
+	TransactionSell       TransactionType = "sell"
+	TransactionDividend   TransactionType = "dividend"
+	TransactionSplit      TransactionType = "split"
+	TransactionFee        TransactionType = "fee"
+	TransactionDeposit    TransactionType = "deposit"
+	TransactionWithdrawal TransactionType = "withdrawal"
+)
+
+func (t TransactionType) IsValid() bool {
+	switch t {
+	case TransactionBuy, TransactionSell, TransactionDividend, TransactionSplit,
+		TransactionFee, TransactionDeposit, TransactionWithdrawal:
+		return true
+	}
+	return false
+}
+
+// Transaction is an entry of a portfolio's ledger, holdings are derived by replaying them in trade date order
+type Transaction struct {
+	ID          string          `json:"id"`
+	PortfolioID string          `json:"portfolio_id"`
+	Type        TransactionType `json:"type"`
+	Symbol      string          `json:"symbol,omitempty"`
+	Quantity    float64         `json:"quantity"`              // Shares bought or sold
+	Price       float64         `json:"price"`                 // Price per share for buys and sells
+	Amount      float64         `json:"amount"`                // Cash amount for dividends, fees, deposits and withdrawals
+	Fees        float64         `json:"fees"`                  // Commission paid on a buy or sell
+	Currency    string          `json:"currency"`              // Currency of the price, amount and fees
+	SplitRatio  float64         `json:"split_ratio"`           // New shares per old share, e.g. 4 for a 4:1 split
+	LotID       string          `json:"lot_id,omitempty"`      // Buy transaction a specific-lot sell is matched against
+	ExternalID  string          `json:"external_id,omitempty"` // Import key, a re-imported row with the same key is skipped
+	TradeDate   time.Time       `json:"trade_date"`
+	Notes       string          `json:"notes"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// CreateTransactionRequest represents the request body to record a transaction
+type CreateTransactionRequest struct {
+	Type       TransactionType `json:"type"`
+	Symbol     string          `json:"symbol"`
+	Quantity   float64         `json:"quantity"`
+	Price      float64         `json:"price"`
+	Amount     float64         `json:"amount"`
+	Fees       float64         `json:"fees"`
+	Currency   string          `json:"currency"` // Defaults to the symbol's currency, or the base currency for cash entries
+	SplitRatio float64         `json:"split_ratio"`
+	LotID      string          `json:"lot_id"`     // Only for sells in portfolios using the specific-lot method
+	TradeDate  string          `json:"trade_date"` // YYYY-MM-DD, defaults to today
+	Notes      string          `json:"notes"`
+}
+
+// InsufficientHoldingsError is returned when a sell exceeds the shares held at its trade date
+type InsufficientHoldingsError struct {
+	Symbol    string
+	Held      float64
+	Requested float64
+	TradeDate time.Time
+}
+
+func (e *InsufficientHoldingsError) Error() string {
+	return fmt.Sprintf("cannot sell %g shares of %s on %s, only %g held",
+		e.Requested, e.Symbol, e.TradeDate.Format("2006-01-02"), e.Held)
+}
+
+// TransactionNotFoundError is returned when a transaction is not part of a portfolio's ledger
+type TransactionNotFoundError struct {
+	PortfolioID   string
+	TransactionID string
+}
+
+func (e *TransactionNotFoundError) Error() string {
+	return fmt.Sprintf("transaction %s not found in portfolio %s", e.TransactionID, e.PortfolioID)
+}
+
+// StockHistoryError is returned when removing a holding whose ledger holds more than its buys, such as
+// sells, dividends or splits, which deleting it would erase
+type StockHistoryError struct {
+	Symbol  string
+	Entries int
+}
+
+func (e *StockHistoryError) Error() string {
+	return fmt.Sprintf("%s has %d ledger entries besides its buys", e.Symbol, e.Entries)
+}
+
+// TransactionFilter narrows a ledger listing, empty fields match everything
+type TransactionFilter struct {
+	Symbol string
+	Type   TransactionType
+	From   time.Time // Trade date range, a zero bound leaves that side open
+	To     time.Time
+}
@@ -38,9 +38,12 @@ type LoginUserResponse struct {
 	Token string `json:"token"`
 }
 
-// AddStockRequest represents the request to add a stock
+// AddStockRequest represents the request body to add a stock, the symbol comes from the URL
 type AddStockRequest struct {
-	Symbol string `json:"symbol"`
+	Quantity    float64 `json:"quantity"`
+	AverageCost float64 `json:"average_cost"` // Defaults to the latest close
+	Currency    string  `json:"currency"`     // Currency of the average cost, defaults to the listing's currency
+	AcquiredAt  string  `json:"acquired_at"`  // YYYY-MM-DD, defaults to today
 }
 
 // StockOwnershipError represents an ownership validation error
@@ -53,6 +56,35 @@ func (e *StockOwnershipError) Error() string {
 	return fmt.Sprintf("user %s does not own stock %s", e.UserID, e.StockID)
 }
 
+// StockNotFoundError is returned when a stock is not part of a portfolio
+type StockNotFoundError struct {
+	PortfolioID string
+	StockID     string
+}
+
+func (e *StockNotFoundError) Error() string {
+	return fmt.Sprintf("stock %s not found in portfolio %s", e.StockID, e.PortfolioID)
+}
+
+// PortfolioOwnershipError is returned when a portfolio does not exist or belongs to another user
+type PortfolioOwnershipError struct {
+	UserID      string
+	PortfolioID string
+}
+
+func (e *PortfolioOwnershipError) Error() string {
+	return fmt.Sprintf("user %s does not own portfolio %s", e.UserID, e.PortfolioID)
+}
+
+// PortfolioNameTakenError is returned when the user already has a portfolio with the name
+type PortfolioNameTakenError struct {
+	Name string
+}
+
+func (e *PortfolioNameTakenError) Error() string {
+	return fmt.Sprintf("a portfolio named %q already exists", e.Name)
+}
+
 func NewUser(params RegisterUser) (*User, error) {
 	hashedPswrd, err := bcrypt.GenerateFromPassword([]byte(params.Password), 12)
 	if err != nil {
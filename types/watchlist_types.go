@@ -0,0 +1,84 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Watchlist is a named list of symbols a user tracks without holding them
+type Watchlist struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"user_id"`
+	Name      string           `json:"name"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	Entries   []WatchlistEntry `json:"entries,omitempty"`
+}
+
+// WatchlistEntry is a tracked symbol, its prices come from the latest bar of the symbol's price history
+type WatchlistEntry struct {
+	ID          string    `json:"id"`
+	WatchlistID string    `json:"watchlist_id"`
+	Symbol      string    `json:"symbol"`
+	Interval    Interval  `json:"interval"`
+	Notes       string    `json:"notes"`
+	Date        time.Time `json:"date"` // Date of the latest price bar
+	Open        float64   `json:"open"`
+	High        float64   `json:"high"`
+	Low         float64   `json:"low"`
+	Close       float64   `json:"close"`
+	Volume      int64     `json:"volume"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AddWatchlistEntryRequest represents the request body to add a symbol to a watchlist
+type AddWatchlistEntryRequest struct {
+	Symbol   string   `json:"symbol"`
+	Interval Interval `json:"interval"` // Defaults to monthly
+	Notes    string   `json:"notes"`
+}
+
+// PromoteWatchlistEntryRequest represents the request body to turn a watchlist entry into a holding
+type PromoteWatchlistEntryRequest struct {
+	PortfolioID string `json:"portfolio_id"` // Defaults to the user's default portfolio
+	AddStockRequest
+}
+
+// WatchlistNotFoundError is returned when a watchlist does not exist or belongs to another user
+type WatchlistNotFoundError struct {
+	UserID      string
+	WatchlistID string
+}
+
+func (e *WatchlistNotFoundError) Error() string {
+	return fmt.Sprintf("watchlist %s not found for user %s", e.WatchlistID, e.UserID)
+}
+
+// WatchlistEntryNotFoundError is returned when an entry is not part of a watchlist
+type WatchlistEntryNotFoundError struct {
+	WatchlistID string
+	EntryID     string
+}
+
+func (e *WatchlistEntryNotFoundError) Error() string {
+	return fmt.Sprintf("entry %s not found in watchlist %s", e.EntryID, e.WatchlistID)
+}
+
+// WatchlistNameTakenError is returned when the user already has a watchlist with the name
+type WatchlistNameTakenError struct {
+	Name string
+}
+
+func (e *WatchlistNameTakenError) Error() string {
+	return fmt.Sprintf("a watchlist named %q already exists", e.Name)
+}
+
+// WatchlistEntryExistsError is returned when the symbol is already on the watchlist
+type WatchlistEntryExistsError struct {
+	Symbol string
+}
+
+func (e *WatchlistEntryExistsError) Error() string {
+	return fmt.Sprintf("%s is already on this watchlist", e.Symbol)
+}
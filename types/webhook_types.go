@@ -0,0 +1,128 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WebhookEvent names an event pushed to webhooks
+type WebhookEvent string
+
+const (
+	WebhookEventAnalysisCompleted WebhookEvent = "analysis.completed"
+	WebhookEventAlertTriggered    WebhookEvent = "alert.triggered"
+	WebhookEventStockAdded        WebhookEvent = "stock.added"
+	WebhookEventStockRemoved      WebhookEvent = "stock.removed"
+	WebhookEventPing              WebhookEvent = "ping" // Sent on demand to test an endpoint
+)
+
+// IsValid reports whether e is an event webhooks can subscribe to
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookEventAnalysisCompleted, WebhookEventAlertTriggered, WebhookEventStockAdded, WebhookEventStockRemoved, WebhookEventPing:
+		return true
+	}
+	return false
+}
+
+// Webhook is a user registered endpoint receiving signed event payloads
+type Webhook struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"user_id"`
+	URL       string         `json:"url"`
+	Events    []WebhookEvent `json:"events"`           // Empty subscribes to every event
+	Secret    string         `json:"secret,omitempty"` // HMAC-SHA256 key, only returned when the webhook is created
+	Enabled   bool           `json:"enabled"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook receives event, pings reach every webhook
+func (w *Webhook) Subscribes(event WebhookEvent) bool {
+	if len(w.Events) == 0 || event == WebhookEventPing {
+		return true
+	}
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the state of a delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending    WebhookDeliveryStatus = "pending" // Waiting for its first attempt or a retry
+	WebhookDeliveryDelivering WebhookDeliveryStatus = "delivering"
+	WebhookDeliverySucceeded  WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed     WebhookDeliveryStatus = "failed" // Gave up after the last retry
+)
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of its last attempt
+type WebhookDelivery struct {
+	ID            string                `json:"id"`
+	WebhookID     string                `json:"webhook_id"`
+	Event         WebhookEvent          `json:"event"`
+	Payload       json.RawMessage       `json:"payload"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	ResponseCode  int                   `json:"response_code,omitempty"`
+	LastError     string                `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at,omitempty"`
+	ReplayOf      string                `json:"replay_of,omitempty"` // Delivery this one replays
+	CreatedAt     time.Time             `json:"created_at"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+}
+
+// WebhookPayload is the body posted to a webhook
+type WebhookPayload struct {
+	Event      WebhookEvent `json:"event"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Data       any          `json:"data"`
+}
+
+// CreateWebhookRequest represents the request body to register a webhook
+type CreateWebhookRequest struct {
+	URL    string         `json:"url"`
+	Events []WebhookEvent `json:"events"`
+}
+
+// UpdateWebhookRequest represents the request body to update a webhook, missing fields are left unchanged
+type UpdateWebhookRequest struct {
+	URL     *string         `json:"url"`
+	Events  *[]WebhookEvent `json:"events"`
+	Enabled *bool           `json:"enabled"`
+}
+
+// WebhookNotFoundError is returned when a webhook does not exist or belongs to another user
+type WebhookNotFoundError struct {
+	UserID    string
+	WebhookID string
+}
+
+func (e *WebhookNotFoundError) Error() string {
+	return fmt.Sprintf("webhook %s not found for user %s", e.WebhookID, e.UserID)
+}
+
+// WebhookDeliveryNotFoundError is returned when a delivery does not belong to the webhook
+type WebhookDeliveryNotFoundError struct {
+	WebhookID  string
+	DeliveryID string
+}
+
+func (e *WebhookDeliveryNotFoundError) Error() string {
+	return fmt.Sprintf("delivery %s not found for webhook %s", e.DeliveryID, e.WebhookID)
+}
+
+// WebhookTargetError is returned when a webhook URL resolves to a loopback, private or otherwise
+// internal address that deliveries are not sent to
+type WebhookTargetError struct {
+	Address string
+}
+
+func (e *WebhookTargetError) Error() string {
+	return fmt.Sprintf("webhook target %s is not a public address", e.Address)
+}
@@ -0,0 +1,51 @@
+package valuation
+
+import "github.com/ecetinerdem/forseer/types"
+
+// ValueHolding computes the market value, cost basis and unrealized gain of a holding at its latest close,
+// converted with the holding's FX rates. Unset rates leave the values in the holding's currency.
+func ValueHolding(stock *types.Stock) {
+	stock.MarketValue = stock.Quantity * stock.Close * rateOrOne(stock.FXRate)
+	stock.CostBasis = stock.Quantity * stock.AverageCost * rateOrOne(stock.CostFXRate)
+	stock.UnrealizedGain = stock.MarketValue - stock.CostBasis
+	stock.UnrealizedGainPct = percentOf(stock.UnrealizedGain, stock.CostBasis)
+}
+
+// ValueHoldings values every holding and its weight in the total, returning the total market value and cost
+func ValueHoldings(stocks []types.Stock) (totalValue, totalCost float64) {
+	for i := range stocks {
+		ValueHolding(&stocks[i])
+		totalValue += stocks[i].MarketValue
+		totalCost += stocks[i].CostBasis
+	}
+
+	for i := range stocks {
+		stocks[i].Weight = 0
+		if totalValue > 0 {
+			stocks[i].Weight = stocks[i].MarketValue / totalValue
+		}
+	}
+
+	return totalValue, totalCost
+}
+
+// ValuePortfolio values every holding of the portfolio and computes the portfolio totals
+func ValuePortfolio(portfolio *types.Portfolio) {
+	portfolio.TotalValue, portfolio.TotalCost = ValueHoldings(portfolio.Stocks)
+	portfolio.UnrealizedGain = portfolio.TotalValue - portfolio.TotalCost
+	portfolio.UnrealizedGainPct = percentOf(portfolio.UnrealizedGain, portfolio.TotalCost)
+}
+
+func rateOrOne(rate float64) float64 {
+	if rate == 0 {
+		return 1
+	}
+	return rate
+}
+
+func percentOf(value, base float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return value / base * 100
+}
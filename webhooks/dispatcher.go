@@ -0,0 +1,290 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of "timestamp.body"
+// keyed with the webhook secret, prefixed with "sha256=".
+const (
+	HeaderEvent     = "X-Forseer-Event"
+	HeaderDelivery  = "X-Forseer-Delivery"
+	HeaderTimestamp = "X-Forseer-Timestamp"
+	HeaderSignature = "X-Forseer-Signature"
+)
+
+// Store is the persistence the dispatcher needs
+type Store interface {
+	GetEventWebhooks(ctx context.Context, userID string, event types.WebhookEvent) ([]types.Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, delivery *types.WebhookDelivery) (*types.WebhookDelivery, error)
+	ClaimWebhookDelivery(ctx context.Context, deliveryID string) (*types.WebhookDelivery, *types.Webhook, error)
+	RecordWebhookAttempt(ctx context.Context, delivery *types.WebhookDelivery) error
+	GetDueWebhookDeliveries(ctx context.Context, limit int) ([]string, error)
+	ResetInFlightWebhookDeliveries(ctx context.Context) error
+}
+
+// Dispatcher delivers events to webhooks in the background. Every delivery is logged before it is
+// sent; failed attempts are retried with exponential backoff until MaxAttempts, and deliveries that
+// could not be queued or were interrupted are picked up again by a periodic sweep.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+
+	MaxAttempts   int           // Attempts before a delivery is marked failed
+	BaseBackoff   time.Duration // Wait before the first retry, doubled after every failure
+	MaxBackoff    time.Duration
+	SweepInterval time.Duration // How often due deliveries are picked up from the store
+	Workers       int
+
+	queue   chan string
+	workers sync.WaitGroup
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		store:         store,
+		client:        newClient(),
+		MaxAttempts:   6,
+		BaseBackoff:   30 * time.Second,
+		MaxBackoff:    time.Hour,
+		SweepInterval: time.Minute,
+		Workers:       4,
+		queue:         make(chan string, 256),
+	}
+}
+
+// Start runs the delivery workers and the sweep until ctx is cancelled.
+// Deliveries left in flight by a previous process are retried.
+func (d *Dispatcher) Start(ctx context.Context) {
+	if err := d.store.ResetInFlightWebhookDeliveries(ctx); err != nil {
+		log.Printf("Webhook dispatcher could not reset in flight deliveries: %v", err)
+	}
+
+	for range d.Workers {
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			d.work(ctx)
+		}()
+	}
+	go d.sweep(ctx)
+}
+
+// Wait blocks until every worker has recorded its last attempt, call it after the Start context is cancelled
+func (d *Dispatcher) Wait() {
+	d.workers.Wait()
+}
+
+// Publish logs a delivery of event to every webhook of the user subscribed to it and queues them.
+// Failures are logged, publishing never fails the caller's request.
+func (d *Dispatcher) Publish(ctx context.Context, userID string, event types.WebhookEvent, data any) {
+	webhooks, err := d.store.GetEventWebhooks(ctx, userID, event)
+	if err != nil {
+		log.Printf("Webhook event %s could not list webhooks: %v", event, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(types.WebhookPayload{Event: event, OccurredAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		log.Printf("Webhook event %s could not be encoded: %v", event, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if _, err := d.Enqueue(ctx, &types.WebhookDelivery{WebhookID: webhook.ID, Event: event, Payload: payload}); err != nil {
+			log.Printf("Webhook event %s could not be queued for webhook %s: %v", event, webhook.ID, err)
+		}
+	}
+}
+
+// Replay queues a new delivery of the payload of a previous one
+func (d *Dispatcher) Replay(ctx context.Context, original *types.WebhookDelivery) (*types.WebhookDelivery, error) {
+	return d.Enqueue(ctx, &types.WebhookDelivery{
+		WebhookID: original.WebhookID,
+		Event:     original.Event,
+		Payload:   original.Payload,
+		ReplayOf:  original.ID,
+	})
+}
+
+// Enqueue logs a delivery and queues it for its first attempt
+func (d *Dispatcher) Enqueue(ctx context.Context, delivery *types.WebhookDelivery) (*types.WebhookDelivery, error) {
+	created, err := d.store.CreateWebhookDelivery(ctx, delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	d.push(created.ID)
+	return created, nil
+}
+
+// push queues a delivery without blocking, a full queue leaves it to the sweep
+func (d *Dispatcher) push(deliveryID string) {
+	select {
+	case d.queue <- deliveryID:
+	default:
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case deliveryID := <-d.queue:
+			d.deliver(ctx, deliveryID)
+		}
+	}
+}
+
+func (d *Dispatcher) sweep(ctx context.Context) {
+	ticker := time.NewTicker(d.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		ids, err := d.store.GetDueWebhookDeliveries(ctx, cap(d.queue))
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Webhook dispatcher could not list due deliveries: %v", err)
+		}
+		for _, id := range ids {
+			d.push(id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver sends a claimed delivery once and schedules a retry when it fails
+func (d *Dispatcher) deliver(ctx context.Context, deliveryID string) {
+	delivery, webhook, err := d.store.ClaimWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		log.Printf("Webhook delivery %s could not be claimed: %v", deliveryID, err)
+		return
+	}
+	if delivery == nil {
+		// Not due, already handled or the webhook was disabled
+		return
+	}
+
+	statusCode, sendErr := d.send(ctx, webhook, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseCode = statusCode
+	delivery.LastError = ""
+	delivery.NextAttemptAt = nil
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = types.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = types.WebhookDeliveryFailed
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.Status = types.WebhookDeliveryPending
+		delivery.LastError = sendErr.Error()
+		next := now.Add(d.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	// Record even when shutting down so the delivery is not left in flight
+	if err := d.store.RecordWebhookAttempt(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Printf("Webhook delivery %s attempt could not be recorded: %v", delivery.ID, err)
+		return
+	}
+
+	if delivery.NextAttemptAt != nil {
+		time.AfterFunc(time.Until(*delivery.NextAttemptAt), func() {
+			if ctx.Err() == nil {
+				d.push(delivery.ID)
+			}
+		})
+	}
+}
+
+// backoff is the wait after the given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.BaseBackoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, d.MaxBackoff)
+}
+
+// send posts the signed payload and returns the response code, any non 2xx response is an error
+func (d *Dispatcher) send(ctx context.Context, webhook *types.Webhook, delivery *types.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "forseer-webhooks/1")
+	req.Header.Set(HeaderEvent, string(delivery.Event))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain a bounded part of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body" keyed with secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature, as sent in the signature header, matches the payload
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	expected := "sha256=" + Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// NewSecret generates a random webhook signing secret
+func NewSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(key), nil
+}
@@ -0,0 +1,242 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ecetinerdem/forseer/types"
+)
+
+// fakeStore holds one webhook and its deliveries in memory
+type fakeStore struct {
+	mu         sync.Mutex
+	webhook    types.Webhook
+	deliveries map[string]types.WebhookDelivery
+}
+
+func newFakeStore(webhook types.Webhook) *fakeStore {
+	return &fakeStore{webhook: webhook, deliveries: make(map[string]types.WebhookDelivery)}
+}
+
+func (s *fakeStore) GetEventWebhooks(ctx context.Context, userID string, event types.WebhookEvent) ([]types.Webhook, error) {
+	if userID != s.webhook.UserID || !s.webhook.Subscribes(event) {
+		return nil, nil
+	}
+	return []types.Webhook{s.webhook}, nil
+}
+
+func (s *fakeStore) CreateWebhookDelivery(ctx context.Context, delivery *types.WebhookDelivery) (*types.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := *delivery
+	created.ID = fmt.Sprintf("d%d", len(s.deliveries)+1)
+	created.Status = types.WebhookDeliveryPending
+	s.deliveries[created.ID] = created
+	return &created, nil
+}
+
+func (s *fakeStore) ClaimWebhookDelivery(ctx context.Context, deliveryID string) (*types.WebhookDelivery, *types.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[deliveryID]
+	if !ok || delivery.Status != types.WebhookDeliveryPending {
+		return nil, nil, nil
+	}
+	delivery.Status = types.WebhookDeliveryDelivering
+	s.deliveries[deliveryID] = delivery
+	webhook := s.webhook
+	return &delivery, &webhook, nil
+}
+
+func (s *fakeStore) RecordWebhookAttempt(ctx context.Context, delivery *types.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (s *fakeStore) GetDueWebhookDeliveries(ctx context.Context, limit int) ([]string, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) ResetInFlightWebhookDeliveries(ctx context.Context) error {
+	return nil
+}
+
+func (s *fakeStore) get(deliveryID string) types.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deliveries[deliveryID]
+}
+
+// received is a request the test receiver got
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		attempts     int // Failed attempts before this one
+		wantStatus   types.WebhookDeliveryStatus
+		wantCode     int
+		wantRetry    bool
+		wantErrorHas string
+	}{
+		{"accepted", http.StatusOK, 0, types.WebhookDeliverySucceeded, http.StatusOK, false, ""},
+		{"accepted with no content", http.StatusNoContent, 2, types.WebhookDeliverySucceeded, http.StatusNoContent, false, ""},
+		{"refused", http.StatusInternalServerError, 0, types.WebhookDeliveryPending, http.StatusInternalServerError, true, "status 500"},
+		{"refused on the last attempt", http.StatusBadGateway, 5, types.WebhookDeliveryFailed, http.StatusBadGateway, false, "status 502"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := make(chan received, 1)
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests <- received{header: r.Header.Clone(), body: body}
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			store := newFakeStore(types.Webhook{ID: "w1", UserID: "u1", URL: receiver.URL + "/hook", Secret: "whsec_test", Enabled: true})
+			dispatcher := NewDispatcher(store)
+			// The receiver listens on loopback, which the delivery client refuses
+			dispatcher.client = receiver.Client()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			dispatcher.Publish(ctx, "u1", types.WebhookEventStockAdded, map[string]string{"symbol": "AAPL"})
+			deliveryID := <-dispatcher.queue
+
+			if tt.attempts > 0 {
+				delivery := store.get(deliveryID)
+				delivery.Attempts = tt.attempts
+				store.RecordWebhookAttempt(ctx, &delivery)
+			}
+
+			before := time.Now()
+			dispatcher.deliver(ctx, deliveryID)
+
+			request := <-requests
+			if got := request.header.Get(HeaderEvent); got != string(types.WebhookEventStockAdded) {
+				t.Errorf("event header %q", got)
+			}
+			if got := request.header.Get(HeaderDelivery); got != deliveryID {
+				t.Errorf("delivery header %q, want %q", got, deliveryID)
+			}
+			if !Verify("whsec_test", request.header.Get(HeaderTimestamp), request.body, request.header.Get(HeaderSignature)) {
+				t.Error("signature does not verify")
+			}
+
+			var payload struct {
+				Event types.WebhookEvent `json:"event"`
+				Data  map[string]string  `json:"data"`
+			}
+			if err := json.Unmarshal(request.body, &payload); err != nil || payload.Event != types.WebhookEventStockAdded || payload.Data["symbol"] != "AAPL" {
+				t.Errorf("unexpected payload %s", request.body)
+			}
+
+			delivery := store.get(deliveryID)
+			if delivery.Status != tt.wantStatus {
+				t.Errorf("status %s, want %s", delivery.Status, tt.wantStatus)
+			}
+			if delivery.Attempts != tt.attempts+1 {
+				t.Errorf("attempts %d, want %d", delivery.Attempts, tt.attempts+1)
+			}
+			if delivery.ResponseCode != tt.wantCode {
+				t.Errorf("response code %d, want %d", delivery.ResponseCode, tt.wantCode)
+			}
+			if !strings.Contains(delivery.LastError, tt.wantErrorHas) || (tt.wantErrorHas == "") != (delivery.LastError == "") {
+				t.Errorf("last error %q, want one containing %q", delivery.LastError, tt.wantErrorHas)
+			}
+			if (delivery.NextAttemptAt != nil) != tt.wantRetry {
+				t.Fatalf("next attempt %v, want a retry %v", delivery.NextAttemptAt, tt.wantRetry)
+			}
+			if tt.wantRetry && delivery.NextAttemptAt.Before(before.Add(dispatcher.backoff(delivery.Attempts))) {
+				t.Errorf("retry at %v is sooner than the backoff", delivery.NextAttemptAt)
+			}
+			if (delivery.DeliveredAt != nil) != (tt.wantStatus == types.WebhookDeliverySucceeded) {
+				t.Errorf("delivered at %v for a %s delivery", delivery.DeliveredAt, delivery.Status)
+			}
+		})
+	}
+}
+
+func TestDeliverRefusesInternalAddresses(t *testing.T) {
+	hit := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit <- struct{}{}
+	}))
+	defer receiver.Close()
+
+	store := newFakeStore(types.Webhook{ID: "w1", UserID: "u1", URL: receiver.URL, Secret: "whsec_test", Enabled: true})
+	dispatcher := NewDispatcher(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dispatcher.Publish(ctx, "u1", types.WebhookEventPing, nil)
+	deliveryID := <-dispatcher.queue
+	dispatcher.deliver(ctx, deliveryID)
+
+	select {
+	case <-hit:
+		t.Fatal("the delivery reached a loopback address")
+	default:
+	}
+
+	delivery := store.get(deliveryID)
+	if delivery.Status != types.WebhookDeliveryPending || !strings.Contains(delivery.LastError, "not a public address") {
+		t.Errorf("delivery %s with error %q, want it pending on a refused address", delivery.Status, delivery.LastError)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"127.8.9.10", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:93.184.216.34", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if got := PublicAddress(netip.MustParseAddr(tt.address)); got != tt.want {
+				t.Errorf("PublicAddress(%s) = %v, want %v", tt.address, got, tt.want)
+			}
+		})
+	}
+}